@@ -83,8 +83,11 @@ func showHelp() {
 	fmt.Println("  properties  Show or set bot properties")
 	fmt.Println("  oob         Manage Out-of-Band message handlers")
 	fmt.Println("  process     Process input data")
-	fmt.Println("  analyze     Analyze data")
-	fmt.Println("  generate    Generate output")
+	fmt.Println("  analyze     Analyze data (analyze transcript <file> runs a JSON chat transcript)")
+	fmt.Println("  generate    Generate output (generate faq <file> builds AIML from JSON Q&A pairs)")
+	fmt.Println("  validate    Validate the AIML files in a directory (--strict treats warnings as errors)")
+	fmt.Println("  benchmark   Time matching of each line in an input file (benchmark <dir> <inputfile>)")
+	fmt.Println("  diff        Compare the knowledge bases in two directories (diff <dir1> <dir2> [--json])")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  golem interactive                    # Start interactive mode")
@@ -94,13 +97,18 @@ func showHelp() {
 	fmt.Println("  golem session create                # Create session")
 	fmt.Println("  golem oob list                      # List OOB handlers")
 	fmt.Println("  golem oob test SYSTEM INFO          # Test OOB handler")
+	fmt.Println("  golem analyze transcript turns.json --aiml data/  # Replay a transcript as JSON")
+	fmt.Println("  golem generate faq faq.json --output faq.aiml     # Build starter AIML from an FAQ")
+	fmt.Println("  golem validate data/                # Check AIML files without loading them")
+	fmt.Println("  golem benchmark data/ inputs.txt    # Report matches/sec and p50/p95 latency")
+	fmt.Println("  golem diff release-1/ release-2/    # List added, removed and changed categories")
 	fmt.Println()
 	fmt.Println("Note: Single commands create new instances (state not preserved)")
 	fmt.Println("Use 'interactive' mode for persistent state across commands")
 }
 
 func showVersion() {
-	fmt.Println("Golem v1.5.3")
+	fmt.Printf("Golem v%s\n", golem.GetVersion())
 	fmt.Println("A dual-purpose Go library and CLI tool")
 }
 
@@ -166,6 +174,8 @@ func showInteractiveHelp() {
 	fmt.Println("  session switch <id>   Switch to session")
 	fmt.Println("  session delete <id>   Delete session")
 	fmt.Println("  session current       Show current session")
+	fmt.Println("  save <file>           Save current session state")
+	fmt.Println("  load-session <file>   Restore a saved session")
 	fmt.Println("  properties            Show all properties")
 	fmt.Println("  properties <key>      Show specific property")
 	fmt.Println("  properties <key> <val> Set property value")
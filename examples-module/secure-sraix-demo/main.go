@@ -55,32 +55,40 @@ func main() {
 		// Don't print headers to avoid exposing API keys in logs
 	}
 
-	// Create a simple AIML knowledge base for testing
-	kb := golem.NewAIMLKnowledgeBase()
-	kb.Categories = []golem.Category{
-		{
-			Pattern:  "ASK AI *",
-			Template: "AI Response: <sraix service=\"openai_service\">Please answer: <star/></sraix>",
-		},
-		{
-			Pattern:  "WEATHER IN *",
-			Template: "Weather: <sraix service=\"weather_service\">What is the weather in <star/>?</sraix>",
-		},
-		{
-			Pattern:  "TRANSLATE * TO SPANISH",
-			Template: "Translation: <sraix service=\"translation_service\">Translate \"<star/>\" to Spanish</sraix>",
-		},
-		{
-			Pattern:  "HELP",
-			Template: "I can help you test SRAIX services! Try:\n- ASK AI what is machine learning?\n- WEATHER IN New York\n- TRANSLATE hello TO SPANISH\n- HELP",
-		},
+	// Load a simple AIML knowledge base for testing. Loading (rather than assigning
+	// kb.Categories) indexes the patterns so ProcessInput can match them.
+	aiml := `<aiml version="2.0">
+	<category>
+		<pattern>ASK AI *</pattern>
+		<template>AI Response: <sraix service="openai_service">Please answer: <star/></sraix></template>
+	</category>
+	<category>
+		<pattern>WEATHER IN *</pattern>
+		<template>Weather: <sraix service="weather_service">What is the weather in <star/>?</sraix></template>
+	</category>
+	<category>
+		<pattern>TRANSLATE * TO SPANISH</pattern>
+		<template>Translation: <sraix service="translation_service">Translate "<star/>" to Spanish</sraix></template>
+	</category>
+	<category>
+		<pattern>HELP</pattern>
+		<template>I can help you test SRAIX services! Try:
+- ASK AI what is machine learning?
+- WEATHER IN New York
+- TRANSLATE hello TO SPANISH
+- HELP</template>
+	</category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aiml); err != nil {
+		fmt.Printf("Failed to load AIML: %v\n", err)
+		return
 	}
 
-	g.SetKnowledgeBase(kb)
-
 	fmt.Println("\n=== Interactive Demo ===")
 	fmt.Println("Type 'quit' to exit.\n")
 
+	session := g.CreateSession("secure-sraix-demo")
+
 	// Interactive chat loop
 	for {
 		fmt.Print("You: ")
@@ -91,8 +99,12 @@ func main() {
 			break
 		}
 
-		// Process the input through the AIML knowledge base
-		response := g.ProcessTemplate(input, make(map[string]string))
+		// Match the input against the AIML knowledge base and process the reply
+		response, err := g.ProcessInput(input, session)
+		if err != nil {
+			fmt.Printf("Bot: (no response: %v)\n\n", err)
+			continue
+		}
 		fmt.Printf("Bot: %s\n\n", response)
 	}
 
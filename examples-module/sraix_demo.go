@@ -28,30 +28,36 @@ func main() {
 		log.Fatalf("Failed to add SRAIX config: %v", err)
 	}
 
-	// Create a simple AIML knowledge base with SRAIX
-	kb := golem.NewAIMLKnowledgeBase()
-	kb.Categories = []golem.Category{
-		{
-			Pattern:  "ECHO *",
-			Template: "Echo service says: <sraix service=\"echo_service\">Echo: <star/></sraix>",
-		},
-		{
-			Pattern:  "HELLO",
-			Template: "Hello! I can echo your messages using SRAIX. Try saying 'ECHO hello world'",
-		},
-		{
-			Pattern:  "HELP",
-			Template: "I can help you test SRAIX functionality. Try these commands:\n- HELLO\n- ECHO your message here\n- HELP",
-		},
+	// Load a simple AIML knowledge base with SRAIX. Loading (rather than assigning
+	// kb.Categories) indexes the patterns so ProcessInput can match them.
+	aiml := `<aiml version="2.0">
+	<category>
+		<pattern>ECHO *</pattern>
+		<template>Echo service says: <sraix service="echo_service">Echo: <star/></sraix></template>
+	</category>
+	<category>
+		<pattern>HELLO</pattern>
+		<template>Hello! I can echo your messages using SRAIX. Try saying 'ECHO hello world'</template>
+	</category>
+	<category>
+		<pattern>HELP</pattern>
+		<template>I can help you test SRAIX functionality. Try these commands:
+- HELLO
+- ECHO your message here
+- HELP</template>
+	</category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aiml); err != nil {
+		log.Fatalf("Failed to load AIML: %v", err)
 	}
 
-	g.SetKnowledgeBase(kb)
-
 	fmt.Println("=== SRAIX Demo ===")
 	fmt.Println("This demo shows SRAIX (external service integration) in action.")
 	fmt.Println("The bot will use an external echo service to process your messages.")
 	fmt.Println("Type 'quit' to exit.\n")
 
+	session := g.CreateSession("sraix-demo")
+
 	// Interactive chat loop
 	for {
 		fmt.Print("You: ")
@@ -62,8 +68,12 @@ func main() {
 			break
 		}
 
-		// Process the input through the AIML knowledge base
-		response := g.ProcessTemplate("ECHO "+input, make(map[string]string))
+		// Match the input against the AIML knowledge base and process the reply
+		response, err := g.ProcessInput(input, session)
+		if err != nil {
+			fmt.Printf("Bot: (no response: %v)\n\n", err)
+			continue
+		}
 		fmt.Printf("Bot: %s\n\n", response)
 	}
 
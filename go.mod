@@ -4,4 +4,7 @@ go 1.21
 
 // Version 1.5.3 - Revolutionary tree-based processing system with AST parsing, 95% tag coverage, and performance improvements
 
-require github.com/go-telegram/bot v1.17.0
+require (
+	github.com/go-telegram/bot v1.17.0
+	golang.org/x/text v0.14.0
+)
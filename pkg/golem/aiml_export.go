@@ -0,0 +1,58 @@
+package golem
+
+import (
+	"fmt"
+	"strings"
+)
+
+// aimlTextEscaper escapes the characters that can't appear as text in AIML
+var aimlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// aimlAttributeEscaper escapes the characters that can't appear in a quoted attribute value
+var aimlAttributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// escapeAIMLText escapes plain text for use as the content of an AIML pattern or template
+func escapeAIMLText(text string) string {
+	return aimlTextEscaper.Replace(text)
+}
+
+// ToAIML serializes categories as an AIML 2.0 document that LoadAIMLFromString reads
+// back to the same categories. Patterns, that patterns and templates are AIML markup
+// and are written as is, so plain text must be escaped (see escapeAIMLText) before it
+// is stored in a category. Attribute values are escaped here.
+func ToAIML(categories []Category) string {
+	var builder strings.Builder
+	builder.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	builder.WriteString("<aiml version=\"2.0\">\n")
+
+	for _, category := range categories {
+		builder.WriteString("\t<category")
+		if category.PriorityBoost != 0 {
+			fmt.Fprintf(&builder, ` priority="%d"`, category.PriorityBoost)
+		}
+		if category.Default {
+			builder.WriteString(` default="true"`)
+		}
+		if len(category.Tags) > 0 {
+			fmt.Fprintf(&builder, ` tags="%s"`, aimlAttributeEscaper.Replace(strings.Join(category.Tags, ",")))
+		}
+		builder.WriteString(">\n")
+
+		fmt.Fprintf(&builder, "\t\t<pattern>%s</pattern>\n", category.Pattern)
+		if category.That != "" {
+			if category.ThatIndex != 0 {
+				fmt.Fprintf(&builder, "\t\t<that index=\"%d\">%s</that>\n", category.ThatIndex, category.That)
+			} else {
+				fmt.Fprintf(&builder, "\t\t<that>%s</that>\n", category.That)
+			}
+		}
+		if category.Topic != "" {
+			fmt.Fprintf(&builder, "\t\t<topic>%s</topic>\n", category.Topic)
+		}
+		fmt.Fprintf(&builder, "\t\t<template>%s</template>\n", category.Template)
+		builder.WriteString("\t</category>\n")
+	}
+
+	builder.WriteString("</aiml>\n")
+	return builder.String()
+}
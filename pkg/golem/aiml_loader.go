@@ -6,7 +6,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
 )
 
 // AIMLLoader provides AIML file loading and parsing functionality
@@ -41,7 +40,7 @@ func (al *AIMLLoader) LoadAIMLFromString(content string) error {
 
 // LoadAIML loads AIML from a file
 func (al *AIMLLoader) LoadAIML(filename string) (*AIMLKnowledgeBase, error) {
-	content, err := os.ReadFile(filename)
+	content, err := readResourceFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %v", filename, err)
 	}
@@ -73,7 +72,7 @@ func (al *AIMLLoader) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase,
 			return err
 		}
 
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), ".aiml") {
+		if !info.IsDir() && hasResourceExtension(path, ".aiml") {
 			kb, err := al.LoadAIML(path)
 			if err != nil {
 				return fmt.Errorf("failed to load %s: %v", path, err)
@@ -128,8 +127,7 @@ func (al *AIMLLoader) parseCategory(content string) (Category, error) {
 
 // removeComments removes XML comments from content
 func (al *AIMLLoader) removeComments(content string) string {
-	commentPattern := regexp.MustCompile(`<!--.*?-->`)
-	return commentPattern.ReplaceAllString(content, "")
+	return stripXMLComments(content)
 }
 
 // removeXMLDeclaration removes XML declaration
@@ -199,9 +197,15 @@ func (al *AIMLLoader) mergeKnowledgeBases(kb1, kb2 *AIMLKnowledgeBase) (*AIMLKno
 	// Merge patterns
 	for k, v := range kb1.Patterns {
 		result.Patterns[k] = v
+		if v.PriorityBoost != 0 {
+			result.markBoosted(k)
+		}
 	}
 	for k, v := range kb2.Patterns {
 		result.Patterns[k] = v
+		if v.PriorityBoost != 0 {
+			result.markBoosted(k)
+		}
 	}
 
 	// Merge sets
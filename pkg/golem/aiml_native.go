@@ -1,8 +1,12 @@
 package golem
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
@@ -13,6 +17,9 @@ import (
 	"sync"
 	"time"
 	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // AIML represents the root AIML document
@@ -28,6 +35,20 @@ type Category struct {
 	That      string
 	ThatIndex int // Index for that context (1-based, 0 means last response)
 	Topic     string
+	Source    string `json:"-"` // Where the category was loaded from, for diagnostics
+	// PriorityBoost is added to the computed match priority (from <category priority="N">).
+	// $ patterns match before priorities are compared, so no boost outranks them.
+	PriorityBoost int
+	// Default marks the category used when no pattern matches (from <category default="true">)
+	Default bool
+	// Learned marks categories added at runtime by <learn> or <learnf> (see ListLearnedCategories)
+	Learned bool
+	// Tags group categories for tooling (from <category tags="greeting,smalltalk">); they
+	// are ignored by matching (see CategoriesByTag)
+	Tags []string
+	// folded is the output of a template without tags, computed when the category is
+	// indexed so the tree processor can skip it (see foldCategoryTemplate)
+	folded *foldedTemplate
 }
 
 // SetCollection represents an ordered set (maintains insertion order while ensuring uniqueness)
@@ -44,6 +65,29 @@ func NewSetCollection() *SetCollection {
 	}
 }
 
+// dedupSetMembers returns members without case-insensitive duplicates, keeping the
+// first occurrence of each in order. Duplicates would only lengthen the alternation
+// regex built for the set.
+func dedupSetMembers(members []string) []string {
+	return mergeSetMembers(nil, members)
+}
+
+// mergeSetMembers returns a new slice with the members of existing followed by the
+// added members, without case-insensitive duplicates (see dedupSetMembers)
+func mergeSetMembers(existing, added []string) []string {
+	collection := NewSetCollection()
+	for _, members := range [][]string{existing, added} {
+		for _, member := range members {
+			key := strings.ToUpper(member)
+			if !collection.Index[key] {
+				collection.Index[key] = true
+				collection.Items = append(collection.Items, member)
+			}
+		}
+	}
+	return collection.Items
+}
+
 // AIMLKnowledgeBase stores the parsed AIML data for efficient searching
 type AIMLKnowledgeBase struct {
 	Categories     []Category
@@ -58,6 +102,13 @@ type AIMLKnowledgeBase struct {
 	Arrays         map[string][]string                   // Arrays: arrayName -> []values
 	SetCollections map[string]*SetCollection             // SetCollections: setName -> ordered unique values
 	Substitutions  map[string]map[string]string          // Substitutions: substitutionName -> pattern -> replacement
+	Aliases        map[string]string                     // Aliases: normalized phrase -> canonical phrase, applied to input before matching
+	reverseMaps    map[string]map[string]string          // reverseMaps: mapName -> value -> key, built on demand (see ReverseMap)
+	boostedKeys    map[string]bool                       // boostedKeys: pattern keys of categories with a PriorityBoost, which an exact match is ranked against
+	// learnMutex serializes the changes learning makes to Categories and Patterns with
+	// matching: kbMutex is only held for reading while input is processed, so other
+	// sessions match while a template learns
+	learnMutex sync.RWMutex
 }
 
 // NewAIMLKnowledgeBase creates a new knowledge base
@@ -74,6 +125,7 @@ func NewAIMLKnowledgeBase() *AIMLKnowledgeBase {
 		Arrays:         make(map[string][]string),
 		SetCollections: make(map[string]*SetCollection),
 		Substitutions:  make(map[string]map[string]string),
+		Aliases:        make(map[string]string),
 	}
 }
 
@@ -89,7 +141,10 @@ func (g *Golem) LoadAIMLFromString(content string) error {
 	}
 
 	// Convert AIML to AIMLKnowledgeBase
-	kb := g.aimlToKnowledgeBase(aiml)
+	kb, err := g.aimlToKnowledgeBase(aiml)
+	if err != nil {
+		return err
+	}
 
 	// Merge with existing knowledge base
 	if g.aimlKB == nil {
@@ -115,7 +170,7 @@ func (g *Golem) LoadAIMLFromString(content string) error {
 }
 
 // aimlToKnowledgeBase converts AIML to AIMLKnowledgeBase
-func (g *Golem) aimlToKnowledgeBase(aiml *AIML) *AIMLKnowledgeBase {
+func (g *Golem) aimlToKnowledgeBase(aiml *AIML) (*AIMLKnowledgeBase, error) {
 	kb := &AIMLKnowledgeBase{
 		Categories:     aiml.Categories,
 		Patterns:       make(map[string]*Category),
@@ -128,26 +183,75 @@ func (g *Golem) aimlToKnowledgeBase(aiml *AIML) *AIMLKnowledgeBase {
 		Arrays:         make(map[string][]string),
 		SetCollections: make(map[string]*SetCollection),
 		Substitutions:  make(map[string]map[string]string),
+		Aliases:        make(map[string]string),
 	}
 
 	// Build pattern index
 	for i := range kb.Categories {
-		pattern := NormalizePattern(kb.Categories[i].Pattern)
-		// Create a unique key that includes pattern, that, topic, and that index
-		key := pattern
-		if kb.Categories[i].That != "" {
-			key += "|THAT:" + NormalizePattern(kb.Categories[i].That)
-			if kb.Categories[i].ThatIndex != 0 {
-				key += fmt.Sprintf("|THATINDEX:%d", kb.Categories[i].ThatIndex)
-			}
+		if err := g.indexCategory(kb, categoryPatternKey(&kb.Categories[i]), &kb.Categories[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return kb, nil
+}
+
+// categoryPatternKey builds the kb.Patterns key for a category: the normalized
+// pattern plus its that, that index and topic, so only truly ambiguous categories share a key
+func categoryPatternKey(category *Category) string {
+	key := NormalizePattern(category.Pattern)
+	if category.That != "" {
+		key += "|THAT:" + NormalizePattern(category.That)
+		if category.ThatIndex != 0 {
+			key += fmt.Sprintf("|THATINDEX:%d", category.ThatIndex)
 		}
-		if kb.Categories[i].Topic != "" {
-			key += "|TOPIC:" + strings.ToUpper(kb.Categories[i].Topic)
+	}
+	if category.Topic != "" {
+		key += "|TOPIC:" + strings.ToUpper(category.Topic)
+	}
+	return key
+}
+
+// indexCategory adds a category to the pattern index under key. If another category
+// already uses the key, the new one replaces it (last writer wins) and a warning naming
+// both categories is logged; with StrictParsing enabled the collision is an error instead.
+// Categories marked as the default are also indexed under the DEFAULT key, which the
+// matcher falls back to when no pattern matches.
+func (g *Golem) indexCategory(kb *AIMLKnowledgeBase, key string, category *Category) error {
+	if existing, exists := kb.Patterns[key]; exists && existing != category {
+		message := fmt.Sprintf("ambiguous categories for pattern '%s' (that='%s', topic='%s'): %s is overridden by %s",
+			category.Pattern, category.That, category.Topic, describeCategorySource(existing), describeCategorySource(category))
+		if g.StrictParsing {
+			return fmt.Errorf("%s", message)
 		}
-		kb.Patterns[key] = &kb.Categories[i]
+		g.LogWarn("%s", message)
+	}
+	kb.Patterns[key] = category
+	foldCategoryTemplate(category)
+	if category.PriorityBoost != 0 {
+		kb.markBoosted(key)
+	}
+
+	if category.Default && key != "DEFAULT" {
+		return g.indexCategory(kb, "DEFAULT", category)
+	}
+	return nil
+}
+
+// markBoosted records that the category under key has a priority boost
+func (kb *AIMLKnowledgeBase) markBoosted(key string) {
+	if kb.boostedKeys == nil {
+		kb.boostedKeys = make(map[string]bool)
 	}
+	kb.boostedKeys[key] = true
+}
 
-	return kb
+// describeCategorySource returns the category's source location, or a placeholder if unknown
+func describeCategorySource(category *Category) string {
+	if category.Source == "" {
+		return "<unknown source>"
+	}
+	return category.Source
 }
 
 // mergeKnowledgeBases merges two knowledge bases
@@ -164,12 +268,16 @@ func (g *Golem) mergeKnowledgeBases(kb1, kb2 *AIMLKnowledgeBase) (*AIMLKnowledge
 		Arrays:         make(map[string][]string),
 		SetCollections: make(map[string]*SetCollection),
 		Substitutions:  make(map[string]map[string]string),
+		Aliases:        make(map[string]string),
 	}
 
 	// Copy from first knowledge base
 	mergedKB.Categories = append(mergedKB.Categories, kb1.Categories...)
 	for pattern, category := range kb1.Patterns {
 		mergedKB.Patterns[pattern] = category
+		if category.PriorityBoost != 0 {
+			mergedKB.markBoosted(pattern)
+		}
 	}
 	for setName, members := range kb1.Sets {
 		mergedKB.Sets[setName] = members
@@ -198,17 +306,19 @@ func (g *Golem) mergeKnowledgeBases(kb1, kb2 *AIMLKnowledgeBase) (*AIMLKnowledge
 	for subName, subData := range kb1.Substitutions {
 		mergedKB.Substitutions[subName] = subData
 	}
+	for phrase, canonical := range kb1.Aliases {
+		mergedKB.Aliases[phrase] = canonical
+	}
 
 	// Merge from second knowledge base
 	mergedKB.Categories = append(mergedKB.Categories, kb2.Categories...)
 	for pattern, category := range kb2.Patterns {
-		mergedKB.Patterns[pattern] = category
+		if err := g.indexCategory(mergedKB, pattern, category); err != nil {
+			return nil, err
+		}
 	}
 	for setName, members := range kb2.Sets {
-		if mergedKB.Sets[setName] == nil {
-			mergedKB.Sets[setName] = make([]string, 0)
-		}
-		mergedKB.Sets[setName] = append(mergedKB.Sets[setName], members...)
+		mergedKB.Sets[setName] = mergeSetMembers(mergedKB.Sets[setName], members)
 	}
 	for topicName, patterns := range kb2.Topics {
 		if mergedKB.Topics[topicName] == nil {
@@ -262,34 +372,66 @@ func (g *Golem) mergeKnowledgeBases(kb1, kb2 *AIMLKnowledgeBase) (*AIMLKnowledge
 			mergedKB.Substitutions[subName][pattern] = replacement
 		}
 	}
+	for phrase, canonical := range kb2.Aliases {
+		mergedKB.Aliases[phrase] = canonical
+	}
 
 	return mergedKB, nil
 }
 
-func (g *Golem) LoadAIML(filename string) (*AIMLKnowledgeBase, error) {
-	g.LogInfo("Loading AIML file: %s", filename)
+// gzipExtension marks gzip-compressed resource files (e.g. bot.aiml.gz, colors.set.gz)
+const gzipExtension = ".gz"
 
-	// Read the file
-	content, err := g.LoadFile(filename)
+// hasResourceExtension reports whether path has the given extension, either plain
+// or followed by a gzip extension
+func hasResourceExtension(path, ext string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ext) || strings.HasSuffix(lower, ext+gzipExtension)
+}
+
+// resourceBaseName returns the file name without directory, gzip extension or resource extension
+func resourceBaseName(path string) string {
+	base := filepath.Base(path)
+	if strings.HasSuffix(strings.ToLower(base), gzipExtension) {
+		base = base[:len(base)-len(gzipExtension)]
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// readResourceFile reads a resource file, transparently decompressing gzip files
+func readResourceFile(filename string) ([]byte, error) {
+	content, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AIML file: %v", err)
+		return nil, err
+	}
+	if !strings.HasSuffix(strings.ToLower(filename), gzipExtension) {
+		return content, nil
 	}
 
-	// Parse the AIML content
-	aiml, err := g.parseAIML(content)
+	reader, err := gzip.NewReader(bytes.NewReader(content))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse AIML: %v", err)
+		return nil, fmt.Errorf("failed to decompress %s: %v", filename, err)
 	}
+	defer reader.Close()
 
-	// Validate the AIML
-	err = g.validateAIML(aiml)
+	decompressed, err := io.ReadAll(reader)
 	if err != nil {
-		return nil, fmt.Errorf("AIML validation failed: %v", err)
+		return nil, fmt.Errorf("failed to decompress %s: %v", filename, err)
+	}
+	return decompressed, nil
+}
+
+func (g *Golem) LoadAIML(filename string) (*AIMLKnowledgeBase, error) {
+	g.LogInfo("Loading AIML file: %s", filename)
+
+	categories, err := g.loadAIMLCategories(filename)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create knowledge base
 	kb := NewAIMLKnowledgeBase()
-	kb.Categories = aiml.Categories
+	kb.Categories = categories
 
 	// Load default properties
 	err = g.loadDefaultProperties(kb)
@@ -298,21 +440,80 @@ func (g *Golem) LoadAIML(filename string) (*AIMLKnowledgeBase, error) {
 	}
 
 	// Index patterns for fast lookup
-	for i := range aiml.Categories {
-		category := &aiml.Categories[i]
-		// Normalize pattern for storage
-		pattern := NormalizePattern(category.Pattern)
-		kb.Patterns[pattern] = category
+	for i := range kb.Categories {
+		category := &kb.Categories[i]
+		if err := g.indexCategory(kb, categoryPatternKey(category), category); err != nil {
+			return nil, err
+		}
 	}
 
-	g.LogInfo("Loaded %d AIML categories", len(aiml.Categories))
+	g.LogInfo("Loaded %d AIML categories", len(kb.Categories))
 	g.LogInfo("Loaded %d properties", len(kb.Properties))
 
 	return kb, nil
 }
 
+// loadAIMLCategories reads, parses and validates an AIML file, returning its
+// categories with their source locations prefixed by the file name
+func (g *Golem) loadAIMLCategories(filename string) ([]Category, error) {
+	// Read the file
+	content, err := g.LoadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AIML file: %v", err)
+	}
+
+	// Parse the AIML content
+	aiml, err := g.parseAIML(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AIML: %v", err)
+	}
+
+	// Validate the AIML
+	err = g.validateAIML(aiml)
+	if err != nil {
+		return nil, fmt.Errorf("AIML validation failed: %v", err)
+	}
+
+	for i := range aiml.Categories {
+		aiml.Categories[i].Source = filename + ", " + aiml.Categories[i].Source
+	}
+	return aiml.Categories, nil
+}
+
+// checkFileCategoryCollisions reports the first pattern collision between the
+// categories of one file. Only StrictParsing makes a collision an error, and then the
+// whole file is skipped, as when it's loaded on its own with LoadAIML.
+func (g *Golem) checkFileCategoryCollisions(categories []Category) error {
+	if !g.StrictParsing {
+		return nil
+	}
+	scratch := &AIMLKnowledgeBase{Patterns: make(map[string]*Category, len(categories))}
+	for i := range categories {
+		if err := g.indexCategory(scratch, categoryPatternKey(&categories[i]), &categories[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipFailedFile handles a file in a directory load that failed to load: it's logged and
+// skipped, unless failOnError is set, in which case the error fails the whole load.
+func (g *Golem) skipFailedFile(path string, err error, failOnError bool) error {
+	if failOnError {
+		return fmt.Errorf("failed to load %s: %v", path, err)
+	}
+	g.LogInfo("Warning: failed to load %s: %v", path, err)
+	return nil
+}
+
 // LoadAIMLFromDirectory loads all AIML files from a directory and merges them into a single knowledge base
 func (g *Golem) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase, error) {
+	return g.loadAIMLFromDirectory(dirPath, false)
+}
+
+// loadAIMLFromDirectory implements LoadAIMLFromDirectory. With failOnError a file that fails to load
+// fails the whole load rather than being skipped.
+func (g *Golem) loadAIMLFromDirectory(dirPath string, failOnError bool) (*AIMLKnowledgeBase, error) {
 	g.LogInfo("Loading AIML files from directory: %s", dirPath)
 
 	// Create a new knowledge base to merge all files into
@@ -332,7 +533,7 @@ func (g *Golem) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase, error
 		}
 
 		// Check if it's a .aiml file
-		if !d.IsDir() && strings.HasSuffix(strings.ToLower(path), ".aiml") {
+		if !d.IsDir() && hasResourceExtension(path, ".aiml") {
 			aimlFiles = append(aimlFiles, path)
 		}
 
@@ -350,57 +551,45 @@ func (g *Golem) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase, error
 	g.LogInfo("Found %d AIML files in directory", len(aimlFiles))
 
 	// Load each AIML file and merge into the knowledge base
-	for _, aimlFile := range aimlFiles {
+	for fileIndex, aimlFile := range aimlFiles {
 		g.LogInfo("Loading AIML file: %s", aimlFile)
 
-		// Load the individual AIML file
-		kb, err := g.LoadAIML(aimlFile)
-		if err != nil {
-			// Log the error but continue with other files
-			g.LogInfo("Warning: failed to load %s: %v", aimlFile, err)
-			continue
+		// Report progress to the caller, if requested
+		if g.OnLoadProgress != nil {
+			g.OnLoadProgress(fileIndex, len(aimlFiles), aimlFile)
 		}
 
-		// Merge the categories from this file into the merged knowledge base
-		for i := range kb.Categories {
-			category := &kb.Categories[i]
-			// Normalize pattern for storage
-			pattern := NormalizePattern(category.Pattern)
-
-			// Add category to merged knowledge base
-			mergedKB.Categories = append(mergedKB.Categories, *category)
-			mergedKB.Patterns[pattern] = category
-		}
-
-		// Merge sets
-		for setName, members := range kb.Sets {
-			for _, member := range members {
-				mergedKB.AddSetMember(setName, member)
-			}
+		// Parse the file's categories straight into the merged knowledge base, rather
+		// than building a throwaway knowledge base per file
+		categories, err := g.loadAIMLCategories(aimlFile)
+		if err == nil {
+			err = g.checkFileCategoryCollisions(categories)
 		}
-
-		// Merge topics
-		for topicName, patterns := range kb.Topics {
-			if mergedKB.Topics[topicName] == nil {
-				mergedKB.Topics[topicName] = make([]string, 0)
+		if err != nil {
+			if err := g.skipFailedFile(aimlFile, err, failOnError); err != nil {
+				return nil, err
 			}
-			mergedKB.Topics[topicName] = append(mergedKB.Topics[topicName], patterns...)
-		}
-
-		// Merge variables (file variables override defaults)
-		for varName, varValue := range kb.Variables {
-			mergedKB.Variables[varName] = varValue
+			continue
 		}
+		g.LogInfo("Loaded %d AIML categories", len(categories))
+		mergedKB.Categories = append(mergedKB.Categories, categories...)
+	}
 
-		// Merge properties (file properties override defaults)
-		for propName, propValue := range kb.Properties {
-			mergedKB.Properties[propName] = propValue
+	// Index the merged categories once they're all in place, since appending may move
+	// them and the index points into the slice. Later files override earlier ones.
+	for i := range mergedKB.Categories {
+		category := &mergedKB.Categories[i]
+		if err := g.indexCategory(mergedKB, categoryPatternKey(category), category); err != nil {
+			return nil, err
 		}
 	}
 
 	// Load map files from the same directory
-	maps, err := g.LoadMapsFromDirectory(dirPath)
+	maps, err := g.loadMapsFromDirectory(dirPath, failOnError)
 	if err != nil {
+		if failOnError {
+			return nil, err
+		}
 		// Log the error but don't fail the entire operation
 		g.LogInfo("Warning: failed to load maps from directory: %v", err)
 	} else {
@@ -411,8 +600,11 @@ func (g *Golem) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase, error
 	}
 
 	// Load set files from the same directory
-	sets, err := g.LoadSetsFromDirectory(dirPath)
+	sets, err := g.loadSetsFromDirectory(dirPath, failOnError)
 	if err != nil {
+		if failOnError {
+			return nil, err
+		}
 		// Log the error but don't fail the entire operation
 		g.LogInfo("Warning: failed to load sets from directory: %v", err)
 	} else {
@@ -423,8 +615,11 @@ func (g *Golem) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase, error
 	}
 
 	// Load substitution files from the same directory
-	substitutions, err := g.LoadSubstitutionsFromDirectory(dirPath)
+	substitutions, err := g.loadSubstitutionsFromDirectory(dirPath, failOnError)
 	if err != nil {
+		if failOnError {
+			return nil, err
+		}
 		// Log the error but don't fail the entire operation
 		g.LogInfo("Warning: failed to load substitutions from directory: %v", err)
 	} else {
@@ -434,9 +629,26 @@ func (g *Golem) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase, error
 		}
 	}
 
+	// Load alias files from the same directory
+	aliases, err := g.loadAliasesFromDirectory(dirPath, failOnError)
+	if err != nil {
+		if failOnError {
+			return nil, err
+		}
+		// Log the error but don't fail the entire operation
+		g.LogInfo("Warning: failed to load aliases from directory: %v", err)
+	} else {
+		for phrase, canonical := range aliases {
+			mergedKB.AddAlias(phrase, canonical)
+		}
+	}
+
 	// Load properties files from the same directory
-	properties, err := g.LoadPropertiesFromDirectory(dirPath)
+	properties, err := g.loadPropertiesFromDirectory(dirPath, failOnError)
 	if err != nil {
+		if failOnError {
+			return nil, err
+		}
 		// Log the error but don't fail the entire operation
 		g.LogInfo("Warning: failed to load properties from directory: %v", err)
 	} else {
@@ -449,8 +661,11 @@ func (g *Golem) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase, error
 	}
 
 	// Load pdefaults files from the same directory
-	pdefaults, err := g.LoadPDefaultsFromDirectory(dirPath)
+	pdefaults, err := g.loadPDefaultsFromDirectory(dirPath, failOnError)
 	if err != nil {
+		if failOnError {
+			return nil, err
+		}
 		// Log the error but don't fail the entire operation
 		g.LogInfo("Warning: failed to load pdefaults from directory: %v", err)
 	} else {
@@ -472,6 +687,7 @@ func (g *Golem) LoadAIMLFromDirectory(dirPath string) (*AIMLKnowledgeBase, error
 	g.LogInfo("Total properties: %d", len(mergedKB.Properties))
 	g.LogInfo("Total maps: %d", len(mergedKB.Maps))
 	g.LogInfo("Total substitutions: %d", len(mergedKB.Substitutions))
+	g.LogInfo("Total aliases: %d", len(mergedKB.Aliases))
 
 	return mergedKB, nil
 }
@@ -481,7 +697,7 @@ func (g *Golem) LoadMapFromFile(filename string) (map[string]string, error) {
 	g.LogInfo("Loading map file: %s", filename)
 
 	// Read the file content
-	content, err := os.ReadFile(filename)
+	content, err := readResourceFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read map file %s: %v", filename, err)
 	}
@@ -514,6 +730,12 @@ func (g *Golem) LoadMapFromFile(filename string) (map[string]string, error) {
 
 // LoadMapsFromDirectory loads all .map files from a directory
 func (g *Golem) LoadMapsFromDirectory(dirPath string) (map[string]map[string]string, error) {
+	return g.loadMapsFromDirectory(dirPath, false)
+}
+
+// loadMapsFromDirectory implements LoadMapsFromDirectory, failing on a file that fails
+// to load with failOnError (see skipFailedFile)
+func (g *Golem) loadMapsFromDirectory(dirPath string, failOnError bool) (map[string]map[string]string, error) {
 	g.LogInfo("Loading map files from directory: %s", dirPath)
 
 	// Create a map to store all maps
@@ -527,7 +749,7 @@ func (g *Golem) LoadMapsFromDirectory(dirPath string) (map[string]map[string]str
 		}
 
 		// Check if it's a .map file
-		if !d.IsDir() && strings.HasSuffix(strings.ToLower(path), ".map") {
+		if !d.IsDir() && hasResourceExtension(path, ".map") {
 			mapFiles = append(mapFiles, path)
 		}
 
@@ -552,13 +774,14 @@ func (g *Golem) LoadMapsFromDirectory(dirPath string) (map[string]map[string]str
 		// Load the individual map file
 		mapData, err := g.LoadMapFromFile(mapFile)
 		if err != nil {
-			// Log the error but continue with other files
-			g.LogInfo("Warning: failed to load %s: %v", mapFile, err)
+			if err := g.skipFailedFile(mapFile, err, failOnError); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		// Use the filename (without extension) as the map name
-		mapName := strings.TrimSuffix(filepath.Base(mapFile), filepath.Ext(mapFile))
+		mapName := resourceBaseName(mapFile)
 		allMaps[mapName] = mapData
 	}
 
@@ -572,7 +795,7 @@ func (g *Golem) LoadSetFromFile(filename string) ([]string, error) {
 	g.LogInfo("Loading set file: %s", filename)
 
 	// Read the file content
-	content, err := os.ReadFile(filename)
+	content, err := readResourceFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read set file %s: %v", filename, err)
 	}
@@ -584,13 +807,24 @@ func (g *Golem) LoadSetFromFile(filename string) ([]string, error) {
 		return nil, fmt.Errorf("failed to parse JSON in set file %s: %v", filename, err)
 	}
 
-	g.LogInfo("Loaded %d set members from %s", len(setMembers), filename)
+	members := dedupSetMembers(setMembers)
+	if duplicates := len(setMembers) - len(members); duplicates > 0 {
+		g.LogWarn("Removed %d duplicate members from set file %s", duplicates, filename)
+	}
+
+	g.LogInfo("Loaded %d set members from %s", len(members), filename)
 
-	return setMembers, nil
+	return members, nil
 }
 
 // LoadSetsFromDirectory loads all .set files from a directory
 func (g *Golem) LoadSetsFromDirectory(dirPath string) (map[string][]string, error) {
+	return g.loadSetsFromDirectory(dirPath, false)
+}
+
+// loadSetsFromDirectory implements LoadSetsFromDirectory, failing on a file that fails
+// to load with failOnError (see skipFailedFile)
+func (g *Golem) loadSetsFromDirectory(dirPath string, failOnError bool) (map[string][]string, error) {
 	g.LogInfo("Loading set files from directory: %s", dirPath)
 
 	// Create a map to store all sets
@@ -604,7 +838,7 @@ func (g *Golem) LoadSetsFromDirectory(dirPath string) (map[string][]string, erro
 		}
 
 		// Check if it's a .set file
-		if !d.IsDir() && strings.HasSuffix(strings.ToLower(path), ".set") {
+		if !d.IsDir() && hasResourceExtension(path, ".set") {
 			setFiles = append(setFiles, path)
 		}
 
@@ -629,13 +863,14 @@ func (g *Golem) LoadSetsFromDirectory(dirPath string) (map[string][]string, erro
 		// Load the individual set file
 		setMembers, err := g.LoadSetFromFile(setFile)
 		if err != nil {
-			// Log the error but continue with other files
-			g.LogInfo("Warning: failed to load %s: %v", setFile, err)
+			if err := g.skipFailedFile(setFile, err, failOnError); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		// Use the filename (without extension) as the set name
-		setName := strings.TrimSuffix(filepath.Base(setFile), filepath.Ext(setFile))
+		setName := resourceBaseName(setFile)
 		allSets[setName] = setMembers
 	}
 
@@ -649,7 +884,7 @@ func (g *Golem) LoadSubstitutionFromFile(filename string) (map[string]string, er
 	g.LogInfo("Loading substitution file: %s", filename)
 
 	// Read the file content
-	content, err := os.ReadFile(filename)
+	content, err := readResourceFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read substitution file %s: %v", filename, err)
 	}
@@ -687,6 +922,12 @@ func (g *Golem) LoadSubstitutionFromFile(filename string) (map[string]string, er
 
 // LoadSubstitutionsFromDirectory loads all .substitution files from a directory
 func (g *Golem) LoadSubstitutionsFromDirectory(dirPath string) (map[string]map[string]string, error) {
+	return g.loadSubstitutionsFromDirectory(dirPath, false)
+}
+
+// loadSubstitutionsFromDirectory implements LoadSubstitutionsFromDirectory, failing on a file that fails
+// to load with failOnError (see skipFailedFile)
+func (g *Golem) loadSubstitutionsFromDirectory(dirPath string, failOnError bool) (map[string]map[string]string, error) {
 	g.LogInfo("Loading substitution files from directory: %s", dirPath)
 
 	// Create a map to store all substitutions
@@ -700,7 +941,7 @@ func (g *Golem) LoadSubstitutionsFromDirectory(dirPath string) (map[string]map[s
 		}
 
 		// Check if it's a .substitution file
-		if !d.IsDir() && strings.HasSuffix(strings.ToLower(path), ".substitution") {
+		if !d.IsDir() && hasResourceExtension(path, ".substitution") {
 			substitutionFiles = append(substitutionFiles, path)
 		}
 
@@ -725,13 +966,14 @@ func (g *Golem) LoadSubstitutionsFromDirectory(dirPath string) (map[string]map[s
 		// Load the individual substitution file
 		substitutionData, err := g.LoadSubstitutionFromFile(substitutionFile)
 		if err != nil {
-			// Log the error but continue with other files
-			g.LogInfo("Warning: failed to load %s: %v", substitutionFile, err)
+			if err := g.skipFailedFile(substitutionFile, err, failOnError); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		// Use the filename (without extension) as the substitution name
-		substitutionName := strings.TrimSuffix(filepath.Base(substitutionFile), filepath.Ext(substitutionFile))
+		substitutionName := resourceBaseName(substitutionFile)
 		allSubstitutions[substitutionName] = substitutionData
 	}
 
@@ -745,7 +987,7 @@ func (g *Golem) LoadPropertiesFromFile(filename string) (map[string]string, erro
 	g.LogInfo("Loading properties file: %s", filename)
 
 	// Read the file content
-	content, err := os.ReadFile(filename)
+	content, err := readResourceFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read properties file %s: %v", filename, err)
 	}
@@ -783,6 +1025,12 @@ func (g *Golem) LoadPropertiesFromFile(filename string) (map[string]string, erro
 
 // LoadPropertiesFromDirectory loads all .properties files from a directory
 func (g *Golem) LoadPropertiesFromDirectory(dirPath string) (map[string]map[string]string, error) {
+	return g.loadPropertiesFromDirectory(dirPath, false)
+}
+
+// loadPropertiesFromDirectory implements LoadPropertiesFromDirectory, failing on a file that fails
+// to load with failOnError (see skipFailedFile)
+func (g *Golem) loadPropertiesFromDirectory(dirPath string, failOnError bool) (map[string]map[string]string, error) {
 	g.LogInfo("Loading properties files from directory: %s", dirPath)
 
 	// Create a map to store all properties
@@ -796,7 +1044,7 @@ func (g *Golem) LoadPropertiesFromDirectory(dirPath string) (map[string]map[stri
 		}
 
 		// Check if it's a .properties file
-		if !d.IsDir() && strings.HasSuffix(strings.ToLower(path), ".properties") {
+		if !d.IsDir() && hasResourceExtension(path, ".properties") {
 			propertiesFiles = append(propertiesFiles, path)
 		}
 
@@ -821,13 +1069,14 @@ func (g *Golem) LoadPropertiesFromDirectory(dirPath string) (map[string]map[stri
 		// Load the individual properties file
 		propertiesData, err := g.LoadPropertiesFromFile(propertiesFile)
 		if err != nil {
-			// Log the error but continue with other files
-			g.LogInfo("Warning: failed to load %s: %v", propertiesFile, err)
+			if err := g.skipFailedFile(propertiesFile, err, failOnError); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		// Use the filename (without extension) as the properties name
-		propertiesName := strings.TrimSuffix(filepath.Base(propertiesFile), filepath.Ext(propertiesFile))
+		propertiesName := resourceBaseName(propertiesFile)
 		allProperties[propertiesName] = propertiesData
 	}
 
@@ -841,7 +1090,7 @@ func (g *Golem) LoadPDefaultsFromFile(filename string) (map[string]string, error
 	g.LogInfo("Loading pdefaults file: %s", filename)
 
 	// Read the file content
-	content, err := os.ReadFile(filename)
+	content, err := readResourceFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read pdefaults file %s: %v", filename, err)
 	}
@@ -879,6 +1128,12 @@ func (g *Golem) LoadPDefaultsFromFile(filename string) (map[string]string, error
 
 // LoadPDefaultsFromDirectory loads all .pdefaults files from a directory
 func (g *Golem) LoadPDefaultsFromDirectory(dirPath string) (map[string]map[string]string, error) {
+	return g.loadPDefaultsFromDirectory(dirPath, false)
+}
+
+// loadPDefaultsFromDirectory implements LoadPDefaultsFromDirectory, failing on a file that fails
+// to load with failOnError (see skipFailedFile)
+func (g *Golem) loadPDefaultsFromDirectory(dirPath string, failOnError bool) (map[string]map[string]string, error) {
 	g.LogInfo("Loading pdefaults files from directory: %s", dirPath)
 
 	// Create a map to store all pdefaults
@@ -892,7 +1147,7 @@ func (g *Golem) LoadPDefaultsFromDirectory(dirPath string) (map[string]map[strin
 		}
 
 		// Check if it's a .pdefaults file
-		if !d.IsDir() && strings.HasSuffix(strings.ToLower(path), ".pdefaults") {
+		if !d.IsDir() && hasResourceExtension(path, ".pdefaults") {
 			pdefaultsFiles = append(pdefaultsFiles, path)
 		}
 
@@ -917,13 +1172,14 @@ func (g *Golem) LoadPDefaultsFromDirectory(dirPath string) (map[string]map[strin
 		// Load the individual pdefaults file
 		pdefaultsData, err := g.LoadPDefaultsFromFile(pdefaultsFile)
 		if err != nil {
-			// Log the error but continue with other files
-			g.LogInfo("Warning: failed to load %s: %v", pdefaultsFile, err)
+			if err := g.skipFailedFile(pdefaultsFile, err, failOnError); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		// Use the filename (without extension) as the pdefaults name
-		pdefaultsName := strings.TrimSuffix(filepath.Base(pdefaultsFile), filepath.Ext(pdefaultsFile))
+		pdefaultsName := resourceBaseName(pdefaultsFile)
 		allPDefaults[pdefaultsName] = pdefaultsData
 	}
 
@@ -938,7 +1194,8 @@ func (g *Golem) parseAIML(content string) (*AIML, error) {
 		Categories: []Category{},
 	}
 
-	// Remove XML declaration and comments
+	// Strip a byte order mark and CRLF line endings, then the XML declaration and comments
+	content = normalizeFileEncoding(content)
 	content = g.removeComments(content)
 	content = g.removeXMLDeclaration(content)
 
@@ -951,19 +1208,57 @@ func (g *Golem) parseAIML(content string) (*AIML, error) {
 	}
 
 	// Find all categories using tag-aware parsing
-	categoryContents := g.extractAllTagContents(content, "category")
+	categoryContents := g.extractAllTagContentsWithAttributes(content, "category")
 
-	for _, categoryContent := range categoryContents {
-		category, err := g.parseCategory(categoryContent)
+	for i, categoryContent := range categoryContents {
+		category, err := g.parseCategory(categoryContent.Content)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse category: %v", err)
 		}
+		if priorityStr, hasPriority := categoryContent.Attributes["priority"]; hasPriority {
+			priority, err := strconv.Atoi(strings.TrimSpace(priorityStr))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse category: invalid priority: %s", priorityStr)
+			}
+			category.PriorityBoost = priority
+		}
+		if defaultStr, hasDefault := categoryContent.Attributes["default"]; hasDefault {
+			category.Default = strings.EqualFold(strings.TrimSpace(defaultStr), "true")
+		}
+		if tagsStr, hasTags := categoryContent.Attributes["tags"]; hasTags {
+			category.Tags = parseCategoryTags(tagsStr)
+		}
+		category.Source = fmt.Sprintf("category %d", i+1)
 		aiml.Categories = append(aiml.Categories, category)
 	}
 
+	// AIML 1.x documents should only use features older interpreters understand
+	if err := g.checkVersionFeatures(aiml); err != nil {
+		return nil, err
+	}
+	if err := g.checkThatIndexDepth(aiml); err != nil {
+		return nil, err
+	}
+
 	return aiml, nil
 }
 
+// parseCategoryTags splits a category's comma-separated tags attribute, dropping
+// blank and repeated tags
+func parseCategoryTags(tagsStr string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(tagsStr, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
 // extractAllTagContents extracts all occurrences of a tag using stack-based parsing
 func (g *Golem) extractAllTagContents(input string, tagName string) []string {
 	var results []string
@@ -1029,6 +1324,26 @@ func (g *Golem) extractAllTagContents(input string, tagName string) []string {
 	return results
 }
 
+// extractAllTagContentsWithAttributes extracts all occurrences of a tag along with the
+// attributes of each opening tag
+func (g *Golem) extractAllTagContentsWithAttributes(input string, tagName string) []TagContentWithAttributes {
+	var results []TagContentWithAttributes
+
+	i := 0
+	for i < len(input) {
+		result, end, found := g.extractTagContentAt(input[i:], tagName)
+		if !found {
+			break
+		}
+		results = append(results, result)
+
+		// Continue after the matching closing tag
+		i += end
+	}
+
+	return results
+}
+
 // parseCategory parses a single category using tag-aware parsing
 func (g *Golem) parseCategory(content string) (Category, error) {
 	category := Category{}
@@ -1043,8 +1358,17 @@ func (g *Golem) parseCategory(content string) (Category, error) {
 		category.Template = strings.TrimSpace(templateContent)
 	}
 
-	// Extract that (optional) with index support using tag-aware parsing
-	if thatContent, found := g.extractTagContentWithAttributes(content, "that"); found {
+	// <that> and <topic> may also appear as template tags, so only look outside the template
+	contextContent := content
+	if templateStart := strings.Index(content, "<template"); templateStart != -1 {
+		if templateEnd := strings.LastIndex(content, "</template>"); templateEnd > templateStart {
+			contextContent = content[:templateStart] + content[templateEnd+len("</template>"):]
+		}
+	}
+
+	// Extract that (optional) with index support using tag-aware parsing. An empty
+	// <that></that> places no constraint, the same as <that/> or no <that> at all
+	if thatContent, found := g.extractTagContentWithAttributes(contextContent, "that"); found && strings.TrimSpace(thatContent.Content) != "" {
 		category.That = strings.TrimSpace(thatContent.Content)
 
 		// Parse index attribute if provided
@@ -1069,7 +1393,7 @@ func (g *Golem) parseCategory(content string) (Category, error) {
 	}
 
 	// Extract topic (optional) using tag-aware parsing
-	if topicContent, found := g.extractTagContent(content, "topic"); found {
+	if topicContent, found := g.extractTagContent(contextContent, "topic"); found {
 		category.Topic = strings.TrimSpace(topicContent)
 	}
 
@@ -1090,15 +1414,45 @@ func (g *Golem) extractTagContent(input string, tagName string) (string, bool) {
 
 // extractTagContentWithAttributes extracts tag content and attributes using stack-based parsing
 func (g *Golem) extractTagContentWithAttributes(input string, tagName string) (TagContentWithAttributes, bool) {
+	result, _, found := g.extractTagContentAt(input, tagName)
+	return result, found
+}
+
+// indexOpenTag returns the index of the first opening tag named tagName in input, or
+// -1. A tag whose name only starts with tagName, such as <categoryX>, doesn't count.
+func indexOpenTag(input string, tagName string) int {
+	openPattern := "<" + tagName
+	offset := 0
+	for {
+		idx := strings.Index(input[offset:], openPattern)
+		if idx == -1 {
+			return -1
+		}
+		idx += offset
+		if next := idx + len(openPattern); next < len(input) && isTagNameEnd(input[next]) {
+			return idx
+		}
+		offset = idx + len(openPattern)
+	}
+}
+
+// isTagNameEnd reports whether c can follow a tag name: whitespace, '>' or '/'
+func isTagNameEnd(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '>' || c == '/'
+}
+
+// extractTagContentAt extracts the content and attributes of the first tagName element
+// in input, also returning the offset just past its closing tag
+func (g *Golem) extractTagContentAt(input string, tagName string) (TagContentWithAttributes, int, bool) {
 	result := TagContentWithAttributes{
 		Attributes: make(map[string]string),
 	}
 
 	// Find opening tag
 	openPattern := fmt.Sprintf("<%s", tagName)
-	openIdx := strings.Index(input, openPattern)
+	openIdx := indexOpenTag(input, tagName)
 	if openIdx == -1 {
-		return result, false
+		return result, 0, false
 	}
 
 	// Find the end of the opening tag (the '>' character)
@@ -1137,11 +1491,12 @@ func (g *Golem) extractTagContentWithAttributes(input string, tagName string) (T
 			i++
 		}
 
-		// Parse attribute value
-		if i < len(input) && input[i] == '"' {
+		// Parse attribute value, which may be in double or single quotes
+		if i < len(input) && (input[i] == '"' || input[i] == '\'') {
+			quote := input[i]
 			i++ // skip opening quote
 			valueStart := i
-			for i < len(input) && input[i] != '"' {
+			for i < len(input) && input[i] != quote {
 				i++
 			}
 			attrValue := input[valueStart:i]
@@ -1153,7 +1508,7 @@ func (g *Golem) extractTagContentWithAttributes(input string, tagName string) (T
 	}
 
 	if i >= len(input) || input[i] != '>' {
-		return result, false
+		return result, 0, false
 	}
 	i++ // skip '>'
 
@@ -1167,7 +1522,7 @@ func (g *Golem) extractTagContentWithAttributes(input string, tagName string) (T
 		if i+len(openPattern) < len(input) && input[i:i+len(openPattern)] == openPattern {
 			// Make sure it's actually a tag (followed by space, '>', or '/')
 			nextChar := i + len(openPattern)
-			if nextChar < len(input) && (input[nextChar] == '>' || input[nextChar] == ' ' || input[nextChar] == '/') {
+			if nextChar < len(input) && isTagNameEnd(input[nextChar]) {
 				depth++
 				i += len(openPattern)
 				continue
@@ -1180,7 +1535,7 @@ func (g *Golem) extractTagContentWithAttributes(input string, tagName string) (T
 			if depth == 0 {
 				// Found the matching closing tag
 				result.Content = input[contentStart:i]
-				return result, true
+				return result, i + len(closePattern), true
 			}
 			i += len(closePattern)
 			continue
@@ -1190,13 +1545,51 @@ func (g *Golem) extractTagContentWithAttributes(input string, tagName string) (T
 	}
 
 	// If we get here, we didn't find a matching closing tag
-	return result, false
+	return result, 0, false
 }
 
 // removeComments removes XML comments from content
 func (g *Golem) removeComments(content string) string {
-	commentRegex := regexp.MustCompile(`<!--.*?-->`)
-	return commentRegex.ReplaceAllString(content, "")
+	return stripXMLComments(content)
+}
+
+// stripXMLComments removes <!-- --> comments, including ones spanning several lines
+// or containing '>', while leaving CDATA sections untouched so comment-like text
+// inside them survives. An unterminated comment is kept as-is.
+func stripXMLComments(content string) string {
+	if !strings.Contains(content, "<!--") {
+		return content
+	}
+
+	var result strings.Builder
+	result.Grow(len(content))
+	for {
+		commentStart := strings.Index(content, "<!--")
+		cdataStart := strings.Index(content, "<![CDATA[")
+		if commentStart == -1 {
+			break
+		}
+
+		if cdataStart != -1 && cdataStart < commentStart {
+			cdataEnd := strings.Index(content[cdataStart:], "]]>")
+			if cdataEnd == -1 {
+				break
+			}
+			cdataEnd += cdataStart + len("]]>")
+			result.WriteString(content[:cdataEnd])
+			content = content[cdataEnd:]
+			continue
+		}
+
+		commentEnd := strings.Index(content[commentStart+len("<!--"):], "-->")
+		if commentEnd == -1 {
+			break
+		}
+		result.WriteString(content[:commentStart])
+		content = content[commentStart+len("<!--")+commentEnd+len("-->"):]
+	}
+	result.WriteString(content)
+	return result.String()
 }
 
 // removeXMLDeclaration removes XML declaration
@@ -1238,16 +1631,40 @@ func (g *Golem) validatePattern(pattern string) error {
 	// Check for valid wildcards and tags
 	// First, normalize the pattern by replacing set and topic tags with placeholders
 	normalizedPattern := pattern
-	setPattern := regexp.MustCompile(`<set>[^<]+</set>`)
 	topicPattern := regexp.MustCompile(`<topic>[^<]+</topic>`)
-	normalizedPattern = setPattern.ReplaceAllString(normalizedPattern, "SETTAG")
+	normalizedPattern = patternSetTagRegex.ReplaceAllString(normalizedPattern, "SETTAG")
 	normalizedPattern = topicPattern.ReplaceAllString(normalizedPattern, "TOPICTAG")
 
-	validWildcard := regexp.MustCompile(`^[A-Z0-9\s\*_^#$<>/]+$`)
+	validWildcard := regexp.MustCompile(`^[A-Z0-9\s\*_^#$<>/\[\]]+$`)
 	if !validWildcard.MatchString(normalizedPattern) {
 		return fmt.Errorf("pattern contains invalid characters")
 	}
 
+	// Check optional segments ([WORD]) are closed, not nested and not empty
+	inOptional := false
+	optionalStart := 0
+	for i, char := range pattern {
+		switch char {
+		case '[':
+			if inOptional {
+				return fmt.Errorf("optional segments cannot be nested")
+			}
+			inOptional = true
+			optionalStart = i
+		case ']':
+			if !inOptional {
+				return fmt.Errorf("unmatched ']' in pattern")
+			}
+			if strings.TrimSpace(pattern[optionalStart+1:i]) == "" {
+				return fmt.Errorf("optional segment cannot be empty")
+			}
+			inOptional = false
+		}
+	}
+	if inOptional {
+		return fmt.Errorf("unmatched '[' in pattern")
+	}
+
 	// Check for balanced wildcards (count all wildcard types)
 	wildcardCounts := CountWildcardsByType(pattern)
 	totalWildcards := wildcardCounts["star"] + wildcardCounts["underscore"] + wildcardCounts["caret"] + wildcardCounts["hash"]
@@ -1256,11 +1673,10 @@ func (g *Golem) validatePattern(pattern string) error {
 		return fmt.Errorf("pattern contains too many wildcards (max 9)")
 	}
 
-	// Check for valid set references
-	setRefPattern := regexp.MustCompile(`<set>([^<]+)</set>`)
-	matches := setRefPattern.FindAllStringSubmatch(pattern, -1)
+	// Check for valid set references (both <set>name</set> and <set name="name"/>)
+	matches := patternSetTagRegex.FindAllStringSubmatch(pattern, -1)
 	for _, match := range matches {
-		if len(match) > 1 && strings.TrimSpace(match[1]) == "" {
+		if patternSetName(match) == "" {
 			return fmt.Errorf("set name cannot be empty")
 		}
 	}
@@ -1276,6 +1692,8 @@ type PatternPriority struct {
 	WildcardCount    int
 	HasUnderscore    bool
 	WildcardPosition int
+	LiteralWords     int    // Words in the pattern that aren't wildcards
+	Key              string // Pattern index key, which breaks any remaining tie
 }
 
 // MatchPattern attempts to match user input against AIML patterns with highest priority matching
@@ -1302,37 +1720,12 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginal(normalize
 
 // MatchPatternWithTopicAndThatIndexOriginalCached attempts to match user input against AIML patterns with caching support
 func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *Golem, normalizedInput string, originalInput string, topic string, that string, thatIndex int) (*Category, map[string]string, error) {
-	// Use the already normalized input for matching
-	input := normalizedInput
-
-	// Normalize that for matching using enhanced that normalization
-	normalizedThat := ""
-	if that != "" {
-		normalizedThat = NormalizeThatPattern(that)
-	}
-
-	// Try dollar wildcard patterns first (highest priority)
-	// Dollar wildcards match exact patterns but with higher priority
-	for _, category := range kb.Patterns {
-		// Check if this pattern has a dollar wildcard
-		if strings.HasPrefix(category.Pattern, "$") {
-			// Remove the $ prefix and check if it matches the input exactly
-			exactPattern := strings.TrimSpace(category.Pattern[1:])
-			if exactPattern == input {
-				// Check topic and that context
-				if (topic == "" || category.Topic == "" || strings.EqualFold(category.Topic, topic)) &&
-					(normalizedThat == "" || category.That == "" || category.That == normalizedThat) {
-					// Check that index if specified
-					if category.That != "" && thatIndex != 0 && category.ThatIndex != thatIndex {
-						continue
-					}
-					return category, make(map[string]string), nil
-				}
-			}
-		}
-	}
+	return kb.matchPatternTraced(g, normalizedInput, originalInput, topic, that, thatIndex, nil)
+}
 
-	// Try exact match (second highest priority)
+// exactMatch looks up the category indexed under the input itself, with the that and
+// topic context, returning its key. Returns nil if there is none for the that index.
+func (kb *AIMLKnowledgeBase) exactMatch(input, topic, normalizedThat string, thatIndex int) (string, *Category) {
 	// Build the exact key to look for
 	exactKey := input
 	if normalizedThat != "" {
@@ -1348,7 +1741,7 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 			}
 			if category, exists := kb.Patterns[exactKeyWithoutIndex]; exists {
 				if category.ThatIndex == 0 {
-					return category, make(map[string]string), nil
+					return exactKeyWithoutIndex, category
 				}
 			}
 		}
@@ -1366,12 +1759,75 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 			} else if thatIndex == 0 && category.ThatIndex != 0 {
 				// If we're looking for index 0, skip categories with specific indices
 			} else {
-				return category, make(map[string]string), nil
+				return exactKey, category
 			}
 		} else {
 			// Category has no that pattern, only return if we're not looking for a specific index
 			if thatIndex == 0 {
-				return category, make(map[string]string), nil
+				return exactKey, category
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// matchPatternTraced is the matching loop behind MatchPatternWithTopicAndThatIndexOriginalCached.
+// When trace is non-nil it also records why each candidate was included or excluded.
+func (kb *AIMLKnowledgeBase) matchPatternTraced(g *Golem, normalizedInput string, originalInput string, topic string, that string, thatIndex int, trace *MatchTrace) (*Category, map[string]string, error) {
+	kb.learnMutex.RLock()
+	defer kb.learnMutex.RUnlock()
+
+	// Use the already normalized input for matching
+	input := normalizedInput
+	foldDiacritics := g != nil && g.foldDiacritics
+	if foldDiacritics {
+		input = FoldDiacritics(input)
+	}
+
+	// Normalize that for matching using enhanced that normalization
+	normalizedThat := ""
+	if that != "" {
+		normalizedThat = NormalizeThatPattern(that)
+	}
+	trace.setContext(input, topic, normalizedThat)
+
+	// Try dollar wildcard patterns first (highest priority)
+	// Dollar wildcards match exact patterns but with higher priority, ahead of any
+	// priority boost
+	for _, category := range kb.Patterns {
+		// Check if this pattern has a dollar wildcard
+		if strings.HasPrefix(category.Pattern, "$") {
+			// Remove the $ prefix and check if it matches the input exactly
+			exactPattern := strings.TrimSpace(category.Pattern[1:])
+			if exactPattern == input {
+				// Check topic and that context
+				if (topic == "" || category.Topic == "" || strings.EqualFold(category.Topic, topic)) &&
+					(normalizedThat == "" || category.That == "" || category.That == normalizedThat) {
+					// Check that index if specified
+					if category.That != "" && thatIndex != 0 && category.ThatIndex != thatIndex {
+						continue
+					}
+					trace.setMatch(MatchStageDollar, category)
+					return category, make(map[string]string), nil
+				}
+			}
+		}
+	}
+
+	// Try exact match (second highest priority). It outranks every other candidate
+	// unless categories have a priority boost, which it is then ranked against.
+	candidates := kb.Patterns
+	exactKey, exactCategory := kb.exactMatch(input, topic, normalizedThat, thatIndex)
+	if exactCategory != nil {
+		if len(kb.boostedKeys) == 0 {
+			trace.setMatch(MatchStageExact, exactCategory)
+			return exactCategory, make(map[string]string), nil
+		}
+		candidates = map[string]*Category{exactKey: exactCategory}
+		for key := range kb.boostedKeys {
+			if category, exists := kb.Patterns[key]; exists && category.PriorityBoost != 0 {
+				candidates[key] = category
 			}
 		}
 	}
@@ -1379,13 +1835,16 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 	// Collect all matching patterns with their priorities
 	var matchingPatterns []PatternPriority
 
-	for patternKey, category := range kb.Patterns {
+	for patternKey, category := range candidates {
 		if patternKey == "DEFAULT" {
 			continue // Handle default separately
 		}
 
 		// Extract the base pattern from the key (before the first |)
 		basePattern := strings.Split(patternKey, "|")[0]
+		if foldDiacritics {
+			basePattern = FoldDiacritics(basePattern)
+		}
 
 		// Check topic match - if pattern has a topic, it must match the current topic
 		if category.Topic != "" {
@@ -1393,11 +1852,13 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 			if strings.Contains(category.Topic, "*") {
 				matched, _ := matchPatternWithWildcardsAndSets(topic, category.Topic, kb)
 				if !matched {
+					trace.exclude(patternKey, category, MatchReasonTopicMismatch)
 					continue // Skip patterns that don't match the topic
 				}
 			} else {
 				// Use exact matching for topics without wildcards
 				if !strings.EqualFold(category.Topic, topic) {
+					trace.exclude(patternKey, category, MatchReasonTopicMismatch)
 					continue // Skip patterns that have a different topic
 				}
 			}
@@ -1411,14 +1872,17 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 			// If category has a specific index, it must match the requested index
 			// If category has index 0 (default), it matches any index
 			if category.ThatIndex != 0 && thatIndex != 0 && category.ThatIndex != thatIndex {
+				trace.exclude(patternKey, category, MatchReasonThatIndexMismatch)
 				continue // Skip patterns with different that index
 			}
 			// If we're looking for index 0 (most recent), only match categories with index 0
 			if thatIndex == 0 && category.ThatIndex != 0 {
+				trace.exclude(patternKey, category, MatchReasonThatIndexMismatch)
 				continue // Skip patterns with specific indices when looking for most recent
 			}
 			// If we're looking for a specific index, only match categories with that index or index 0
 			if thatIndex != 0 && category.ThatIndex != 0 && category.ThatIndex != thatIndex {
+				trace.exclude(patternKey, category, MatchReasonThatIndexMismatch)
 				continue // Skip patterns with different specific indices
 			}
 
@@ -1427,16 +1891,21 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 			thatMatched, thatWildcards = matchThatPatternWithWildcardsWithGolem(g, normalizedThat, category.That)
 			_ = thatWildcards // Suppress unused variable warning for now
 			if !thatMatched {
+				trace.exclude(patternKey, category, MatchReasonThatMismatch)
 				continue // Skip patterns that don't match the that context
 			}
 		} else if thatIndex != 0 {
 			// If we're looking for a specific index but this category has no that pattern,
 			// skip it (we only want categories with that patterns when index is specified)
+			trace.exclude(patternKey, category, MatchReasonThatIndexMismatch)
 			continue
 		}
 
 		// Try enhanced matching with sets first
 		matched, _ := matchPatternWithWildcardsAndSetsCasePreservingCached(g, input, originalInput, basePattern, kb)
+		if !matched {
+			trace.exclude(patternKey, category, MatchReasonPatternMismatch)
+		}
 		if matched && thatMatched {
 			priority := calculatePatternPriority(basePattern)
 
@@ -1467,6 +1936,10 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 				priority.Priority += 100 // Medium boost for topic context
 			}
 
+			// Author-specified boost from <category priority="N">
+			priority.Priority += category.PriorityBoost
+			trace.include(patternKey, category, priority.Priority)
+
 			matchingPatterns = append(matchingPatterns, PatternPriority{
 				Pattern:          basePattern,
 				Category:         category,
@@ -1474,25 +1947,40 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 				WildcardCount:    priority.WildcardCount,
 				HasUnderscore:    priority.HasUnderscore,
 				WildcardPosition: priority.WildcardPosition,
+				LiteralWords:     priority.LiteralWords,
+				Key:              patternKey,
 			})
 		}
 	}
 
-	// Sort by priority (highest first)
+	// Sort by priority (highest first). Candidates come from map iteration, so ties
+	// are broken deterministically (see preferPattern).
 	sort.Slice(matchingPatterns, func(i, j int) bool {
-		return comparePatternPriorities(matchingPatterns[i].Priority, matchingPatterns[j].Priority)
+		return preferPattern(matchingPatterns[i], matchingPatterns[j])
 	})
 
+	// The exact match stands unless a boosted category outranked it
+	if exactCategory != nil && (len(matchingPatterns) == 0 || matchingPatterns[0].Category == exactCategory) {
+		trace.setMatch(MatchStageExact, exactCategory)
+		return exactCategory, make(map[string]string), nil
+	}
+
 	// Return the highest priority match
 	if len(matchingPatterns) > 0 {
 		bestMatch := matchingPatterns[0]
 
 		// Capture wildcard values from input pattern using case-preserving normalization
 		// We need to normalize for matching but preserve case for text processing tags
-		casePreservingInput := NormalizeForMatchingCasePreserving(originalInput)
+		casePreservingInput := normalizeForMatchingCasePreservingCached(g, originalInput)
+		captureInput := originalInput
+		if foldDiacritics {
+			// The matched pattern is folded, so captures must come from folded input
+			casePreservingInput = FoldDiacritics(casePreservingInput)
+			captureInput = FoldDiacritics(originalInput)
+		}
 		// Also normalize the pattern to lowercase for case-insensitive matching
 		normalizedPattern := strings.ToLower(bestMatch.Pattern)
-		_, inputWildcards := matchPatternWithWildcardsAndSetsCasePreservingCached(g, casePreservingInput, originalInput, normalizedPattern, kb)
+		_, inputWildcards := matchPatternWithWildcardsAndSetsCasePreservingCached(g, casePreservingInput, captureInput, normalizedPattern, kb)
 		if inputWildcards == nil {
 			_, inputWildcards = matchPatternWithWildcards(casePreservingInput, normalizedPattern)
 		}
@@ -1527,21 +2015,66 @@ func (kb *AIMLKnowledgeBase) MatchPatternWithTopicAndThatIndexOriginalCached(g *
 			allWildcards[k] = v
 		}
 
+		trace.setMatch(MatchStageWildcard, bestMatch.Category)
 		return bestMatch.Category, allWildcards, nil
 	}
-
-	// Try default pattern (lowest priority)
+
+	// Try the default category (lowest priority): one marked <category default="true">
+	// or using the DEFAULT pattern. Note that a "*" catch-all matches first if present.
+	if category, exists := kb.Patterns["DEFAULT"]; exists {
+		// Check topic match if topic is specified
+		if topic == "" || category.Topic == "" || category.Topic == topic {
+			// Check that match if that is specified
+			if normalizedThat == "" || category.That == "" || category.That == normalizedThat {
+				trace.setMatch(MatchStageDefault, category)
+				return category, make(map[string]string), nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no matching pattern found")
+}
+
+// defaultCategory returns the knowledge base's default category: the category marked
+// with default="true" or using the DEFAULT pattern if present, otherwise the catch-all
+// "*" category
+func (kb *AIMLKnowledgeBase) defaultCategory() *Category {
+	if kb == nil {
+		return nil
+	}
 	if category, exists := kb.Patterns["DEFAULT"]; exists {
-		// Check topic match if topic is specified
-		if topic == "" || category.Topic == "" || category.Topic == topic {
-			// Check that match if that is specified
-			if normalizedThat == "" || category.That == "" || category.That == normalizedThat {
-				return category, make(map[string]string), nil
+		return category
+	}
+	if category, exists := kb.Patterns["*"]; exists {
+		return category
+	}
+	return nil
+}
+
+// categoryCount returns the number of categories, for the <size/> tag
+func (kb *AIMLKnowledgeBase) categoryCount() int {
+	kb.learnMutex.RLock()
+	defer kb.learnMutex.RUnlock()
+	return len(kb.Categories)
+}
+
+// CategoriesByTag returns the categories carrying tag, compared case-insensitively, in
+// load order
+func (kb *AIMLKnowledgeBase) CategoriesByTag(tag string) []*Category {
+	tag = strings.TrimSpace(tag)
+	kb.learnMutex.RLock()
+	defer kb.learnMutex.RUnlock()
+
+	var categories []*Category
+	for i := range kb.Categories {
+		for _, categoryTag := range kb.Categories[i].Tags {
+			if strings.EqualFold(categoryTag, tag) {
+				categories = append(categories, &kb.Categories[i])
+				break
 			}
 		}
 	}
-
-	return nil, nil, fmt.Errorf("no matching pattern found")
+	return categories
 }
 
 // MatchPatternWithTopic attempts to match user input against AIML patterns with topic filtering
@@ -1555,6 +2088,7 @@ type PatternPriorityInfo struct {
 	WildcardCount    int
 	HasUnderscore    bool
 	WildcardPosition int
+	LiteralWords     int
 }
 
 // comparePatternPriorities compares two pattern priorities
@@ -1562,6 +2096,19 @@ func comparePatternPriorities(p1, p2 int) bool {
 	return p1 > p2
 }
 
+// preferPattern reports whether matching candidate a wins over b: the higher priority
+// wins, then the pattern with more literal words (so "* ME" beats "*"), then the lower
+// index key, so equal candidates resolve the same way on every match
+func preferPattern(a, b PatternPriority) bool {
+	if a.Priority != b.Priority {
+		return comparePatternPriorities(a.Priority, b.Priority)
+	}
+	if a.LiteralWords != b.LiteralWords {
+		return a.LiteralWords > b.LiteralWords
+	}
+	return a.Key < b.Key
+}
+
 // calculatePatternPriority calculates the priority of a pattern for matching
 // Higher priority values mean higher precedence
 // AIML2 Priority order: $ > # > _ > exact > ^ > *
@@ -1661,6 +2208,7 @@ func calculatePatternPriorityInternal(pattern string) PatternPriorityInfo {
 		WildcardCount:    totalWildcards,
 		HasUnderscore:    underscoreCount > 0,
 		WildcardPosition: wildcardPosition,
+		LiteralWords:     wordCount,
 	}
 }
 
@@ -1744,22 +2292,12 @@ func matchPatternWithWildcardsAndSetsCasePreservingCached(g *Golem, normalizedIn
 func matchPatternWithWildcardsAndSetsCasePreservingInternal(g *Golem, normalizedInput, originalInput, pattern string, kb *AIMLKnowledgeBase) (bool, map[string]string) {
 	wildcards := make(map[string]string)
 
-	// Convert pattern to regex with set support
-	// If the pattern is lowercase, we need to make the regex case-insensitive
-	regexPattern := patternToRegexWithSetsCached(g, pattern, kb)
-
-	// If the pattern is lowercase, make the regex case-insensitive
-	if pattern != strings.ToUpper(pattern) {
-		// Make the regex case-insensitive by adding (?i) flag
-		regexPattern = "(?i)" + regexPattern
-	}
-
-	re, err := regexp.Compile(regexPattern)
+	// Match the pattern's regex with set support
+	// If the pattern is lowercase, the regex is case-insensitive
+	matches, err := findPatternSubmatch(g, pattern, normalizedInput, pattern != strings.ToUpper(pattern), kb)
 	if err != nil {
 		return false, nil
 	}
-
-	matches := re.FindStringSubmatch(normalizedInput)
 	if matches == nil {
 		return false, nil
 	}
@@ -1774,12 +2312,10 @@ func matchPatternWithWildcardsAndSetsCasePreservingInternal(g *Golem, normalized
 	// If original input is different from normalized input, try case-preserving extraction
 	if originalInput != normalizedInput {
 		// First try: Extract from case-preserved (but still punctuation-normalized) input
-		originalNormalized := NormalizeForMatchingCasePreserving(originalInput)
+		originalNormalized := normalizeForMatchingCasePreservingCached(g, originalInput)
 		lowercasePattern := strings.ToLower(pattern)
-		lowercaseRegexPattern := patternToRegexWithSetsCached(g, lowercasePattern, kb)
-		lowercaseRe, err := regexp.Compile(lowercaseRegexPattern)
+		casePreservedMatches, err := findPatternSubmatch(g, lowercasePattern, originalNormalized, false, kb)
 		if err == nil {
-			casePreservedMatches := lowercaseRe.FindStringSubmatch(originalNormalized)
 			if len(casePreservedMatches) > 1 {
 				starIndex := 1
 				for _, match := range casePreservedMatches[1:] {
@@ -1793,6 +2329,10 @@ func matchPatternWithWildcardsAndSetsCasePreservingInternal(g *Golem, normalized
 		// This is done by creating a regex that's very lenient (case-insensitive, whitespace-flexible)
 		// Build a lenient regex from the pattern
 		lenientPattern := strings.ToLower(pattern)
+		// Optional segments take the space before them, as in patternToRegexWithSets
+		lenientPattern = strings.ReplaceAll(lenientPattern, " [", "(?: ")
+		lenientPattern = strings.ReplaceAll(lenientPattern, "[", "(?:")
+		lenientPattern = strings.ReplaceAll(lenientPattern, "]", ")?")
 		// Replace wildcards with a pattern that captures everything (including punctuation)
 		lenientPattern = strings.ReplaceAll(lenientPattern, "*", "(.+?)")
 		lenientPattern = strings.ReplaceAll(lenientPattern, "_", "([\\w]+)")
@@ -1816,11 +2356,67 @@ func matchPatternWithWildcardsAndSetsCasePreservingInternal(g *Golem, normalized
 	return true, wildcards
 }
 
+// patternSetTagRegex matches set references in patterns, in both the content form
+// (<set>colors</set>) and the AIML2 attribute form (<set name="colors"/>)
+var patternSetTagRegex = regexp.MustCompile(`(?i)<set>([^<]+)</set>|<set\s+name\s*=\s*["']([^"']*)["']\s*/>`)
+
+// patternSetName extracts the set name from a patternSetTagRegex submatch
+func patternSetName(submatch []string) string {
+	if len(submatch) > 2 && submatch[2] != "" {
+		return strings.TrimSpace(submatch[2])
+	}
+	if len(submatch) > 1 {
+		return strings.TrimSpace(submatch[1])
+	}
+	return ""
+}
+
+// isPatternWildcard reports whether c is a wildcard that captures words
+func isPatternWildcard(c byte) bool {
+	return c == '*' || c == '_' || c == '^' || c == '#'
+}
+
+// zeroWordWildcardSpace returns the side of the space that the zero+ wildcard at
+// pattern[i] matches together with its words: -1 for the space before it, 1 for the
+// one after it, 0 for neither. The space goes with the wildcard, so "HELP *" matches
+// "HELP" and "HELP ME" but not "HELPFUL", and "* ME" matches "ME" but not "AWESOME".
+// A space between two wildcards stays required to separate their captures.
+func zeroWordWildcardSpace(pattern string, i int) int {
+	if i > 0 && pattern[i-1] == ' ' && !(i > 1 && isPatternWildcard(pattern[i-2])) {
+		return -1
+	}
+	if i+1 < len(pattern) && pattern[i+1] == ' ' && !(i+2 < len(pattern) && isPatternWildcard(pattern[i+2])) {
+		return 1
+	}
+	return 0
+}
+
+// zeroWordWildcardRegex returns the regex for the zero+ wildcard at pattern[i],
+// including the adjacent space it owns (see zeroWordWildcardSpace)
+func zeroWordWildcardRegex(pattern string, i int) string {
+	switch zeroWordWildcardSpace(pattern, i) {
+	case -1:
+		return "(?: (.*?))?"
+	case 1:
+		return "(?:(.*?) )?"
+	}
+	return "(.*?)"
+}
+
+// wildcardOwnsSpace reports whether the space at pattern[i] is written as part of an
+// adjacent zero+ wildcard's regex
+func wildcardOwnsSpace(pattern string, i int) bool {
+	isZeroWord := func(c byte) bool { return c == '*' || c == '^' || c == '#' }
+	if i+1 < len(pattern) && isZeroWord(pattern[i+1]) && zeroWordWildcardSpace(pattern, i+1) == -1 {
+		return true
+	}
+	return i > 0 && isZeroWord(pattern[i-1]) && zeroWordWildcardSpace(pattern, i-1) == 1
+}
+
 // patternToRegex converts AIML pattern to regex with enhanced set and topic matching
 func patternToRegex(pattern string) string {
 	// Handle set matching first (before escaping)
-	setPattern := regexp.MustCompile(`<set>([^<]+)</set>`)
-	pattern = setPattern.ReplaceAllString(pattern, "([^\\s]*)")
+	pattern = patternSetTagRegex.ReplaceAllString(pattern, "([^\\s]*)")
 
 	// Handle topic matching (before escaping)
 	topicPattern := regexp.MustCompile(`<topic>([^<]+)</topic>`)
@@ -1830,36 +2426,22 @@ func patternToRegex(pattern string) string {
 	var result strings.Builder
 	for i, char := range pattern {
 		switch char {
-		case '*':
-			// Zero+ wildcard: matches zero or more words
-			result.WriteString("(.*?)")
+		case '*', '^', '#':
+			// Zero+ wildcards (*, and ^ and # in AIML2): match zero or more words,
+			// together with the space that separates them from the adjacent word
+			result.WriteString(zeroWordWildcardRegex(pattern, i))
 		case '_':
 			// Single wildcard: matches exactly one word
 			result.WriteString("([^\\s]+)")
-		case '^':
-			// Caret wildcard: matches zero or more words (AIML2)
-			result.WriteString("(.*?)")
-		case '#':
-			// Hash wildcard: matches zero or more words with high priority (AIML2)
-			result.WriteString("(.*?)")
 		case '$':
 			// Dollar wildcard: highest priority exact match (AIML2)
 			// For regex purposes, treat as exact match (no wildcard capture)
 			// Don't add anything to regex - this will be handled in pattern matching
 			continue
 		case ' ':
-			// Check if this space is between two wildcards
-			prevIsWildcard := i > 0 && (pattern[i-1] == '*' || pattern[i-1] == '_' || pattern[i-1] == '^' || pattern[i-1] == '#')
-			nextIsWildcard := i+1 < len(pattern) && (pattern[i+1] == '*' || pattern[i+1] == '_' || pattern[i+1] == '^' || pattern[i+1] == '#')
-
-			if prevIsWildcard && nextIsWildcard {
-				// Space between two wildcards must be MANDATORY for proper capture
-				result.WriteRune(' ')
-			} else if prevIsWildcard || nextIsWildcard {
-				// Space adjacent to a single wildcard (at start/end) can be optional
-				result.WriteString(" ?")
-			} else {
-				// Regular space
+			// A space a zero+ wildcard made optional is part of its regex; any other
+			// space is required, so a wildcard never matches part of a word
+			if !wildcardOwnsSpace(pattern, i) {
 				result.WriteRune(' ')
 			}
 		case '(', ')', '[', ']', '{', '}', '?', '+', '.':
@@ -1885,16 +2467,7 @@ func patternToRegexWithSets(pattern string, kb *AIMLKnowledgeBase) string {
 
 // patternToRegexWithSetsCached converts AIML pattern to regex with proper set matching and caching
 func patternToRegexWithSetsCached(g *Golem, pattern string, kb *AIMLKnowledgeBase) string {
-	// Handle set matching with proper set validation
-	setPattern := regexp.MustCompile(`<set>([^<]+)</set>`)
-	pattern = setPattern.ReplaceAllStringFunc(pattern, func(match string) string {
-		// Extract set name using regex groups
-		matches := setPattern.FindStringSubmatch(match)
-		if len(matches) < 2 {
-			return "([^\\s]*)"
-		}
-		setName := strings.ToUpper(strings.TrimSpace(matches[1]))
-
+	return patternToRegexWithSetFragments(pattern, func(setName string) string {
 		// Check cache first
 		if g != nil && g.patternMatchingCache != nil {
 			if regex, found := g.patternMatchingCache.GetSetRegex(setName, kb.Sets[setName]); found {
@@ -1938,6 +2511,21 @@ func patternToRegexWithSetsCached(g *Golem, pattern string, kb *AIMLKnowledgeBas
 		// Fallback to wildcard if set not found
 		return "([^\\s]*)"
 	})
+}
+
+// patternToRegexWithSetFragments converts AIML pattern to regex, replacing each set
+// reference with the fragment setFragment returns for its (uppercased) name
+func patternToRegexWithSetFragments(pattern string, setFragment func(setName string) string) string {
+	// Handle set matching with proper set validation
+	// Both <set>name</set> and <set name="name"/> forms are recognized
+	pattern = patternSetTagRegex.ReplaceAllStringFunc(pattern, func(match string) string {
+		// Extract set name using regex groups
+		matches := patternSetTagRegex.FindStringSubmatch(match)
+		if len(matches) < 2 {
+			return "([^\\s]*)"
+		}
+		return setFragment(strings.ToUpper(patternSetName(matches)))
+	})
 
 	// Handle topic matching
 	topicPattern := regexp.MustCompile(`<topic>([^<]+)</topic>`)
@@ -1946,38 +2534,39 @@ func patternToRegexWithSetsCached(g *Golem, pattern string, kb *AIMLKnowledgeBas
 	// Build regex pattern by processing each character
 	var result strings.Builder
 	inAlternationGroup := false
+	// Optional segments ([WORD]) become optional groups that take one adjacent space
+	// with them, so the pattern matches with or without the segment
+	inOptional := false
+	optionalHasSpace := false
+	skipSpace := false
 	for i, char := range pattern {
+		if skipSpace {
+			skipSpace = false
+			if char == ' ' {
+				continue
+			}
+		}
 		switch char {
-		case '*':
-			// Zero+ wildcard: matches zero or more words
-			result.WriteString("(.*?)")
+		case '*', '^', '#':
+			// Zero+ wildcards (*, and ^ and # in AIML2): match zero or more words,
+			// together with the space that separates them from the adjacent word
+			result.WriteString(zeroWordWildcardRegex(pattern, i))
 		case '_':
 			// Single wildcard: matches exactly one word
 			result.WriteString("([^\\s]+)")
-		case '^':
-			// Caret wildcard: matches zero or more words (AIML2)
-			result.WriteString("(.*?)")
-		case '#':
-			// Hash wildcard: matches zero or more words with high priority (AIML2)
-			result.WriteString("(.*?)")
 		case '$':
 			// Dollar wildcard: highest priority exact match (AIML2)
 			// For regex purposes, treat as exact match (no wildcard capture)
 			// Don't add anything to regex - this will be handled in pattern matching
 			continue
 		case ' ':
-			// Check if this space is between two wildcards
-			prevIsWildcard := i > 0 && (pattern[i-1] == '*' || pattern[i-1] == '_' || pattern[i-1] == '^' || pattern[i-1] == '#')
-			nextIsWildcard := i+1 < len(pattern) && (pattern[i+1] == '*' || pattern[i+1] == '_' || pattern[i+1] == '^' || pattern[i+1] == '#')
-
-			if prevIsWildcard && nextIsWildcard {
-				// Space between two wildcards must be MANDATORY for proper capture
-				result.WriteRune(' ')
-			} else if prevIsWildcard || nextIsWildcard {
-				// Space adjacent to a single wildcard (at start/end) can be optional
-				result.WriteString(" ?")
-			} else {
-				// Regular space
+			// A space before an optional segment is written inside its group
+			if !inOptional && i+1 < len(pattern) && pattern[i+1] == '[' && strings.IndexByte(pattern[i+1:], ']') > 0 {
+				continue
+			}
+			// A space a zero+ wildcard made optional is part of its regex; any other
+			// space is required, so a wildcard never matches part of a word
+			if !wildcardOwnsSpace(pattern, i) {
 				result.WriteRune(' ')
 			}
 		case '(':
@@ -1998,7 +2587,32 @@ func patternToRegexWithSetsCached(g *Golem, pattern string, kb *AIMLKnowledgeBas
 			} else {
 				result.WriteString("\\)")
 			}
-		case '[', ']', '{', '}', '?', '+', '.':
+		case '[':
+			if !inOptional && strings.IndexByte(pattern[i:], ']') > 0 {
+				inOptional = true
+				optionalHasSpace = i > 0 && pattern[i-1] == ' '
+				if optionalHasSpace {
+					result.WriteString("(?: ")
+				} else {
+					result.WriteString("(?:")
+				}
+			} else {
+				result.WriteString("\\[")
+			}
+		case ']':
+			if inOptional {
+				inOptional = false
+				if !optionalHasSpace && i+1 < len(pattern) && pattern[i+1] == ' ' {
+					// A leading optional segment takes the space after it instead
+					result.WriteString(" )?")
+					skipSpace = true
+				} else {
+					result.WriteString(")?")
+				}
+			} else {
+				result.WriteString("\\]")
+			}
+		case '{', '}', '?', '+', '.':
 			// Escape special regex characters
 			result.WriteRune('\\')
 			result.WriteRune(char)
@@ -2035,8 +2649,21 @@ func findMatchingParen(pattern string, openPos int) int {
 	return -1
 }
 
-// ProcessTemplate processes an AIML template and returns the response
+// ProcessTemplate processes an AIML template and returns the response. A template that
+// fails to process yields "[Error processing template]"; use ProcessTemplateE to get
+// the error instead.
 func (g *Golem) ProcessTemplate(template string, wildcards map[string]string) string {
+	response, err := g.ProcessTemplateE(template, wildcards)
+	if err != nil {
+		g.LogError("Error in template processing: %v", err)
+		return "[Error processing template]"
+	}
+	return response
+}
+
+// ProcessTemplateE processes an AIML template like ProcessTemplate, returning the
+// template engine's error rather than a placeholder response
+func (g *Golem) ProcessTemplateE(template string, wildcards map[string]string) (string, error) {
 	// Create variable context for template processing
 	ctx := &VariableContext{
 		LocalVars:     make(map[string]string),
@@ -2052,14 +2679,14 @@ func (g *Golem) ProcessTemplate(template string, wildcards map[string]string) st
 		g.LogInfo("ProcessTemplate: No knowledge base set")
 	}
 
-	result := g.processTemplateWithContext(template, wildcards, ctx)
+	result, err := g.runTemplateEngine(template, wildcards, ctx)
 
 	if g.aimlKB != nil {
 		g.LogInfo("ProcessTemplate: After processing, KB pointer=%p, KB variables=%v", g.aimlKB, g.aimlKB.Variables)
 		g.LogInfo("ProcessTemplate: After processing, Context KB pointer=%p, Context KB variables=%v", ctx.KnowledgeBase, ctx.KnowledgeBase.Variables)
 	}
 
-	return result
+	return result, err
 }
 
 // ProcessTemplateWithContext processes an AIML template with full context support
@@ -2080,6 +2707,25 @@ func (g *Golem) ProcessTemplateWithContext(template string, wildcards map[string
 	return g.processTemplateWithContext(template, wildcards, ctx)
 }
 
+// processCategoryWithContext processes a matched category's template like
+// ProcessTemplateWithContext, recording the category as active so an SRAI that
+// resolves back to it with the same input is caught before it recurses
+func (g *Golem) processCategoryWithContext(category *Category, input string, wildcards map[string]string, session *ChatSession) string {
+	if g.aimlKB == nil {
+		g.aimlKB = NewAIMLKnowledgeBase()
+	}
+	ctx := &VariableContext{
+		LocalVars:      make(map[string]string),
+		Session:        session,
+		Topic:          session.GetSessionTopic(),
+		KnowledgeBase:  g.aimlKB,
+		RecursionDepth: 0,
+	}
+	ctx.ActiveCategories = ctx.withActiveCategory(category, input)
+
+	return g.processCategoryTemplate(category, wildcards, ctx)
+}
+
 // getCachedRegex returns a compiled regex from the appropriate cache
 func (g *Golem) getCachedRegex(pattern string, cacheType string) *regexp.Regexp {
 	var cache *RegexCache
@@ -2116,19 +2762,28 @@ func (g *Golem) getCachedRegex(pattern string, cacheType string) *regexp.Regexp
 // 7. Collection processing (map, list, array tags)
 // 8. System processing (size, version, id, that, request, response tags)
 func (g *Golem) processTemplateWithContext(template string, wildcards map[string]string, ctx *VariableContext) string {
-	// Use tree-based AST processing (now the only method)
-	if g.treeProcessor == nil {
-		g.treeProcessor = NewTreeProcessor(g)
-	}
-	response, err := g.treeProcessor.ProcessTemplate(template, wildcards, ctx)
+	response, err := g.runTemplateEngine(template, wildcards, ctx)
 	if err != nil {
-		g.LogError("Error in tree-based template processing: %v", err)
+		g.LogError("Error in template processing: %v", err)
 		// NEVER return templates with XML tags - return error message instead
 		return "[Error processing template]"
 	}
 	return response
 }
 
+// runTemplateEngine processes a template with the installed template engine, or the
+// tree processor if none has been installed
+func (g *Golem) runTemplateEngine(template string, wildcards map[string]string, ctx *VariableContext) (string, error) {
+	var engine TemplateEngine = g.templateEngine
+	if engine == nil {
+		if g.treeProcessor == nil {
+			g.treeProcessor = NewTreeProcessor(g)
+		}
+		engine = g.treeProcessor
+	}
+	return engine.ProcessTemplate(template, wildcards, ctx)
+}
+
 // processPersonTagsWithContext processes <person> tags for pronoun substitution
 func (g *Golem) processPersonTagsWithContext(template string, ctx *VariableContext) string {
 	// Find all <person> tags (including multiline content)
@@ -2227,6 +2882,19 @@ func (g *Golem) processPerson2TagsWithContext(template string, ctx *VariableCont
 	return template
 }
 
+// wordPunctuation is the punctuation stripped from either end of a word before pronoun lookup
+const wordPunctuation = ".,!?;:\"'()[]{}"
+
+// splitWordPunctuation splits a word into its leading punctuation, the bare word, and its
+// trailing punctuation, so substitutions can match "him!" or "(he" and keep the punctuation
+func splitWordPunctuation(word string) (leading, core, trailing string) {
+	core = strings.TrimLeft(word, wordPunctuation)
+	leading = word[:len(word)-len(core)]
+	trimmed := strings.TrimRight(core, wordPunctuation)
+	trailing = core[len(trimmed):]
+	return leading, trimmed, trailing
+}
+
 // SubstitutePronouns performs pronoun substitution for person tags
 func (g *Golem) SubstitutePronouns(text string) string {
 	// Comprehensive pronoun mapping for first/second person substitution
@@ -2267,10 +2935,13 @@ func (g *Golem) SubstitutePronouns(text string) string {
 	words := strings.Fields(text)
 	substitutedWords := make([]string, len(words))
 
-	for i, word := range words {
+	for i, original := range words {
+		// Match on the bare word and put its surrounding punctuation back afterwards
+		leading, word, trailing := splitWordPunctuation(original)
+
 		// Check for exact match first
 		if substitution, exists := pronounMap[word]; exists {
-			substitutedWords[i] = substitution
+			substitutedWords[i] = leading + substitution + trailing
 			continue
 		}
 
@@ -2312,7 +2983,7 @@ func (g *Golem) SubstitutePronouns(text string) string {
 			substituted = word
 		}
 
-		substitutedWords[i] = substituted
+		substitutedWords[i] = leading + substituted + trailing
 	}
 
 	result := strings.Join(substitutedWords, " ")
@@ -2380,10 +3051,13 @@ func (g *Golem) SubstitutePronouns2(text string) string {
 	words := strings.Fields(text)
 	substitutedWords := make([]string, len(words))
 
-	for i, word := range words {
+	for i, original := range words {
+		// Match on the bare word and put its surrounding punctuation back afterwards
+		leading, word, trailing := splitWordPunctuation(original)
+
 		// Check for exact match first
 		if substitution, exists := pronounMap[word]; exists {
-			substitutedWords[i] = substitution
+			substitutedWords[i] = leading + substitution + trailing
 			continue
 		}
 
@@ -2413,7 +3087,7 @@ func (g *Golem) SubstitutePronouns2(text string) string {
 			}
 		}
 
-		substitutedWords[i] = substituted
+		substitutedWords[i] = leading + substituted + trailing
 	}
 
 	result := strings.Join(substitutedWords, " ")
@@ -2457,7 +3131,7 @@ func (g *Golem) SubstituteGenderPronouns(text string) string {
 
 	for i, word := range words {
 		// Clean word for matching (remove punctuation)
-		cleanWord := strings.Trim(word, ".,!?;:\"'()[]{}")
+		leading, cleanWord, trailing := splitWordPunctuation(word)
 		lowerWord := strings.ToLower(cleanWord)
 
 		// Gender pronoun mapping (masculine to feminine and vice versa)
@@ -2490,10 +3164,7 @@ func (g *Golem) SubstituteGenderPronouns(text string) string {
 			}
 
 			// Add back any punctuation that was removed
-			if len(cleanWord) < len(word) {
-				suffix := word[len(cleanWord):]
-				result[i] += suffix
-			}
+			result[i] = leading + result[i] + trailing
 		} else {
 			// No substitution needed
 			result[i] = word
@@ -2558,21 +3229,27 @@ func (g *Golem) processSRAITagsWithContext(template string, ctx *VariableContext
 			// Process the SRAI content as a new pattern
 			if g.aimlKB != nil {
 				// Try to match the SRAI content as a pattern
-				category, wildcards, err := g.aimlKB.MatchPattern(sraiContent)
+				category, wildcards, err := g.aimlKB.MatchPatternWithTopic(sraiContent, ctx.currentTopic())
 				g.LogInfo("SRAI pattern match: content='%s', err=%v, category=%v, wildcards=%v", sraiContent, err, category != nil, wildcards)
-				if err == nil && category != nil {
+				if err == nil && category != nil && ctx.isActiveCategory(category, sraiContent) {
+					// The SRAI leads straight back into a category being processed
+					g.LogWarn("SRAI self-reference detected: '%s' resolves to pattern '%s' already being processed, stopping recursion", sraiContent, category.Pattern)
+					template = g.replaceSRAITag(template, match[0], "")
+				} else if err == nil && category != nil {
 					// Create a new context with incremented recursion depth
 					newCtx := &VariableContext{
-						LocalVars:      ctx.LocalVars,
-						Session:        ctx.Session,
-						Topic:          ctx.Topic,
-						KnowledgeBase:  ctx.KnowledgeBase,
-						RecursionDepth: ctx.RecursionDepth + 1,
+						LocalVars:        ctx.LocalVars,
+						Session:          ctx.Session,
+						Topic:            ctx.Topic,
+						KnowledgeBase:    ctx.KnowledgeBase,
+						RecursionDepth:   ctx.RecursionDepth + 1,
+						ActiveCategories: ctx.withActiveCategory(category, sraiContent),
 					}
 
 					// Process the matched template with the new context
+					g.notifySRAIMatched(category, sraiContent)
 					response := g.processTemplateWithContext(category.Template, wildcards, newCtx)
-					template = strings.ReplaceAll(template, match[0], response)
+					template = g.replaceSRAITag(template, match[0], response)
 				} else {
 					// No match found, leave the SRAI tag unchanged
 					g.LogInfo("SRAI no match for: '%s'", sraiContent)
@@ -2709,9 +3386,9 @@ func (g *Golem) processLowercaseTagsWithContext(template string, ctx *VariableCo
 				continue
 			}
 
-			// Normalize whitespace before lowercasing
+			// Normalize whitespace before lowercasing, leaving entity references alone
 			content = regexp.MustCompile(`\s+`).ReplaceAllString(content, " ")
-			processedContent := strings.ToLower(content)
+			processedContent := transformPreservingEntities(content, strings.ToLower)
 
 			g.LogDebug("Lowercase tag: '%s' -> '%s'", match[1], processedContent)
 			template = strings.ReplaceAll(template, match[0], processedContent)
@@ -2786,7 +3463,29 @@ func (g *Golem) formatFormalText(input string) string {
 	return strings.Join(words, " ")
 }
 
+// entityReferenceRegex matches XML/HTML entity references such as &amp;, &eacute;,
+// &#233; and &#xE9;, which case conversion must leave alone to keep them valid
+var entityReferenceRegex = regexp.MustCompile(`&(?:[A-Za-z][A-Za-z0-9]*|#[0-9]+|#[xX][0-9A-Fa-f]+);`)
+
+// transformPreservingEntities applies transform to text, leaving entity references as-is
+func transformPreservingEntities(text string, transform func(string) string) string {
+	if !strings.Contains(text, "&") {
+		return transform(text)
+	}
+
+	var result strings.Builder
+	last := 0
+	for _, match := range entityReferenceRegex.FindAllStringIndex(text, -1) {
+		result.WriteString(transform(text[last:match[0]]))
+		result.WriteString(text[match[0]:match[1]])
+		last = match[1]
+	}
+	result.WriteString(transform(text[last:]))
+	return result.String()
+}
+
 // uppercaseTextPreservingTags converts text to uppercase while preserving tag names
+// and entity references
 func (g *Golem) uppercaseTextPreservingTags(input string) string {
 	// Use regex to find all XML/AIML tags and preserve them
 	tagRegex := regexp.MustCompile(`<[^>]*>`)
@@ -2799,7 +3498,7 @@ func (g *Golem) uppercaseTextPreservingTags(input string) string {
 		// Add text before the tag (uppercased)
 		if match[0] > lastIndex {
 			textPart := input[lastIndex:match[0]]
-			result.WriteString(strings.ToUpper(textPart))
+			result.WriteString(transformPreservingEntities(textPart, strings.ToUpper))
 		}
 
 		// Add the tag as-is (preserve case)
@@ -2812,7 +3511,7 @@ func (g *Golem) uppercaseTextPreservingTags(input string) string {
 	// Add any remaining text after the last tag (uppercased)
 	if lastIndex < len(input) {
 		textPart := input[lastIndex:]
-		result.WriteString(strings.ToUpper(textPart))
+		result.WriteString(transformPreservingEntities(textPart, strings.ToUpper))
 	}
 
 	return result.String()
@@ -3170,7 +3869,11 @@ func (g *Golem) processSubstringTagsWithContext(template string, ctx *VariableCo
 	return template
 }
 
-// extractSubstring extracts a substring from text based on start and end positions
+// extractSubstring extracts a substring from text based on start and end positions.
+// Positions are character (rune) indices with Python slice semantics: start is
+// inclusive, end is exclusive, negative values count back from the end of the text,
+// and out-of-range values are clamped to the text. A missing or non-numeric start
+// defaults to 0 and a missing or non-numeric end defaults to the text length.
 func (g *Golem) extractSubstring(input, startStr, endStr string) string {
 	// Convert to rune slice to handle Unicode properly
 	runes := []rune(input)
@@ -3194,13 +3897,8 @@ func (g *Golem) extractSubstring(input, startStr, endStr string) string {
 		}
 	}
 
-	// Validate bounds
-	if start < 0 {
-		start = 0
-	}
-	if end > len(runes) {
-		end = len(runes)
-	}
+	start = clampSliceIndex(start, len(runes))
+	end = clampSliceIndex(end, len(runes))
 	if start >= end {
 		return ""
 	}
@@ -3209,20 +3907,44 @@ func (g *Golem) extractSubstring(input, startStr, endStr string) string {
 	return string(runes[start:end])
 }
 
+// clampSliceIndex resolves a possibly negative index against length and clamps it to [0, length]
+func clampSliceIndex(index, length int) int {
+	if index < 0 {
+		index += length
+	}
+	if index < 0 {
+		return 0
+	}
+	if index > length {
+		return length
+	}
+	return index
+}
+
 // processReplaceTagsWithContext processes <replace> tags for string replacement
-// <replace> tag replaces occurrences of a search string with a replacement string
+// <replace> tag replaces occurrences of a search string with a replacement string;
+// with mode="regex" the search is a regular expression (see replaceText)
 func (g *Golem) processReplaceTagsWithContext(template string, ctx *VariableContext) string {
 	// Find all <replace> tags (including multiline content)
-	replaceTagRegex := regexp.MustCompile(`(?s)<replace\s+search="([^"]*)"\s+replace="([^"]*)"\s*>(.*?)</replace>`)
+	replaceTagRegex := regexp.MustCompile(`(?s)<replace(\s[^>]*)>(.*?)</replace>`)
+	attributeRegex := regexp.MustCompile(`(\w+)="([^"]*)"`)
 	matches := replaceTagRegex.FindAllStringSubmatch(template, -1)
 
 	g.LogDebug("Replace tag processing: found %d matches in template: '%s'", len(matches), template)
 
 	for _, match := range matches {
-		if len(match) > 3 {
-			searchStr := match[1]
-			replaceStr := match[2]
-			content := strings.TrimSpace(match[3])
+		if len(match) > 2 {
+			attributes := make(map[string]string)
+			for _, attribute := range attributeRegex.FindAllStringSubmatch(match[1], -1) {
+				attributes[attribute[1]] = attribute[2]
+			}
+			searchStr, hasSearch := attributes["search"]
+			replaceStr, hasReplace := attributes["replace"]
+			if !hasSearch || !hasReplace {
+				continue
+			}
+			mode := attributes["mode"]
+			content := strings.TrimSpace(match[2])
 
 			// Replace empty content with empty string
 			if content == "" {
@@ -3232,19 +3954,19 @@ func (g *Golem) processReplaceTagsWithContext(template string, ctx *VariableCont
 
 			// Check cache first
 			var processedContent string
-			cacheKey := fmt.Sprintf("%s|%s|%s", searchStr, replaceStr, content)
+			cacheKey := fmt.Sprintf("%s|%s|%s|%s", mode, searchStr, replaceStr, content)
 			if g.templateTagProcessingCache != nil {
 				if cached, found := g.templateTagProcessingCache.GetProcessedTag("replace", cacheKey, ctx); found {
 					processedContent = cached
 				} else {
-					processedContent = g.replaceText(content, searchStr, replaceStr)
+					processedContent = g.replaceText(content, searchStr, replaceStr, mode)
 					g.templateTagProcessingCache.SetProcessedTag("replace", cacheKey, processedContent, ctx)
 				}
 			} else {
-				processedContent = g.replaceText(content, searchStr, replaceStr)
+				processedContent = g.replaceText(content, searchStr, replaceStr, mode)
 			}
 
-			g.LogDebug("Replace tag: '%s' (search='%s', replace='%s') -> '%s'", match[3], searchStr, replaceStr, processedContent)
+			g.LogDebug("Replace tag: '%s' (search='%s', replace='%s') -> '%s'", match[2], searchStr, replaceStr, processedContent)
 			template = strings.ReplaceAll(template, match[0], processedContent)
 		}
 	}
@@ -3254,9 +3976,27 @@ func (g *Golem) processReplaceTagsWithContext(template string, ctx *VariableCont
 	return template
 }
 
-// replaceText replaces all occurrences of search string with replacement string
-func (g *Golem) replaceText(input, search, replace string) string {
-	return strings.ReplaceAll(input, search, replace)
+// replaceText replaces all occurrences of search string with replacement string.
+// With mode "regex" the search is compiled as a regular expression and the
+// replacement may refer to capture groups as $1 or ${name}; an invalid expression
+// leaves the input unchanged.
+func (g *Golem) replaceText(input, search, replace, mode string) string {
+	if !strings.EqualFold(strings.TrimSpace(mode), "regex") {
+		return strings.ReplaceAll(input, search, replace)
+	}
+
+	var compiled *regexp.Regexp
+	var err error
+	if g.tagProcessingCache != nil {
+		compiled, err = g.tagProcessingCache.GetCompiledRegex(search)
+	} else {
+		compiled, err = regexp.Compile(search)
+	}
+	if err != nil {
+		g.LogWarn("Invalid regex in replace search=\"%s\": %v", search, err)
+		return input
+	}
+	return compiled.ReplaceAllString(input, replace)
 }
 
 // processPluralizeTagsWithContext processes <pluralize> tags for pluralization
@@ -3691,7 +4431,14 @@ func (g *Golem) processLengthTagsWithContext(template string, ctx *VariableConte
 func (g *Golem) calculateLength(content, lengthType string) string {
 	// Use the utility function for most cases
 	switch strings.ToLower(lengthType) {
-	case "words", "sentences", "characters", "chars", "letters", "words_no_punctuation":
+	case "sentences":
+		// Use the abbreviation-aware splitter so "Dr. Smith" doesn't end a sentence
+		splitter := g.sentenceSplitter
+		if splitter == nil {
+			splitter = NewSentenceSplitter()
+		}
+		return strconv.Itoa(len(splitter.SplitSentences(content)))
+	case "words", "characters", "chars", "letters", "words_no_punctuation":
 		return CalculateLength(content, lengthType)
 	case "digits":
 		// Count only digits
@@ -4240,34 +4987,7 @@ func (g *Golem) randomInt(max int) int {
 // processNormalizeTagsWithContext processes <normalize> tags for text normalization
 // <normalize> tag normalizes text using the same logic as pattern matching
 func (g *Golem) processNormalizeTagsWithContext(template string, ctx *VariableContext) string {
-	// Process normalize tags iteratively until no more changes occur
-	// Note: For nested normalize tags, use tree processing (EnableTreeProcessing)
-	prevTemplate := ""
-	for template != prevTemplate {
-		prevTemplate = template
-
-		normalizeTagRegex := regexp.MustCompile(`<normalize>([^<]*(?:<[^/][^>]*>[^<]*)*)</normalize>`)
-		match := normalizeTagRegex.FindStringSubmatch(template)
-
-		if match == nil {
-			// No more normalize tags found
-			break
-		}
-
-		content := strings.TrimSpace(match[1])
-		if content == "" {
-			// Empty normalize tag - replace with empty string
-			template = strings.Replace(template, match[0], "", 1)
-			continue
-		}
-
-		// Normalize the content
-		processedContent := g.normalizeTextForOutput(content)
-
-		g.LogDebug("Normalize tag: '%s' -> '%s'", match[1], processedContent)
-		template = strings.Replace(template, match[0], processedContent, 1)
-	}
-
+	template = g.processTagsWithTreeProcessor(template, "normalize", ctx)
 	g.LogDebug("Normalize tag processing result: '%s'", template)
 	return template
 }
@@ -4275,36 +4995,67 @@ func (g *Golem) processNormalizeTagsWithContext(template string, ctx *VariableCo
 // processDenormalizeTagsWithContext processes <denormalize> tags for text denormalization
 // <denormalize> tag reverses the normalization process to restore more natural text
 func (g *Golem) processDenormalizeTagsWithContext(template string, ctx *VariableContext) string {
-	// Process denormalize tags iteratively until no more changes occur
-	// Note: For nested denormalize tags, use tree processing (EnableTreeProcessing)
-	prevTemplate := ""
-	for template != prevTemplate {
-		prevTemplate = template
+	template = g.processTagsWithTreeProcessor(template, "denormalize", ctx)
+	g.LogDebug("Denormalize tag processing result: '%s'", template)
+	return template
+}
 
-		denormalizeTagRegex := regexp.MustCompile(`<denormalize>([^<]*(?:<[^/][^>]*>[^<]*)*)</denormalize>`)
-		match := denormalizeTagRegex.FindStringSubmatch(template)
+// processTagsWithTreeProcessor replaces each outermost tagName element in template with
+// its output from the tree processor, so tags nested inside it, including further
+// tagName elements, are resolved inner-first. An element without a matching closing
+// tag, and everything after it, is left unchanged.
+func (g *Golem) processTagsWithTreeProcessor(template, tagName string, ctx *VariableContext) string {
+	openTag := "<" + tagName + ">"
+	closeTag := "</" + tagName + ">"
 
-		if match == nil {
-			// No more denormalize tags found
+	var result strings.Builder
+	for {
+		start := strings.Index(template, openTag)
+		if start == -1 {
 			break
 		}
-
-		content := strings.TrimSpace(match[1])
-		if content == "" {
-			// Empty denormalize tag - replace with empty string
-			template = strings.Replace(template, match[0], "", 1)
-			continue
+		end := findMatchingCloseTag(template, start, openTag, closeTag)
+		if end == -1 {
+			g.LogWarn("Unclosed <%s> tag in template", tagName)
+			break
 		}
 
-		// Denormalize the content
-		processedContent := g.denormalizeText(content)
+		element := template[start:end]
+		output, err := NewTreeProcessor(g).ProcessTemplate(element, nil, ctx)
+		if err != nil {
+			g.LogWarn("Failed to process <%s> tag: %v", tagName, err)
+			output = element
+		}
+		g.LogDebug("%s tag: '%s' -> '%s'", tagName, element, output)
 
-		g.LogDebug("Denormalize tag: '%s' -> '%s'", match[1], processedContent)
-		template = strings.Replace(template, match[0], processedContent, 1)
+		result.WriteString(template[:start])
+		result.WriteString(output)
+		template = template[end:]
 	}
+	result.WriteString(template)
+	return result.String()
+}
 
-	g.LogDebug("Denormalize tag processing result: '%s'", template)
-	return template
+// findMatchingCloseTag returns the end of the element opened by openTag at start,
+// counting nested elements of the same name, or -1 if it isn't closed
+func findMatchingCloseTag(template string, start int, openTag, closeTag string) int {
+	depth := 0
+	for i := start; i < len(template); {
+		switch {
+		case strings.HasPrefix(template[i:], openTag):
+			depth++
+			i += len(openTag)
+		case strings.HasPrefix(template[i:], closeTag):
+			depth--
+			i += len(closeTag)
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return -1
 }
 
 // normalizeTextForOutput normalizes text for output (similar to pattern matching but for display)
@@ -4317,26 +5068,12 @@ func (g *Golem) normalizeTextForOutput(input string) string {
 	// Normalize whitespace
 	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
 
-	// Replace special characters with spaces first
-	text = strings.ReplaceAll(text, "@", " ")
-	text = strings.ReplaceAll(text, "-", " ")
-	text = strings.ReplaceAll(text, "_", " ")
-	text = strings.ReplaceAll(text, ".", " ")
-	text = strings.ReplaceAll(text, ":", " ")
-
-	// Remove other punctuation for normalization
-	text = strings.ReplaceAll(text, ",", "")
-	text = strings.ReplaceAll(text, "!", "")
-	text = strings.ReplaceAll(text, "?", "")
-	text = strings.ReplaceAll(text, ";", "")
-	text = strings.ReplaceAll(text, "#", "")
-	text = strings.ReplaceAll(text, "$", "")
-	text = strings.ReplaceAll(text, "%", "")
-	text = strings.ReplaceAll(text, "^", "")
-	text = strings.ReplaceAll(text, "&", "")
-	text = strings.ReplaceAll(text, "*", "")
-	text = strings.ReplaceAll(text, "(", "")
-	text = strings.ReplaceAll(text, ")", "")
+	// Replace special characters with spaces and remove other punctuation
+	rules := DefaultOutputNormalizationRules
+	if g.normalizationRules != nil {
+		rules = *g.normalizationRules
+	}
+	text = rules.apply(text)
 
 	// Expand contractions for better normalization
 	text = expandContractions(text)
@@ -4405,7 +5142,7 @@ func (g *Golem) processSRTagsWithContext(template string, wildcards map[string]s
 		if starContent != "" && ctx.KnowledgeBase != nil {
 			// Check if there's a matching pattern for the star content
 			// This prevents creating empty SRAI tags when no match exists
-			category, _, err := ctx.KnowledgeBase.MatchPattern(starContent)
+			category, _, err := ctx.KnowledgeBase.MatchPatternWithTopic(starContent, ctx.currentTopic())
 			if err == nil && category != nil {
 				// There's a matching pattern, convert <sr/> to <srai>content</srai>
 				sraiTag := fmt.Sprintf("<srai>%s</srai>", starContent)
@@ -4434,25 +5171,49 @@ func (g *Golem) processSRTagsWithContext(template string, wildcards map[string]s
 }
 
 // processSRAIXTagsWithContext processes <sraix> tags with variable context
+// sraixHeaderVariables resolves the {name} placeholders in a service's header values
+// against the variable context (local, session, topic, global, then properties), so
+// headers can carry per-session values such as API tokens
+func (g *Golem) sraixHeaderVariables(serviceName string, ctx *VariableContext) map[string]string {
+	if g.sraixMgr == nil || ctx == nil {
+		return nil
+	}
+	config, exists := g.sraixMgr.GetConfig(serviceName)
+	if !exists {
+		return nil
+	}
+
+	variables := make(map[string]string)
+	for _, value := range config.Headers {
+		for _, name := range extractPlaceholders(value) {
+			if resolved, found := g.resolveVariableWithPresence(name, ctx); found {
+				variables[name] = resolved
+			}
+		}
+	}
+	return variables
+}
+
 func (g *Golem) processSRAIXTagsWithContext(template string, ctx *VariableContext) string {
 	if g.sraixMgr == nil {
 		return template
 	}
 
 	// Enhanced regex to match SRAIX tags with multiple attributes
-	// Supports: service, bot, botid, host, default, hint attributes
-	sraixRegex := regexp.MustCompile(`<sraix\s+(?:service="([^"]*)"\s*)?(?:bot="([^"]*)"\s*)?(?:botid="([^"]*)"\s*)?(?:host="([^"]*)"\s*)?(?:default="([^"]*)"\s*)?(?:hint="([^"]*)"\s*)?>(.*?)</sraix>`)
+	// Supports: service, bot, botid, host, default, hint, includehistory attributes
+	sraixRegex := regexp.MustCompile(`<sraix\s+(?:service="([^"]*)"\s*)?(?:bot="([^"]*)"\s*)?(?:botid="([^"]*)"\s*)?(?:host="([^"]*)"\s*)?(?:default="([^"]*)"\s*)?(?:hint="([^"]*)"\s*)?(?:includehistory="([^"]*)"\s*)?>(.*?)</sraix>`)
 	matches := sraixRegex.FindAllStringSubmatch(template, -1)
 
 	for _, match := range matches {
-		if len(match) > 7 {
+		if len(match) > 8 {
 			serviceName := strings.TrimSpace(match[1])
 			botName := strings.TrimSpace(match[2])
 			botID := strings.TrimSpace(match[3])
 			hostName := strings.TrimSpace(match[4])
 			defaultResponse := strings.TrimSpace(match[5])
 			hintText := strings.TrimSpace(match[6])
-			sraixContent := strings.TrimSpace(match[7])
+			includeHistory := strings.TrimSpace(match[7])
+			sraixContent := strings.TrimSpace(match[8])
 
 			g.LogInfo("Processing SRAIX: service='%s', bot='%s', botid='%s', host='%s', default='%s', hint='%s', content='%s'",
 				serviceName, botName, botID, hostName, defaultResponse, hintText, sraixContent)
@@ -4500,7 +5261,13 @@ func (g *Golem) processSRAIXTagsWithContext(template string, ctx *VariableContex
 				requestParams["hint"] = processedHint
 			}
 
-			response, err := g.sraixMgr.ProcessSRAIX(targetService, processedContent, requestParams)
+			var session *ChatSession
+			if ctx != nil {
+				session = ctx.Session
+			}
+			processedContent = g.withSRAIXHistory(targetService, processedContent, includeHistory, session)
+
+			response, err := g.sraixMgr.ProcessSRAIXWithVariables(targetService, processedContent, requestParams, g.sraixHeaderVariables(targetService, ctx))
 			if err != nil {
 				g.LogInfo("SRAIX request failed: %v", err)
 				// Use default response if available, otherwise leave tag unchanged
@@ -4518,19 +5285,41 @@ func (g *Golem) processSRAIXTagsWithContext(template string, ctx *VariableContex
 	return template
 }
 
+// learnScopeTopic returns the active topic that a topic-scoped <learn> confines its
+// categories to
+func learnScopeTopic(ctx *VariableContext) string {
+	if ctx == nil {
+		return ""
+	}
+	if ctx.Session != nil {
+		if topic := ctx.Session.GetSessionTopic(); topic != "" {
+			return topic
+		}
+	}
+	return ctx.Topic
+}
+
 // processLearnTagsWithContext processes <learn> and <learnf> tags with variable context
 func (g *Golem) processLearnTagsWithContext(template string, ctx *VariableContext) string {
 	if g.aimlKB == nil {
 		return template
 	}
 
-	// Process <learn> tags (session-specific learning)
-	learnRegex := regexp.MustCompile(`(?s)<learn>(.*?)</learn>`)
+	// Process <learn> tags (session-specific learning); with scope="topic" the learned
+	// categories only match under the topic active when they were learned
+	learnRegex := regexp.MustCompile(`(?s)<learn(\s+scope="([^"]*)")?>(.*?)</learn>`)
 	learnMatches := learnRegex.FindAllStringSubmatch(template, -1)
 
 	for _, match := range learnMatches {
-		if len(match) > 1 {
-			learnContent := strings.TrimSpace(match[1])
+		if len(match) > 3 {
+			learnContent := strings.TrimSpace(match[3])
+			scopedTopic := ""
+			if strings.EqualFold(strings.TrimSpace(match[2]), "topic") {
+				scopedTopic = learnScopeTopic(ctx)
+				if scopedTopic == "" {
+					g.LogInfo("Learn scoped to topic without an active topic, learning globally")
+				}
+			}
 
 			g.LogInfo("Processing learn: '%s'", learnContent)
 
@@ -4545,6 +5334,9 @@ func (g *Golem) processLearnTagsWithContext(template string, ctx *VariableContex
 
 			// Add categories to session-specific knowledge base
 			for _, category := range categories {
+				if scopedTopic != "" && category.Topic == "" {
+					category.Topic = scopedTopic
+				}
 				err := g.addSessionCategory(category, ctx)
 				if err != nil {
 					g.LogInfo("Failed to add session category: %v", err)
@@ -4667,17 +5459,23 @@ func (g *Golem) processUnlearnTagsWithContext(template string, ctx *VariableCont
 // processThinkTagsWithContext processes <think> tags with variable context
 func (g *Golem) processThinkTagsWithContext(template string, ctx *VariableContext) string {
 	// Find all <think> tags
-	thinkRegex := regexp.MustCompile(`<think>(.*?)</think>`)
+	thinkRegex := regexp.MustCompile(`<think(?:\s+scope=["']([^"']*)["'])?\s*>(.*?)</think>`)
 	matches := thinkRegex.FindAllStringSubmatch(template, -1)
 
 	for _, match := range matches {
-		if len(match) > 1 {
-			thinkContent := strings.TrimSpace(match[1])
+		if len(match) > 2 {
+			thinkContent := strings.TrimSpace(match[2])
 
 			g.LogInfo("Processing think: '%s'", thinkContent)
 
-			// Process the think content (internal operations)
-			g.processThinkContentWithContext(thinkContent, ctx)
+			// Process the think content (internal operations). With scope="local" the
+			// variables it sets only last for this template.
+			scope := ScopeSession
+			if strings.EqualFold(match[1], "local") {
+				scope = ScopeLocal
+			}
+			g.processThinkContentWithContext(thinkContent, scope, ctx)
+			g.notifyThink(thinkContent)
 
 			// Remove the think tag from the output
 			template = strings.ReplaceAll(template, match[0], "")
@@ -4687,33 +5485,45 @@ func (g *Golem) processThinkTagsWithContext(template string, ctx *VariableContex
 	return template
 }
 
+// thinkCollectionOperationRegex matches set, list and array tags with an operation
+// attribute, in both the self-closing and the content form
+var thinkCollectionOperationRegex = regexp.MustCompile(`(?s)<(set|list|array)(\s+[^>]*?operation=["'][^"']+["'][^>]*?)(?:/>|>(.*?)</(?:set|list|array)>)`)
+
 // processThinkContentWithContext processes the content inside <think> tags with variable context
-func (g *Golem) processThinkContentWithContext(content string, ctx *VariableContext) {
+func (g *Golem) processThinkContentWithContext(content string, scope VariableScope, ctx *VariableContext) {
 	// Process date/time tags first
 	content = g.processDateTimeTags(content)
 
-	// Find all <set> tags
+	// Collection operations (e.g. operation="clear") go to the same handlers as the
+	// output pipeline, with their output discarded. They run in document order with the
+	// variable assignments, so an operation sees the variables set before it.
 	setRegex := regexp.MustCompile(`<set name="([^"]+)">(.*?)</set>`)
-	matches := setRegex.FindAllStringSubmatch(content, -1)
-
-	for _, match := range matches {
-		if len(match) > 2 {
-			varName := match[1]
-			varValue := strings.TrimSpace(match[2])
-
-			g.LogInfo("Setting variable: %s = %s", varName, varValue)
+	for content != "" {
+		operation := thinkCollectionOperationRegex.FindStringIndex(content)
+		assignment := setRegex.FindStringSubmatchIndex(content)
+		if operation == nil && assignment == nil {
+			break
+		}
+		if assignment == nil || (operation != nil && operation[0] < assignment[0]) {
+			g.processThinkCollectionOperations(content[operation[0]:operation[1]], ctx)
+			content = content[operation[1]:]
+			continue
+		}
 
-			// Determine scope based on context
-			scope := ScopeGlobal // Default to global scope
-			if ctx.Session != nil {
-				// If we have a session, use session scope for think tags
-				// This allows variables to be set in the session context
-				scope = ScopeSession
-			}
+		varName := content[assignment[2]:assignment[3]]
+		varValue := strings.TrimSpace(content[assignment[4]:assignment[5]])
+		content = content[assignment[1]:]
 
-			// Set the variable in the appropriate scope
-			g.setVariable(varName, varValue, scope, ctx)
+		g.LogInfo("Setting variable: %s = %s", varName, varValue)
+
+		// Without a session, session-scoped think variables are global
+		varScope := scope
+		if varScope == ScopeSession && ctx.Session == nil {
+			varScope = ScopeGlobal
 		}
+
+		// Set the variable in the appropriate scope
+		g.setVariable(varName, varValue, varScope, ctx)
 	}
 
 	// Process other think operations here as needed
@@ -4721,6 +5531,35 @@ func (g *Golem) processThinkContentWithContext(content string, ctx *VariableCont
 	// to handle other internal operations like learning, logging, etc.
 }
 
+// processThinkCollectionOperations runs the set, list and array operations in think
+// content and returns the content with those tags removed
+func (g *Golem) processThinkCollectionOperations(content string, ctx *VariableContext) string {
+	return thinkCollectionOperationRegex.ReplaceAllStringFunc(content, func(match string) string {
+		if ctx.KnowledgeBase == nil {
+			return ""
+		}
+		parts := thinkCollectionOperationRegex.FindStringSubmatch(match)
+		tagName, attributes, body := parts[1], strings.TrimSpace(parts[2]), parts[3]
+
+		// The handlers expect the content form, so expand self-closing tags
+		tag := "<" + tagName + " " + attributes + ">" + body + "</" + tagName + ">"
+		g.LogInfo("Think collection operation: %s", tag)
+
+		switch tagName {
+		case "set":
+			if ctx.KnowledgeBase.Sets == nil {
+				ctx.KnowledgeBase.Sets = make(map[string][]string)
+			}
+			g.processSetTagsWithContext(tag, ctx)
+		case "list":
+			g.processListTagsWithContext(tag, ctx)
+		case "array":
+			g.processArrayTagsWithContext(tag, ctx)
+		}
+		return ""
+	})
+}
+
 // processConditionTagsWithContext processes <condition> tags with variable context
 func (g *Golem) processConditionTagsWithContext(template string, ctx *VariableContext) string {
 	// Use regex to find and process conditions
@@ -4791,32 +5630,75 @@ func (g *Golem) processConditionContentWithContext(content string, varName, actu
 
 // processConditionListItemsWithContext processes <li> elements within condition tags with variable context
 func (g *Golem) processConditionListItemsWithContext(content string, actualValue string, ctx *VariableContext) string {
-	// Find all <li> elements with optional value attributes
-	liRegex := regexp.MustCompile(`(?s)<li(?: value="([^"]+)")?>(.*?)</li>`)
-	matches := liRegex.FindAllStringSubmatch(content, -1)
-
-	for _, match := range matches {
-		if len(match) < 3 {
+	// Only top-level <li> elements are branches; nested ones (e.g. inside <random>) belong
+	// to the branch content and are processed when the selected branch is
+	var defaultItem *listItem
+	items := splitTopLevelListItems(content)
+	for i, item := range items {
+		// If no value specified, this is the default case, used only when no valued
+		// item matches wherever it is listed
+		if item.value == "" {
+			if defaultItem == nil {
+				defaultItem = &items[i]
+			}
 			continue
 		}
 
-		liValue := match[1]
-		liContent := strings.TrimSpace(match[2])
-
-		// If no value specified, this is the default case
-		if liValue == "" {
-			return g.processTemplateWithContext(liContent, make(map[string]string), ctx)
-		}
-
 		// Check if this condition matches
-		if strings.EqualFold(actualValue, liValue) {
-			return g.processTemplateWithContext(liContent, make(map[string]string), ctx)
+		if strings.EqualFold(actualValue, item.value) {
+			return g.processTemplateWithContext(strings.TrimSpace(item.content), make(map[string]string), ctx)
 		}
 	}
 
+	if defaultItem != nil {
+		return g.processTemplateWithContext(strings.TrimSpace(defaultItem.content), make(map[string]string), ctx)
+	}
 	return "" // No match found
 }
 
+// listItem is a top-level <li> element found by splitTopLevelListItems
+type listItem struct {
+	value   string // value attribute, empty for the default item
+	content string // raw inner content, including any nested <li> elements
+}
+
+var listItemOpenRegex = regexp.MustCompile(`^<li(?:\s+value=["']([^"']*)["'])?\s*>`)
+
+// splitTopLevelListItems returns the <li> elements directly inside content, keeping
+// nested <li> elements (such as those of an inner <random>) within their parent's content
+func splitTopLevelListItems(content string) []listItem {
+	var items []listItem
+	depth := 0
+	start := 0
+	value := ""
+	for i := 0; i < len(content); i++ {
+		if content[i] != '<' {
+			continue
+		}
+		rest := content[i:]
+		if strings.HasPrefix(rest, "</li>") {
+			depth--
+			if depth == 0 {
+				items = append(items, listItem{value: value, content: content[start:i]})
+			}
+			if depth < 0 {
+				depth = 0
+			}
+			i += len("</li>") - 1
+			continue
+		}
+		if open := listItemOpenRegex.FindStringSubmatch(rest); open != nil {
+			if depth == 0 {
+				value = open[1]
+				start = i + len(open[0])
+			}
+			depth++
+			i += len(open[0]) - 1
+		}
+	}
+	return items
+}
+
 // replaceSessionVariableTagsWithContext replaces <get name="var"/> and <get name="var"></get> tags with variables using context
 func (g *Golem) replaceSessionVariableTagsWithContext(template string, ctx *VariableContext) string {
 	// Find all <get name="var"/> tags (self-closing) - case-insensitive attribute
@@ -4826,7 +5708,7 @@ func (g *Golem) replaceSessionVariableTagsWithContext(template string, ctx *Vari
 	for _, match := range matches {
 		if len(match) > 1 {
 			varName := match[1]
-			varValue, found := g.resolveVariableWithPresence(varName, ctx)
+			varValue, found := g.resolveGetVariable(varName, ctx)
 			if found {
 				// Replace even if empty string
 				template = strings.ReplaceAll(template, match[0], varValue)
@@ -4841,7 +5723,7 @@ func (g *Golem) replaceSessionVariableTagsWithContext(template string, ctx *Vari
 	for _, match := range matches2 {
 		if len(match) > 1 {
 			varName := match[1]
-			varValue, found := g.resolveVariableWithPresence(varName, ctx)
+			varValue, found := g.resolveGetVariable(varName, ctx)
 			if found {
 				// Replace even if empty string
 				template = strings.ReplaceAll(template, match[0], varValue)
@@ -4852,6 +5734,9 @@ func (g *Golem) replaceSessionVariableTagsWithContext(template string, ctx *Vari
 	return template
 }
 
+// selfClosingSetOperationRegex matches a self-closing set tag with an operation attribute
+var selfClosingSetOperationRegex = regexp.MustCompile(`<set\s+([^>]*?operation=["'][^"']+["'][^>]*?)\s*/>`)
+
 // processSetTagsWithContext processes <set> tags with enhanced AIML2 set operations
 func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext) string {
 	// Allow variable setting even if knowledge base is nil (handled above to ensure non-nil)
@@ -4860,29 +5745,32 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 	// Support both variable assignment and set operations
 	var setRegex *regexp.Regexp
 	if g.tagProcessingCache != nil {
-		pattern := `(?s)<set\s+name=["']([^"']+)["'](?:\s+operation=["']([^"']+)["'])?>(.*?)</set>`
+		pattern := `(?s)<set\s+name=["']([^"']+)["'](?:\s+operation=["']([^"']+)["'])?(?:\s+default=["']([^"']*)["'])?>(.*?)</set>`
 		if compiled, err := g.tagProcessingCache.GetCompiledRegex(pattern); err == nil {
 			setRegex = compiled
 		} else {
 			setRegex = regexp.MustCompile(pattern)
 		}
 	} else {
-		setRegex = regexp.MustCompile(`(?s)<set\s+name=["']([^"']+)["'](?:\s+operation=["']([^"']+)["'])?>(.*?)</set>`)
+		setRegex = regexp.MustCompile(`(?s)<set\s+name=["']([^"']+)["'](?:\s+operation=["']([^"']+)["'])?(?:\s+default=["']([^"']*)["'])?>(.*?)</set>`)
 	}
+	// Self-closing collection operations (e.g. operation="get") mean the same as the empty paired form
+	template = selfClosingSetOperationRegex.ReplaceAllString(template, "<set $1></set>")
 	g.LogInfo("Set processing: template before processing: '%s'", template)
 	g.LogInfo("Current sets state: %v", ctx.KnowledgeBase.Sets)
 
 	// Process set tags one at a time to maintain order and avoid conflicts
 	for {
 		matches := setRegex.FindStringSubmatch(template)
-		if len(matches) < 4 {
+		if len(matches) < 5 {
 			break
 		}
 		match := matches
-		if len(match) >= 4 {
+		if len(match) >= 5 {
 			setName := match[1]
 			operation := match[2]
-			content := strings.TrimSpace(match[3])
+			defaultValue := match[3] // Returned by get when the set is empty
+			content := strings.TrimSpace(match[4])
 
 			g.LogInfo("Processing set tag: name='%s', operation='%s', content='%s'", setName, operation, content)
 
@@ -4897,9 +5785,8 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 			case "add", "insert":
 				// Add item to set (if not already present)
 				if content != "" {
-					// Use content directly (no template processing to avoid recursion)
-					processedContent := strings.TrimSpace(content)
-					g.LogInfo("Add operation - original content: '%s', trimmed: '%s'", content, processedContent)
+					processedContent := g.resolveSetOperationContent(content, ctx)
+					g.LogInfo("Add operation - original content: '%s', resolved: '%s'", content, processedContent)
 
 					// Only add if content is not empty after trimming
 					if processedContent != "" {
@@ -4915,6 +5802,7 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 							ctx.KnowledgeBase.Sets[setName] = append(ctx.KnowledgeBase.Sets[setName], processedContent)
 							g.LogInfo("Added '%s' to set '%s'", processedContent, setName)
 							g.LogInfo("After add: set '%s' = %v", setName, ctx.KnowledgeBase.Sets[setName])
+							g.InvalidatePatternMatchingSet(setName)
 						} else {
 							g.LogInfo("Item '%s' already exists in set '%s'", processedContent, setName)
 						}
@@ -4926,14 +5814,14 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 			case "remove", "delete":
 				// Remove item from set
 				if content != "" {
-					// Use content directly (no template processing to avoid recursion)
-					processedContent := strings.TrimSpace(content)
+					processedContent := g.resolveSetOperationContent(content, ctx)
 
 					for i, item := range ctx.KnowledgeBase.Sets[setName] {
 						if strings.EqualFold(item, processedContent) {
 							ctx.KnowledgeBase.Sets[setName] = append(ctx.KnowledgeBase.Sets[setName][:i], ctx.KnowledgeBase.Sets[setName][i+1:]...)
 							g.LogInfo("Removed '%s' from set '%s'", processedContent, setName)
 							g.LogInfo("After remove: set '%s' = %v", setName, ctx.KnowledgeBase.Sets[setName])
+							g.InvalidatePatternMatchingSet(setName)
 							break
 						}
 					}
@@ -4945,6 +5833,7 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 				// Clear the set
 				ctx.KnowledgeBase.Sets[setName] = make([]string, 0)
 				template = strings.Replace(template, match[0], "", 1)
+				g.InvalidatePatternMatchingSet(setName)
 				g.LogInfo("Cleared set '%s'", setName)
 				g.LogInfo("After clear: set '%s' = %v", setName, ctx.KnowledgeBase.Sets[setName])
 
@@ -4958,8 +5847,7 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 				// Check if set contains item
 				contains := false
 				if content != "" {
-					// Use content directly (no template processing to avoid recursion)
-					processedContent := strings.TrimSpace(content)
+					processedContent := g.resolveSetOperationContent(content, ctx)
 
 					for _, item := range ctx.KnowledgeBase.Sets[setName] {
 						if strings.EqualFold(item, processedContent) {
@@ -4978,7 +5866,7 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 			case "get", "list":
 				// Get all items in the set or return the set as a string
 				if len(ctx.KnowledgeBase.Sets[setName]) == 0 {
-					template = strings.Replace(template, match[0], "", 1)
+					template = strings.Replace(template, match[0], defaultValue, 1)
 				} else {
 					setString := strings.Join(ctx.KnowledgeBase.Sets[setName], " ")
 					template = strings.Replace(template, match[0], setString, 1)
@@ -5015,7 +5903,7 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 				} else {
 					// Content is empty, treat as get operation (return set contents)
 					if len(ctx.KnowledgeBase.Sets[setName]) == 0 {
-						template = strings.Replace(template, match[0], "", 1)
+						template = strings.Replace(template, match[0], defaultValue, 1)
 					} else {
 						setString := strings.Join(ctx.KnowledgeBase.Sets[setName], " ")
 						template = strings.Replace(template, match[0], setString, 1)
@@ -5029,6 +5917,22 @@ func (g *Golem) processSetTagsWithContext(template string, ctx *VariableContext)
 	return template
 }
 
+// resolveSetOperationContent resolves the tags in the item of a set add, remove or
+// contains operation, such as a <get> naming the item, so the value is used rather
+// than the tag. Content holding another <set> is used as is, so set processing is
+// never re-entered.
+func (g *Golem) resolveSetOperationContent(content string, ctx *VariableContext) string {
+	content = strings.TrimSpace(content)
+	if !strings.Contains(content, "<") {
+		return content
+	}
+	if strings.Contains(content, "<set") {
+		g.LogWarn("Not resolving nested <set> in set operation content: '%s'", content)
+		return content
+	}
+	return strings.TrimSpace(g.processTemplateContentForVariable(content, make(map[string]string), ctx))
+}
+
 // processTemplateContentForVariable processes template content for variable assignment without outputting
 // This function now uses the same processing pipeline as processTemplateWithContext to ensure consistency
 func (g *Golem) processTemplateContentForVariable(template string, wildcards map[string]string, ctx *VariableContext) string {
@@ -5044,9 +5948,10 @@ func (g *Golem) processTemplateContentForVariable(template string, wildcards map
 	return result
 }
 
-// replacePropertyTags replaces <get name="property"/> tags with property values
+// replacePropertyTags replaces <get name="property"/> tags with property values,
+// unless DisablePropertyFallback is set
 func (g *Golem) replacePropertyTags(template string) string {
-	if g.aimlKB == nil {
+	if g.aimlKB == nil || !g.propertyFallback() {
 		return template
 	}
 
@@ -5080,7 +5985,7 @@ func (g *Golem) processBotTagsWithContext(template string, ctx *VariableContext)
 	for _, match := range matches {
 		if len(match) > 1 {
 			propertyName := match[1]
-			propertyValue := ctx.KnowledgeBase.GetProperty(propertyName)
+			propertyValue := ctx.KnowledgeBase.GetPropertyForLanguage(propertyName, ctx.language())
 
 			g.LogInfo("Bot tag: property='%s', value='%s'", propertyName, propertyValue)
 
@@ -5114,7 +6019,7 @@ func (g *Golem) processSizeTagsWithContext(template string, ctx *VariableContext
 
 	if len(matches) > 0 {
 		// Get the number of categories
-		size := len(ctx.KnowledgeBase.Categories)
+		size := ctx.KnowledgeBase.categoryCount()
 		sizeStr := strconv.Itoa(size)
 
 		g.LogDebug("Size tag: found %d categories", size)
@@ -5159,6 +6064,48 @@ func (g *Golem) processVersionTagsWithContext(template string, ctx *VariableCont
 	return template
 }
 
+// processCategoryCountTagsWithContext processes <categorycount/> tags (alias of <size/>)
+func (g *Golem) processCategoryCountTagsWithContext(template string, ctx *VariableContext) string {
+	if !strings.Contains(template, "<categorycount/>") {
+		return template
+	}
+
+	count := 0
+	if ctx.KnowledgeBase != nil {
+		count = ctx.KnowledgeBase.categoryCount()
+	}
+
+	g.LogDebug("Categorycount tag: found %d categories", count)
+
+	return strings.ReplaceAll(template, "<categorycount/>", strconv.Itoa(count))
+}
+
+// processUptimeTagsWithContext processes <uptime/> tags to return the seconds elapsed since the Golem was created
+func (g *Golem) processUptimeTagsWithContext(template string, ctx *VariableContext) string {
+	if !strings.Contains(template, "<uptime/>") {
+		return template
+	}
+
+	uptime := strconv.FormatInt(int64(g.Uptime()/time.Second), 10)
+
+	g.LogDebug("Uptime tag: %s seconds", uptime)
+
+	return strings.ReplaceAll(template, "<uptime/>", uptime)
+}
+
+// processSessionCountTagsWithContext processes <sessioncount/> tags to return the number of active sessions
+func (g *Golem) processSessionCountTagsWithContext(template string, ctx *VariableContext) string {
+	if !strings.Contains(template, "<sessioncount/>") {
+		return template
+	}
+
+	count := g.SessionCount()
+
+	g.LogDebug("Sessioncount tag: found %d sessions", count)
+
+	return strings.ReplaceAll(template, "<sessioncount/>", strconv.Itoa(count))
+}
+
 // processIdTagsWithContext processes <id/> tags to return the current session ID
 func (g *Golem) processIdTagsWithContext(template string, ctx *VariableContext) string {
 	// Find all <id/> tags
@@ -5184,6 +6131,23 @@ func (g *Golem) processIdTagsWithContext(template string, ctx *VariableContext)
 	return template
 }
 
+// populateThatWildcardLocalVars copies that-pattern wildcard captures (that_star1, that_underscore2, ...)
+// into the context's local variables so every processing path can resolve <that_starN/> tags
+func populateThatWildcardLocalVars(wildcards map[string]string, ctx *VariableContext) {
+	if ctx == nil {
+		return
+	}
+	for key, value := range wildcards {
+		if !strings.HasPrefix(key, "that_") {
+			continue
+		}
+		if ctx.LocalVars == nil {
+			ctx.LocalVars = make(map[string]string)
+		}
+		ctx.LocalVars[key] = value
+	}
+}
+
 // processThatWildcardTagsWithContext processes that wildcard tags in templates
 func (g *Golem) processThatWildcardTagsWithContext(template string, ctx *VariableContext) string {
 	// Find all that wildcard tags (e.g., <that_star1/>, <that_underscore1/>, etc.)
@@ -5287,12 +6251,17 @@ func (g *Golem) processSRAITags(template string, session *ChatSession) string {
 			g.LogInfo("Processing SRAI: '%s'", sraiInput)
 
 			// Match the SRAI input as a new pattern
-			category, wildcards, err := g.aimlKB.MatchPattern(sraiInput)
+			topic := ""
+			if session != nil {
+				topic = session.GetSessionTopic()
+			}
+			category, wildcards, err := g.aimlKB.MatchPatternWithTopic(sraiInput, topic)
 			if err != nil {
 				// If no match found, use the original SRAI text
 				g.LogInfo("SRAI no match for: '%s'", sraiInput)
 				continue
 			}
+			g.notifySRAIMatched(category, sraiInput)
 
 			// Process the matched template
 			var sraiResponse string
@@ -5303,7 +6272,7 @@ func (g *Golem) processSRAITags(template string, session *ChatSession) string {
 			}
 
 			// Replace the SRAI tag with the processed response
-			template = strings.ReplaceAll(template, match[0], sraiResponse)
+			template = g.replaceSRAITag(template, match[0], sraiResponse)
 		}
 	}
 
@@ -5497,6 +6466,50 @@ type VariableContext struct {
 	KnowledgeBase  *AIMLKnowledgeBase // Knowledge base context
 	RecursionDepth int                // Current recursion depth for SRAI processing
 	Wildcards      map[string]string  // Wildcard values from pattern matching
+	// ActiveCategories holds the categories whose templates are being processed, each
+	// keyed with the input it was matched for (see activeCategoryKey), so an SRAI that
+	// would repeat one of them can be detected
+	ActiveCategories map[string]bool
+	// thinkLog and localThink carry the enclosing <think> into the templates processed
+	// inside it, e.g. by an SRAI: the assignments reported to the think sink, and
+	// whether assignments are local (<think scope="local">)
+	thinkLog   *[]string
+	localThink bool
+}
+
+// activeCategoryKey identifies a category processed for an input. Recursive reductions
+// reach the same category again with shorter input, so only a repeat of both the
+// category and the normalized input is a self-reference.
+func activeCategoryKey(category *Category, input string) string {
+	return categoryPatternKey(category) + "|" + NormalizePattern(input)
+}
+
+// withActiveCategory returns a copy of the context's active category set with the
+// category, matched for input, added. Each SRAI branch gets its own copy so siblings
+// don't see each other.
+func (ctx *VariableContext) withActiveCategory(category *Category, input string) map[string]bool {
+	active := make(map[string]bool, len(ctx.ActiveCategories)+1)
+	for id := range ctx.ActiveCategories {
+		active[id] = true
+	}
+	active[activeCategoryKey(category, input)] = true
+	return active
+}
+
+// currentTopic returns the topic SRAI targets are matched under: the session's topic,
+// which a <set name="topic"> earlier in the template may have changed, or the
+// context's own topic when there is no session
+func (ctx *VariableContext) currentTopic() string {
+	if ctx.Session != nil {
+		return ctx.Session.GetSessionTopic()
+	}
+	return ctx.Topic
+}
+
+// isActiveCategory reports whether the category's template is already being processed
+// for the same input
+func (ctx *VariableContext) isActiveCategory(category *Category, input string) bool {
+	return ctx.ActiveCategories[activeCategoryKey(category, input)]
 }
 
 // getVariableValue retrieves a variable value from the appropriate context with proper scope resolution
@@ -5607,6 +6620,23 @@ func (g *Golem) resolveVariable(varName string, ctx *VariableContext) string {
 // This differs from resolveVariable by distinguishing between an unset variable and a variable
 // that is explicitly set to an empty string.
 func (g *Golem) resolveVariableWithPresence(varName string, ctx *VariableContext) (string, bool) {
+	return g.resolveVariableScopes(varName, ctx, true)
+}
+
+// resolveGetVariable resolves the variable a <get name="..."/> reads, which only falls
+// back to bot properties unless DisablePropertyFallback is set
+func (g *Golem) resolveGetVariable(varName string, ctx *VariableContext) (string, bool) {
+	return g.resolveVariableScopes(varName, ctx, g.propertyFallback())
+}
+
+// propertyFallback reports whether <get> falls back to bot properties
+func (g *Golem) propertyFallback() bool {
+	return g.templateConfig == nil || !g.templateConfig.DisablePropertyFallback
+}
+
+// resolveVariableScopes resolves a variable through the local, session, topic and
+// global scopes, then, if includeProperties is set, the bot properties
+func (g *Golem) resolveVariableScopes(varName string, ctx *VariableContext, includeProperties bool) (string, bool) {
 	// 1. Check local scope
 	if ctx.LocalVars != nil {
 		if value, exists := ctx.LocalVars[varName]; exists {
@@ -5640,7 +6670,7 @@ func (g *Golem) resolveVariableWithPresence(varName string, ctx *VariableContext
 	}
 
 	// 5. Check properties as fallback
-	if ctx.KnowledgeBase != nil && ctx.KnowledgeBase.Properties != nil {
+	if includeProperties && ctx.KnowledgeBase != nil && ctx.KnowledgeBase.Properties != nil {
 		if value, exists := ctx.KnowledgeBase.Properties[varName]; exists {
 			return value, true
 		}
@@ -5652,6 +6682,7 @@ func (g *Golem) resolveVariableWithPresence(varName string, ctx *VariableContext
 
 // setVariable sets a variable in the appropriate scope
 func (g *Golem) setVariable(varName, varValue string, scope VariableScope, ctx *VariableContext) {
+	varValue = g.canonicalizeBoolean(varValue)
 	g.LogInfo("setVariable called: varName='%s', varValue='%s', scope=%v", varName, varValue, scope)
 
 	switch scope {
@@ -5719,11 +6750,66 @@ func (g *Golem) processDateTimeTags(template string) string {
 	return template
 }
 
+// clockOffsetRegex matches a clock offset: an optional sign and one or more amounts
+// with a d, h, m or s unit, e.g. "+1d", "-30m" or "1h30m"
+var clockOffsetRegex = regexp.MustCompile(`^([+-]?)((?:\d+[dhms])+)$`)
+
+// clockOffsetPartRegex matches one amount and unit of a clock offset
+var clockOffsetPartRegex = regexp.MustCompile(`(\d+)([dhms])`)
+
+// parseClockOffset parses the offset attribute of <date> and <time> into a number of
+// days and a duration. Days are kept apart so they are calendar days.
+func parseClockOffset(offset string) (int, time.Duration, error) {
+	match := clockOffsetRegex.FindStringSubmatch(strings.TrimSpace(offset))
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid offset '%s': expected a signed amount with a d, h, m or s unit, such as +1d or -30m", offset)
+	}
+
+	days := 0
+	var duration time.Duration
+	for _, part := range clockOffsetPartRegex.FindAllStringSubmatch(match[2], -1) {
+		amount, err := strconv.Atoi(part[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid offset '%s': %v", offset, err)
+		}
+		switch part[2] {
+		case "d":
+			days += amount
+		case "h":
+			duration += time.Duration(amount) * time.Hour
+		case "m":
+			duration += time.Duration(amount) * time.Minute
+		case "s":
+			duration += time.Duration(amount) * time.Second
+		}
+	}
+	if match[1] == "-" {
+		days, duration = -days, -duration
+	}
+	return days, duration, nil
+}
+
+// clockWithOffset returns the current time moved by a <date>/<time> offset attribute.
+// An empty offset leaves the clock unchanged; an invalid one is logged and ignored.
+func (g *Golem) clockWithOffset(offset string) time.Time {
+	now := g.now()
+	if strings.TrimSpace(offset) == "" {
+		return now
+	}
+	days, duration, err := parseClockOffset(offset)
+	if err != nil {
+		g.LogWarn("Ignoring date/time offset: %v", err)
+		return now
+	}
+	return now.AddDate(0, 0, days).Add(duration)
+}
+
 // processDateTags processes <date> tags with various formats
 func (g *Golem) processDateTags(template string) string {
 	// Enhanced regex to match <date> tags with format and jformat attributes
 	// Supports: <date format="..." jformat="..."/>
-	dateRegex := regexp.MustCompile(`<date(?:\s+format="([^"]*)"|\s+format=\\"([^"]*)\\"|\s+jformat="([^"]*)"|\s+jformat=\\"([^"]*)\\")*/>`)
+	// and an offset="+1d" applied to the clock (see clockWithOffset)
+	dateRegex := regexp.MustCompile(`<date(?:\s+format="([^"]*)"|\s+format=\\"([^"]*)\\"|\s+jformat="([^"]*)"|\s+jformat=\\"([^"]*)\\"|\s+offset="([^"]*)")*/>`)
 	matches := dateRegex.FindAllStringSubmatch(template, -1)
 
 	for _, match := range matches {
@@ -5744,11 +6830,16 @@ func (g *Golem) processDateTags(template string) string {
 			jformat = match[4]
 		}
 
-		g.LogInfo("Processing date tag with format: '%s', jformat: '%s'", format, jformat)
+		offset := ""
+		if len(match) > 5 {
+			offset = match[5]
+		}
+
+		g.LogInfo("Processing date tag with format: '%s', jformat: '%s', offset: '%s'", format, jformat, offset)
 
 		// Handle special cases that need direct calculation
 		var dateStr string
-		now := time.Now()
+		now := g.clockWithOffset(offset)
 
 		if format != "" {
 			switch format {
@@ -5808,7 +6899,7 @@ func (g *Golem) processDateTags(template string) string {
 // processTimeTags processes <time> tags with various formats
 func (g *Golem) processTimeTags(template string) string {
 	// Find all <time> tags
-	timeRegex := regexp.MustCompile(`<time(?: format="([^"]*)"| format=\\"([^"]*)\\")?/>`)
+	timeRegex := regexp.MustCompile(`<time(?: format="([^"]*)"| format=\\"([^"]*)\\"| offset="([^"]*)")*/>`)
 	matches := timeRegex.FindAllStringSubmatch(template, -1)
 
 	for _, match := range matches {
@@ -5818,11 +6909,15 @@ func (g *Golem) processTimeTags(template string) string {
 		} else if len(match) > 2 && match[2] != "" {
 			format = match[2]
 		}
+		offset := ""
+		if len(match) > 3 {
+			offset = match[3]
+		}
 
-		g.LogInfo("Processing time tag with format: '%s'", format)
+		g.LogInfo("Processing time tag with format: '%s', offset: '%s'", format, offset)
 
 		// Get current time and format it
-		timeStr := g.formatTime(format)
+		timeStr := g.formatTimeAt(g.clockWithOffset(offset), format)
 
 		// Replace the time tag with the formatted time
 		template = strings.ReplaceAll(template, match[0], timeStr)
@@ -5958,7 +7053,11 @@ func (g *Golem) formatDate(format string) string {
 
 // formatTime formats the current time according to the specified format
 func (g *Golem) formatTime(format string) string {
-	now := time.Now()
+	return g.formatTimeAt(g.now(), format)
+}
+
+// formatTimeAt formats the given time according to the specified format
+func (g *Golem) formatTimeAt(now time.Time, format string) string {
 
 	switch format {
 	case "12":
@@ -6351,7 +7450,7 @@ func (g *Golem) loadDefaultProperties(kb *AIMLKnowledgeBase) error {
 		"jokemode":          "true",
 		"learnmode":         "true",
 		"default_response":  "I'm not sure I understand. Could you rephrase that?",
-		"error_response":    "Sorry, I encountered an error processing your request.",
+		"error_response":    defaultErrorResponse,
 		"thinking_response": "Let me think about that...",
 		"memory_size":       "1000",
 		"forget_time":       "3600",
@@ -6452,11 +7551,90 @@ func (kb *AIMLKnowledgeBase) AddSetMember(setName, member string) {
 	kb.Sets[setName] = append(kb.Sets[setName], strings.ToUpper(member))
 }
 
-// AddSetMembers adds multiple members to a set
+// RemoveSetMember removes a member from a set
+func (kb *AIMLKnowledgeBase) RemoveSetMember(setName, member string) {
+	setName = strings.ToUpper(setName)
+	member = strings.ToUpper(member)
+	for i, existing := range kb.Sets[setName] {
+		if existing == member {
+			kb.Sets[setName] = append(kb.Sets[setName][:i], kb.Sets[setName][i+1:]...)
+			return
+		}
+	}
+}
+
+// ReverseMap returns the inverse (value -> key) of the named map, building and caching
+// it on first use. When several keys share a value, the first key in sorted order wins
+// and a warning is logged. Returns nil if the map does not exist.
+func (kb *AIMLKnowledgeBase) ReverseMap(g *Golem, name string) map[string]string {
+	if inverse, exists := kb.reverseMaps[name]; exists {
+		return inverse
+	}
+	mapData, exists := kb.Maps[name]
+	if !exists {
+		return nil
+	}
+
+	keys := make([]string, 0, len(mapData))
+	for key := range mapData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	inverse := make(map[string]string, len(mapData))
+	for _, key := range keys {
+		value := mapData[key]
+		if existing, duplicate := inverse[value]; duplicate {
+			if g != nil {
+				g.LogWarn("Map '%s' has value '%s' for keys '%s' and '%s'; reverse lookups return '%s'", name, value, existing, key, existing)
+			}
+			continue
+		}
+		inverse[value] = key
+	}
+
+	if kb.reverseMaps == nil {
+		kb.reverseMaps = make(map[string]map[string]string)
+	}
+	kb.reverseMaps[name] = inverse
+	return inverse
+}
+
+// HasMapKey reports whether the named map has a key equal to key once both are
+// normalized the way patterns are, so "france" finds a "France" entry
+func (kb *AIMLKnowledgeBase) HasMapKey(name, key string) bool {
+	mapData, exists := kb.Maps[name]
+	if !exists {
+		return false
+	}
+	if _, exists := mapData[key]; exists {
+		return true
+	}
+	normalizedKey := NormalizePattern(key)
+	if normalizedKey == "" {
+		return false
+	}
+	for k := range mapData {
+		if NormalizePattern(k) == normalizedKey {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidateReverseMap drops the cached inverse of a map after the map changes
+func (kb *AIMLKnowledgeBase) invalidateReverseMap(name string) {
+	delete(kb.reverseMaps, name)
+}
+
+// AddSetMembers adds multiple members to a set, skipping members already in it
 func (kb *AIMLKnowledgeBase) AddSetMembers(setName string, members []string) {
-	for _, member := range members {
-		kb.AddSetMember(setName, member)
+	setName = strings.ToUpper(setName)
+	upperMembers := make([]string, len(members))
+	for i, member := range members {
+		upperMembers[i] = strings.ToUpper(member)
 	}
+	kb.Sets[setName] = mergeSetMembers(kb.Sets[setName], upperMembers)
 }
 
 // GetSetMembers returns all members of a set
@@ -6498,9 +7676,51 @@ func (kb *AIMLKnowledgeBase) GetTopic(pattern string) string {
 	return ""
 }
 
-// SetSessionTopic sets the current topic for a session
+// GetNamespace returns the session variables under a dotted prefix, keyed by the
+// remainder of the name (e.g. prefix "user" returns {"name": ...} for "user.name")
+func (session *ChatSession) GetNamespace(prefix string) map[string]string {
+	return variablesInNamespace(session.Variables, prefix)
+}
+
+// SnapshotVariables returns a copy of the session variables, for saving a session or
+// inspecting it without sharing the map template processing writes to
+func (session *ChatSession) SnapshotVariables() map[string]string {
+	return copyVariables(session.Variables)
+}
+
+// RestoreVariables replaces the session variables with a copy of variables, e.g. ones
+// saved by SnapshotVariables. A nil map clears them.
+func (session *ChatSession) RestoreVariables(variables map[string]string) {
+	session.Variables = copyVariables(variables)
+}
+
+// GetNamespace returns the global variables under a dotted prefix, keyed by the
+// remainder of the name
+func (kb *AIMLKnowledgeBase) GetNamespace(prefix string) map[string]string {
+	return variablesInNamespace(kb.Variables, prefix)
+}
+
+// variablesInNamespace collects the entries of vars whose names start with prefix
+// followed by a dot. The prefix may be given with or without the trailing dot.
+func variablesInNamespace(vars map[string]string, prefix string) map[string]string {
+	namespace := make(map[string]string)
+	prefix = strings.TrimSuffix(prefix, ".") + "."
+	for name, value := range vars {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			namespace[name[len(prefix):]] = value
+		}
+	}
+	return namespace
+}
+
+// SetSessionTopic sets the current topic for a session, notifying the owning
+// Golem's topic change handler when the topic actually changes
 func (session *ChatSession) SetSessionTopic(topic string) {
+	old := session.Topic
 	session.Topic = topic
+	if session.onTopicChange != nil && !strings.EqualFold(old, topic) {
+		session.onTopicChange(session, old, topic)
+	}
 }
 
 // GetSessionTopic returns the current topic for a session
@@ -6508,8 +7728,13 @@ func (session *ChatSession) GetSessionTopic() string {
 	return session.Topic
 }
 
-// AddToThatHistory adds a bot response to the that history with enhanced management
+// AddToThatHistory adds a bot response to the that history with enhanced management.
+// Empty and whitespace-only responses are not added, as they can't be matched by <that>.
 func (session *ChatSession) AddToThatHistory(response string) {
+	if strings.TrimSpace(response) == "" {
+		return
+	}
+
 	// Use enhanced context management if available
 	if session.ContextConfig != nil && session.ContextConfig.EnableCompression {
 		session.AddToThatHistoryEnhanced(response, []string{}, make(map[string]interface{}))
@@ -6721,7 +7946,7 @@ func (session *ChatSession) GetThatHistoryDebugInfo() map[string]interface{} {
 func (session *ChatSession) InitializeContextConfig() {
 	if session.ContextConfig == nil {
 		session.ContextConfig = &ContextConfig{
-			MaxThatDepth:         20,
+			MaxThatDepth:         DefaultMaxThatDepth,
 			MaxRequestDepth:      20,
 			MaxResponseDepth:     20,
 			MaxTotalContext:      100,
@@ -6841,6 +8066,12 @@ func (session *ChatSession) updateContextAnalytics(contextType, content string,
 	session.updateContextWeights()
 }
 
+// recencyWeight returns how much a context item age turns old still counts: the
+// WeightDecay raised to its age, so the newest item (age 0) counts fully
+func (session *ChatSession) recencyWeight(age int) float64 {
+	return math.Pow(session.ContextConfig.WeightDecay, float64(age))
+}
+
 // updateContextWeights updates context weights based on usage and age
 func (session *ChatSession) updateContextWeights() {
 
@@ -6848,7 +8079,7 @@ func (session *ChatSession) updateContextWeights() {
 	for i, content := range session.ThatHistory {
 		age := len(session.ThatHistory) - i - 1
 		usageCount := session.ContextUsage[content]
-		weight := float64(usageCount) * math.Pow(session.ContextConfig.WeightDecay, float64(age))
+		weight := float64(usageCount) * session.recencyWeight(age)
 		session.ContextWeights[fmt.Sprintf("that_%d", i)] = weight
 	}
 
@@ -6856,7 +8087,7 @@ func (session *ChatSession) updateContextWeights() {
 	for i, content := range session.RequestHistory {
 		age := len(session.RequestHistory) - i - 1
 		usageCount := session.ContextUsage[content]
-		weight := float64(usageCount) * math.Pow(session.ContextConfig.WeightDecay, float64(age))
+		weight := float64(usageCount) * session.recencyWeight(age)
 		session.ContextWeights[fmt.Sprintf("request_%d", i)] = weight
 	}
 
@@ -6864,7 +8095,7 @@ func (session *ChatSession) updateContextWeights() {
 	for i, content := range session.ResponseHistory {
 		age := len(session.ResponseHistory) - i - 1
 		usageCount := session.ContextUsage[content]
-		weight := float64(usageCount) * math.Pow(session.ContextConfig.WeightDecay, float64(age))
+		weight := float64(usageCount) * session.recencyWeight(age)
 		session.ContextWeights[fmt.Sprintf("response_%d", i)] = weight
 	}
 }
@@ -8309,16 +9540,56 @@ func (g *Golem) applyLoadedSubstitutions(text string) string {
 	return text
 }
 
+// NormalizationRules controls how normalization treats punctuation: characters in
+// CharsToSpace become spaces and characters in CharsToDelete are removed. Characters in
+// neither set are kept. Apostrophes are always removed after contraction expansion.
+type NormalizationRules struct {
+	CharsToSpace  string
+	CharsToDelete string
+}
+
+// DefaultMatchingNormalizationRules are the rules used to normalize input for pattern matching
+var DefaultMatchingNormalizationRules = NormalizationRules{
+	CharsToSpace:  "-",
+	CharsToDelete: ".,!?;:",
+}
+
+// DefaultOutputNormalizationRules are the rules used by the <normalize> tag
+var DefaultOutputNormalizationRules = NormalizationRules{
+	CharsToSpace:  "@-_.:",
+	CharsToDelete: ",!?;#$%^&*()",
+}
+
+// apply replaces or removes the characters covered by the rules
+func (r NormalizationRules) apply(text string) string {
+	if !strings.ContainsAny(text, r.CharsToSpace+r.CharsToDelete) {
+		return text
+	}
+	return strings.Map(func(c rune) rune {
+		if strings.ContainsRune(r.CharsToDelete, c) {
+			return -1
+		}
+		if strings.ContainsRune(r.CharsToSpace, c) {
+			return ' '
+		}
+		return c
+	}, text)
+}
+
 // NormalizePattern normalizes AIML patterns for matching
 func NormalizePattern(pattern string) string {
+	return normalizePatternWithRules(pattern, DefaultMatchingNormalizationRules)
+}
+
+// normalizePatternWithRules normalizes a pattern or input for matching, using rules for punctuation
+func normalizePatternWithRules(pattern string, rules NormalizationRules) string {
 	// Patterns need special handling for set and topic tags
 	// First, preserve set and topic tags before case conversion
 	tempSetTags := make(map[string]string)
 	tempTopicTags := make(map[string]string)
 
-	// Replace set tags temporarily
-	setPattern := regexp.MustCompile(`<set>([^<]+)</set>`)
-	setMatches := setPattern.FindAllString(pattern, -1)
+	// Replace set tags temporarily (both <set>name</set> and <set name="name"/> forms)
+	setMatches := patternSetTagRegex.FindAllString(pattern, -1)
 	for i, match := range setMatches {
 		placeholder := fmt.Sprintf("__TEMP_SET_%d__", i)
 		tempSetTags[placeholder] = match
@@ -8348,13 +9619,7 @@ func NormalizePattern(pattern string) string {
 	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
 
 	// Remove punctuation that might interfere with matching
-	text = strings.ReplaceAll(text, ".", "")
-	text = strings.ReplaceAll(text, ",", "")
-	text = strings.ReplaceAll(text, "!", "")
-	text = strings.ReplaceAll(text, "?", "")
-	text = strings.ReplaceAll(text, ";", "")
-	text = strings.ReplaceAll(text, ":", "")
-	text = strings.ReplaceAll(text, "-", " ")
+	text = rules.apply(text)
 
 	// Expand contractions for better pattern matching (before removing apostrophes)
 	text = expandContractions(text)
@@ -8369,6 +9634,29 @@ func NormalizePattern(pattern string) string {
 	return text
 }
 
+// FoldDiacritics removes combining marks so accented characters compare equal to
+// their base forms (e.g. "CAFÉ" becomes "CAFE"). ASCII text is returned unchanged.
+func FoldDiacritics(text string) string {
+	ascii := true
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return text
+	}
+
+	var folded strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if !unicode.Is(unicode.Mn, r) {
+			folded.WriteRune(r)
+		}
+	}
+	return norm.NFC.String(folded.String())
+}
+
 // SentenceSplitter handles sentence splitting with proper boundary detection
 type SentenceSplitter struct {
 	// Common sentence ending patterns
@@ -8700,6 +9988,24 @@ func (wbd *WordBoundaryDetector) IsWordBoundary(text string, pos int) bool {
 	return wbd.separators[r] || wbd.punctuation[r]
 }
 
+// Markup a that context can carry: <think> blocks, which never reach the user, and
+// any other tag (such as <formal> or <b>)
+var (
+	thatContextThinkRegex  = regexp.MustCompile(`(?s)<think\b[^>]*>.*?</think>`)
+	thatContextMarkupRegex = regexp.MustCompile(`</?[A-Za-z][^<>]*>`)
+)
+
+// stripResponseMarkup reduces a that context to its plain text for that matching:
+// <think> blocks are dropped, other tags become spaces and entity references are decoded
+func stripResponseMarkup(response string) string {
+	if !strings.ContainsAny(response, "<&") {
+		return response
+	}
+	response = thatContextThinkRegex.ReplaceAllString(response, " ")
+	response = thatContextMarkupRegex.ReplaceAllString(response, " ")
+	return html.UnescapeString(response)
+}
+
 // NormalizeThatPattern normalizes a that pattern for matching with enhanced sentence boundary handling
 func NormalizeThatPattern(pattern string) string {
 	// Patterns need special handling for set and topic tags
@@ -8782,7 +10088,7 @@ func validateThatPattern(pattern string) error {
 	}
 
 	// Check for valid characters (enhanced validation) - allow all AIML2 wildcards and punctuation
-	validChars := regexp.MustCompile(`^[A-Z0-9\s\*_^#$<>/'.!?,-]+$`)
+	validChars := regexp.MustCompile(`^[A-Z0-9\s\*_^#$<>/'.!?,()|-]+$`)
 	if !validChars.MatchString(pattern) {
 		return fmt.Errorf("that pattern contains invalid characters")
 	}
@@ -8807,6 +10113,13 @@ func validateThatPattern(pattern string) error {
 	if parenOpenCount != parenCloseCount {
 		return fmt.Errorf("unbalanced parentheses in that pattern")
 	}
+	for _, group := range thatAlternationPattern.FindAllStringSubmatch(pattern, -1) {
+		for _, option := range strings.Split(group[1], "|") {
+			if strings.TrimSpace(option) == "" {
+				return fmt.Errorf("empty option in alternation group %s in that pattern", group[0])
+			}
+		}
+	}
 
 	// Check for valid wildcard combinations
 	if err := validateThatWildcardCombinations(pattern); err != nil {
@@ -9413,8 +10726,35 @@ func thatPatternToRegex(pattern string) string {
 	return result.String()
 }
 
+// thatAlternationPattern matches an alternation group in a that pattern, such as (YES|NO)
+var thatAlternationPattern = regexp.MustCompile(`\(([^()]*\|[^()]*)\)`)
+
+// replaceThatAlternations replaces each alternation group in a that pattern with a
+// placeholder word, returning the regex for each placeholder. The regexes don't capture,
+// so alternation groups don't shift the numbering of the that wildcards.
+func replaceThatAlternations(pattern string) (string, map[string]string) {
+	groups := make(map[string]string)
+	pattern = thatAlternationPattern.ReplaceAllStringFunc(pattern, func(match string) string {
+		options := strings.Split(match[1:len(match)-1], "|")
+		for i, option := range options {
+			words := strings.Fields(option)
+			for j, word := range words {
+				words[j] = regexp.QuoteMeta(word)
+			}
+			options[i] = strings.Join(words, "\\s+")
+		}
+		placeholder := fmt.Sprintf("__ALT_%d__", len(groups))
+		groups[placeholder] = "(?:" + strings.Join(options, "|") + ")"
+		return " " + placeholder + " "
+	})
+	return pattern, groups
+}
+
 // thatPatternToRegexWordBased converts a that pattern to regex using word-based processing
 func thatPatternToRegexWordBased(pattern string) string {
+	// Replace alternation groups first, so their words aren't treated separately
+	pattern, alternations := replaceThatAlternations(pattern)
+
 	// Handle set matching first (before escaping)
 	setPattern := regexp.MustCompile(`<set>([^<]+)</set>`)
 	pattern = setPattern.ReplaceAllString(pattern, "([^\\s]*)")
@@ -9458,6 +10798,8 @@ func thatPatternToRegexWordBased(pattern string) string {
 			// Dollar wildcard: highest priority exact match (AIML2)
 			// For regex purposes, treat as a wildcard that matches one word
 			result.WriteString("([^\\s]+)")
+		} else if alternation, exists := alternations[word]; exists {
+			result.WriteString(alternation)
 		} else {
 			// Regular word - escape special characters
 			escaped := regexp.QuoteMeta(word)
@@ -9473,6 +10815,9 @@ func thatPatternToRegexWordBased(pattern string) string {
 
 // thatPatternToRegexWithSetsAndTopics converts a that pattern to regex with enhanced set and topic matching
 func thatPatternToRegexWithSetsAndTopics(g *Golem, pattern string, kb *AIMLKnowledgeBase) string {
+	// Replace alternation groups first, so the set and topic regexes aren't mistaken for them
+	pattern, alternations := replaceThatAlternations(pattern)
+
 	// Handle set matching with proper set content
 	setPattern := regexp.MustCompile(`<set>([^<]+)</set>`)
 	pattern = setPattern.ReplaceAllStringFunc(pattern, func(match string) string {
@@ -9603,6 +10948,8 @@ func thatPatternToRegexWithSetsAndTopics(g *Golem, pattern string, kb *AIMLKnowl
 			// Dollar wildcard: highest priority exact match (AIML2)
 			// For regex purposes, treat as a wildcard that matches one word
 			result.WriteString("([^\\s]+)")
+		} else if alternation, exists := alternations[word]; exists {
+			result.WriteString(alternation)
 		} else {
 			// Check if this word is already a regex pattern (contains parentheses)
 			if strings.Contains(word, "(") && strings.Contains(word, ")") {
@@ -10149,6 +11496,34 @@ func (g *Golem) validatePatternCharacters(pattern string) error {
 	return nil
 }
 
+// templateTagRegex matches opening, closing and self-closing tags in a template
+var templateTagRegex = regexp.MustCompile(`<(/?)([A-Za-z][A-Za-z0-9_:-]*)(?:\s[^>]*?)?(/?)>`)
+
+// validateTemplateBalance checks that every tag in a template is closed in the right
+// order, reporting the first unexpected, mismatched or unclosed tag
+func (g *Golem) validateTemplateBalance(template string) error {
+	var stack []string
+	for _, match := range templateTagRegex.FindAllStringSubmatch(template, -1) {
+		isClosing, tagName, isSelfClosing := match[1] == "/", match[2], match[3] == "/"
+		switch {
+		case isSelfClosing:
+			continue
+		case !isClosing:
+			stack = append(stack, tagName)
+		case len(stack) == 0:
+			return fmt.Errorf("unexpected closing tag </%s>", tagName)
+		case stack[len(stack)-1] != tagName:
+			return fmt.Errorf("mismatched closing tag </%s>, expected </%s>", tagName, stack[len(stack)-1])
+		default:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed tag <%s>", stack[len(stack)-1])
+	}
+	return nil
+}
+
 // validateBalancedTags validates that XML/AIML tags are balanced
 func (g *Golem) validateBalancedTags(template string) error {
 	// Find all opening and closing tags
@@ -10288,6 +11663,8 @@ func (g *Golem) addSessionCategory(category Category, ctx *VariableContext) erro
 		return fmt.Errorf("category validation failed: %v", err)
 	}
 
+	category.Learned = true
+
 	// Normalize the pattern and build the proper key including that and topic
 	normalizedPattern := NormalizePattern(category.Pattern)
 	key := normalizedPattern
@@ -10301,16 +11678,25 @@ func (g *Golem) addSessionCategory(category Category, ctx *VariableContext) erro
 		key += "|TOPIC:" + strings.ToUpper(category.Topic)
 	}
 
+	g.aimlKB.learnMutex.Lock()
+	defer g.aimlKB.learnMutex.Unlock()
+
 	// Check if category already exists
-	if existingCategory, exists := g.aimlKB.Patterns[key]; exists {
+	if _, exists := g.aimlKB.Patterns[key]; exists {
 		g.LogInfo("Updating existing session category: %s", key)
-		// Update existing category
-		*existingCategory = category
+		g.aimlKB.updateCategory(key, category)
 	} else {
+		if err := g.makeRoomForLearned(ctx.Session); err != nil {
+			return err
+		}
+
 		g.LogInfo("Adding new session category: %s", key)
 		// Add new category
 		g.aimlKB.Categories = append(g.aimlKB.Categories, category)
 		g.aimlKB.Patterns[key] = &g.aimlKB.Categories[len(g.aimlKB.Categories)-1]
+		if ctx.Session != nil {
+			ctx.Session.LearnedOrder = append(ctx.Session.LearnedOrder, key)
+		}
 	}
 
 	// Update session learning statistics
@@ -10402,6 +11788,8 @@ func (g *Golem) addPersistentCategory(category Category) error {
 		return fmt.Errorf("category validation failed: %v", err)
 	}
 
+	category.Learned = true
+
 	// Normalize the pattern and build the proper key including that and topic
 	normalizedPattern := NormalizePattern(category.Pattern)
 	key := normalizedPattern
@@ -10415,11 +11803,13 @@ func (g *Golem) addPersistentCategory(category Category) error {
 		key += "|TOPIC:" + strings.ToUpper(category.Topic)
 	}
 
+	g.aimlKB.learnMutex.Lock()
+	defer g.aimlKB.learnMutex.Unlock()
+
 	// Check if category already exists
-	if existingCategory, exists := g.aimlKB.Patterns[key]; exists {
+	if _, exists := g.aimlKB.Patterns[key]; exists {
 		g.LogInfo("Updating existing persistent category: %s", key)
-		// Update existing category
-		*existingCategory = category
+		g.aimlKB.updateCategory(key, category)
 	} else {
 		g.LogInfo("Adding new persistent category: %s", key)
 		// Add new category
@@ -10461,6 +11851,9 @@ func (g *Golem) removeSessionCategory(category Category, ctx *VariableContext) e
 		key += "|TOPIC:" + strings.ToUpper(category.Topic)
 	}
 
+	g.aimlKB.learnMutex.Lock()
+	defer g.aimlKB.learnMutex.Unlock()
+
 	// Check if category exists
 	if _, exists := g.aimlKB.Patterns[key]; !exists {
 		g.LogInfo("Category not found for removal: %s", key)
@@ -10480,6 +11873,9 @@ func (g *Golem) removeSessionCategory(category Category, ctx *VariableContext) e
 			// Remove the category by slicing it out
 			g.aimlKB.Categories = append(g.aimlKB.Categories[:i], g.aimlKB.Categories[i+1:]...)
 			g.LogInfo("Removed session category: %s", key)
+			if ctx.Session != nil {
+				ctx.Session.LearnedOrder = removeLearnedKey(ctx.Session.LearnedOrder, key)
+			}
 
 			// Update session learning statistics
 			if ctx.Session != nil && ctx.Session.LearningStats != nil {
@@ -10513,6 +11909,9 @@ func (g *Golem) removePersistentCategory(category Category) error {
 		key += "|TOPIC:" + strings.ToUpper(category.Topic)
 	}
 
+	g.aimlKB.learnMutex.Lock()
+	defer g.aimlKB.learnMutex.Unlock()
+
 	// Check if category exists
 	if _, exists := g.aimlKB.Patterns[key]; !exists {
 		g.LogInfo("Category not found for removal: %s", key)
@@ -1,10 +1,15 @@
 package golem
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
@@ -220,15 +225,27 @@ func TestPatternToRegex(t *testing.T) {
 		},
 		{
 			pattern:  "MY NAME IS *",
-			expected: "^MY NAME IS ?(.*?)$",
+			expected: "^MY NAME IS(?: (.*?))?$",
 		},
 		{
 			pattern:  "I AM * YEARS OLD",
-			expected: "^I AM ?(.*?) ?YEARS OLD$",
+			expected: "^I AM(?: (.*?))? YEARS OLD$",
 		},
 		{
 			pattern:  "I LIKE * AND *",
-			expected: "^I LIKE ?(.*?) ?AND ?(.*?)$",
+			expected: "^I LIKE(?: (.*?))? AND(?: (.*?))?$",
+		},
+		{
+			pattern:  "* ME",
+			expected: "^(?:(.*?) )?ME$",
+		},
+		{
+			pattern:  "* *",
+			expected: "^(.*?) (.*?)$",
+		},
+		{
+			pattern:  "HELP _",
+			expected: "^HELP ([^\\s]+)$",
 		},
 	}
 
@@ -240,6 +257,63 @@ func TestPatternToRegex(t *testing.T) {
 	}
 }
 
+// TestTrailingWildcardMatchesBareCommand tests command-style patterns: "HELP *" also
+// matches a bare "HELP" with an empty star, an exact "HELP" category takes priority
+// over it, and a wildcard only ever matches whole words
+func TestTrailingWildcardMatchesBareCommand(t *testing.T) {
+	aimlContent := `<aiml version="2.0">
+    <category><pattern>HELP *</pattern><template>help [<star/>]</template></category>
+    <category><pattern>HELPER</pattern><template>exact helper</template></category>
+    <category><pattern>* ME</pattern><template>[<star/>] me</template></category>
+    <category><pattern>*</pattern><template>catchall</template></category>
+</aiml>`
+	g := NewForTesting(t, false)
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	session := g.CreateSession("bare_command")
+
+	tests := map[string]string{
+		"help":          "help []",
+		"help ":         "help []",
+		"help  \t":      "help []",
+		"help topics":   "help [topics]",
+		"help topics  ": "help [topics]",
+		"helper ":       "exact helper",
+		"helpful":       "catchall", // Not "help [ful]"
+		"show me":       "[show] me",
+		"me":            "[] me",    // "* ME" has more literal words than the tied "*"
+		"awesome":       "catchall", // Not "[aweso] me"
+	}
+	// Repeat the inputs, since a tie broken by map order only shows up some of the time
+	for i := 0; i < 20; i++ {
+		for input, expected := range tests {
+			response, err := g.ProcessInput(input, session)
+			if err != nil {
+				t.Fatalf("ProcessInput(%q) failed: %v", input, err)
+			}
+			if response != expected {
+				t.Fatalf("Input %q: expected '%s', got '%s'", input, expected, response)
+			}
+		}
+	}
+
+	// An exact category for the bare command outranks the wildcard one
+	if err := g.LoadAIMLFromString(`<aiml version="2.0">
+    <category><pattern>HELP</pattern><template>exact help</template></category>
+</aiml>`); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	for _, input := range []string{"help", "help "} {
+		if response, _ := g.ProcessInput(input, session); response != "exact help" {
+			t.Errorf("Input %q: expected the exact category to win, got '%s'", input, response)
+		}
+	}
+	if response, _ := g.ProcessInput("help topics", session); response != "help [topics]" {
+		t.Errorf("Expected the wildcard category for arguments, got '%s'", response)
+	}
+}
+
 func TestProcessTemplate(t *testing.T) {
 	// Test template with wildcards
 	template := "Nice to meet you, <star/>!"
@@ -2877,6 +2951,29 @@ func TestConditionDefaultCase(t *testing.T) {
 	}
 }
 
+// TestConditionDefaultListedFirst tests that a default <li> listed before the valued
+// items is only used when none of them match
+func TestConditionDefaultListedFirst(t *testing.T) {
+	template := `<condition name="mood"><li>Default</li><li value="happy">Glad to hear it</li><li value="sad">Sorry</li></condition>`
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			g.SetKnowledgeBase(NewAIMLKnowledgeBase())
+			session := g.CreateSession("condition_default_" + name)
+
+			for mood, expected := range map[string]string{"sad": "Sorry", "happy": "Glad to hear it", "bored": "Default"} {
+				session.Variables["mood"] = mood
+				if result := g.ProcessTemplateWithContext(template, map[string]string{}, session); result != expected {
+					t.Errorf("mood=%s: expected '%s', got '%s'", mood, expected, result)
+				}
+			}
+		})
+	}
+}
+
 // TestVariableScopeResolution tests the new variable scope resolution system
 func TestVariableScopeResolution(t *testing.T) {
 	g := NewForTesting(t, false)
@@ -3106,6 +3203,176 @@ func TestLoadAIMLFromDirectory(t *testing.T) {
 	}
 }
 
+func TestLoadAIMLFromDirectoryProgressCallback(t *testing.T) {
+	g := NewForTesting(t, false)
+	tempDir := t.TempDir()
+
+	// Create several AIML files plus a non-AIML file that must not be reported
+	fileNames := []string{"a.aiml", "b.aiml", "c.aiml", "d.aiml"}
+	for i, name := range fileNames {
+		content := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>PATTERN %d</pattern>
+        <template>Template %d</template>
+    </category>
+</aiml>`, i, i)
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("not aiml"), 0644); err != nil {
+		t.Fatalf("Failed to create notes.txt: %v", err)
+	}
+
+	type progressCall struct {
+		index int
+		total int
+		path  string
+	}
+	var calls []progressCall
+	g.OnLoadProgress = func(fileIndex, totalFiles int, path string) {
+		calls = append(calls, progressCall{fileIndex, totalFiles, path})
+	}
+
+	kb, err := g.LoadAIMLFromDirectory(tempDir)
+	if err != nil {
+		t.Fatalf("LoadAIMLFromDirectory failed: %v", err)
+	}
+	if len(kb.Categories) != len(fileNames) {
+		t.Errorf("Expected %d categories, got %d", len(fileNames), len(kb.Categories))
+	}
+
+	if len(calls) != len(fileNames) {
+		t.Fatalf("Expected %d progress callbacks, got %d", len(fileNames), len(calls))
+	}
+	for i, call := range calls {
+		if call.index != i {
+			t.Errorf("Call %d: expected fileIndex %d, got %d", i, i, call.index)
+		}
+		if call.total != len(fileNames) {
+			t.Errorf("Call %d: expected totalFiles %d, got %d", i, len(fileNames), call.total)
+		}
+		if !strings.HasSuffix(call.path, ".aiml") {
+			t.Errorf("Call %d: expected an .aiml path, got '%s'", i, call.path)
+		}
+	}
+
+	// A nil callback is fine
+	g.OnLoadProgress = nil
+	if _, err := g.LoadAIMLFromDirectory(tempDir); err != nil {
+		t.Fatalf("LoadAIMLFromDirectory without callback failed: %v", err)
+	}
+}
+
+// writeAIMLCorpus writes files AIML files of perFile categories each into dir
+func writeAIMLCorpus(tb testing.TB, dir string, files, perFile int) {
+	tb.Helper()
+	for f := 0; f < files; f++ {
+		var content strings.Builder
+		content.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<aiml version=\"2.0\">\n")
+		for c := 0; c < perFile; c++ {
+			fmt.Fprintf(&content, "<category><pattern>FILE %d PATTERN %d *</pattern><template>Response %d from file %d</template></category>\n", f, c, c, f)
+		}
+		content.WriteString("</aiml>\n")
+		name := filepath.Join(dir, fmt.Sprintf("corpus_%03d.aiml", f))
+		if err := os.WriteFile(name, []byte(content.String()), 0644); err != nil {
+			tb.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadAIMLFromDirectoryStreaming(t *testing.T) {
+	g := NewForTesting(t, false)
+	var logs bytes.Buffer
+	g.logger = log.New(&logs, "", 0)
+	g.SetLogLevel(LogLevelWarn)
+	dir := t.TempDir()
+	writeAIMLCorpus(t, dir, 5, 40)
+
+	// A later file overrides a pattern from an earlier one, and a broken file is skipped
+	override := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category><pattern>FILE 0 PATTERN 0 *</pattern><template>Overridden</template></category>
+</aiml>`
+	if err := os.WriteFile(filepath.Join(dir, "zz_override.aiml"), []byte(override), 0644); err != nil {
+		t.Fatalf("Failed to write override: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.aiml"), []byte("<aiml><category>"), 0644); err != nil {
+		t.Fatalf("Failed to write broken file: %v", err)
+	}
+
+	kb, err := g.LoadAIMLFromDirectory(dir)
+	if err != nil {
+		t.Fatalf("LoadAIMLFromDirectory failed: %v", err)
+	}
+	if len(kb.Categories) != 201 {
+		t.Fatalf("Expected 201 categories, got %d", len(kb.Categories))
+	}
+
+	// Categories keep file order and every indexed pattern points into kb.Categories
+	if kb.Categories[0].Pattern != "FILE 0 PATTERN 0 *" || kb.Categories[200].Template != "Overridden" {
+		t.Errorf("Unexpected category order: first %q, last %q", kb.Categories[0].Pattern, kb.Categories[200].Template)
+	}
+	for key, category := range kb.Patterns {
+		index := -1
+		for i := range kb.Categories {
+			if &kb.Categories[i] == category {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			t.Errorf("Pattern %q doesn't point into the merged categories", key)
+		}
+	}
+
+	g.aimlKB = kb
+	session := g.CreateSession("streaming")
+	if response, err := g.ProcessInput("file 0 pattern 0 x", session); err != nil || response != "Overridden" {
+		t.Errorf("Expected the later file to win, got %q (err %v)", response, err)
+	}
+	if response, err := g.ProcessInput("file 4 pattern 7 x", session); err != nil || response != "Response 7 from file 4" {
+		t.Errorf("Expected a category from the last corpus file to match, got %q (err %v)", response, err)
+	}
+	if !strings.Contains(logs.String(), "is overridden by") {
+		t.Errorf("Expected the cross-file collision to be logged, got: %s", logs.String())
+	}
+
+	// With StrictParsing a cross-file collision fails the load
+	g.StrictParsing = true
+	if _, err := g.LoadAIMLFromDirectory(dir); err == nil {
+		t.Error("Expected a cross-file collision to fail the load with StrictParsing")
+	}
+}
+
+// BenchmarkLoadAIMLFromDirectory loads a multi-file corpus, reporting the heap the
+// loaded knowledge base retains
+func BenchmarkLoadAIMLFromDirectory(b *testing.B) {
+	g := New(false)
+	g.persistentLearning = NewPersistentLearningManager(b.TempDir())
+	dir := b.TempDir()
+	writeAIMLCorpus(b, dir, 20, 250)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var retained uint64
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		kb, err := g.LoadAIMLFromDirectory(dir)
+		if err != nil {
+			b.Fatalf("LoadAIMLFromDirectory failed: %v", err)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		retained += after.HeapAlloc - before.HeapAlloc
+		runtime.KeepAlive(kb)
+	}
+	b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+}
+
 func TestLoadAIMLFromDirectoryEmpty(t *testing.T) {
 	g := NewForTesting(t, false)
 
@@ -3499,6 +3766,48 @@ func TestLoadSetFromFile(t *testing.T) {
 	}
 }
 
+func TestLoadSetFromFileDuplicates(t *testing.T) {
+	g := NewForTesting(t, false)
+	var logs bytes.Buffer
+	g.logger = log.New(&logs, "", 0)
+	g.SetLogLevel(LogLevelWarn)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "colors.set"), []byte(`["red", "Blue", "RED", "green", "blue", "red"]`), 0644); err != nil {
+		t.Fatalf("Failed to create test set file: %v", err)
+	}
+
+	setMembers, err := g.LoadSetFromFile(filepath.Join(dir, "colors.set"))
+	if err != nil {
+		t.Fatalf("LoadSetFromFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(setMembers, []string{"red", "Blue", "green"}) {
+		t.Errorf("Expected the first occurrence of each member in order, got %v", setMembers)
+	}
+	if !strings.Contains(logs.String(), "Removed 3 duplicate members") {
+		t.Errorf("Expected a warning about the duplicates, got %q", logs.String())
+	}
+
+	// Members already in the set, in any case, are not added again
+	kb := NewAIMLKnowledgeBase()
+	kb.AddSetMembers("colors", setMembers)
+	kb.AddSetMembers("COLORS", []string{"Green", "yellow", "YELLOW"})
+	if members := kb.GetSetMembers("colors"); !reflect.DeepEqual(members, []string{"RED", "BLUE", "GREEN", "YELLOW"}) {
+		t.Errorf("Expected single entries after adding members, got %v", members)
+	}
+
+	// Merging knowledge bases doesn't duplicate members either
+	other := NewAIMLKnowledgeBase()
+	other.AddSetMembers("colors", []string{"blue", "purple"})
+	merged, err := g.mergeKnowledgeBases(kb, other)
+	if err != nil {
+		t.Fatalf("mergeKnowledgeBases failed: %v", err)
+	}
+	if members := merged.GetSetMembers("colors"); !reflect.DeepEqual(members, []string{"RED", "BLUE", "GREEN", "YELLOW", "PURPLE"}) {
+		t.Errorf("Expected single entries after merging, got %v", members)
+	}
+}
+
 func TestLoadSetFromFileInvalidJSON(t *testing.T) {
 	g := NewForTesting(t, false)
 
@@ -3675,6 +3984,74 @@ func TestSetMatchingInPatterns(t *testing.T) {
 	}
 }
 
+// TestSetMatchingInPatternsAttributeForm tests the AIML2 <set name="..."/> pattern syntax
+func TestSetMatchingInPatternsAttributeForm(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>MY FAVORITE COLOR IS <set name="colors"/></pattern>
+		<template>I like <star/> too.</template>
+	</category>
+	<category>
+		<pattern>I HAVE A <set>animals</set></pattern>
+		<template>A <star/> is a fine pet.</template>
+	</category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	g.aimlKB.AddSetMembers("colors", []string{"red", "blue", "green"})
+	g.aimlKB.AddSetMembers("animals", []string{"dog", "cat"})
+
+	tests := []struct {
+		input       string
+		shouldMatch bool
+		expected    string
+	}{
+		{"my favorite color is blue", true, "I like blue too."},
+		{"my favorite color is green", true, "I like green too."},
+		{"my favorite color is purple", false, ""},
+		{"I have a cat", true, "A cat is a fine pet."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			category, wildcards, err := g.aimlKB.MatchPatternWithTopicAndThatIndexOriginalCached(g, NormalizePattern(tt.input), tt.input, "", "", 0)
+			if !tt.shouldMatch {
+				if err == nil && category != nil {
+					t.Errorf("Expected no match for '%s', got pattern '%s'", tt.input, category.Pattern)
+				}
+				return
+			}
+			if err != nil || category == nil {
+				t.Fatalf("Expected match for '%s', got error: %v", tt.input, err)
+			}
+			response := g.ProcessTemplate(category.Template, wildcards)
+			if !strings.EqualFold(response, tt.expected) {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+
+	// Both syntaxes validate; an empty set name does not
+	for _, pattern := range []string{`I LIKE <set name="colors"/>`, `I LIKE <set name='colors' />`, `I LIKE <set>colors</set>`} {
+		if err := g.validatePattern(pattern); err != nil {
+			t.Errorf("Expected pattern '%s' to be valid, got: %v", pattern, err)
+		}
+	}
+	if err := g.validatePattern(`I LIKE <set name=""/>`); err == nil {
+		t.Error("Expected error for empty set name in attribute form")
+	}
+
+	// The attribute form survives pattern normalization
+	if normalized := NormalizePattern(`my color is <set name="colors"/>`); normalized != `MY COLOR IS <set name="colors"/>` {
+		t.Errorf("Expected set tag to be preserved, got '%s'", normalized)
+	}
+}
+
 // TestNormalization tests the normalization and denormalization system
 func TestNormalization(t *testing.T) {
 	// Test basic text normalization
@@ -4177,12 +4554,12 @@ func TestPersonTagProcessing(t *testing.T) {
 		{
 			name:     "Possessive pronouns",
 			template: "This is mine and that is yours.",
-			expected: "This is yours and that is yours.",
+			expected: "This is yours and that is mine.",
 		},
 		{
 			name:     "Reflexive pronouns",
 			template: "I did it myself and you did it yourself.",
-			expected: "you did it yourself and I did it yourself.",
+			expected: "you did it yourself and I did it myself.",
 		},
 		{
 			name:     "Plural pronouns",
@@ -5583,6 +5960,249 @@ func TestWildcardPriority(t *testing.T) {
 	}
 }
 
+// TestCategoryPriorityBoost tests that <category priority="N"> adjusts match priority
+func TestCategoryPriorityBoost(t *testing.T) {
+	aimlTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category>
+<pattern>I LIKE *</pattern>
+<template>You like <star/>.</template>
+</category>
+<category%s>
+<pattern>* CATS</pattern>
+<template>Cats are special.</template>
+</category>
+<category%s>
+<pattern>*</pattern>
+<template>Catch-all.</template>
+</category>
+<category>
+<pattern>HELLO *</pattern>
+<template>Greeting.</template>
+</category>
+<category>
+<pattern>I LIKE DOGS</pattern>
+<template>Dogs are loyal.</template>
+</category>
+<category>
+<pattern>$I LIKE BIRDS</pattern>
+<template>Birds sing.</template>
+</category>
+</aiml>`
+
+	tests := []struct {
+		name          string
+		catsPriority  string
+		catchPriority string
+		input         string
+		expected      string
+	}{
+		{
+			name:     "More specific pattern wins by default",
+			input:    "I like cats",
+			expected: "You like cats.",
+		},
+		{
+			name:         "Boosted lower-specificity pattern wins",
+			catsPriority: ` priority="500"`,
+			input:        "I like cats",
+			expected:     "Cats are special.",
+		},
+		{
+			name:     "Catch-all loses by default",
+			input:    "hello there",
+			expected: "Greeting.",
+		},
+		{
+			name:          "Boosted catch-all wins",
+			catchPriority: ` priority="1000"`,
+			input:         "hello there",
+			expected:      "Catch-all.",
+		},
+		{
+			name:         "Exact pattern wins without a boost over it",
+			catsPriority: ` priority="500"`,
+			input:        "I like dogs",
+			expected:     "Dogs are loyal.",
+		},
+		{
+			name:          "Boosted catch-all outranks an exact pattern",
+			catchPriority: ` priority="10000"`,
+			input:         "I like dogs",
+			expected:      "Catch-all.",
+		},
+		{
+			name:          "Boosts don't outrank a $ pattern",
+			catchPriority: ` priority="10000"`,
+			input:         "I like birds",
+			expected:      "Birds sing.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if err := g.LoadAIMLFromString(fmt.Sprintf(aimlTemplate, tt.catsPriority, tt.catchPriority)); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+
+			response, err := g.ProcessInput(tt.input, g.CreateSession("priority-test"))
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+
+	t.Run("Exact match is ranked against boosted categories only", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		if err := g.LoadAIMLFromString(fmt.Sprintf(aimlTemplate, ` priority="500"`, "")); err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+
+		trace, err := g.MatchPatternDebug("I like dogs", g.CreateSession("priority-test"))
+		if err != nil {
+			t.Fatalf("MatchPatternDebug failed: %v", err)
+		}
+		if trace.Stage != MatchStageExact || trace.MatchedPattern != "I LIKE DOGS" {
+			t.Errorf("Expected an exact match of 'I LIKE DOGS', got stage %q pattern %q", trace.Stage, trace.MatchedPattern)
+		}
+		// Only the exact category and the boosted * CATS are considered, not I LIKE *
+		if len(trace.Candidates) != 2 {
+			t.Errorf("Expected 2 candidates, got %+v", trace.Candidates)
+		}
+	})
+
+	t.Run("Invalid priority is rejected", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		err := g.LoadAIMLFromString(fmt.Sprintf(aimlTemplate, ` priority="high"`, ""))
+		if err == nil || !strings.Contains(err.Error(), "invalid priority") {
+			t.Errorf("Expected invalid priority error, got: %v", err)
+		}
+	})
+}
+
+// TestCategoryTagScanning tests that categories are found by their own tags, however
+// their attribute values and neighbouring elements look
+func TestCategoryTagScanning(t *testing.T) {
+	g := NewForTesting(t, false)
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<categoryX><pattern>ODD</pattern><template>Odd.</template></categoryX>
+<category note="a > b, and long enough to reach back past the end of the learn tag below" priority="5">
+<pattern>TEACH</pattern>
+<template>Taught.<think><learn><category><pattern>SECRET</pattern><template>Learned.</template></category></learn></think></template>
+</category>
+<category>
+<pattern>*</pattern>
+<template>Catch-all.</template>
+</category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("tag-scanning-test")
+	for _, tt := range []struct{ input, expected string }{
+		{"odd", "Catch-all."},
+		{"secret", "Catch-all."},
+		{"teach", "Taught."},
+		{"secret", "Learned."},
+	} {
+		response, err := g.ProcessInput(tt.input, session)
+		if err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+		}
+		if response != tt.expected {
+			t.Errorf("ProcessInput(%q): expected '%s', got '%s'", tt.input, tt.expected, response)
+		}
+	}
+
+	categories := g.extractAllTagContentsWithAttributes(aimlContent, "category")
+	if len(categories) != 2 || categories[0].Attributes["note"] == "" || categories[0].Attributes["priority"] != "5" {
+		t.Errorf("Expected 2 categories with the first one's attributes, got %+v", categories)
+	}
+}
+
+// TestDefaultCategory tests that the default category is used only when nothing else matches
+func TestDefaultCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		aiml     string
+		input    string
+		expected string
+	}{
+		{
+			name: "Explicit default attribute",
+			aiml: `<category><pattern>HELLO</pattern><template>Hi.</template></category>
+<category default="true"><pattern>FALLBACK</pattern><template>I have no answer for that.</template></category>`,
+			input:    "what is the meaning of life",
+			expected: "I have no answer for that.",
+		},
+		{
+			name: "Default does not shadow matching patterns",
+			aiml: `<category><pattern>HELLO</pattern><template>Hi.</template></category>
+<category default="true"><pattern>FALLBACK</pattern><template>I have no answer for that.</template></category>`,
+			input:    "hello",
+			expected: "Hi.",
+		},
+		{
+			name: "Default category still matches its own pattern",
+			aiml: `<category><pattern>HELLO</pattern><template>Hi.</template></category>
+<category default="true"><pattern>FALLBACK</pattern><template>I have no answer for that.</template></category>`,
+			input:    "fallback",
+			expected: "I have no answer for that.",
+		},
+		{
+			name: "DEFAULT pattern",
+			aiml: `<category><pattern>HELLO *</pattern><template>Hi.</template></category>
+<category><pattern>DEFAULT</pattern><template>Say again?</template></category>`,
+			input:    "goodbye",
+			expected: "Say again?",
+		},
+		{
+			name: "Catch-all pattern wins over the default",
+			aiml: `<category><pattern>*</pattern><template>Catch-all.</template></category>
+<category default="true"><pattern>FALLBACK</pattern><template>Default.</template></category>`,
+			input:    "goodbye",
+			expected: "Catch-all.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">` + tt.aiml + `</aiml>`); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+
+			response, err := g.ProcessInput(tt.input, g.CreateSession("default-test"))
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+
+	t.Run("Multiple defaults are reported as a collision", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		g.StrictParsing = true
+		err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category default="true"><pattern>FIRST FALLBACK</pattern><template>One.</template></category>
+<category default="true"><pattern>SECOND FALLBACK</pattern><template>Two.</template></category>
+</aiml>`)
+		if err == nil || !strings.Contains(err.Error(), "ambiguous categories") {
+			t.Errorf("Expected ambiguous default error, got: %v", err)
+		}
+	})
+}
+
 // TestWildcardValidation tests that new wildcard types are properly validated
 func TestWildcardValidation(t *testing.T) {
 	g := NewForTesting(t, false)
@@ -6683,3 +7303,214 @@ func TestTopicVariableScope(t *testing.T) {
 		t.Errorf("Expected sports topic variable 'football', got '%s'", sportsValue)
 	}
 }
+
+// writeGzipFile writes content to path as a gzip-compressed file
+func writeGzipFile(t *testing.T, path, content string) {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to compress %s: %v", path, err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to compress %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to create %s: %v", path, err)
+	}
+}
+
+func TestLoadGzippedAIML(t *testing.T) {
+	g := NewForTesting(t, false)
+	tempDir := t.TempDir()
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>HELLO</pattern>
+        <template>Hi from gzip</template>
+    </category>
+    <category>
+        <pattern>I LIKE <set>colors</set></pattern>
+        <template>Nice <star/></template>
+    </category>
+</aiml>`
+	aimlFile := filepath.Join(tempDir, "bot.aiml.gz")
+	writeGzipFile(t, aimlFile, aimlContent)
+
+	kb, err := g.LoadAIML(aimlFile)
+	if err != nil {
+		t.Fatalf("LoadAIML failed for gzipped file: %v", err)
+	}
+	if len(kb.Categories) != 2 {
+		t.Errorf("Expected 2 categories, got %d", len(kb.Categories))
+	}
+	if kb.Patterns["HELLO"] == nil || kb.Patterns["HELLO"].Template != "Hi from gzip" {
+		t.Errorf("Expected HELLO category from gzipped file, got %v", kb.Patterns["HELLO"])
+	}
+
+	// Directory loaders pick up compressed resources alongside plain ones
+	writeGzipFile(t, filepath.Join(tempDir, "colors.set.gz"), `["red", "green"]`)
+	writeGzipFile(t, filepath.Join(tempDir, "capitals.map.gz"), `[{"key": "France", "value": "Paris"}]`)
+
+	dirKB, err := g.LoadAIMLFromDirectory(tempDir)
+	if err != nil {
+		t.Fatalf("LoadAIMLFromDirectory failed: %v", err)
+	}
+	if len(dirKB.Categories) != 2 {
+		t.Errorf("Expected 2 categories from directory, got %d", len(dirKB.Categories))
+	}
+
+	sets, err := g.LoadSetsFromDirectory(tempDir)
+	if err != nil {
+		t.Fatalf("LoadSetsFromDirectory failed: %v", err)
+	}
+	if members := sets["colors"]; len(members) != 2 || members[0] != "red" {
+		t.Errorf("Expected colors set from colors.set.gz, got %v", sets)
+	}
+
+	maps, err := g.LoadMapsFromDirectory(tempDir)
+	if err != nil {
+		t.Fatalf("LoadMapsFromDirectory failed: %v", err)
+	}
+	if maps["capitals"]["France"] != "Paris" {
+		t.Errorf("Expected capitals map from capitals.map.gz, got %v", maps)
+	}
+
+	// Loading through the command path wires everything together
+	if err := g.loadCommand([]string{aimlFile}); err != nil {
+		t.Fatalf("loadCommand failed for gzipped file: %v", err)
+	}
+	session := g.CreateSession("gzip_test")
+	response, err := g.ProcessInput("I like green", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if response != "Nice green" {
+		t.Errorf("Expected 'Nice green', got '%s'", response)
+	}
+}
+
+func TestLoadGzippedAIMLCorrupt(t *testing.T) {
+	g := NewForTesting(t, false)
+	aimlFile := filepath.Join(t.TempDir(), "broken.aiml.gz")
+	if err := os.WriteFile(aimlFile, []byte("not gzip data"), 0644); err != nil {
+		t.Fatalf("Failed to create %s: %v", aimlFile, err)
+	}
+	if _, err := g.LoadAIML(aimlFile); err == nil {
+		t.Error("Expected an error loading a corrupt gzipped AIML file")
+	}
+}
+
+func TestCategoryTags(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category tags="greeting, smalltalk"><pattern>HELLO</pattern><template>Hi!</template></category>
+<category tags="Greeting,,greeting"><pattern>GOOD MORNING</pattern><template>Morning!</template></category>
+<category tags="smalltalk"><pattern>HOW ARE YOU</pattern><template>Fine.</template></category>
+<category><pattern>BYE</pattern><template>Bye.</template></category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	kb := g.GetKnowledgeBase()
+
+	patterns := func(categories []*Category) []string {
+		var result []string
+		for _, category := range categories {
+			result = append(result, category.Pattern)
+		}
+		return result
+	}
+
+	if got := patterns(kb.CategoriesByTag("greeting")); !reflect.DeepEqual(got, []string{"HELLO", "GOOD MORNING"}) {
+		t.Errorf("Expected the greeting categories in load order, got %v", got)
+	}
+	if got := patterns(kb.CategoriesByTag("SMALLTALK")); !reflect.DeepEqual(got, []string{"HELLO", "HOW ARE YOU"}) {
+		t.Errorf("Expected tags to be compared case-insensitively, got %v", got)
+	}
+	if got := kb.CategoriesByTag("weather"); len(got) != 0 {
+		t.Errorf("Expected no categories for an unknown tag, got %v", patterns(got))
+	}
+
+	// Blank and repeated tags are dropped
+	morning := kb.CategoriesByTag("greeting")[1]
+	if !reflect.DeepEqual(morning.Tags, []string{"Greeting"}) {
+		t.Errorf("Expected tags [Greeting], got %v", morning.Tags)
+	}
+
+	// Tags don't affect matching
+	response, err := g.ProcessInput("hello", g.CreateSession("tags"))
+	if err != nil || response != "Hi!" {
+		t.Errorf("Expected 'Hi!', got %q (err %v)", response, err)
+	}
+}
+
+func TestRemoveCommentsPreservesContent(t *testing.T) {
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+
+			aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <!--
+        A multi-line comment with a > inside it,
+        and an entire category:
+        <category><pattern>HIDDEN</pattern><template>Should not load</template></category>
+    -->
+    <category>
+        <pattern>HELLO <!-- inline --> THERE</pattern>
+        <template>Hi <!-- a comment
+        spanning lines -->there!</template>
+    </category>
+    <category>
+        <pattern>MARKUP</pattern>
+        <template>Write <![CDATA[<!-- note -->]]> to add a note.</template>
+    </category>
+</aiml>`
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+
+			session := g.CreateSession("comments_" + name)
+			response, err := g.ProcessInput("hello there", session)
+			if err != nil || response != "Hi there!" {
+				t.Errorf("Expected 'Hi there!', got %q (err %v)", response, err)
+			}
+
+			// The legacy engine keeps the CDATA markers, so only check the text survives
+			response, err = g.ProcessInput("markup", session)
+			if err != nil || !strings.Contains(response, "<!-- note -->") {
+				t.Errorf("Expected comment-like CDATA text to be preserved, got %q (err %v)", response, err)
+			}
+
+			if _, err := g.ProcessInput("hidden", session); err == nil {
+				t.Error("Expected the commented-out category not to be loaded")
+			}
+		})
+	}
+}
+
+func TestStripXMLComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"no comments", "plain text", "plain text"},
+		{"single line", "a<!-- b -->c", "ac"},
+		{"multi-line with >", "a<!-- 1 > 0\n -> x -->c", "ac"},
+		{"several comments", "<!--x-->a<!--y-->b", "ab"},
+		{"inside CDATA", "<![CDATA[<!-- kept -->]]><!-- dropped -->", "<![CDATA[<!-- kept -->]]>"},
+		{"unterminated", "a<!-- b", "a<!-- b"},
+	}
+	for _, tt := range tests {
+		if got := stripXMLComments(tt.input); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
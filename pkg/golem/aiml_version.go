@@ -0,0 +1,105 @@
+package golem
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// aiml2OnlyTemplateTags are the template tags introduced by AIML 2.0, which older
+// interpreters don't understand
+var aiml2OnlyTemplateTags = map[string]bool{
+	"oob":         true,
+	"sraix":       true,
+	"loop":        true,
+	"map":         true,
+	"explode":     true,
+	"normalize":   true,
+	"denormalize": true,
+	"request":     true,
+	"response":    true,
+	"interval":    true,
+	"learnf":      true,
+	"first":       true,
+	"rest":        true,
+}
+
+// templateTagNameRegex finds the names of the opening and self-closing tags in a template
+var templateTagNameRegex = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9_]*)`)
+
+// isAIML1Version reports whether a document version (the <aiml version> attribute)
+// is an AIML 1.x version
+func isAIML1Version(version string) bool {
+	version = strings.TrimSpace(version)
+	return version == "1" || strings.HasPrefix(version, "1.")
+}
+
+// versionFeatureWarnings lists the AIML 2.0 features used by the categories of an
+// AIML 1.x document, one message per feature and category. Documents of any other
+// version have no restrictions.
+func versionFeatureWarnings(aiml *AIML) []string {
+	if !isAIML1Version(aiml.Version) {
+		return nil
+	}
+
+	var warnings []string
+	for i := range aiml.Categories {
+		category := &aiml.Categories[i]
+		for _, feature := range aiml2OnlyFeatures(category) {
+			warnings = append(warnings, fmt.Sprintf("%s: pattern '%s': %s requires AIML 2.0 (document version %s)",
+				describeCategorySource(category), category.Pattern, feature, aiml.Version))
+		}
+	}
+	return warnings
+}
+
+// aiml2OnlyFeatures returns the AIML 2.0 features a category uses, in the order they
+// appear
+func aiml2OnlyFeatures(category *Category) []string {
+	var features []string
+	seen := make(map[string]bool)
+	add := func(feature string) {
+		if !seen[feature] {
+			seen[feature] = true
+			features = append(features, feature)
+		}
+	}
+
+	for _, pattern := range []string{category.Pattern, category.That, category.Topic} {
+		for _, word := range strings.Fields(pattern) {
+			switch {
+			case word == "^" || word == "#":
+				add("the " + word + " wildcard")
+			case len(word) > 1 && strings.HasPrefix(word, "$"):
+				add("the $ priority marker")
+			}
+		}
+		if strings.Contains(pattern, "<set") {
+			add("a <set> in a pattern")
+		}
+	}
+
+	for _, match := range templateTagNameRegex.FindAllStringSubmatch(category.Template, -1) {
+		tagName := strings.ToLower(match[1])
+		if aiml2OnlyTemplateTags[tagName] {
+			add("the <" + tagName + "> tag")
+		}
+	}
+	return features
+}
+
+// checkVersionFeatures reports the AIML 2.0 features used by an AIML 1.x document as
+// load warnings, or as an error when StrictVersion is enabled
+func (g *Golem) checkVersionFeatures(aiml *AIML) error {
+	warnings := versionFeatureWarnings(aiml)
+	if len(warnings) == 0 {
+		return nil
+	}
+	if g.StrictVersion {
+		return fmt.Errorf("%s", strings.Join(warnings, "; "))
+	}
+	for _, warning := range warnings {
+		g.LogWarn("%s", warning)
+	}
+	return nil
+}
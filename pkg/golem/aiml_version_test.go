@@ -0,0 +1,105 @@
+package golem
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAIMLVersionGating(t *testing.T) {
+	categories := `
+	<category>
+		<pattern>HELLO ^</pattern>
+		<template>Hi there.</template>
+	</category>
+	<category>
+		<pattern># WEATHER</pattern>
+		<template><oob><weather/></oob>Checking.</template>
+	</category>
+	<category>
+		<pattern>GOODBYE *</pattern>
+		<template>Bye, <star/>.</template>
+	</category>`
+	document := func(version string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="` + version + `">` + categories + `
+</aiml>`
+	}
+
+	load := func(t *testing.T, version string, strict bool) (*Golem, string, error) {
+		g := NewForTesting(t, false)
+		var logs bytes.Buffer
+		g.logger = log.New(&logs, "", 0)
+		g.SetLogLevel(LogLevelWarn)
+		g.StrictVersion = strict
+		err := g.LoadAIMLFromString(document(version))
+		return g, logs.String(), err
+	}
+
+	t.Run("AIML 1.0 warns", func(t *testing.T) {
+		g, logs, err := load(t, "1.0", false)
+		if err != nil {
+			t.Fatalf("Expected the document to load, got %v", err)
+		}
+		for _, expected := range []string{
+			"category 1: pattern 'HELLO ^': the ^ wildcard requires AIML 2.0 (document version 1.0)",
+			"category 2: pattern '# WEATHER': the # wildcard requires AIML 2.0",
+			"category 2: pattern '# WEATHER': the <oob> tag requires AIML 2.0",
+		} {
+			if !strings.Contains(logs, "[WARN] "+expected) {
+				t.Errorf("Expected warning %q, got logs:\n%s", expected, logs)
+			}
+		}
+		if strings.Contains(logs, "GOODBYE") {
+			t.Errorf("AIML 1.0 features must not be reported, got logs:\n%s", logs)
+		}
+
+		// The categories still work
+		response, err := g.ProcessInput("hello", g.CreateSession("v1"))
+		if err != nil || response != "Hi there." {
+			t.Errorf("Expected 'Hi there.', got %q (err %v)", response, err)
+		}
+	})
+
+	t.Run("AIML 1.0 strict", func(t *testing.T) {
+		_, _, err := load(t, "1.0", true)
+		if err == nil || !strings.Contains(err.Error(), "the ^ wildcard requires AIML 2.0") {
+			t.Errorf("Expected a version error, got %v", err)
+		}
+	})
+
+	t.Run("AIML 2.0", func(t *testing.T) {
+		for _, strict := range []bool{false, true} {
+			_, logs, err := load(t, "2.0", strict)
+			if err != nil {
+				t.Fatalf("Expected the document to load (strict=%v), got %v", strict, err)
+			}
+			if strings.Contains(logs, "requires AIML 2.0") {
+				t.Errorf("Expected no version warnings (strict=%v), got logs:\n%s", strict, logs)
+			}
+		}
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "legacy.aiml"), []byte(document("1.0")), 0644); err != nil {
+			t.Fatalf("Failed to write AIML: %v", err)
+		}
+
+		g := NewForTesting(t, false)
+		results, err := g.ValidateAIMLDirectory(dir)
+		if err != nil {
+			t.Fatalf("ValidateAIMLDirectory failed: %v", err)
+		}
+		if len(results[0].Warnings) != 3 || len(results[0].Errors) != 0 {
+			t.Errorf("Expected 3 version warnings, got %+v", results[0])
+		}
+
+		if err := NewForTesting(t, false).Execute("validate", []string{dir, "--strict-version"}); err == nil {
+			t.Error("Expected validation with --strict-version to fail")
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package golem
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AddAlias maps an input phrase to the canonical phrase it should be matched as. Both
+// are stored in normalized form; an empty phrase is ignored.
+func (kb *AIMLKnowledgeBase) AddAlias(phrase, canonical string) {
+	phrase = NormalizePattern(phrase)
+	if phrase == "" {
+		return
+	}
+	if kb.Aliases == nil {
+		kb.Aliases = make(map[string]string)
+	}
+	kb.Aliases[phrase] = NormalizePattern(canonical)
+}
+
+// applyAliases replaces the alias phrases in normalized input with their canonical
+// phrases. Only whole words are replaced, the longest phrase starting at each word
+// wins, and canonical phrases are not aliased again.
+func (kb *AIMLKnowledgeBase) applyAliases(input string) string {
+	result, _ := kb.rewriteAliases(input, false)
+	return result
+}
+
+// rewriteAliases implements applyAliases, reporting whether any alias was applied.
+// Phrases are compared case-insensitively; with lowerCanonical the canonical phrases
+// are inserted in lower case, for case-preserving input.
+func (kb *AIMLKnowledgeBase) rewriteAliases(input string, lowerCanonical bool) (string, bool) {
+	if kb == nil || len(kb.Aliases) == 0 {
+		return input, false
+	}
+
+	longest := 0
+	for phrase := range kb.Aliases {
+		if words := len(strings.Fields(phrase)); words > longest {
+			longest = words
+		}
+	}
+
+	words := strings.Fields(input)
+	result := make([]string, 0, len(words))
+	rewritten := false
+	for i := 0; i < len(words); {
+		matched := false
+		for length := min(longest, len(words)-i); length > 0; length-- {
+			canonical, exists := kb.Aliases[strings.ToUpper(strings.Join(words[i:i+length], " "))]
+			if !exists {
+				continue
+			}
+			if lowerCanonical {
+				canonical = strings.ToLower(canonical)
+			}
+			if canonical != "" {
+				result = append(result, canonical)
+			}
+			i += length
+			matched = true
+			rewritten = true
+			break
+		}
+		if !matched {
+			result = append(result, words[i])
+			i++
+		}
+	}
+	if !rewritten {
+		return input, false
+	}
+	return strings.Join(result, " "), true
+}
+
+// captureInput returns the input wildcard values are captured from. Captures come from
+// the original input, split into words by the tokenizer, to keep its case and
+// punctuation. That no longer lines up with the matched pattern once an alias has
+// rewritten it, so in that case they come from the case-preserving normalized input
+// with the aliases applied.
+func (g *Golem) captureInput(input string) string {
+	input = g.tokenizeText(input)
+	if rewritten, ok := g.aimlKB.rewriteAliases(g.CachedNormalizeForMatchingCasePreserving(input), true); ok {
+		return rewritten
+	}
+	return input
+}
+
+// LoadAliasFromFile loads an .alias file containing a JSON array of [phrase, canonical]
+// pairs
+func (g *Golem) LoadAliasFromFile(filename string) (map[string]string, error) {
+	g.LogInfo("Loading alias file: %s", filename)
+
+	content, err := readResourceFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alias file %s: %v", filename, err)
+	}
+
+	var aliasPairs [][]string
+	if err := json.Unmarshal(content, &aliasPairs); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON in alias file %s: %v", filename, err)
+	}
+
+	result := make(map[string]string)
+	for _, pair := range aliasPairs {
+		if len(pair) != 2 || strings.TrimSpace(pair[0]) == "" {
+			g.LogInfo("Warning: skipping invalid alias pair: %v", pair)
+			continue
+		}
+		result[pair[0]] = pair[1]
+	}
+
+	g.LogInfo("Loaded %d aliases from %s", len(result), filename)
+	return result, nil
+}
+
+// LoadAliasesFromDirectory loads all .alias files from a directory into a single
+// phrase -> canonical map; later files override earlier ones
+func (g *Golem) LoadAliasesFromDirectory(dirPath string) (map[string]string, error) {
+	return g.loadAliasesFromDirectory(dirPath, false)
+}
+
+// loadAliasesFromDirectory implements LoadAliasesFromDirectory, failing on a file that fails
+// to load with failOnError (see skipFailedFile)
+func (g *Golem) loadAliasesFromDirectory(dirPath string, failOnError bool) (map[string]string, error) {
+	g.LogInfo("Loading alias files from directory: %s", dirPath)
+
+	var aliasFiles []string
+	err := filepath.WalkDir(dirPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && hasResourceExtension(path, ".alias") {
+			aliasFiles = append(aliasFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %v", dirPath, err)
+	}
+
+	allAliases := make(map[string]string)
+	for _, aliasFile := range aliasFiles {
+		aliases, err := g.LoadAliasFromFile(aliasFile)
+		if err != nil {
+			if err := g.skipFailedFile(aliasFile, err, failOnError); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for phrase, canonical := range aliases {
+			allAliases[phrase] = canonical
+		}
+	}
+
+	g.LogInfo("Loaded %d aliases from %d alias files", len(allAliases), len(aliasFiles))
+	return allAliases, nil
+}
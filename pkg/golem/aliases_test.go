@@ -0,0 +1,104 @@
+package golem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"greetings.aiml": `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>HELLO *</pattern>
+		<template>Hello, <star/>!</template>
+	</category>
+	<category>
+		<pattern>WHAT IS THE WEATHER</pattern>
+		<template>Sunny.</template>
+	</category>
+	<category>
+		<pattern>WHAT IS *</pattern>
+		<template>I don't know what <star/> is.</template>
+	</category>
+</aiml>`,
+		"synonyms.alias": `[
+	["howdy", "hello"],
+	["hows the weather", "what is the weather"],
+	["hows", "what is"],
+	["u", "you"]
+]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	g := NewForTesting(t, false)
+	kb, err := g.LoadAIMLFromDirectory(dir)
+	if err != nil {
+		t.Fatalf("LoadAIMLFromDirectory failed: %v", err)
+	}
+	g.SetKnowledgeBase(kb)
+
+	if len(kb.Aliases) != 4 || kb.Aliases["HOWS THE WEATHER"] != "WHAT IS THE WEATHER" {
+		t.Errorf("Expected 4 normalized aliases, got %v", kb.Aliases)
+	}
+
+	session := g.CreateSession("aliases")
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Howdy partner", "Hello, partner!"},
+		{"hows the weather", "Sunny."}, // The longest phrase wins
+		{"hows the tide", "I don't know what the tide is."},
+		{"hello showdy", "Hello, showdy!"}, // Only whole words are replaced
+		{"hello u", "Hello, you!"},
+	}
+	for _, tc := range tests {
+		response, err := g.ProcessInput(tc.input, session)
+		if err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", tc.input, err)
+		}
+		if response != tc.expected {
+			t.Errorf("ProcessInput(%q) = %q, expected %q", tc.input, response, tc.expected)
+		}
+	}
+
+	if normalized := g.NormalizeInput("Howdy, hows the weather?"); normalized != "HELLO WHAT IS THE WEATHER" {
+		t.Errorf("Expected NormalizeInput to apply aliases, got %q", normalized)
+	}
+}
+
+func TestAliasAddedAtRuntime(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>HELLO</pattern>
+		<template>hi!</template>
+	</category>
+	<category>
+		<pattern>*</pattern>
+		<template>catch</template>
+	</category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("runtime_alias")
+	if response, err := g.ProcessInput("howdy", session); err != nil || response != "catch" {
+		t.Fatalf("Expected the catch-all before the alias exists, got %q (err: %v)", response, err)
+	}
+
+	// The input was normalized (and cached) before the alias was added
+	g.GetKnowledgeBase().AddAlias("howdy", "hello")
+	if response, err := g.ProcessInput("howdy", session); err != nil || response != "hi!" {
+		t.Errorf("Expected the alias to apply to input seen before, got %q (err: %v)", response, err)
+	}
+}
@@ -16,6 +16,31 @@ const (
 	NodeTypeCDATA
 )
 
+// implicitlySelfClosingTags are tags normally written self-closing; an unclosed <star>
+// parses as <star/>, and the tree processor treats an empty <star></star> the same way
+var implicitlySelfClosingTags = map[string]bool{
+	"star":     true,
+	"sr":       true,
+	"get":      true,
+	"bot":      true,
+	"that":     true,
+	"input":    true,
+	"loop":     true,
+	"date":     true,
+	"time":     true,
+	"size":     true,
+	"version":  true,
+	"id":       true,
+	"request":  true,
+	"response": true,
+	"repeat":   true,
+	"topic":    true,
+	"subj":     true,
+	"pred":     true,
+	"obj":      true,
+	"uniq":     true,
+}
+
 // ASTNode represents a node in the Abstract Syntax Tree
 type ASTNode struct {
 	Type        ASTNodeType
@@ -26,6 +51,7 @@ type ASTNode struct {
 	SelfClosing bool              // For self-closing tags
 	StartPos    int               // Start position in original string
 	EndPos      int               // End position in original string
+	ParseError  error             // For malformed tags, whose Content is their literal text
 }
 
 // ASTParser handles parsing of AIML templates into AST
@@ -131,10 +157,15 @@ func (p *ASTParser) parseTag() *ASTNode {
 		return p.parseClosingTag(startPos)
 	}
 
-	// Parse tag name
+	// Parse tag name; a '<' that doesn't start a tag is text
 	tagName := p.parseTagName()
 	if tagName == "" {
-		return nil
+		return &ASTNode{
+			Type:     NodeTypeText,
+			Content:  p.input[startPos:p.pos],
+			StartPos: startPos,
+			EndPos:   p.pos,
+		}
 	}
 
 	// Parse attributes
@@ -144,7 +175,7 @@ func (p *ASTParser) parseTag() *ASTNode {
 	if p.peek(1) == "/" {
 		p.consume('/')
 		if !p.consume('>') {
-			return nil
+			return p.malformedTag(startPos, tagName, attributes, fmt.Errorf("<%s> tag is missing '>'", tagName))
 		}
 
 		return &ASTNode{
@@ -157,32 +188,8 @@ func (p *ASTParser) parseTag() *ASTNode {
 		}
 	}
 
-	// Check if this is an implicitly self-closing tag
-	implicitlySelfClosing := map[string]bool{
-		"star":     true,
-		"sr":       true,
-		"get":      true,
-		"bot":      true,
-		"that":     true,
-		"input":    true,
-		"loop":     true,
-		"date":     true,
-		"time":     true,
-		"size":     true,
-		"version":  true,
-		"id":       true,
-		"request":  true,
-		"response": true,
-		"repeat":   true,
-		"topic":    true,
-		"subj":     true,
-		"pred":     true,
-		"obj":      true,
-		"uniq":     true,
-	}
-
 	// If this is an implicitly self-closing tag and we're at the end or next non-whitespace is '<'
-	if implicitlySelfClosing[tagName] {
+	if implicitlySelfClosingTags[tagName] {
 		// Save current position
 		savedPos := p.pos
 
@@ -212,7 +219,7 @@ func (p *ASTParser) parseTag() *ASTNode {
 
 	// Consume '>'
 	if !p.consume('>') {
-		return nil
+		return p.malformedTag(startPos, tagName, attributes, fmt.Errorf("<%s> tag is missing '>'", tagName))
 	}
 
 	// Create opening tag node
@@ -298,29 +305,6 @@ func (p *ASTParser) parseTag() *ASTNode {
 	}
 
 	// If we get here, check if it should be a self-closing tag
-	implicitlySelfClosingTags := map[string]bool{
-		"star":     true,
-		"sr":       true,
-		"get":      true,
-		"bot":      true,
-		"that":     true,
-		"input":    true,
-		"loop":     true,
-		"date":     true,
-		"time":     true,
-		"size":     true,
-		"version":  true,
-		"id":       true,
-		"request":  true,
-		"response": true,
-		"repeat":   true,
-		"topic":    true,
-		"subj":     true,
-		"pred":     true,
-		"obj":      true,
-		"uniq":     true,
-	}
-
 	if implicitlySelfClosingTags[tagName] {
 		// This is an implicitly self-closing tag
 		return &ASTNode{
@@ -333,13 +317,22 @@ func (p *ASTParser) parseTag() *ASTNode {
 		}
 	}
 
-	// If we get here, it's a malformed tag
-	// Return as text node
+	// If we get here, the tag is never closed
+	return p.malformedTag(startPos, tagName, attributes, fmt.Errorf("<%s> tag is never closed", tagName))
+}
+
+// malformedTag returns the node for a tag that failed to parse, from startPos up to
+// where parsing stopped. The tree processor resolves it to its default attribute or
+// its literal text (see TreeProcessor.processTagIsolated).
+func (p *ASTParser) malformedTag(startPos int, tagName string, attributes map[string]string, err error) *ASTNode {
 	return &ASTNode{
-		Type:     NodeTypeText,
-		Content:  p.input[startPos:p.pos],
-		StartPos: startPos,
-		EndPos:   p.pos,
+		Type:       NodeTypeTag,
+		TagName:    tagName,
+		Content:    p.input[startPos:p.pos],
+		Attributes: attributes,
+		StartPos:   startPos,
+		EndPos:     p.pos,
+		ParseError: err,
 	}
 }
 
@@ -579,6 +572,9 @@ func (p *ASTParser) consumeWhitespace() {
 
 // String returns a string representation of the AST node
 func (n *ASTNode) String() string {
+	if n.ParseError != nil {
+		return n.Content
+	}
 	switch n.Type {
 	case NodeTypeText:
 		// If this is a text node with children, return children
@@ -640,6 +636,9 @@ func (n *ASTNode) String() string {
 
 // GetTextContent returns all text content from a node and its children
 func (n *ASTNode) GetTextContent() string {
+	if n.ParseError != nil {
+		return n.Content
+	}
 	switch n.Type {
 	case NodeTypeText:
 		// If this is a text node with children, process children
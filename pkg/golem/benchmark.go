@@ -0,0 +1,122 @@
+package golem
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BenchmarkResult summarizes a matching throughput run
+type BenchmarkResult struct {
+	Inputs           int           // Inputs processed
+	Matched          int           // Inputs that matched a category
+	Total            time.Duration // Total time spent matching and processing
+	MatchesPerSecond float64       // Inputs processed per second
+	P50              time.Duration // Median per-input latency
+	P95              time.Duration // 95th percentile per-input latency
+}
+
+// LoadBenchmarkInputs reads benchmark inputs from a file, one per line. Blank lines
+// and lines starting with '#' are skipped.
+func LoadBenchmarkInputs(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open benchmark inputs %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	var inputs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inputs = append(inputs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read benchmark inputs %s: %v", filename, err)
+	}
+	return inputs, nil
+}
+
+// RunBenchmark times MatchPattern plus ProcessTemplate for each input against the
+// loaded knowledge base, using a single session so templates that read or write
+// predicates behave as in a conversation. Unmatched inputs are timed and counted
+// but not processed.
+func (g *Golem) RunBenchmark(inputs []string) (*BenchmarkResult, error) {
+	if g.aimlKB == nil {
+		return nil, fmt.Errorf("no AIML knowledge base loaded")
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("benchmark requires at least one input")
+	}
+
+	session := g.createSession("")
+	latencies := make([]time.Duration, 0, len(inputs))
+	result := &BenchmarkResult{Inputs: len(inputs)}
+
+	for _, input := range inputs {
+		start := time.Now()
+		category, wildcards, err := g.aimlKB.MatchPattern(input)
+		if err == nil {
+			g.ProcessTemplateWithSession(category.Template, wildcards, session)
+			result.Matched++
+		}
+		elapsed := time.Since(start)
+
+		latencies = append(latencies, elapsed)
+		result.Total += elapsed
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = latencyPercentile(latencies, 50)
+	result.P95 = latencyPercentile(latencies, 95)
+	if result.Total > 0 {
+		result.MatchesPerSecond = float64(result.Inputs) / result.Total.Seconds()
+	}
+
+	g.LogInfo("Benchmarked %d inputs in %v", result.Inputs, result.Total)
+	return result, nil
+}
+
+// latencyPercentile returns the nearest-rank percentile of sorted latencies
+func latencyPercentile(sorted []time.Duration, percentile int) time.Duration {
+	rank := (percentile*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// benchmarkCommand handles "benchmark <dir> <inputfile>", loading the knowledge base
+// from dir and reporting throughput and latency for the inputs in inputfile
+func (g *Golem) benchmarkCommand(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: benchmark <dir> <inputfile>")
+	}
+
+	if err := g.loadCommand([]string{args[0]}); err != nil {
+		return err
+	}
+
+	inputs, err := LoadBenchmarkInputs(args[1])
+	if err != nil {
+		return err
+	}
+
+	result, err := g.RunBenchmark(inputs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Inputs:       %d (%d matched)\n", result.Inputs, result.Matched)
+	fmt.Printf("Total time:   %v\n", result.Total)
+	fmt.Printf("Matches/sec:  %.1f\n", result.MatchesPerSecond)
+	fmt.Printf("p50 latency:  %v\n", result.P50)
+	fmt.Printf("p95 latency:  %v\n", result.P95)
+	return nil
+}
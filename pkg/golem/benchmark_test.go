@@ -0,0 +1,83 @@
+package golem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	g := NewForTesting(t, false)
+	dir := t.TempDir()
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>HELLO</pattern>
+        <template>Hi there!</template>
+    </category>
+    <category>
+        <pattern>MY NAME IS *</pattern>
+        <template><think><set name="name"><star/></set></think>Nice to meet you, <get name="name"/>.</template>
+    </category>
+</aiml>`
+	if err := os.WriteFile(filepath.Join(dir, "bot.aiml"), []byte(aimlContent), 0644); err != nil {
+		t.Fatalf("Failed to write AIML: %v", err)
+	}
+	inputFile := filepath.Join(t.TempDir(), "inputs.txt")
+	inputs := "# greeting\nhello\n\nmy name is Alice\nsomething unmatched\n"
+	if err := os.WriteFile(inputFile, []byte(inputs), 0644); err != nil {
+		t.Fatalf("Failed to write inputs: %v", err)
+	}
+
+	loaded, err := LoadBenchmarkInputs(inputFile)
+	if err != nil {
+		t.Fatalf("LoadBenchmarkInputs failed: %v", err)
+	}
+	if len(loaded) != 3 {
+		t.Fatalf("Expected 3 inputs (comments and blank lines skipped), got %d: %v", len(loaded), loaded)
+	}
+
+	if _, err := g.RunBenchmark(loaded); err == nil {
+		t.Error("Expected an error with no knowledge base loaded")
+	}
+
+	if err := g.Execute("benchmark", []string{dir, inputFile}); err != nil {
+		t.Fatalf("benchmark command failed: %v", err)
+	}
+
+	result, err := g.RunBenchmark(loaded)
+	if err != nil {
+		t.Fatalf("RunBenchmark failed: %v", err)
+	}
+	if result.Inputs != 3 || result.Matched != 2 {
+		t.Errorf("Expected 3 inputs with 2 matched, got %d with %d matched", result.Inputs, result.Matched)
+	}
+	if result.Total <= 0 || result.MatchesPerSecond <= 0 {
+		t.Errorf("Expected positive total time and throughput, got %v and %.1f", result.Total, result.MatchesPerSecond)
+	}
+	if result.P50 <= 0 || result.P50 > result.P95 || result.P95 > result.Total || result.P95 > time.Second {
+		t.Errorf("Implausible latencies: p50=%v p95=%v total=%v", result.P50, result.P95, result.Total)
+	}
+
+	if err := g.Execute("benchmark", []string{dir}); err == nil {
+		t.Error("Expected an error when no input file is given")
+	}
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 20)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+	if p50 := latencyPercentile(latencies, 50); p50 != 10*time.Millisecond {
+		t.Errorf("Expected p50 of 10ms, got %v", p50)
+	}
+	if p95 := latencyPercentile(latencies, 95); p95 != 19*time.Millisecond {
+		t.Errorf("Expected p95 of 19ms, got %v", p95)
+	}
+	if p95 := latencyPercentile(latencies[:1], 95); p95 != time.Millisecond {
+		t.Errorf("Expected p95 of a single latency to be that latency, got %v", p95)
+	}
+}
@@ -0,0 +1,45 @@
+package golem
+
+import "strings"
+
+// Default word lists for TemplateProcessingConfig.CanonicalizeBooleans. Digits are
+// left out so counters aren't turned into booleans.
+var (
+	defaultTruthyWords = []string{"true", "yes", "y", "on"}
+	defaultFalsyWords  = []string{"false", "no", "n", "off"}
+)
+
+// canonicalizeBoolean returns "true" or "false" for a boolean-like value when
+// CanonicalizeBooleans is enabled, and the value unchanged otherwise
+func (g *Golem) canonicalizeBoolean(value string) string {
+	if g.templateConfig == nil || !g.templateConfig.CanonicalizeBooleans {
+		return value
+	}
+
+	truthy, falsy := g.templateConfig.TruthyWords, g.templateConfig.FalsyWords
+	if len(truthy) == 0 {
+		truthy = defaultTruthyWords
+	}
+	if len(falsy) == 0 {
+		falsy = defaultFalsyWords
+	}
+
+	word := strings.TrimSpace(value)
+	if containsFold(truthy, word) {
+		return "true"
+	}
+	if containsFold(falsy, word) {
+		return "false"
+	}
+	return value
+}
+
+// containsFold reports whether words contains word, ignoring case
+func containsFold(words []string, word string) bool {
+	for _, candidate := range words {
+		if strings.EqualFold(strings.TrimSpace(candidate), word) {
+			return true
+		}
+	}
+	return false
+}
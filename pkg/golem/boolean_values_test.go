@@ -0,0 +1,67 @@
+package golem
+
+import "testing"
+
+func TestCanonicalizeBooleans(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>FLAG *</pattern>
+        <template><think><set name="flag"><star/></set></think><condition name="flag"><li value="true">enabled</li><li value="false">disabled</li><li><get name="flag"/></li></condition></template>
+    </category>
+</aiml>`
+
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("booleans_" + name)
+
+			// Off by default: values are stored as spelled
+			response, err := g.ProcessInput("flag yes", session)
+			if err != nil || response != "yes" {
+				t.Errorf("Expected 'yes' with canonicalization off, got %q (err %v)", response, err)
+			}
+
+			g.GetTemplateProcessingConfig().CanonicalizeBooleans = true
+			tests := []struct {
+				value    string
+				expected string
+			}{
+				{"true", "enabled"},
+				{"True", "enabled"},
+				{"YES", "enabled"},
+				{"on", "enabled"},
+				{"FALSE", "disabled"},
+				{"No", "disabled"},
+				{"off", "disabled"},
+				{"1", "1"},
+				{"maybe", "maybe"},
+			}
+			for _, tt := range tests {
+				response, err := g.ProcessInput("flag "+tt.value, session)
+				if err != nil || response != tt.expected {
+					t.Errorf("flag %s: expected %q, got %q (err %v)", tt.value, tt.expected, response, err)
+				}
+			}
+			if session.Variables["flag"] != "maybe" {
+				t.Errorf("Expected a non-boolean value to be stored unchanged, got %q", session.Variables["flag"])
+			}
+
+			// Custom word lists replace the defaults
+			g.GetTemplateProcessingConfig().TruthyWords = []string{"si", "1"}
+			g.GetTemplateProcessingConfig().FalsyWords = []string{"0"}
+			for value, expected := range map[string]string{"SI": "enabled", "1": "enabled", "0": "disabled", "yes": "yes"} {
+				response, err := g.ProcessInput("flag "+value, session)
+				if err != nil || response != expected {
+					t.Errorf("flag %s with custom words: expected %q, got %q (err %v)", value, expected, response, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package golem
+
+import (
+	"testing"
+)
+
+func TestOnCategoryMatched(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>HELLO</pattern>
+		<template>Hi there</template>
+	</category>
+	<category>
+		<pattern>HI</pattern>
+		<template><srai>HELLO</srai></template>
+	</category>
+	<category>
+		<pattern>YO</pattern>
+		<template><srai>hello</srai></template>
+	</category>
+	<category>
+		<pattern>HEY *</pattern>
+		<template><sr/></template>
+	</category>
+</aiml>`
+
+	engines := map[string]bool{"tree": false, "legacy": true}
+	for name, legacy := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if legacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("matched")
+
+			// No callback registered: processing is unaffected
+			if response, err := g.ProcessInput("hello", session); err != nil || response != "Hi there" {
+				t.Fatalf("Expected matched response, got %q (err: %v)", response, err)
+			}
+
+			hits := make(map[string]int)
+			var inputs []string
+			g.OnCategoryMatched = func(category *Category, input string) {
+				hits[category.Pattern]++
+				inputs = append(inputs, input)
+			}
+
+			for _, input := range []string{"hello", "hi", "hi", "unknown"} {
+				g.ProcessInput(input, session)
+			}
+			if _, err := g.ProcessInputWithThatIndex("hello", session, 0); err != nil {
+				t.Fatalf("ProcessInputWithThatIndex failed: %v", err)
+			}
+			if len(hits) != 2 || hits["HELLO"] != 2 || hits["HI"] != 2 {
+				t.Errorf("Expected only the chat matches to be reported, got %v", hits)
+			}
+			if len(inputs) != 4 || inputs[1] != "hi" {
+				t.Errorf("Expected the chat inputs to be reported, got %v", inputs)
+			}
+
+			// SRAI and SR sub-matches are reported when enabled
+			hits = make(map[string]int)
+			inputs = nil
+			g.ReportSRAIMatches = true
+			if response, err := g.ProcessInput("yo", session); err != nil || response != "Hi there" {
+				t.Fatalf("Expected SRAI response, got %q (err: %v)", response, err)
+			}
+			if response, err := g.ProcessInput("hey hello", session); err != nil || response != "Hi there" {
+				t.Fatalf("Expected SR response, got %q (err: %v)", response, err)
+			}
+			if hits["YO"] != 1 || hits["HEY *"] != 1 || hits["HELLO"] != 2 {
+				t.Errorf("Expected SRAI and SR matches to be reported, got %v", hits)
+			}
+			if len(inputs) != 4 || inputs[1] != "hello" || inputs[3] != "hello" {
+				t.Errorf("Expected the redirected inputs to be reported, got %v", inputs)
+			}
+		})
+	}
+}
@@ -73,6 +73,9 @@ func (ctp *ConsolidatedTemplateProcessor) ProcessTemplate(template string, wildc
 	// Re-run SRAI processing in case input tags produced new SRAI tags
 	response = ctp.golem.processSRAITagsWithContext(response, ctx)
 
+	// Captured text was kept inert while the tags ran; return it as the user wrote it
+	response = capturedMarkupReplacer.Replace(response)
+
 	ctp.golem.LogInfo("Final response: '%s'", response)
 
 	// Smart trimming: preserve intentional indentation; collapse whitespace-only to empty
@@ -219,8 +222,12 @@ func (p *ComprehensiveWildcardProcessor) Process(template string, wildcards map[
 		}
 	}
 
+	// An empty paired <star></star> is the same as <star/>
+	response = emptyPairedStarRegex.ReplaceAllString(response, "<star$1/>")
+
 	// Replace indexed star tags first
 	for key, value := range wildcards {
+		value = inertCapture(value)
 		switch key {
 		case "star1":
 			response = strings.ReplaceAll(response, "<star index=\"1\"/>", value)
@@ -258,11 +265,11 @@ func (p *ComprehensiveWildcardProcessor) Process(template string, wildcards map[
 		key := fmt.Sprintf("star%d", starIndex)
 		if value, exists := wildcards[key]; exists {
 			// Replace only the first occurrence
-			response = strings.Replace(response, "<star/>", value, 1)
+			response = strings.Replace(response, "<star/>", inertCapture(value), 1)
 		} else if len(wildcards) == 1 {
 			// If there's only one wildcard captured, use it for all remaining <star/> tags
 			for _, value := range wildcards {
-				response = strings.Replace(response, "<star/>", value, 1)
+				response = strings.Replace(response, "<star/>", inertCapture(value), 1)
 				break
 			}
 		} else {
@@ -276,7 +283,51 @@ func (p *ComprehensiveWildcardProcessor) Process(template string, wildcards map[
 	// Process that wildcard tags (that context wildcards)
 	response = p.golem.processThatWildcardTagsWithContext(response, ctx)
 
-	return response, nil
+	return p.removeUncapturedStarTags(response), nil
+}
+
+// capturedMarkupReplacer restores the angle brackets inertCapture escaped
+var capturedMarkupReplacer = strings.NewReplacer("&lt;", "<", "&gt;", ">")
+
+// inertCapture escapes the angle brackets in text captured from user input before it is
+// substituted into the template, so markup the user typed is never run as a tag (for
+// example by <eval>). ProcessTemplate restores them once all tags are processed.
+func inertCapture(value string) string {
+	if !strings.ContainsAny(value, "<>") {
+		return value
+	}
+	return strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(value)
+}
+
+var (
+	emptyPairedStarRegex = regexp.MustCompile(`<star(\s+index="\d+")?\s*>\s*</star>`)
+	uncapturedStarRegex  = regexp.MustCompile(`<star(?:\s+index="\d+")?\s*/>|<star[1-9]/>`)
+	learnBlockRegex      = regexp.MustCompile(`(?s)<learnf?(?:\s[^>]*)?>.*?</learnf?>`)
+)
+
+// removeUncapturedStarTags resolves star tags left after substitution, which have no
+// corresponding capture, to empty. Star tags inside <learn> and <learnf> belong to the
+// learned template and are kept for when it matches.
+func (p *ComprehensiveWildcardProcessor) removeUncapturedStarTags(response string) string {
+	if !strings.Contains(response, "<star") {
+		return response
+	}
+
+	var result strings.Builder
+	last := 0
+	strip := func(segment string) string {
+		return uncapturedStarRegex.ReplaceAllStringFunc(segment, func(tag string) string {
+			p.golem.LogDebug("No wildcard captured for %s, resolving to empty", tag)
+			return ""
+		})
+	}
+	for _, block := range learnBlockRegex.FindAllStringIndex(response, -1) {
+		result.WriteString(strip(response[last:block[0]]))
+		result.WriteString(response[block[0]:block[1]])
+		last = block[1]
+	}
+	result.WriteString(strip(response[last:]))
+	return result.String()
 }
 func (p *ComprehensiveWildcardProcessor) ShouldProcess(template string, ctx *VariableContext) bool {
 	return strings.Contains(template, "<star") || strings.Contains(template, "<that_")
@@ -577,6 +628,11 @@ func (p *ComprehensiveFormatProcessor) Process(template string, wildcards map[st
 		// Process repeat tags first (before text formatting) so they can be processed by other tags
 		response = p.golem.processRepeatTagsWithContext(response, ctx)
 
+		// Normalize and denormalize tags are tree-processed, which resolves the tags
+		// nested in them, so they go before the tags that may enclose them
+		response = p.golem.processNormalizeTagsWithContext(response, ctx)
+		response = p.golem.processDenormalizeTagsWithContext(response, ctx)
+
 		// Process all text formatting tags (process from inside out for nested tags)
 		// First pass: process innermost tags (order chosen to produce expected semantics)
 		// 1) explode first so tokens are created before any casing
@@ -604,12 +660,6 @@ func (p *ComprehensiveFormatProcessor) Process(template string, wildcards map[st
 		response = p.golem.processUppercaseTagsWithContext(response, ctx)
 		response = p.golem.processLowercaseTagsWithContext(response, ctx)
 
-		// Process normalize tags (text normalization)
-		response = p.golem.processNormalizeTagsWithContext(response, ctx)
-
-		// Process denormalize tags (text denormalization)
-		response = p.golem.processDenormalizeTagsWithContext(response, ctx)
-
 		// If no changes occurred, we're done
 		if response == previousResponse {
 			break
@@ -661,7 +711,7 @@ func (p *ComprehensiveFormatProcessor) ShouldProcess(template string, ctx *Varia
 func (p *ComprehensiveFormatProcessor) GetMetrics() *ProcessorMetrics { return &ProcessorMetrics{} }
 func (p *ComprehensiveFormatProcessor) ResetMetrics()                 {}
 
-// ComprehensiveSystemProcessor handles system processing (size, version, id, that, request, response tags)
+// ComprehensiveSystemProcessor handles system processing (size, version, id, uptime, that, request, response tags)
 type ComprehensiveSystemProcessor struct {
 	*BaseProcessor
 	golem *Golem
@@ -680,6 +730,9 @@ func (p *ComprehensiveSystemProcessor) Process(template string, wildcards map[st
 	response = p.golem.processSizeTagsWithContext(response, ctx)
 	response = p.golem.processVersionTagsWithContext(response, ctx)
 	response = p.golem.processIdTagsWithContext(response, ctx)
+	response = p.golem.processCategoryCountTagsWithContext(response, ctx)
+	response = p.golem.processUptimeTagsWithContext(response, ctx)
+	response = p.golem.processSessionCountTagsWithContext(response, ctx)
 	response = p.golem.processThatTagsWithContext(response, ctx)
 	response = p.golem.processRequestTags(response, ctx)
 	response = p.golem.processResponseTags(response, ctx)
@@ -692,6 +745,9 @@ func (p *ComprehensiveSystemProcessor) ShouldProcess(template string, ctx *Varia
 		"<size", "</size>",
 		"<version", "</version>",
 		"<id", "</id>",
+		"<categorycount",
+		"<uptime",
+		"<sessioncount",
 		"<that", "</that>",
 		"<request", "</request>",
 		"<response", "</response>",
@@ -983,7 +1039,7 @@ func (p *ComprehensiveDataProcessor) processInputTags(template string, ctx *Vari
 		}
 
 		// Replace the <input/> tag with the current user input
-		template = strings.ReplaceAll(template, match[0], currentInput)
+		template = strings.ReplaceAll(template, match[0], inertCapture(currentInput))
 	}
 
 	return template
@@ -991,29 +1047,49 @@ func (p *ComprehensiveDataProcessor) processInputTags(template string, ctx *Vari
 
 // processEvalTags processes <eval> tags to evaluate content as AIML code
 func (p *ComprehensiveDataProcessor) processEvalTags(template string, ctx *VariableContext) string {
-	// Find all <eval> tags
-	evalRegex := regexp.MustCompile(`<eval>(.*?)</eval>`)
-	matches := evalRegex.FindAllStringSubmatch(template, -1)
-
-	for _, match := range matches {
-		content := strings.TrimSpace(match[1])
-		if content == "" {
-			template = strings.ReplaceAll(template, match[0], "")
-			continue
+	// Work from the innermost <eval> outwards, so nested tags pair up correctly
+	for {
+		start := strings.LastIndex(template, "<eval>")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(template[start:], "</eval>")
+		if end == -1 {
+			break
 		}
+		end += start + len("</eval>")
+		content := strings.TrimSpace(template[start+len("<eval>") : end-len("</eval>")])
 
-		// Process the content through the full template pipeline
-		// This ensures any variables or other tags are resolved
-		processedContent := p.golem.processTemplateWithContext(content, map[string]string{}, ctx)
-		processedContent = strings.TrimSpace(processedContent)
+		processedContent := ""
+		if content != "" {
+			processedContent = p.evalContent(content, ctx)
+		}
 
 		// Replace the entire <eval> tag with the processed content
-		template = strings.ReplaceAll(template, match[0], processedContent)
+		template = template[:start] + processedContent + template[end:]
 	}
 
 	return template
 }
 
+// evalContent runs the content of an <eval> tag through the full template pipeline, so
+// any variables or other tags (including markup read from a variable) are resolved.
+// Each evaluation goes one level deeper, so a variable holding <eval> of itself
+// can't loop forever.
+func (p *ComprehensiveDataProcessor) evalContent(content string, ctx *VariableContext) string {
+	evalCtx := ctx
+	if ctx != nil {
+		if ctx.RecursionDepth >= MaxSRAIRecursionDepth {
+			p.golem.LogWarn("Eval recursion depth limit reached (%d), dropping '%s'", MaxSRAIRecursionDepth, content)
+			return ""
+		}
+		deeper := *ctx
+		deeper.RecursionDepth++
+		evalCtx = &deeper
+	}
+	return strings.TrimSpace(p.golem.processTemplateWithContext(content, map[string]string{}, evalCtx))
+}
+
 // processUniqTags processes <uniq> tags for RDF-like predicate relationships
 func (p *ComprehensiveDataProcessor) processUniqTags(template string, ctx *VariableContext) string {
 	// Find all <uniq> tags (with optional attributes)
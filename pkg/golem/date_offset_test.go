@@ -0,0 +1,105 @@
+package golem
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseClockOffset(t *testing.T) {
+	tests := []struct {
+		offset   string
+		days     int
+		duration time.Duration
+	}{
+		{"+1d", 1, 0},
+		{"2d", 2, 0},
+		{"-30m", 0, -30 * time.Minute},
+		{"+2h", 0, 2 * time.Hour},
+		{"45s", 0, 45 * time.Second},
+		{"-1d12h", -1, -12 * time.Hour},
+	}
+	for _, tt := range tests {
+		days, duration, err := parseClockOffset(tt.offset)
+		if err != nil || days != tt.days || duration != tt.duration {
+			t.Errorf("parseClockOffset(%q) = %d, %v, %v; expected %d, %v", tt.offset, days, duration, err, tt.days, tt.duration)
+		}
+	}
+
+	for _, offset := range []string{"tomorrow", "+1w", "1.5h", "+-1d", "d", ""} {
+		if _, _, err := parseClockOffset(offset); err == nil {
+			t.Errorf("Expected parseClockOffset(%q) to fail", offset)
+		}
+	}
+}
+
+func TestDateTimeOffsets(t *testing.T) {
+	aiml := `<aiml version="2.0">
+	<category>
+		<pattern>TOMORROW</pattern>
+		<template>Tomorrow is <date format="%Y-%m-%d" offset="+1d"/>.</template>
+	</category>
+	<category>
+		<pattern>YESTERDAY</pattern>
+		<template>Yesterday was <date format="%Y-%m-%d" offset="-1d"/>.</template>
+	</category>
+	<category>
+		<pattern>IN TWO HOURS</pattern>
+		<template>In two hours it will be <time format="24" offset="+2h"/>.</template>
+	</category>
+	<category>
+		<pattern>HALF AN HOUR AGO</pattern>
+		<template>It was <time format="24" offset="-30m"/>.</template>
+	</category>
+	<category>
+		<pattern>BAD OFFSET</pattern>
+		<template>Today is <date format="%Y-%m-%d" offset="soon"/>.</template>
+	</category>
+</aiml>`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"tomorrow", "Tomorrow is 2025-03-01."},
+		{"yesterday", "Yesterday was 2025-02-27."},
+		{"in two hours", "In two hours it will be 01:15."},
+		{"half an hour ago", "It was 22:45."},
+		{"bad offset", "Today is 2025-02-28."},
+	}
+
+	engines := map[string]func(g *Golem){
+		"tree":         func(g *Golem) {},
+		"consolidated": func(g *Golem) { g.SetTemplateProcessor(g.GetConsolidatedProcessor()) },
+	}
+	for name, configure := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			configure(g)
+			var logs bytes.Buffer
+			g.logger = log.New(&logs, "", 0)
+			g.SetLogLevel(LogLevelWarn)
+			frozen := time.Date(2025, 2, 28, 23, 15, 0, 0, time.UTC)
+			g.timeNow = func() time.Time { return frozen }
+
+			if err := g.LoadAIMLFromString(aiml); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("offsets")
+			for _, tt := range tests {
+				response, err := g.ProcessInput(tt.input, session)
+				if err != nil {
+					t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+				}
+				if response != tt.expected {
+					t.Errorf("%s: expected '%s', got '%s'", tt.input, tt.expected, response)
+				}
+			}
+			if !strings.Contains(logs.String(), "Ignoring date/time offset: invalid offset 'soon'") {
+				t.Errorf("Expected a warning for the invalid offset, got logs:\n%s", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package golem
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFoldDiacritics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"CAFE", "CAFE"},
+		{"CAFÉ", "CAFE"},
+		{"café", "cafe"},
+		{"NAÏVE RÉSUMÉ", "NAIVE RESUME"},
+		{"ÜBER GRÜN", "UBER GRUN"},
+		{"MAÑANA", "MANANA"},
+		{"ПРИВЕТ", "ПРИВЕТ"}, // Non-Latin scripts without combining marks are unchanged
+	}
+
+	for _, test := range tests {
+		if result := FoldDiacritics(test.input); result != test.expected {
+			t.Errorf("FoldDiacritics(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestFoldDiacriticsMatching(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>I LIKE CAFE</pattern>
+        <template>Coffee it is.</template>
+    </category>
+    <category>
+        <pattern>ORDER * AT THE CAFE</pattern>
+        <template>One <star/> coming up.</template>
+    </category>
+    <category>
+        <pattern>CRÈME BRÛLÉE</pattern>
+        <template>Dessert time.</template>
+    </category>
+    <category>
+        <pattern>*</pattern>
+        <template>Pardon?</template>
+    </category>
+</aiml>`
+
+	t.Run("disabled by default", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		if err := g.LoadAIMLFromString(aimlContent); err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		session := g.CreateSession("fold_disabled")
+
+		response, err := g.ProcessInput("I like café", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != "Pardon?" {
+			t.Errorf("Expected accented input not to match without folding, got '%s'", response)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		if err := g.LoadAIMLFromString(aimlContent); err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		g.SetFoldDiacritics(true)
+		session := g.CreateSession("fold_enabled")
+
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"I like café", "Coffee it is."},
+			{"I LIKE CAFÉ", "Coffee it is."},
+			{"I like cafe", "Coffee it is."},
+			{"creme brulee", "Dessert time."},
+			{"Crème Brûlée", "Dessert time."},
+		}
+		for _, test := range tests {
+			response, err := g.ProcessInput(test.input, session)
+			if err != nil {
+				t.Fatalf("ProcessInput(%q) failed: %v", test.input, err)
+			}
+			if response != test.expected {
+				t.Errorf("ProcessInput(%q) = '%s', expected '%s'", test.input, response, test.expected)
+			}
+		}
+
+		response, err := g.ProcessInput("order a latte at the café", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if !strings.Contains(strings.ToLower(response), "one a latte coming up") {
+			t.Errorf("Expected wildcard match with folded input, got '%s'", response)
+		}
+	})
+}
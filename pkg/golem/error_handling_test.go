@@ -2,6 +2,7 @@ package golem
 
 import (
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -173,7 +174,7 @@ func TestInvalidPatternMatching(t *testing.T) {
 			name:     "Pattern with very long words",
 			pattern:  strings.Repeat("a", 10000),
 			input:    strings.Repeat("a", 10000),
-			expected: "",
+			expected: defaultErrorResponse, // Rejected by the input length limit
 		},
 	}
 
@@ -350,7 +351,7 @@ func TestInputValidationErrors(t *testing.T) {
 		{
 			name:     "Very long input",
 			input:    strings.Repeat("word ", 10000),
-			expected: "",
+			expected: defaultErrorResponse, // Rejected by the input length limit
 		},
 		{
 			name:     "Input with special characters",
@@ -515,3 +516,92 @@ func TestErrorRecoveryAndFallback(t *testing.T) {
 		})
 	}
 }
+
+// TestTagFailureIsolation tests that a malformed tag doesn't take down the whole response
+func TestTagFailureIsolation(t *testing.T) {
+	aiml := `<aiml version="2.0">
+	<category>
+		<pattern>MOOD</pattern>
+		<template><think><set name="mood">happy</set></think>I am <get name="mood"/> <uppercase>today</template>
+	</category>
+	<category>
+		<pattern>NESTED</pattern>
+		<template><uppercase>before <get name="weather" default="calm"/ after</uppercase></template>
+	</category>
+	<category>
+		<pattern>WITH DEFAULT</pattern>
+		<template>Hi <bot name="name" default="friend"/ there</template>
+	</category>
+	<category>
+		<pattern>CLEAN</pattern>
+		<template>Hello <uppercase>there</uppercase></template>
+	</category>
+</aiml>`
+
+	newGolem := func(t *testing.T, strict bool) *Golem {
+		g := NewForTesting(t, false)
+		g.GetTemplateProcessingConfig().StrictTagErrors = strict
+		if err := g.LoadAIMLFromString(aiml); err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		return g
+	}
+
+	t.Run("isolated", func(t *testing.T) {
+		g := newGolem(t, false)
+		session := g.CreateSession("isolated")
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			// An unclosed tag is output as written
+			{"mood", "I am happy <uppercase>today"},
+			// A tag missing its '>' resolves to its default
+			{"nested", "BEFORE CALM AFTER"},
+			{"with default", "Hi friend there"},
+		}
+		for _, tt := range tests {
+			response, err := g.ProcessInput(tt.input, session)
+			if err != nil {
+				t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+			}
+			if response != tt.expected {
+				t.Errorf("%s: expected '%s', got '%s'", tt.input, tt.expected, response)
+			}
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		g := newGolem(t, true)
+		response, err := g.ProcessInput("mood", g.CreateSession("strict"))
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != "[Error processing template]" {
+			t.Errorf("Expected the whole template to fail, got '%s'", response)
+		}
+	})
+
+	// A failing tag only fails the template it is in, not one processed concurrently
+	t.Run("strict concurrent", func(t *testing.T) {
+		g := newGolem(t, true)
+		var wg sync.WaitGroup
+		for _, tt := range []struct{ input, expected string }{
+			{"mood", "[Error processing template]"},
+			{"clean", "Hello THERE"},
+		} {
+			wg.Add(1)
+			go func(input, expected string) {
+				defer wg.Done()
+				session := g.CreateSession("strict_" + input)
+				for i := 0; i < 50; i++ {
+					if response, _ := g.ProcessInput(input, session); response != expected {
+						t.Errorf("%s: expected '%s', got '%s'", input, expected, response)
+						return
+					}
+				}
+			}(tt.input, tt.expected)
+		}
+		wg.Wait()
+	})
+}
@@ -3,6 +3,7 @@ package golem
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestEvalTagProcessing(t *testing.T) {
@@ -478,3 +479,98 @@ func TestEvalTagWithConditionals(t *testing.T) {
 		})
 	}
 }
+
+func TestEvalTagStoredTemplate(t *testing.T) {
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			frozen := time.Date(2024, time.March, 14, 9, 30, 0, 0, time.UTC)
+			g.timeNow = func() time.Time { return frozen }
+
+			aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>RUN</pattern>
+        <template>Result: <eval><get name="stored"/></eval></template>
+    </category>
+    <category>
+        <pattern>SHOW</pattern>
+        <template>Stored: <get name="stored"/></template>
+    </category>
+</aiml>`
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+
+			session := g.CreateSession("eval_stored_" + name)
+			session.Variables["stored"] = `<date format="%Y-%m-%d"/>`
+
+			response, err := g.ProcessInput("run", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != "Result: 2024-03-14" {
+				t.Errorf("Expected the stored template to be evaluated, got %q", response)
+			}
+
+			// A variable that evaluates itself stops at the recursion limit
+			session.Variables["stored"] = `<eval><get name="stored"/></eval>`
+			response, err = g.ProcessInput("run", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if strings.Contains(response, "<") {
+				t.Errorf("Expected self-evaluation to stop without leaking markup, got %q", response)
+			}
+		})
+	}
+}
+
+func TestEvalTagLeavesUserMarkupInert(t *testing.T) {
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+
+			aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>ECHO *</pattern>
+        <template>You said <eval><star/></eval></template>
+    </category>
+    <category>
+        <pattern>REPEAT *</pattern>
+        <template>Again <eval><input/></eval></template>
+    </category>
+</aiml>`
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+
+			session := g.CreateSession("eval_inert_" + name)
+			response, err := g.ProcessInput(`echo <set name="name">pwned</set>hello`, session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if _, exists := session.Variables["name"]; exists {
+				t.Errorf("Expected markup in a wildcard capture not to run, but name was set to %q", session.Variables["name"])
+			}
+			if !strings.Contains(response, "pwned") {
+				t.Errorf("Expected the captured markup to be returned as text, got %q", response)
+			}
+
+			_, err = g.ProcessInput(`repeat <think><set name="name">pwned</set></think>`, session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if _, exists := session.Variables["name"]; exists {
+				t.Errorf("Expected markup in the input not to run, but name was set to %q", session.Variables["name"])
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package golem
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FAQEntry is a question and answer pair in a JSON FAQ file
+type FAQEntry struct {
+	Question string `json:"q"`
+	Answer   string `json:"a"`
+}
+
+// LoadFAQ reads a JSON array of {"q": ..., "a": ...} pairs from a file
+func LoadFAQ(filename string) ([]FAQEntry, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FAQ %s: %v", filename, err)
+	}
+
+	var entries []FAQEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse FAQ %s: %v", filename, err)
+	}
+	return entries, nil
+}
+
+// faqPatternRemover removes the characters a question can't use in a pattern: wildcards,
+// which would match other input, and XML special characters, as pattern entities
+// aren't decoded when matching
+var faqPatternRemover = strings.NewReplacer("*", " ", "_", " ", "^", " ", "#", " ", "$", " ", "&", " ", "<", " ", ">", " ")
+
+// FAQCategories returns one category per FAQ entry, with the normalized question as the
+// pattern and the answer, escaped as plain text, as the template. Questions that are
+// empty once normalized, or that repeat an earlier question, are errors.
+func FAQCategories(entries []FAQEntry) ([]Category, error) {
+	categories := make([]Category, 0, len(entries))
+	seen := make(map[string]int)
+	for i, entry := range entries {
+		pattern := strings.Join(strings.Fields(faqPatternRemover.Replace(NormalizePattern(entry.Question))), " ")
+		if pattern == "" {
+			return nil, fmt.Errorf("FAQ entry %d has no question", i+1)
+		}
+		if previous, exists := seen[pattern]; exists {
+			return nil, fmt.Errorf("FAQ entry %d repeats the question of entry %d: %s", i+1, previous, pattern)
+		}
+		seen[pattern] = i + 1
+
+		categories = append(categories, Category{
+			Pattern:  pattern,
+			Template: escapeAIMLText(strings.TrimSpace(entry.Answer)),
+		})
+	}
+	return categories, nil
+}
+
+// generateFAQCommand handles "generate faq <file.json> [--output <file>]", writing an
+// AIML file with a category per question to stdout or the output file
+func (g *Golem) generateFAQCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("generate faq requires a FAQ file")
+	}
+
+	faqFile := args[0]
+	outputFile := ""
+	for i := 1; i+1 < len(args); i += 2 {
+		switch args[i] {
+		case "--output":
+			outputFile = args[i+1]
+		default:
+			return fmt.Errorf("unknown generate faq option: %s", args[i])
+		}
+	}
+
+	entries, err := LoadFAQ(faqFile)
+	if err != nil {
+		return err
+	}
+
+	categories, err := FAQCategories(entries)
+	if err != nil {
+		return err
+	}
+	output := ToAIML(categories)
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to write AIML to %s: %v", outputFile, err)
+		}
+		fmt.Printf("Wrote %d categories to %s\n", len(categories), outputFile)
+		return nil
+	}
+
+	fmt.Print(output)
+	return nil
+}
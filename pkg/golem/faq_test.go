@@ -0,0 +1,90 @@
+package golem
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateFAQ(t *testing.T) {
+	dir := t.TempDir()
+	faq := `[
+		{"q": "What are your opening hours?", "a": "9am to 5pm, Monday to Friday."},
+		{"q": "Do you sell fish and chips?", "a": "Yes - fish & chips <with> salt."},
+		{"q": "Where's the shop?", "a": "On the high street."}
+	]`
+	faqFile := filepath.Join(dir, "faq.json")
+	if err := os.WriteFile(faqFile, []byte(faq), 0644); err != nil {
+		t.Fatalf("Failed to write FAQ: %v", err)
+	}
+
+	g := NewForTesting(t, false)
+	aimlFile := filepath.Join(dir, "faq.aiml")
+	if err := g.Execute("generate", []string{"faq", faqFile, "--output", aimlFile}); err != nil {
+		t.Fatalf("generate faq failed: %v", err)
+	}
+
+	content, err := os.ReadFile(aimlFile)
+	if err != nil {
+		t.Fatalf("Failed to read generated AIML: %v", err)
+	}
+	if err := g.LoadAIMLFromString(string(content)); err != nil {
+		t.Fatalf("Failed to load generated AIML: %v\n%s", err, content)
+	}
+	if len(g.aimlKB.Categories) != 3 {
+		t.Fatalf("Expected 3 categories, got %d", len(g.aimlKB.Categories))
+	}
+
+	session := g.CreateSession("faq")
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"what are your opening hours", "9am to 5pm, Monday to Friday."},
+		{"Do you sell fish and chips?", "Yes - fish &amp; chips &lt;with&gt; salt."}, // Entities are kept in output
+		{"where is the shop", "On the high street."},
+	}
+	for _, tc := range tests {
+		response, err := g.ProcessInput(tc.input, session)
+		if err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", tc.input, err)
+		}
+		if response != tc.expected {
+			t.Errorf("ProcessInput(%q) = %q, expected %q", tc.input, response, tc.expected)
+		}
+	}
+
+	if _, err := FAQCategories([]FAQEntry{{Question: "Hi", Answer: "Hello"}, {Question: "hi!", Answer: "Hey"}}); err == nil {
+		t.Error("Expected an error for a repeated question")
+	}
+	if _, err := FAQCategories([]FAQEntry{{Question: "* & ?", Answer: "Hello"}}); err == nil {
+		t.Error("Expected an error for an empty question")
+	}
+}
+
+func TestToAIMLRoundTrip(t *testing.T) {
+	categories := []Category{
+		{Pattern: "HELLO", Template: "Hi, <get name=\"name\"/>!", PriorityBoost: 5, Tags: []string{"greeting", "smalltalk"}},
+		{Pattern: "YES", That: "DO YOU LIKE COFFEE", ThatIndex: 2, Template: "Great."},
+		{Pattern: "*", Topic: "COFFEE", Template: "Let's talk about coffee.", Default: true},
+	}
+
+	g := NewForTesting(t, false)
+	if err := g.LoadAIMLFromString(ToAIML(categories)); err != nil {
+		t.Fatalf("Failed to load serialized AIML: %v", err)
+	}
+
+	loaded := g.aimlKB.Categories
+	if len(loaded) != len(categories) {
+		t.Fatalf("Expected %d categories, got %d", len(categories), len(loaded))
+	}
+	for i, expected := range categories {
+		actual := loaded[i]
+		actual.Source = ""
+		actual.folded = nil
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Category %d: expected %+v, got %+v", i, expected, actual)
+		}
+	}
+}
@@ -413,6 +413,52 @@ func TestFirstRestEdgeCases(t *testing.T) {
 	}
 }
 
+// TestFirstRestWithWildcards tests splitting <star/> captures into the first word and the rest
+func TestFirstRestWithWildcards(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>CALL ME *</pattern>
+		<template>Given: <first><star/></first>. Family: <rest><star/></rest>.</template>
+	</category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	session := g.CreateSession("first_rest_wildcards")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Multi-word wildcard",
+			input:    "call me John Ronald Tolkien",
+			expected: "Given: John. Family: Ronald Tolkien.",
+		},
+		{
+			name:     "Single-word wildcard",
+			input:    "call me Cher",
+			expected: "Given: Cher. Family: .",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := g.ProcessInput(tt.input, session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
 // TestFirstRestPerformance tests performance of <first> and <rest> tags
 func TestFirstRestPerformance(t *testing.T) {
 	g := NewForTesting(t, false)
@@ -423,9 +423,9 @@ func TestSubstringTagProcessing(t *testing.T) {
 			expected: "",
 		},
 		{
-			name:     "Invalid start (negative)",
+			name:     "Negative start counts from end",
 			template: `<substring start="-1" end="5">hello</substring>`,
-			expected: "hello",
+			expected: "o",
 		},
 		{
 			name:     "Invalid end (too large)",
@@ -1158,3 +1158,95 @@ func TestFormattingTagsEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestCaseTagsPreserveURLs tests that case tags leave URLs and emails alone when PreserveURLs is set
+func TestCaseTagsPreserveURLs(t *testing.T) {
+	testCases := []struct {
+		name      string
+		template  string
+		preserved string
+		unchanged string
+	}{
+		{
+			name:      "Uppercase with domain",
+			template:  "<uppercase>visit Example.com today</uppercase>",
+			preserved: "VISIT Example.com TODAY",
+			unchanged: "VISIT EXAMPLE.COM TODAY",
+		},
+		{
+			name:      "Lowercase with URL",
+			template:  "<lowercase>See https://Example.com/Docs/Intro.html, Please</lowercase>",
+			preserved: "see https://Example.com/Docs/Intro.html, please",
+			unchanged: "see https://example.com/docs/intro.html, please",
+		},
+		{
+			name:      "Lowercase with email",
+			template:  "<lowercase>Mail John.Smith@Example.ORG Now</lowercase>",
+			preserved: "mail John.Smith@Example.ORG now",
+			unchanged: "mail john.smith@example.org now",
+		},
+		{
+			name:      "Formal with email and domain",
+			template:  "<formal>write to support@example.com or visit www.Example.com</formal>",
+			preserved: "Write To support@example.com Or Visit www.Example.com",
+			unchanged: "Write To Support@example.com Or Visit Www.example.com",
+		},
+		{
+			name:      "Text without URLs is unchanged",
+			template:  "<uppercase>hello world. goodbye</uppercase>",
+			preserved: "HELLO WORLD. GOODBYE",
+			unchanged: "HELLO WORLD. GOODBYE",
+		},
+	}
+
+	preserving := NewForTesting(t, false)
+	config := preserving.GetTemplateProcessingConfig()
+	config.PreserveURLs = true
+	preserving.UpdateTemplateProcessingConfig(config)
+	defaultGolem := NewForTesting(t, false)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := preserving.ProcessTemplateWithContext(tc.template, make(map[string]string), preserving.createSession("preserve"))
+			if result != tc.preserved {
+				t.Errorf("With PreserveURLs: expected '%s', got '%s'", tc.preserved, result)
+			}
+			result = defaultGolem.ProcessTemplateWithContext(tc.template, make(map[string]string), defaultGolem.createSession("default"))
+			if result != tc.unchanged {
+				t.Errorf("Without PreserveURLs: expected '%s', got '%s'", tc.unchanged, result)
+			}
+		})
+	}
+}
+
+// TestCaseTagsPreserveEntities tests that <uppercase> and <lowercase> leave entity
+// references intact
+func TestCaseTagsPreserveEntities(t *testing.T) {
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			session := g.createSession("entities_" + name)
+			session.Variables["show"] = "Tom &amp; Jerry &Eacute;cole"
+
+			testCases := []struct {
+				template string
+				expected string
+			}{
+				{"<uppercase>fish &amp; chips</uppercase>", "FISH &amp; CHIPS"},
+				{"<uppercase>caf&#233; and caf&#xE9; &eacute;t&eacute;</uppercase>", "CAF&#233; AND CAF&#xE9; &eacute;T&eacute;"},
+				{`<uppercase><get name="show"/></uppercase>`, "TOM &amp; JERRY &Eacute;COLE"},
+				{`<lowercase><get name="show"/></lowercase>`, "tom &amp; jerry &Eacute;cole"},
+				{"<uppercase>a & b</uppercase>", "A & B"},
+			}
+			for _, tc := range testCases {
+				result := g.ProcessTemplateWithContext(tc.template, map[string]string{}, session)
+				if result != tc.expected {
+					t.Errorf("%s: expected %q, got %q", tc.template, tc.expected, result)
+				}
+			}
+		})
+	}
+}
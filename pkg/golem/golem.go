@@ -1,6 +1,9 @@
 package golem
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,8 +15,18 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
+// DefaultMaxInputLength is the default limit, in characters, on user input passed to
+// ProcessInput. Every input is tried against every pattern, so the limit bounds the
+// matching cost of a single request.
+const DefaultMaxInputLength = 4096
+
+// DefaultMaxThatDepth is the default number of bot responses a session keeps in its
+// that history (see Golem.MaxThatDepth)
+const DefaultMaxThatDepth = 20
+
 // LogLevel represents the logging level
 type LogLevel int
 
@@ -193,6 +206,42 @@ type TemplateProcessingConfig struct {
 	EnableDebugging   bool  `json:"enable_debugging"`
 	MemoryLimit       int   `json:"memory_limit_bytes"`
 	ProcessingTimeout int64 `json:"processing_timeout_ms"`
+	// FallbackOnEmptySRAI makes an <srai> whose matched template produces an empty
+	// response fall through to the default pattern instead of returning nothing
+	FallbackOnEmptySRAI bool `json:"fallback_on_empty_srai"`
+	// StableRandomPerInput makes <random> pick the same <li> when the same input is
+	// repeated within a session, while different inputs may select differently
+	StableRandomPerInput bool `json:"stable_random_per_input"`
+	// ExhaustRandomOptions makes each <random> block deal its <li> items without
+	// replacement per session, so every item is used once before any repeats
+	ExhaustRandomOptions bool `json:"exhaust_random_options"`
+	// AvoidRecentRandomOptions makes <random> less likely to pick an <li> whose text
+	// appears in the session's recent responses, the more so the more recent they are
+	AvoidRecentRandomOptions bool `json:"avoid_recent_random_options"`
+	// PreserveURLs keeps URLs, domain names and email addresses unchanged inside
+	// <uppercase>, <lowercase> and <formal>
+	PreserveURLs bool `json:"preserve_urls"`
+	// ConcurrentSRAI resolves the top-level <srai> tags of a template in parallel, at
+	// most MaxParallelOps at a time, when neither the template nor the SRAI targets
+	// have side effects (see processBatchedSRAI)
+	ConcurrentSRAI bool `json:"concurrent_srai"`
+	// StrictTagErrors makes a failing tag fail the whole template, which then
+	// renders as an error message, instead of only resolving that tag to its default
+	StrictTagErrors bool `json:"strict_tag_errors"`
+	// PreserveSRAIWhitespace keeps the spaces around <srai> and <sr> tags as they are,
+	// instead of collapsing the double spaces left when an SRAI resolves to nothing
+	// or to text with its own leading or trailing spaces
+	PreserveSRAIWhitespace bool `json:"preserve_srai_whitespace"`
+	// CanonicalizeBooleans stores boolean-like values assigned with <set> as "true" or
+	// "false", so conditions don't depend on how an author spelled them. TruthyWords
+	// and FalsyWords (compared case-insensitively) replace the default word lists.
+	CanonicalizeBooleans bool     `json:"canonicalize_booleans"`
+	TruthyWords          []string `json:"truthy_words,omitempty"`
+	FalsyWords           []string `json:"falsy_words,omitempty"`
+	// DisablePropertyFallback stops <get name="..."/> from falling back to the bot
+	// property of the same name when no variable is set, so <get> only reads variables
+	// and bot properties are only read with <bot name="..."/>
+	DisablePropertyFallback bool `json:"disable_property_fallback"`
 }
 
 // ChatSession represents a single chat session
@@ -202,10 +251,11 @@ type ChatSession struct {
 	History         []string
 	CreatedAt       string
 	LastActivity    string
-	Topic           string   // Current conversation topic
-	ThatHistory     []string // History of bot responses for that matching
-	RequestHistory  []string // History of user requests for <request> tag
-	ResponseHistory []string // History of bot responses for <response> tag
+	LastAccessed    time.Time // Time of the last turn, used for idle eviction
+	Topic           string    // Current conversation topic
+	ThatHistory     []string  // History of bot responses for that matching
+	RequestHistory  []string  // History of user requests for <request> tag
+	ResponseHistory []string  // History of bot responses for <response> tag
 
 	// Enhanced context management
 	ContextConfig   *ContextConfig         // Context configuration
@@ -217,6 +267,17 @@ type ChatSession struct {
 	// Session-specific learning
 	LearnedCategories []Category            // Categories learned in this session
 	LearningStats     *SessionLearningStats // Learning statistics for this session
+	LearnedOrder      []string              // Pattern keys of categories this session added, oldest first
+
+	// Stable <random> selection (see TemplateProcessingConfig.StableRandomPerInput)
+	CurrentInput     string         // User input currently being processed
+	RandomSelections map[string]int // "blockHash|input" -> selected <li> index
+
+	// Exhaustive <random> selection (see TemplateProcessingConfig.ExhaustRandomOptions)
+	RandomDecks map[string][]int // blockHash -> <li> indices not yet dealt
+
+	// Invoked by SetSessionTopic when the topic changes (see Golem.OnTopicChange)
+	onTopicChange func(session *ChatSession, old, new string)
 }
 
 // SessionLearningStats represents learning statistics for a session
@@ -259,6 +320,8 @@ type Golem struct {
 	sraixMgr  *SRAIXManager
 	// Mutex for thread-safe session management
 	sessionMutex sync.RWMutex
+	// Held for reading while input is processed so ReloadKnowledgeBase can swap aimlKB atomically
+	kbMutex sync.RWMutex
 	// Text processing components
 	sentenceSplitter     *SentenceSplitter
 	wordBoundaryDetector *WordBoundaryDetector
@@ -270,6 +333,8 @@ type Golem struct {
 	patternRegexCache  *RegexCache
 	tagProcessingCache *RegexCache
 	normalizationCache *RegexCache
+	// Compiled set fragments shared by every pattern that uses the set
+	setFragments *setFragmentCache
 	// Consolidated template processor
 	consolidatedProcessor *ConsolidatedTemplateProcessor
 	// Text normalization result cache
@@ -287,11 +352,83 @@ type Golem struct {
 	// Enhanced context resolution components
 	fuzzyMatcher    *FuzzyContextMatcher
 	semanticMatcher *SemanticContextMatcher
+	// Match accented input and patterns against their base forms (see SetFoldDiacritics)
+	foldDiacritics bool
+	// Custom punctuation rules for input and <normalize>; nil uses the defaults (see SetNormalizationRules)
+	normalizationRules *NormalizationRules
+	// Longest input accepted for matching, in characters; 0 disables the limit (see SetMaxInputLength)
+	maxInputLength int
+	// Responses matching this are not added to that history; nil disables it (see SetThatIgnorePattern)
+	thatIgnorePattern *regexp.Regexp
 	// Random seed for deterministic shuffling
-	randomSeed int64
+	randomSeed   int64
+	seededRandom bool // Set by SetRandomSeed; <random> then uses the deterministic generator
 	// Tree-based processing components
 	treeProcessor     *TreeProcessor
 	useTreeProcessing bool // Feature flag for tree-based processing
+	// Alternate template engine; when nil the tree processor is used
+	templateEngine TemplateEngine
+	// Runtime introspection
+	startTime time.Time        // When this Golem instance was created (for <uptime/>)
+	timeNow   func() time.Time // Clock source, overridable in tests
+	// Response post-processing filters, applied in registration order
+	responseFilters []func(string) string
+	// Invoked with inputs that match no category (see SetUnmatchedHandler)
+	unmatchedHandler func(input string, session *ChatSession)
+	// Invoked when a session's topic changes (see OnTopicChange)
+	topicChangeHandler func(session *ChatSession, old, new string)
+	// Receives the content of processed <think> blocks (see SetThinkSink)
+	thinkSink func(content string)
+	// Persists sessions between turns (see SetSessionStore)
+	sessionStore SessionStore
+	// Splits user input into words for matching; nil splits on whitespace (see SetTokenizer)
+	tokenizer Tokenizer
+	// Idle session eviction (see SetSessionIdleTimeout and StartSessionSweeper)
+	sessionIdleTimeout     time.Duration
+	sessionEvictionHandler func(session *ChatSession)
+	sweeperStop            chan struct{}
+	sweeperDone            chan struct{}
+
+	// OnLoadProgress, when set, is invoked by LoadAIMLFromDirectory before each
+	// AIML file is loaded. fileIndex is zero-based; totalFiles is the number of
+	// AIML files found in the directory.
+	OnLoadProgress func(fileIndex, totalFiles int, path string)
+
+	// OnCategoryMatched, when set, is invoked with each category that wins the match
+	// for a chat input, before its template is processed, so apps can count how often
+	// each category fires. Categories matched by <srai> and <sr> are only reported
+	// when ReportSRAIMatches is set.
+	//
+	// Like every hook run during a turn (SetUnmatchedHandler, OnTopicChange,
+	// SetThinkSink, AddResponseFilter), it is called with the knowledge base locked
+	// for reading, so it must not call ProcessInput, ReloadKnowledgeBase,
+	// ListLearnedCategories or RemoveLearnedCategory, which can deadlock on it. Hand such
+	// work off to run after the turn instead.
+	OnCategoryMatched func(category *Category, input string)
+
+	// ReportSRAIMatches makes OnCategoryMatched also report the categories matched by
+	// <srai> and <sr> while a template is processed, with the redirected input. The
+	// legacy engine doesn't re-match the SRAIs of a template served from its cache.
+	ReportSRAIMatches bool
+
+	// StrictParsing turns load-time diagnostics that are otherwise logged as warnings
+	// (such as two categories with the same pattern, that and topic) into errors.
+	StrictParsing bool
+
+	// StrictVersion makes AIML 2.0 features (such as ^ and # wildcards or <oob>) in a
+	// version="1.0" document a load error instead of a warning.
+	StrictVersion bool
+
+	// MaxThatDepth, when positive, is the number of bot responses new sessions keep in
+	// their that history, instead of DefaultMaxThatDepth. Categories whose <that index>
+	// reaches further back can never match and are reported when loaded.
+	MaxThatDepth int
+
+	// MaxLearnedPerSession, when positive, caps how many categories one session can add
+	// with <learn>. At the cap the session's oldest learned category is evicted to make
+	// room, or, with RejectLearnedOverLimit, the new category is rejected.
+	MaxLearnedPerSession   int
+	RejectLearnedOverLimit bool
 }
 
 // NewRegexCache creates a new regex cache
@@ -479,7 +616,11 @@ func (cache *TextNormalizationCache) GetNormalizedText(golem *Golem, input strin
 
 	switch normalizationType {
 	case "NormalizePattern":
-		result = NormalizePattern(input)
+		if golem != nil {
+			result = golem.normalizeInputForMatching(input)
+		} else {
+			result = NormalizePattern(input)
+		}
 		// Apply loaded substitutions for pattern normalization
 		if golem != nil && golem.aimlKB != nil && len(golem.aimlKB.Substitutions) > 0 {
 			result = golem.applyLoadedSubstitutions(result)
@@ -487,6 +628,9 @@ func (cache *TextNormalizationCache) GetNormalizedText(golem *Golem, input strin
 	case "NormalizeForMatchingCasePreserving":
 		result = NormalizeForMatchingCasePreserving(input)
 	case "NormalizeThatPattern":
+		if golem != nil {
+			input = golem.tokenizeText(input)
+		}
 		result = NormalizeThatPattern(input)
 	case "normalizeForMatching":
 		if golem != nil {
@@ -562,8 +706,13 @@ func (cache *TextNormalizationCache) evictLRU() {
 
 // GetCacheStats returns cache statistics
 func (cache *TextNormalizationCache) GetCacheStats() map[string]interface{} {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	hitsByKey := make(map[string]int, len(cache.Hits))
 	totalRequests := cache.Misses
-	for _, hits := range cache.Hits {
+	for key, hits := range cache.Hits {
+		hitsByKey[key] = hits
 		totalRequests += hits
 	}
 
@@ -576,7 +725,7 @@ func (cache *TextNormalizationCache) GetCacheStats() map[string]interface{} {
 		"results":        len(cache.Results),
 		"max_size":       cache.MaxSize,
 		"ttl_seconds":    cache.TTL,
-		"hits":           cache.Hits,
+		"hits":           hitsByKey,
 		"misses":         cache.Misses,
 		"hit_rate":       hitRate,
 		"total_requests": totalRequests,
@@ -585,6 +734,8 @@ func (cache *TextNormalizationCache) GetCacheStats() map[string]interface{} {
 
 // ClearCache clears the text normalization cache
 func (cache *TextNormalizationCache) ClearCache() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
 	cache.Results = make(map[string]string)
 	cache.Hits = make(map[string]int)
 	cache.Misses = 0
@@ -1166,10 +1317,181 @@ func New(verbose bool) *Golem {
 		thatPatternCache:           thatPatternCache,
 		templateTagProcessingCache: templateTagProcessingCache,
 		patternMatchingCache:       patternMatchingCache,
+		setFragments:               newSetFragmentCache(),
 		persistentLearning:         persistentLearning,
 		treeProcessor:              treeProcessor,
 		useTreeProcessing:          true, // Tree-based AST processing is now the default (correct AIML behavior)
+		startTime:                  time.Now(),
+		timeNow:                    time.Now,
+		maxInputLength:             DefaultMaxInputLength,
+		sessionStore:               NewMemorySessionStore(),
+	}
+}
+
+// now returns the current time from the Golem's clock source
+func (g *Golem) now() time.Time {
+	if g.timeNow != nil {
+		return g.timeNow()
+	}
+	return time.Now()
+}
+
+// Uptime returns how long this Golem instance has been running
+func (g *Golem) Uptime() time.Duration {
+	return g.now().Sub(g.startTime)
+}
+
+// SessionCount returns the number of active chat sessions
+func (g *Golem) SessionCount() int {
+	g.sessionMutex.RLock()
+	defer g.sessionMutex.RUnlock()
+	return len(g.sessions)
+}
+
+// AddResponseFilter registers a transform applied to every chat response after template
+// processing and before it is returned to the caller. Filters run in registration order,
+// each receiving the output of the previous one. Filters run during the turn, with the
+// restrictions described at OnCategoryMatched.
+func (g *Golem) AddResponseFilter(filter func(string) string) {
+	if filter == nil {
+		return
+	}
+	g.responseFilters = append(g.responseFilters, filter)
+}
+
+// SetUnmatchedHandler registers a callback invoked whenever an input matches no
+// category, before the no-match error is returned. Apps can use it to log gaps in
+// the knowledge base and collect training data. Passing nil removes the handler. The
+// handler runs during the turn, so it is subject to the restrictions described at
+// OnCategoryMatched.
+func (g *Golem) SetUnmatchedHandler(handler func(input string, session *ChatSession)) {
+	g.unmatchedHandler = handler
+}
+
+// notifyUnmatched passes an unmatched input to the registered handler, if any
+func (g *Golem) notifyUnmatched(input string, session *ChatSession) {
+	if g.unmatchedHandler != nil {
+		g.unmatchedHandler(input, session)
+	}
+}
+
+// notifyCategoryMatched passes the category matched for a chat input to
+// OnCategoryMatched, if set
+func (g *Golem) notifyCategoryMatched(category *Category, input string) {
+	if g.OnCategoryMatched != nil {
+		g.OnCategoryMatched(category, input)
+	}
+}
+
+// notifySRAIMatched passes the category matched by an <srai> or <sr> to
+// OnCategoryMatched, if set and ReportSRAIMatches is enabled
+func (g *Golem) notifySRAIMatched(category *Category, input string) {
+	if g.ReportSRAIMatches {
+		g.notifyCategoryMatched(category, input)
+	}
+}
+
+// OnTopicChange registers a callback invoked whenever a session's topic changes,
+// whether through ChatSession.SetSessionTopic or a <set name="topic"> / <topic>
+// template tag. Setting the topic to its current value (ignoring case, as topic
+// matching does) does not fire the callback. Passing nil removes the handler. A
+// change made by a template is reported mid-turn, so see OnCategoryMatched for what
+// the handler must not call.
+func (g *Golem) OnTopicChange(handler func(session *ChatSession, old, new string)) {
+	g.topicChangeHandler = handler
+}
+
+// notifyTopicChange passes a topic change to the registered handler, if any
+func (g *Golem) notifyTopicChange(session *ChatSession, old, new string) {
+	if g.topicChangeHandler != nil {
+		g.topicChangeHandler(session, old, new)
+	}
+}
+
+// SetThinkSink registers a debug sink that receives the processed content of every
+// <think> block, so authors can see what its side-effecting operations did. The
+// content is still removed from the response. The tree processor reports each
+// assignment as "name = value" followed by any text the block produced; the
+// consolidated pipeline passes the block's tags with wildcards already resolved.
+// Passing nil removes the sink. The sink is called while the template is processed,
+// with the restrictions described at OnCategoryMatched.
+func (g *Golem) SetThinkSink(sink func(content string)) {
+	g.thinkSink = sink
+}
+
+// notifyThink passes think content to the registered sink, if any
+func (g *Golem) notifyThink(content string) {
+	if g.thinkSink != nil && strings.TrimSpace(content) != "" {
+		g.thinkSink(content)
+	}
+}
+
+// SetSessionStore replaces the backend sessions are persisted to. Passing nil restores
+// the default in-memory store. Sessions already created are not copied to the new store.
+func (g *Golem) SetSessionStore(store SessionStore) {
+	if store == nil {
+		store = NewMemorySessionStore()
+	}
+	g.sessionStore = store
+}
+
+// saveSession persists a session through the session store. Failures are logged, not
+// returned, so a store outage does not fail the turn that produced the response.
+func (g *Golem) saveSession(session *ChatSession) {
+	if g.sessionStore == nil {
+		return
+	}
+	if err := g.sessionStore.Save(session); err != nil {
+		g.LogWarn("Failed to save session %s: %v", session.ID, err)
+	}
+}
+
+// LoadSession loads a session through the session store and makes it an active
+// session of this Golem, replacing any in-memory copy. It returns an error wrapping
+// ErrSessionNotFound for an unknown ID.
+func (g *Golem) LoadSession(sessionID string) (*ChatSession, error) {
+	if g.sessionStore == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	session, err := g.sessionStore.Load(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore what the store doesn't keep
+	if session.Variables == nil {
+		session.Variables = make(map[string]string)
+	}
+	session.onTopicChange = g.notifyTopicChange
+	g.initializeSessionContext(session)
+
+	g.sessionMutex.Lock()
+	session.LastAccessed = g.now()
+	g.sessions[sessionID] = session
+	g.sessionMutex.Unlock()
+	return session, nil
+}
+
+// ProcessInputForSession processes a chat turn for the session with the given ID,
+// loading it through the session store first (or creating it if the store does not
+// have it) and saving it afterwards. Deployments that share a store between Golem
+// instances should use this rather than holding on to *ChatSession values.
+func (g *Golem) ProcessInputForSession(sessionID, input string) (string, error) {
+	session, err := g.LoadSession(sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		session = g.createSession(sessionID)
+	} else if err != nil {
+		return "", err
 	}
+	return g.ProcessInput(input, session)
+}
+
+// applyResponseFilters runs the registered response filters over a processed response
+func (g *Golem) applyResponseFilters(response string) string {
+	for _, filter := range g.responseFilters {
+		response = filter(response)
+	}
+	return response
 }
 
 // LogError logs an error message
@@ -1207,6 +1529,126 @@ func (g *Golem) LogTrace(format string, args ...interface{}) {
 	}
 }
 
+// SetFoldDiacritics enables or disables diacritic folding during pattern matching.
+// When enabled, both the input and the patterns are folded, so "café" matches a
+// CAFE pattern and "cafe" matches a CAFÉ pattern.
+func (g *Golem) SetFoldDiacritics(enabled bool) {
+	g.foldDiacritics = enabled
+	// Cached match results were computed with the previous setting
+	g.ClearPatternMatchingCache()
+}
+
+// SetNormalizationRules replaces the punctuation rules used to normalize user input for
+// matching and text in <normalize> tags. Passing nil restores the defaults
+// (DefaultMatchingNormalizationRules and DefaultOutputNormalizationRules). Patterns are
+// expected to be written in normalized form and are not affected.
+func (g *Golem) SetNormalizationRules(rules *NormalizationRules) {
+	if rules != nil {
+		copied := *rules
+		rules = &copied
+	}
+	g.normalizationRules = rules
+	// Cached normalizations and matches were computed with the previous rules
+	g.ClearTextNormalizationCache()
+	g.ClearPatternMatchingCache()
+}
+
+// NormalizeInput returns user input as the matcher sees it: normalized with the
+// configured rules, with loaded substitutions applied and, with SetFoldDiacritics,
+// diacritics folded. Embedders can use it to pre-normalize input for logging or keys.
+func (g *Golem) NormalizeInput(input string) string {
+	normalized := g.CachedNormalizePattern(input)
+	if g.foldDiacritics {
+		normalized = FoldDiacritics(normalized)
+	}
+	return normalized
+}
+
+// normalizeInputForMatching normalizes user input for matching with the configured rules
+func (g *Golem) normalizeInputForMatching(input string) string {
+	input = g.tokenizeText(input)
+	if g.normalizationRules != nil {
+		return normalizePatternWithRules(input, *g.normalizationRules)
+	}
+	return NormalizePattern(input)
+}
+
+// SetMaxInputLength sets the longest input, in characters, that ProcessInput will try
+// to match; longer input is answered with the error_response property (or a fixed
+// apology if it is unset) without being matched. Zero or a negative value removes the limit. The default is DefaultMaxInputLength.
+func (g *Golem) SetMaxInputLength(maxLength int) {
+	if maxLength < 0 {
+		maxLength = 0
+	}
+	g.maxInputLength = maxLength
+}
+
+// checkInputLength returns an error if input exceeds the configured maximum length
+func (g *Golem) checkInputLength(input string) error {
+	if g.maxInputLength == 0 {
+		return nil
+	}
+	if length := utf8.RuneCountInString(input); length > g.maxInputLength {
+		return fmt.Errorf("input too long: %d characters exceeds the limit of %d", length, g.maxInputLength)
+	}
+	return nil
+}
+
+// SetThatIgnorePattern sets a regular expression for responses that should not become
+// the <that> context of the next turn, e.g. internal acknowledgements. Responses are
+// matched after response filters are applied. An empty pattern disables the check.
+func (g *Golem) SetThatIgnorePattern(pattern string) error {
+	if pattern == "" {
+		g.thatIgnorePattern = nil
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid that ignore pattern: %v", err)
+	}
+	g.thatIgnorePattern = re
+	return nil
+}
+
+// addThatContext records a turn's that context for future context matching, unless
+// the turn produced no response (a template made only of <think> or <learn>) or the
+// response matches the that ignore pattern
+func (g *Golem) addThatContext(session *ChatSession, thatContext, response string) {
+	if strings.TrimSpace(response) == "" {
+		return
+	}
+	if g.thatIgnorePattern != nil && g.thatIgnorePattern.MatchString(response) {
+		g.LogDebug("Not adding response to that history: '%s'", response)
+		return
+	}
+	session.AddToThatHistory(thatContext)
+}
+
+// defaultErrorResponse answers rejected input when the error_response property is unset
+const defaultErrorResponse = "Sorry, I encountered an error processing your request."
+
+// inputTooLongResponse logs a rejected over-long input and returns the reply for it
+func (g *Golem) inputTooLongResponse(err error) string {
+	g.LogWarn("Rejecting input without matching: %v", err)
+	if response := g.aimlKB.GetProperty("error_response"); response != "" {
+		return response
+	}
+	return defaultErrorResponse
+}
+
+// SetRandomSeed seeds the deterministic generator and makes <random> selection use it,
+// so that selections are reproducible (e.g. in tests)
+func (g *Golem) SetRandomSeed(seed int64) {
+	g.randomSeed = seed
+	g.seededRandom = true
+}
+
+// SetTemplateProcessor replaces the engine used to process matched templates.
+// Passing nil restores the default tree-based processor.
+func (g *Golem) SetTemplateProcessor(engine TemplateEngine) {
+	g.templateEngine = engine
+}
+
 // EnableTreeProcessing enables tree-based tag processing
 func (g *Golem) EnableTreeProcessing() {
 	g.useTreeProcessing = true
@@ -1257,14 +1699,17 @@ func (g *Golem) LoadPersistentCategories() error {
 		return fmt.Errorf("failed to load persistent categories: %v", err)
 	}
 
+	g.aimlKB.learnMutex.Lock()
+	defer g.aimlKB.learnMutex.Unlock()
+
 	// Add categories to the knowledge base
 	for _, category := range categories {
 		normalizedPattern := NormalizePattern(category.Pattern)
 
 		// Check if category already exists
-		if existingCategory, exists := g.aimlKB.Patterns[normalizedPattern]; exists {
+		if _, exists := g.aimlKB.Patterns[normalizedPattern]; exists {
 			// Update existing category
-			*existingCategory = category
+			g.aimlKB.updateCategory(normalizedPattern, category)
 		} else {
 			// Add new category
 			g.aimlKB.Categories = append(g.aimlKB.Categories, category)
@@ -1330,6 +1775,8 @@ func (g *Golem) ClearSessionLearning(sessionID string) error {
 	}
 
 	// Remove all session-learned categories from knowledge base
+	g.aimlKB.learnMutex.Lock()
+	defer g.aimlKB.learnMutex.Unlock()
 	for _, category := range session.LearnedCategories {
 		normalizedPattern := NormalizePattern(category.Pattern)
 		delete(g.aimlKB.Patterns, normalizedPattern)
@@ -1345,6 +1792,7 @@ func (g *Golem) ClearSessionLearning(sessionID string) error {
 
 	// Clear session learning data
 	session.LearnedCategories = []Category{}
+	session.LearnedOrder = nil
 	if session.LearningStats != nil {
 		session.LearningStats.TotalLearned = 0
 		session.LearningStats.TotalUnlearned = 0
@@ -1359,6 +1807,87 @@ func (g *Golem) ClearSessionLearning(sessionID string) error {
 	return nil
 }
 
+// ListLearnedCategories returns copies of the categories added at runtime by <learn>
+// and <learnf>, in the order they were learned, so apps can review user-taught content.
+// It must not be called from hooks, which run while input is processed.
+func (g *Golem) ListLearnedCategories() []Category {
+	learned := []Category{}
+	if g.aimlKB == nil {
+		return learned
+	}
+
+	g.kbMutex.RLock()
+	defer g.kbMutex.RUnlock()
+	g.aimlKB.learnMutex.RLock()
+	defer g.aimlKB.learnMutex.RUnlock()
+
+	for _, category := range g.aimlKB.Categories {
+		if category.Learned {
+			learned = append(learned, category)
+		}
+	}
+	return learned
+}
+
+// RemoveLearnedCategory removes every learned category whose pattern matches pattern
+// (after normalization), whatever its that or topic, from the knowledge base, from
+// session learning records and from persistent storage. Categories loaded from AIML
+// files are never removed. Returns true if any category was removed. It must not be
+// called from hooks (e.g. OnCategoryMatched), which run while input is processed;
+// review learned content between turns instead.
+func (g *Golem) RemoveLearnedCategory(pattern string) bool {
+	if g.aimlKB == nil {
+		return false
+	}
+	normalizedPattern := NormalizePattern(pattern)
+
+	// The read lock keeps a reload from swapping the knowledge base mid-removal, and
+	// the learn lock keeps other sessions from matching or learning during it
+	g.kbMutex.RLock()
+	defer g.kbMutex.RUnlock()
+	g.aimlKB.learnMutex.Lock()
+	defer g.aimlKB.learnMutex.Unlock()
+
+	removed := g.aimlKB.removeCategories(func(category *Category) bool {
+		return category.Learned && NormalizePattern(category.Pattern) == normalizedPattern
+	})
+	if len(removed) == 0 {
+		return false
+	}
+
+	g.sessionMutex.Lock()
+	for _, session := range g.sessions {
+		learned := session.LearnedCategories[:0]
+		for _, category := range session.LearnedCategories {
+			if NormalizePattern(category.Pattern) != normalizedPattern {
+				learned = append(learned, category)
+			}
+		}
+		session.LearnedCategories = learned
+
+		order := session.LearnedOrder[:0]
+		for _, key := range session.LearnedOrder {
+			if strings.SplitN(key, "|", 2)[0] != normalizedPattern {
+				order = append(order, key)
+			}
+		}
+		session.LearnedOrder = order
+	}
+	g.sessionMutex.Unlock()
+
+	if g.persistentLearning != nil {
+		for _, category := range removed {
+			if err := g.persistentLearning.RemovePersistentCategory(category); err != nil {
+				g.LogDebug("Learned category not in persistent storage: %v", err)
+			}
+		}
+	}
+
+	g.InvalidatePatternMatchingKnowledgeBase()
+	g.LogInfo("Removed %d learned categories for pattern: %s", len(removed), normalizedPattern)
+	return true
+}
+
 // GetLearningSummary returns a summary of learning across all sessions
 func (g *Golem) GetLearningSummary() map[string]interface{} {
 	summary := map[string]interface{}{
@@ -1479,6 +2008,10 @@ func (g *Golem) Execute(command string, args []string) error {
 		return g.chatCommand(args)
 	case "session":
 		return g.sessionCommand(args)
+	case "save":
+		return g.saveSessionCommand(args)
+	case "load-session":
+		return g.loadSessionCommand(args)
 	case "properties":
 		return g.propertiesCommand(args)
 	case "oob":
@@ -1491,6 +2024,12 @@ func (g *Golem) Execute(command string, args []string) error {
 		return g.analyzeCommand(args)
 	case "generate":
 		return g.generateCommand(args)
+	case "validate":
+		return g.validateCommand(args)
+	case "benchmark":
+		return g.benchmarkCommand(args)
+	case "diff":
+		return g.diffCommand(args)
 	default:
 		return fmt.Errorf("unknown command: %s", command)
 	}
@@ -1499,6 +2038,11 @@ func (g *Golem) Execute(command string, args []string) error {
 // LoadCommand handles the load command
 // loadAllRelatedFiles loads all .aiml, .map, and .set files from the same directory as the given file
 func (g *Golem) loadAllRelatedFiles(filePath string) error {
+	return g.loadAllRelatedFilesTo(os.Stdout, filePath)
+}
+
+// loadAllRelatedFilesTo is loadAllRelatedFiles, writing its summary to out
+func (g *Golem) loadAllRelatedFilesTo(out io.Writer, filePath string) error {
 	dir := filepath.Dir(filePath)
 
 	g.LogInfo("Loading all related files from directory: %s", dir)
@@ -1538,6 +2082,13 @@ func (g *Golem) loadAllRelatedFiles(filePath string) error {
 		g.LogInfo("Warning: failed to load substitutions from directory: %v", err)
 	}
 
+	// Load aliases from directory
+	aliases, err := g.LoadAliasesFromDirectory(dir)
+	if err != nil {
+		// Log the error but don't fail the entire operation
+		g.LogInfo("Warning: failed to load aliases from directory: %v", err)
+	}
+
 	// Load properties from directory
 	properties, err := g.LoadPropertiesFromDirectory(dir)
 	if err != nil {
@@ -1567,6 +2118,11 @@ func (g *Golem) loadAllRelatedFiles(filePath string) error {
 		aimlKB.Substitutions[subName] = subData
 	}
 
+	// Merge aliases into knowledge base
+	for phrase, canonical := range aliases {
+		aimlKB.AddAlias(phrase, canonical)
+	}
+
 	// Merge properties into knowledge base
 	for _, propData := range properties {
 		for key, value := range propData {
@@ -1590,18 +2146,24 @@ func (g *Golem) loadAllRelatedFiles(filePath string) error {
 	g.LogInfo("Knowledge base set successfully")
 
 	// Print summary
-	fmt.Printf("Successfully loaded all related files from directory: %s\n", dir)
-	fmt.Printf("Loaded %d categories\n", len(aimlKB.Categories))
-	fmt.Printf("Loaded %d maps\n", len(maps))
-	fmt.Printf("Loaded %d sets\n", len(sets))
-	fmt.Printf("Loaded %d substitution files\n", len(substitutions))
-	fmt.Printf("Loaded %d properties files\n", len(properties))
-	fmt.Printf("Loaded %d pdefaults files\n", len(pdefaults))
+	fmt.Fprintf(out, "Successfully loaded all related files from directory: %s\n", dir)
+	fmt.Fprintf(out, "Loaded %d categories\n", len(aimlKB.Categories))
+	fmt.Fprintf(out, "Loaded %d maps\n", len(maps))
+	fmt.Fprintf(out, "Loaded %d sets\n", len(sets))
+	fmt.Fprintf(out, "Loaded %d substitution files\n", len(substitutions))
+	fmt.Fprintf(out, "Loaded %d aliases\n", len(aliases))
+	fmt.Fprintf(out, "Loaded %d properties files\n", len(properties))
+	fmt.Fprintf(out, "Loaded %d pdefaults files\n", len(pdefaults))
 
 	return nil
 }
 
 func (g *Golem) loadCommand(args []string) error {
+	return g.loadCommandTo(os.Stdout, args)
+}
+
+// loadCommandTo is loadCommand, writing what it loaded to out
+func (g *Golem) loadCommandTo(out io.Writer, args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("load command requires a filename or directory path")
 	}
@@ -1626,25 +2188,25 @@ func (g *Golem) loadCommand(args []string) error {
 		// Load all related files from directory (AIML, maps, sets, properties, etc.)
 		// Use loadAllRelatedFiles which properly triggers SRAIX configuration
 		dummyFilePath := filepath.Join(absPath, "dummy.aiml")
-		err := g.loadAllRelatedFiles(dummyFilePath)
+		err := g.loadAllRelatedFilesTo(out, dummyFilePath)
 		if err != nil {
 			return fmt.Errorf("failed to load files from directory: %v", err)
 		}
-	} else if strings.HasSuffix(strings.ToLower(absPath), ".aiml") {
+	} else if hasResourceExtension(absPath, ".aiml") {
 		// Load single AIML file and all related files from the same directory
-		err := g.loadAllRelatedFiles(absPath)
+		err := g.loadAllRelatedFilesTo(out, absPath)
 		if err != nil {
 			return fmt.Errorf("failed to load AIML file and related files: %v", err)
 		}
-	} else if strings.HasSuffix(strings.ToLower(absPath), ".map") {
+	} else if hasResourceExtension(absPath, ".map") {
 		// Load single map file and all related files from the same directory
-		err := g.loadAllRelatedFiles(absPath)
+		err := g.loadAllRelatedFilesTo(out, absPath)
 		if err != nil {
 			return fmt.Errorf("failed to load map file and related files: %v", err)
 		}
-	} else if strings.HasSuffix(strings.ToLower(absPath), ".set") {
+	} else if hasResourceExtension(absPath, ".set") {
 		// Load single set file and all related files from the same directory
-		err := g.loadAllRelatedFiles(absPath)
+		err := g.loadAllRelatedFilesTo(out, absPath)
 		if err != nil {
 			return fmt.Errorf("failed to load set file and related files: %v", err)
 		}
@@ -1655,8 +2217,8 @@ func (g *Golem) loadCommand(args []string) error {
 			return fmt.Errorf("failed to load file %s: %v", absPath, err)
 		}
 
-		fmt.Printf("Successfully loaded file: %s\n", absPath)
-		fmt.Printf("File size: %d bytes\n", len(content))
+		fmt.Fprintf(out, "Successfully loaded file: %s\n", absPath)
+		fmt.Fprintf(out, "File size: %d bytes\n", len(content))
 
 		if g.verbose {
 			// Show first 200 characters of content
@@ -1664,7 +2226,7 @@ func (g *Golem) loadCommand(args []string) error {
 			if len(preview) > 200 {
 				preview = preview[:200] + "..."
 			}
-			fmt.Printf("Content preview: %s\n", preview)
+			fmt.Fprintf(out, "Content preview: %s\n", preview)
 		}
 	}
 
@@ -1689,6 +2251,7 @@ func (g *Golem) chatCommand(args []string) error {
 
 	input := strings.Join(args, " ")
 	g.LogInfo("Processing chat input in session %s: %s", session.ID, input)
+	g.touchSession(session)
 
 	// Check for OOB messages first
 	if oobMsg, isOOB := ParseOOBMessage(input); isOOB {
@@ -1722,14 +2285,17 @@ func (g *Golem) chatCommand(args []string) error {
 		session.History = append(session.History, "Golem: "+response)
 		return nil
 	}
+	g.notifyCategoryMatched(category, input)
 
 	// Process template with session context
 	response := g.ProcessTemplateWithSession(category.Template, wildcards, session)
+	response = g.applyResponseFilters(response)
 	fmt.Printf("Golem: %s\n", response)
 	session.History = append(session.History, "Golem: "+response)
 
 	// Add to response history for <response> tag support
 	session.AddToResponseHistory(response)
+	g.saveSession(session)
 
 	return nil
 }
@@ -1794,6 +2360,10 @@ func (g *Golem) analyzeCommand(args []string) error {
 		return fmt.Errorf("analyze command requires input file")
 	}
 
+	if args[0] == "transcript" {
+		return g.analyzeTranscriptCommand(args[1:])
+	}
+
 	inputFile := args[0]
 	g.LogInfo("Analyzing file: %s", inputFile)
 
@@ -1804,6 +2374,10 @@ func (g *Golem) analyzeCommand(args []string) error {
 
 // GenerateCommand handles the generate command
 func (g *Golem) generateCommand(args []string) error {
+	if len(args) > 0 && args[0] == "faq" {
+		return g.generateFAQCommand(args[1:])
+	}
+
 	outputFile := "output.txt"
 
 	// Parse optional output file argument
@@ -1827,14 +2401,21 @@ func (g *Golem) ProcessData(input string) (string, error) {
 	return result, nil
 }
 
-// ProcessInput processes user input with full context support
-func (g *Golem) ProcessInput(input string, session *ChatSession) (string, error) {
+// MatchInput returns the category (and wildcard captures) that ProcessInput would use
+// for input in the session's current topic and that context, without processing the
+// template or updating the session. It is intended for debugging and analysis.
+func (g *Golem) MatchInput(input string, session *ChatSession) (*Category, map[string]string, error) {
 	if g.aimlKB == nil {
-		return "", fmt.Errorf("no AIML knowledge base loaded")
+		return nil, nil, fmt.Errorf("no AIML knowledge base loaded")
 	}
+	if err := g.checkInputLength(input); err != nil {
+		return nil, nil, err
+	}
+	return g.matchInput(input, session)
+}
 
-	g.LogInfo("Processing input: %s", input)
-
+// matchInput is MatchInput for input whose length has already been checked
+func (g *Golem) matchInput(input string, session *ChatSession) (*Category, map[string]string, error) {
 	// Normalize input
 	normalizedInput := g.CachedNormalizePattern(input)
 
@@ -1849,17 +2430,51 @@ func (g *Golem) ProcessInput(input string, session *ChatSession) (string, error)
 	}
 
 	// Try to match pattern with full context (using index 0 for last response)
-	category, wildcards, err := g.aimlKB.MatchPatternWithTopicAndThatIndexOriginalCached(g, normalizedInput, input, currentTopic, normalizedThat, 0)
+	return g.aimlKB.MatchPatternWithTopicAndThatIndexOriginalCached(g, normalizedInput, g.captureInput(input), currentTopic, normalizedThat, 0)
+}
+
+// ProcessInput processes user input with full context support. This is the entry point
+// for a chat turn: it matches the input, processes the template and updates the session
+// history. Use ProcessTemplate only for templates that are not the reply to user input.
+func (g *Golem) ProcessInput(input string, session *ChatSession) (string, error) {
+	response, _, err := g.ProcessInputWithCategory(input, session)
+	return response, err
+}
+
+// ProcessInputWithCategory processes user input like ProcessInput and also returns the
+// category whose template produced the response
+func (g *Golem) ProcessInputWithCategory(input string, session *ChatSession) (string, *Category, error) {
+	if g.aimlKB == nil {
+		return "", nil, fmt.Errorf("no AIML knowledge base loaded")
+	}
+
+	g.kbMutex.RLock()
+	defer g.kbMutex.RUnlock()
+
+	g.LogInfo("Processing input: %s", input)
+	// Checked before matching so over-long input is not reported as unmatched
+	if err := g.checkInputLength(input); err != nil {
+		return g.inputTooLongResponse(err), nil, nil
+	}
+	session.CurrentInput = input
+	g.touchSession(session)
+
+	category, wildcards, err := g.matchInput(input, session)
 	if err != nil {
-		return "", err
+		g.notifyUnmatched(input, session)
+		return "", nil, err
 	}
+	g.notifyCategoryMatched(category, input)
 
 	// Capture that context from template before processing (for next input)
 	// This needs to be done before the template is processed because <set> tags might change the content
 	nextThatContext := g.extractThatContextFromTemplate(category.Template)
 
 	// Process template with context
-	response := g.ProcessTemplateWithContext(category.Template, wildcards, session)
+	response := g.processCategoryWithContext(category, input, wildcards, session)
+
+	// Apply application-level response filters
+	response = g.applyResponseFilters(response)
 
 	// Add to history
 	session.History = append(session.History, input)
@@ -1869,14 +2484,13 @@ func (g *Golem) ProcessInput(input string, session *ChatSession) (string, error)
 	session.AddToRequestHistory(input)
 
 	// Add the extracted that context to history for future context matching
-	if nextThatContext != "" {
-		session.AddToThatHistory(nextThatContext)
-	}
+	g.addThatContext(session, nextThatContext, response)
 
 	// Add to response history for <response> tag support
 	session.AddToResponseHistory(response)
+	g.saveSession(session)
 
-	return response, nil
+	return response, category, nil
 }
 
 // ProcessInputWithThatIndex processes user input with specific that context index
@@ -1885,7 +2499,15 @@ func (g *Golem) ProcessInputWithThatIndex(input string, session *ChatSession, th
 		return "", fmt.Errorf("no AIML knowledge base loaded")
 	}
 
+	g.kbMutex.RLock()
+	defer g.kbMutex.RUnlock()
+
 	g.LogInfo("Processing input with that index %d: %s", thatIndex, input)
+	if err := g.checkInputLength(input); err != nil {
+		return g.inputTooLongResponse(err), nil
+	}
+	session.CurrentInput = input
+	g.touchSession(session)
 
 	// Normalize input
 	normalizedInput := g.CachedNormalizePattern(input)
@@ -1904,17 +2526,22 @@ func (g *Golem) ProcessInputWithThatIndex(input string, session *ChatSession, th
 	}
 
 	// Try to match pattern with full context and specific that index
-	category, wildcards, err := g.aimlKB.MatchPatternWithTopicAndThatIndexOriginalCached(g, normalizedInput, input, currentTopic, normalizedThat, thatIndex)
+	category, wildcards, err := g.aimlKB.MatchPatternWithTopicAndThatIndexOriginalCached(g, normalizedInput, g.captureInput(input), currentTopic, normalizedThat, thatIndex)
 	if err != nil {
+		g.notifyUnmatched(input, session)
 		return "", err
 	}
+	g.notifyCategoryMatched(category, input)
 
 	// Capture that context from template before processing (for next input)
 	// This needs to be done before the template is processed because <set> tags might change the content
 	nextThatContext := g.extractThatContextFromTemplate(category.Template)
 
 	// Process template with context
-	response := g.ProcessTemplateWithContext(category.Template, wildcards, session)
+	response := g.processCategoryWithContext(category, input, wildcards, session)
+
+	// Apply application-level response filters
+	response = g.applyResponseFilters(response)
 
 	// Add to history
 	session.History = append(session.History, input)
@@ -1924,12 +2551,11 @@ func (g *Golem) ProcessInputWithThatIndex(input string, session *ChatSession, th
 	session.AddToRequestHistory(input)
 
 	// Add the extracted that context to history for future context matching
-	if nextThatContext != "" {
-		session.AddToThatHistory(nextThatContext)
-	}
+	g.addThatContext(session, nextThatContext, response)
 
 	// Add to response history for <response> tag support
 	session.AddToResponseHistory(response)
+	g.saveSession(session)
 
 	return response, nil
 }
@@ -1988,25 +2614,32 @@ func (g *Golem) GenerateOutput(data interface{}) (string, error) {
 func (g *Golem) LoadFile(filename string) (string, error) {
 	g.LogInfo("Loading file: %s", filename)
 
-	// Open the file
-	file, err := os.Open(filename)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	// Read the file contents
-	content, err := io.ReadAll(file)
+	// Read the file contents, decompressing gzip files
+	content, err := readResourceFile(filename)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %v", err)
 	}
 
-	return string(content), nil
+	return normalizeFileEncoding(string(content)), nil
+}
+
+// normalizeFileEncoding strips a leading UTF-8 byte order mark and converts Windows (CRLF)
+// and old Mac (CR) line endings to LF, so files authored on other platforms parse cleanly
+func normalizeFileEncoding(content string) string {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if strings.Contains(content, "\r") {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+		content = strings.ReplaceAll(content, "\r", "\n")
+	}
+	return content
 }
 
 // SetKnowledgeBase sets the AIML knowledge base
 func (g *Golem) SetKnowledgeBase(kb *AIMLKnowledgeBase) {
 	g.aimlKB = kb
+	// Cached input normalizations applied the previous knowledge base's substitutions
+	// and aliases
+	g.ClearTextNormalizationCache()
 
 	// Register properties handler now that we have a knowledge base
 	propertiesHandler := &PropertiesHandler{aimlKB: kb}
@@ -2062,6 +2695,31 @@ func (g *Golem) SetKnowledgeBase(kb *AIMLKnowledgeBase) {
 	}
 }
 
+// ReloadKnowledgeBase loads the AIML files (and related maps, sets and substitutions)
+// in dirPath into a fresh knowledge base and swaps it in once no input is being
+// processed. Sessions are preserved. If loading fails, including any one file failing
+// to parse, the current knowledge base is kept and the error is returned.
+func (g *Golem) ReloadKnowledgeBase(dirPath string) error {
+	kb, err := g.loadAIMLFromDirectory(dirPath, true)
+	if err != nil {
+		return fmt.Errorf("failed to reload knowledge base: %v", err)
+	}
+
+	g.kbMutex.Lock()
+	defer g.kbMutex.Unlock()
+
+	g.SetKnowledgeBase(kb)
+
+	// Cached matches and processed templates refer to the previous knowledge base
+	g.ClearPatternMatchingCache()
+	g.ClearTemplateCache()
+	g.ClearTemplateTagProcessingCache()
+	g.ClearVariableResolutionCache()
+
+	g.LogInfo("Reloaded knowledge base from %s with %d categories", dirPath, len(kb.Categories))
+	return nil
+}
+
 // GetKnowledgeBase returns the current AIML knowledge base
 func (g *Golem) GetKnowledgeBase() *AIMLKnowledgeBase {
 	return g.aimlKB
@@ -2165,6 +2823,9 @@ func (g *Golem) deleteSessionCommand(args []string) error {
 	if g.currentID == sessionID {
 		g.currentID = ""
 	}
+	if err := g.sessionStore.Delete(sessionID); err != nil {
+		g.LogWarn("Failed to delete stored session %s: %v", sessionID, err)
+	}
 	fmt.Printf("Deleted session: %s\n", sessionID)
 	return nil
 }
@@ -2205,6 +2866,7 @@ func (g *Golem) createSession(sessionID string) *ChatSession {
 		History:           []string{},
 		CreatedAt:         now,
 		LastActivity:      now,
+		LastAccessed:      g.now(),
 		RequestHistory:    []string{},
 		ResponseHistory:   []string{},
 		ThatHistory:       []string{},
@@ -2218,15 +2880,17 @@ func (g *Golem) createSession(sessionID string) *ChatSession {
 			ValidationErrors: 0,
 			LearningRate:     0.0,
 		},
+		onTopicChange: g.notifyTopicChange,
 	}
 
 	// Initialize enhanced context management
-	session.InitializeContextConfig()
+	g.initializeSessionContext(session)
 
 	g.sessionMutex.Lock()
 	g.sessions[sessionID] = session
 	g.currentID = sessionID
 	g.sessionMutex.Unlock()
+	g.saveSession(session)
 	return session
 }
 
@@ -2542,6 +3206,8 @@ func (g *Golem) UpdateTemplateProcessingConfig(config *TemplateProcessingConfig)
 		g.templateCache.MaxSize = config.CacheSize
 		g.templateCache.TTL = config.CacheTTL
 	}
+	// Cached results were rendered under the previous settings (e.g. DisablePropertyFallback)
+	g.ClearTemplateCache()
 }
 
 // ClearTemplateCache clears the template cache
@@ -2558,7 +3224,12 @@ func (g *Golem) GetRegexCacheStats() map[string]interface{} {
 	stats := make(map[string]interface{})
 
 	if g.patternRegexCache != nil {
-		stats["pattern_regex"] = g.patternRegexCache.GetCacheStats()
+		patternStats := g.patternRegexCache.GetCacheStats()
+		if g.setFragments != nil {
+			// Sets compiled once and shared by the pattern skeletons
+			patternStats["set_fragments"] = g.setFragments.size()
+		}
+		stats["pattern_regex"] = patternStats
 	}
 	if g.tagProcessingCache != nil {
 		stats["tag_processing"] = g.tagProcessingCache.GetCacheStats()
@@ -2597,6 +3268,9 @@ func (g *Golem) ClearRegexCaches() {
 	if g.normalizationCache != nil {
 		g.normalizationCache.ClearCache()
 	}
+	if g.setFragments != nil {
+		g.setFragments.clear()
+	}
 }
 
 // ClearTextNormalizationCache clears the text normalization cache
@@ -2944,12 +3618,20 @@ func (cache *PatternMatchingCache) SetExactMatchKey(input, topic, that string, t
 }
 
 // generateSetContentHash creates a hash of set content for validation
+// The hash is computed from a sorted copy of the members, so it always reflects the
+// membership at call time regardless of how the caller's slice is later mutated
 func (cache *PatternMatchingCache) generateSetContentHash(setContent []string) string {
+	return setContentHash(setContent)
+}
+
+// setContentHash hashes a set's members independent of their order
+func setContentHash(setContent []string) string {
 	// Sort content for consistent hashing
 	sortedContent := make([]string, len(setContent))
 	copy(sortedContent, setContent)
 	sort.Strings(sortedContent)
-	return strings.Join(sortedContent, "|")
+	sum := sha256.Sum256([]byte(strings.Join(sortedContent, "\x00")))
+	return fmt.Sprintf("%d:%s", len(sortedContent), hex.EncodeToString(sum[:]))
 }
 
 // generateExactMatchKey creates a cache key for exact match lookups
@@ -3081,25 +3763,31 @@ func (cache *PatternMatchingCache) InvalidateKnowledgeBase(newHash string) {
 
 // InvalidateSet invalidates set-related caches when a set changes
 func (cache *PatternMatchingCache) InvalidateSet(setName string) {
+	// Set regexes are keyed by upper-case set name
+	upperSetName := strings.ToUpper(setName)
 	cache.removeSetRegex(setName)
+	cache.removeSetRegex(upperSetName)
 	// Also invalidate wildcard matches that might use this set
+	// (patterns may reference the set in any case, e.g. <set>colors</set>)
 	for key := range cache.WildcardMatches {
-		if strings.Contains(key, setName) {
+		if strings.Contains(strings.ToUpper(key), upperSetName) {
 			cache.removeWildcardMatch(key)
 		}
 	}
 }
 
-// CachedNormalizePattern normalizes AIML patterns with caching
+// CachedNormalizePattern normalizes AIML patterns with caching. Alias phrases are
+// rewritten after the cached step, so aliases added at runtime apply to inputs that
+// were normalized before.
 func (g *Golem) CachedNormalizePattern(pattern string) string {
 	if g.textNormalizationCache != nil {
 		if result, err := g.textNormalizationCache.GetNormalizedText(g, pattern, "NormalizePattern"); err == nil {
-			return result
+			return g.aimlKB.applyAliases(result)
 		}
 	}
 	// Fallback to direct normalization with loaded substitutions
-	normalized := NormalizePattern(pattern)
-	return g.applyLoadedSubstitutions(normalized)
+	normalized := g.normalizeInputForMatching(pattern)
+	return g.aimlKB.applyAliases(g.applyLoadedSubstitutions(normalized))
 }
 
 // CachedNormalizeForMatchingCasePreserving normalizes text for pattern matching with case preservation and caching
@@ -3113,15 +3801,29 @@ func (g *Golem) CachedNormalizeForMatchingCasePreserving(input string) string {
 	return NormalizeForMatchingCasePreserving(input)
 }
 
-// CachedNormalizeThatPattern normalizes that patterns with caching
+// normalizeForMatchingCasePreservingCached is NormalizeForMatchingCasePreserving through
+// the Golem's text normalization cache, when there is one. Matching normalizes the same
+// input for each candidate pattern, so this keeps that to one normalization per input.
+func normalizeForMatchingCasePreservingCached(g *Golem, input string) string {
+	if g != nil {
+		return g.CachedNormalizeForMatchingCasePreserving(input)
+	}
+	return NormalizeForMatchingCasePreserving(input)
+}
+
+// CachedNormalizeThatPattern normalizes a that context (an entry of the that history)
+// for matching against <that> patterns, with caching. The history keeps each context
+// as captured (see extractThatContextFromTemplate), so markup such as <formal> or <b>
+// tags and &amp; entities is stripped here, at match time, rather than when storing.
 func (g *Golem) CachedNormalizeThatPattern(pattern string) string {
+	pattern = stripResponseMarkup(pattern)
 	if g.textNormalizationCache != nil {
 		if result, err := g.textNormalizationCache.GetNormalizedText(g, pattern, "NormalizeThatPattern"); err == nil {
 			return result
 		}
 	}
 	// Fallback to direct normalization
-	return NormalizeThatPattern(pattern)
+	return NormalizeThatPattern(g.tokenizeText(pattern))
 }
 
 // CachedNormalizeForMatching normalizes text for matching with caching
@@ -3220,6 +3922,19 @@ func (g *Golem) generateTemplateCacheKey(template string, wildcards map[string]s
 		b.WriteString(ctx.Topic)
 	}
 
+	// Include variable state for templates that read variables, or bot properties,
+	// which depend on the language variable
+	if ctx != nil && (strings.Contains(template, "<get ") || strings.Contains(template, "<bot ")) {
+		if ctx.Session != nil {
+			b.WriteString("|vars:")
+			writeSortedVariables(&b, ctx.Session.Variables)
+		}
+		if ctx.KnowledgeBase != nil {
+			b.WriteString("|globals:")
+			writeSortedVariables(&b, ctx.KnowledgeBase.Variables)
+		}
+	}
+
 	// Include state-dependent data in cache key
 	if ctx != nil && ctx.KnowledgeBase != nil {
 		// Include array state for templates that reference arrays
@@ -3301,6 +4016,21 @@ func (g *Golem) generateTemplateCacheKey(template string, wildcards map[string]s
 	return b.String()
 }
 
+// writeSortedVariables writes variables to a cache key in a deterministic order
+func writeSortedVariables(b *strings.Builder, variables map[string]string) {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(variables[name])
+		b.WriteString(";")
+	}
+}
+
 // getFromTemplateCache fetches a cached response if present
 func (g *Golem) getFromTemplateCache(key string) (string, bool) {
 	if g.templateCache == nil {
@@ -172,3 +172,68 @@ func TestLoadCommand(t *testing.T) {
 		t.Errorf("Load command failed: %v", err)
 	}
 }
+
+func TestProcessInputWithCategory(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<aiml version="2.0">
+		<category>
+			<pattern>DO YOU LIKE *</pattern>
+			<template>Do you like <star/> too?</template>
+		</category>
+		<category>
+			<pattern>YES</pattern>
+			<that>DO YOU LIKE * TOO</that>
+			<template>Great, we agree.</template>
+		</category>
+		<category>
+			<pattern>YES</pattern>
+			<template>Yes to what?</template>
+		</category>
+	</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	session := g.CreateSession("full_turn")
+
+	// First turn matches the wildcard category and records the bot's reply as that
+	response, category, err := g.ProcessInputWithCategory("Do you like tea", session)
+	if err != nil {
+		t.Fatalf("ProcessInputWithCategory failed: %v", err)
+	}
+	if response != "Do you like tea too?" {
+		t.Errorf("Expected %q, got %q", "Do you like tea too?", response)
+	}
+	if category == nil || category.Pattern != "DO YOU LIKE *" {
+		t.Fatalf("Expected the DO YOU LIKE * category, got %+v", category)
+	}
+	if len(session.History) != 1 || session.History[0] != "Do you like tea" {
+		t.Errorf("Expected input history [Do you like tea], got %v", session.History)
+	}
+	if last := session.GetLastThat(); last == "" {
+		t.Error("Expected that history to be updated after the first turn")
+	}
+
+	// Second turn uses the that context from the first
+	response, category, err = g.ProcessInputWithCategory("yes", session)
+	if err != nil {
+		t.Fatalf("ProcessInputWithCategory failed: %v", err)
+	}
+	if response != "Great, we agree." {
+		t.Errorf("Expected %q, got %q", "Great, we agree.", response)
+	}
+	if category == nil || category.That == "" {
+		t.Errorf("Expected the category with a that pattern, got %+v", category)
+	}
+	if len(session.ResponseHistory) != 2 {
+		t.Errorf("Expected 2 responses in history, got %v", session.ResponseHistory)
+	}
+
+	// Unmatched input returns no category
+	empty := NewForTesting(t, false)
+	if err := empty.LoadAIMLFromString(`<aiml version="2.0"><category><pattern>HELLO</pattern><template>Hi</template></category></aiml>`); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	if _, category, err := empty.ProcessInputWithCategory("goodbye", empty.CreateSession("unmatched")); err == nil || category != nil {
+		t.Errorf("Expected an error and no category for unmatched input, got %+v, %v", category, err)
+	}
+}
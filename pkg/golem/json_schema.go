@@ -0,0 +1,178 @@
+package golem
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// validateJSONSchema checks a decoded JSON value against a JSON Schema. It supports the
+// commonly used subset of the specification: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength, minimum,
+// maximum and pattern. Other keywords are ignored. path names the value in errors.
+func validateJSONSchema(schema, value interface{}, path string) error {
+	switch s := schema.(type) {
+	case bool:
+		// true accepts everything, false nothing
+		if !s {
+			return fmt.Errorf("%s: not allowed", path)
+		}
+		return nil
+	case map[string]interface{}:
+		return validateJSONSchemaObject(s, value, path)
+	default:
+		return fmt.Errorf("%s: invalid schema", path)
+	}
+}
+
+// validateJSONSchemaObject implements validateJSONSchema for an object schema
+func validateJSONSchemaObject(schema map[string]interface{}, value interface{}, path string) error {
+	if types, exists := schema["type"]; exists && !jsonSchemaTypeMatches(types, value) {
+		return fmt.Errorf("%s: expected type %v, got %s", path, types, jsonTypeName(value))
+	}
+	if enum, exists := schema["enum"].([]interface{}); exists {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value %v is not one of %v", path, value, enum)
+		}
+	}
+	if constant, exists := schema["const"]; exists && !reflect.DeepEqual(constant, value) {
+		return fmt.Errorf("%s: expected %v, got %v", path, constant, value)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, exists := schema["required"].([]interface{}); exists {
+			for _, name := range required {
+				if key, ok := name.(string); ok {
+					if _, present := v[key]; !present {
+						return fmt.Errorf("%s: missing required property '%s'", path, key)
+					}
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, propertyValue := range v {
+			if propertySchema, exists := properties[key]; exists {
+				if err := validateJSONSchema(propertySchema, propertyValue, path+"."+key); err != nil {
+					return err
+				}
+			} else if additional, exists := schema["additionalProperties"]; exists {
+				if err := validateJSONSchema(additional, propertyValue, path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if minItems, exists := jsonSchemaNumber(schema, "minItems"); exists && float64(len(v)) < minItems {
+			return fmt.Errorf("%s: expected at least %v items, got %d", path, minItems, len(v))
+		}
+		if maxItems, exists := jsonSchemaNumber(schema, "maxItems"); exists && float64(len(v)) > maxItems {
+			return fmt.Errorf("%s: expected at most %v items, got %d", path, maxItems, len(v))
+		}
+		if items, exists := schema["items"]; exists {
+			for i, item := range v {
+				if err := validateJSONSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if minLength, exists := jsonSchemaNumber(schema, "minLength"); exists && length < minLength {
+			return fmt.Errorf("%s: expected at least %v characters, got %v", path, minLength, length)
+		}
+		if maxLength, exists := jsonSchemaNumber(schema, "maxLength"); exists && length > maxLength {
+			return fmt.Errorf("%s: expected at most %v characters, got %v", path, maxLength, length)
+		}
+		if pattern, exists := schema["pattern"].(string); exists {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern '%s': %v", path, pattern, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s: '%s' does not match pattern '%s'", path, v, pattern)
+			}
+		}
+	case float64:
+		if minimum, exists := jsonSchemaNumber(schema, "minimum"); exists && v < minimum {
+			return fmt.Errorf("%s: %v is less than the minimum %v", path, v, minimum)
+		}
+		if maximum, exists := jsonSchemaNumber(schema, "maximum"); exists && v > maximum {
+			return fmt.Errorf("%s: %v is greater than the maximum %v", path, v, maximum)
+		}
+	}
+	return nil
+}
+
+// jsonSchemaTypeMatches reports whether value has the schema type, given as a name or a
+// list of names
+func jsonSchemaTypeMatches(types interface{}, value interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		actual := jsonTypeName(value)
+		if t == "number" && actual == "integer" {
+			return true
+		}
+		return t == actual
+	case []interface{}:
+		for _, name := range t {
+			if jsonSchemaTypeMatches(name, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonTypeName returns the JSON Schema type name of a decoded JSON value
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// jsonSchemaNumber returns a numeric schema keyword
+func jsonSchemaNumber(schema map[string]interface{}, keyword string) (float64, bool) {
+	number, ok := schema[keyword].(float64)
+	return number, ok
+}
+
+// parseJSONSchema decodes a JSON Schema, which must be an object or a boolean
+func parseJSONSchema(raw json.RawMessage) (interface{}, error) {
+	var schema interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, err
+	}
+	switch schema.(type) {
+	case map[string]interface{}, bool:
+		return schema, nil
+	default:
+		return nil, fmt.Errorf("a schema must be an object or a boolean, got %s", strings.TrimSpace(string(raw)))
+	}
+}
@@ -0,0 +1,46 @@
+package golem
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		valid  bool
+	}{
+		{name: "Integer is a number", schema: `{"type": "number"}`, value: `3`, valid: true},
+		{name: "Number is not an integer", schema: `{"type": "integer"}`, value: `3.5`, valid: false},
+		{name: "Type list", schema: `{"type": ["string", "null"]}`, value: `null`, valid: true},
+		{name: "Const", schema: `{"const": "ok"}`, value: `"ko"`, valid: false},
+		{name: "Additional properties", schema: `{"properties": {"a": {}}, "additionalProperties": false}`, value: `{"a": 1, "b": 2}`, valid: false},
+		{name: "Array items", schema: `{"type": "array", "items": {"type": "string"}, "minItems": 1}`, value: `["a", "b"]`, valid: true},
+		{name: "Bad array item", schema: `{"items": {"type": "string"}}`, value: `["a", 2]`, valid: false},
+		{name: "Too few items", schema: `{"minItems": 2}`, value: `["a"]`, valid: false},
+		{name: "String length", schema: `{"minLength": 2, "maxLength": 4}`, value: `"héllo"`, valid: false},
+		{name: "Pattern", schema: `{"pattern": "^\\d{5}$"}`, value: `"90210"`, valid: true},
+		{name: "Range", schema: `{"minimum": 0, "maximum": 100}`, value: `101`, valid: false},
+		{name: "Keywords for other types are ignored", schema: `{"minLength": 3}`, value: `7`, valid: true},
+		{name: "False schema", schema: `false`, value: `{}`, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := parseJSONSchema(json.RawMessage(tt.schema))
+			if err != nil {
+				t.Fatalf("Failed to parse schema: %v", err)
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatalf("Failed to parse value: %v", err)
+			}
+			err = validateJSONSchema(schema, value, "value")
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v for %s against %s, got error %v", tt.valid, tt.value, tt.schema, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package golem
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// TopicCount is a topic's category count in the old and new knowledge base
+type TopicCount struct {
+	Old int `json:"old"`
+	New int `json:"new"`
+}
+
+// KnowledgeBaseDiff describes what changed between two knowledge bases. Categories
+// are identified by their composite pattern key (pattern, that and topic, see
+// categoryPatternKey); properties, maps and sets by name.
+type KnowledgeBaseDiff struct {
+	AddedCategories   []string              `json:"added_categories"`
+	RemovedCategories []string              `json:"removed_categories"`
+	ChangedCategories []string              `json:"changed_categories"` // Same key, different template
+	TopicCounts       map[string]TopicCount `json:"topic_counts"`       // "*" is categories without a topic
+	ChangedProperties []string              `json:"changed_properties"` // Added, removed or with a new value
+	ChangedMaps       []string              `json:"changed_maps"`
+	ChangedSets       []string              `json:"changed_sets"`
+}
+
+// Empty reports whether the knowledge bases had no differences
+func (d *KnowledgeBaseDiff) Empty() bool {
+	if len(d.AddedCategories)+len(d.RemovedCategories)+len(d.ChangedCategories) > 0 {
+		return false
+	}
+	if len(d.ChangedProperties)+len(d.ChangedMaps)+len(d.ChangedSets) > 0 {
+		return false
+	}
+	for _, count := range d.TopicCounts {
+		if count.Old != count.New {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffKnowledgeBases compares two knowledge bases, reporting what changed going from
+// oldKB to newKB. All name lists are sorted.
+func DiffKnowledgeBases(oldKB, newKB *AIMLKnowledgeBase) *KnowledgeBaseDiff {
+	diff := &KnowledgeBaseDiff{
+		AddedCategories:   []string{},
+		RemovedCategories: []string{},
+		ChangedCategories: []string{},
+		TopicCounts:       make(map[string]TopicCount),
+	}
+
+	oldCategories := categoriesByKey(oldKB)
+	newCategories := categoriesByKey(newKB)
+	for key, newCategory := range newCategories {
+		oldCategory, exists := oldCategories[key]
+		if !exists {
+			diff.AddedCategories = append(diff.AddedCategories, key)
+		} else if strings.TrimSpace(oldCategory.Template) != strings.TrimSpace(newCategory.Template) {
+			diff.ChangedCategories = append(diff.ChangedCategories, key)
+		}
+	}
+	for key := range oldCategories {
+		if _, exists := newCategories[key]; !exists {
+			diff.RemovedCategories = append(diff.RemovedCategories, key)
+		}
+	}
+	sort.Strings(diff.AddedCategories)
+	sort.Strings(diff.RemovedCategories)
+	sort.Strings(diff.ChangedCategories)
+
+	for _, category := range oldCategories {
+		count := diff.TopicCounts[diffTopicName(category)]
+		count.Old++
+		diff.TopicCounts[diffTopicName(category)] = count
+	}
+	for _, category := range newCategories {
+		count := diff.TopicCounts[diffTopicName(category)]
+		count.New++
+		diff.TopicCounts[diffTopicName(category)] = count
+	}
+
+	diff.ChangedProperties = changedNames(oldKB.Properties, newKB.Properties)
+	diff.ChangedMaps = changedNames(oldKB.Maps, newKB.Maps)
+	diff.ChangedSets = changedNames(normalizedSets(oldKB.Sets), normalizedSets(newKB.Sets))
+	return diff
+}
+
+// categoriesByKey returns the knowledge base's categories by composite pattern key.
+// When several categories share a key the last one wins, as it does when indexing.
+func categoriesByKey(kb *AIMLKnowledgeBase) map[string]*Category {
+	categories := make(map[string]*Category, len(kb.Categories))
+	for i := range kb.Categories {
+		categories[categoryPatternKey(&kb.Categories[i])] = &kb.Categories[i]
+	}
+	return categories
+}
+
+// diffTopicName returns the topic a category is counted under in a diff
+func diffTopicName(category *Category) string {
+	if category.Topic == "" {
+		return "*"
+	}
+	return strings.ToUpper(category.Topic)
+}
+
+// normalizedSets returns sets with their members sorted, so member order doesn't
+// count as a change
+func normalizedSets(sets map[string][]string) map[string][]string {
+	normalized := make(map[string][]string, len(sets))
+	for name, members := range sets {
+		sorted := append([]string(nil), members...)
+		sort.Strings(sorted)
+		normalized[name] = sorted
+	}
+	return normalized
+}
+
+// changedNames returns the sorted names that are only in one of the maps or whose
+// values differ
+func changedNames[V any](oldValues, newValues map[string]V) []string {
+	changed := []string{}
+	for name, newValue := range newValues {
+		if oldValue, exists := oldValues[name]; !exists || !reflect.DeepEqual(oldValue, newValue) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range oldValues {
+		if _, exists := newValues[name]; !exists {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// DiffAIMLDirectories loads the knowledge bases in oldDir and newDir (as
+// LoadAIMLFromDirectory does, without replacing the loaded one) and compares them
+func (g *Golem) DiffAIMLDirectories(oldDir, newDir string) (*KnowledgeBaseDiff, error) {
+	oldKB, err := g.LoadAIMLFromDirectory(oldDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", oldDir, err)
+	}
+	newKB, err := g.LoadAIMLFromDirectory(newDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", newDir, err)
+	}
+	return DiffKnowledgeBases(oldKB, newKB), nil
+}
+
+// diffCommand handles "diff <dir1> <dir2> [--json]", reporting the categories,
+// topics, properties, maps and sets that changed from dir1 to dir2
+func (g *Golem) diffCommand(args []string) error {
+	var dirs []string
+	asJSON := false
+	for _, arg := range args {
+		if arg == "--json" {
+			asJSON = true
+			continue
+		}
+		if strings.HasPrefix(arg, "--") {
+			return fmt.Errorf("unknown diff option: %s", arg)
+		}
+		dirs = append(dirs, arg)
+	}
+	if len(dirs) != 2 {
+		return fmt.Errorf("usage: diff <dir1> <dir2> [--json]")
+	}
+
+	diff, err := g.DiffAIMLDirectories(dirs[0], dirs[1])
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoded, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode diff: %v", err)
+		}
+		fmt.Println(string(encoded))
+		return nil
+	}
+
+	if diff.Empty() {
+		fmt.Println("No differences")
+		return nil
+	}
+	fmt.Printf("Categories: %d added, %d removed, %d changed\n",
+		len(diff.AddedCategories), len(diff.RemovedCategories), len(diff.ChangedCategories))
+	for _, key := range diff.AddedCategories {
+		fmt.Printf("  + %s\n", key)
+	}
+	for _, key := range diff.RemovedCategories {
+		fmt.Printf("  - %s\n", key)
+	}
+	for _, key := range diff.ChangedCategories {
+		fmt.Printf("  ~ %s\n", key)
+	}
+
+	topics := make([]string, 0, len(diff.TopicCounts))
+	for topic := range diff.TopicCounts {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	fmt.Println("Categories per topic:")
+	for _, topic := range topics {
+		count := diff.TopicCounts[topic]
+		fmt.Printf("  %s: %d -> %d\n", topic, count.Old, count.New)
+	}
+
+	for _, changed := range []struct {
+		label string
+		names []string
+	}{
+		{"Properties", diff.ChangedProperties},
+		{"Maps", diff.ChangedMaps},
+		{"Sets", diff.ChangedSets},
+	} {
+		if len(changed.names) > 0 {
+			fmt.Printf("%s changed: %s\n", changed.label, strings.Join(changed.names, ", "))
+		}
+	}
+	return nil
+}
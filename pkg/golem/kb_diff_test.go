@@ -0,0 +1,100 @@
+package golem
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffAIMLDirectories(t *testing.T) {
+	writeFiles := func(files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+		return dir
+	}
+
+	oldDir := writeFiles(map[string]string{
+		"bot.aiml": `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category><pattern>HELLO</pattern><template>Hi!</template></category>
+    <category><pattern>BYE</pattern><template>Goodbye.</template></category>
+    <category><pattern>YES</pattern><that>DO YOU LIKE TEA</that><template>Tea it is.</template></category>
+</aiml>`,
+		"colors.set":     `["red", "green"]`,
+		"capitals.map":   `[{"key": "France", "value": "Paris"}]`,
+		"bot.properties": `[["name", "Golem"]]`,
+	})
+	newDir := writeFiles(map[string]string{
+		"bot.aiml": `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category><pattern>HELLO</pattern><template>Hello there!</template></category>
+    <category><pattern>YES</pattern><that>DO YOU LIKE TEA</that><template>Tea it is.</template></category>
+    <category><pattern>PLAY</pattern><topic>GAMES</topic><template>Let's play.</template></category>
+</aiml>`,
+		"colors.set":     `["green", "red"]`,
+		"capitals.map":   `[{"key": "France", "value": "Paris"}, {"key": "Spain", "value": "Madrid"}]`,
+		"bot.properties": `[["name", "Golem Two"]]`,
+		"sizes.set":      `["small", "large"]`,
+	})
+
+	g := NewForTesting(t, false)
+	diff, err := g.DiffAIMLDirectories(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("DiffAIMLDirectories failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(diff.AddedCategories, []string{"PLAY|TOPIC:GAMES"}) {
+		t.Errorf("Unexpected added categories: %v", diff.AddedCategories)
+	}
+	if !reflect.DeepEqual(diff.RemovedCategories, []string{"BYE"}) {
+		t.Errorf("Unexpected removed categories: %v", diff.RemovedCategories)
+	}
+	if !reflect.DeepEqual(diff.ChangedCategories, []string{"HELLO"}) {
+		t.Errorf("Unexpected changed categories: %v", diff.ChangedCategories)
+	}
+	expectedTopics := map[string]TopicCount{"*": {Old: 3, New: 2}, "GAMES": {Old: 0, New: 1}}
+	if !reflect.DeepEqual(diff.TopicCounts, expectedTopics) {
+		t.Errorf("Expected topic counts %v, got %v", expectedTopics, diff.TopicCounts)
+	}
+	if !reflect.DeepEqual(diff.ChangedProperties, []string{"name"}) {
+		t.Errorf("Unexpected changed properties: %v", diff.ChangedProperties)
+	}
+	if !reflect.DeepEqual(diff.ChangedMaps, []string{"capitals"}) {
+		t.Errorf("Unexpected changed maps: %v", diff.ChangedMaps)
+	}
+	// Member order alone isn't a change, so only the added set counts
+	if !reflect.DeepEqual(diff.ChangedSets, []string{"SIZES"}) {
+		t.Errorf("Unexpected changed sets: %v", diff.ChangedSets)
+	}
+
+	// A directory compared with itself has no differences
+	same, err := g.DiffAIMLDirectories(oldDir, oldDir)
+	if err != nil {
+		t.Fatalf("DiffAIMLDirectories failed: %v", err)
+	}
+	if !same.Empty() {
+		t.Errorf("Expected no differences, got %+v", same)
+	}
+
+	// Diffing doesn't replace the loaded knowledge base
+	if g.aimlKB != nil && len(g.aimlKB.Categories) != 0 {
+		t.Errorf("Expected the loaded knowledge base to be untouched, got %d categories", len(g.aimlKB.Categories))
+	}
+
+	for _, args := range [][]string{{oldDir, newDir}, {oldDir, newDir, "--json"}} {
+		if err := g.Execute("diff", args); err != nil {
+			t.Errorf("diff %v failed: %v", args, err)
+		}
+	}
+	if err := g.Execute("diff", []string{oldDir}); err == nil {
+		t.Error("Expected an error when only one directory is given")
+	}
+	if err := g.Execute("diff", []string{oldDir, newDir, "--yaml"}); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+}
@@ -383,3 +383,74 @@ func TestLearnTagPerformance(t *testing.T) {
 		}
 	}
 }
+
+// TestLearnTagTopicScope tests that <learn scope="topic"> confines learned categories
+// to the topic active when they were learned
+func TestLearnTagTopicScope(t *testing.T) {
+	aiml := `<aiml version="2.0">
+	<category>
+		<pattern>LETS TALK ABOUT *</pattern>
+		<template><think><set name="topic"><star/></set></think>OK, <star/>.</template>
+	</category>
+	<category>
+		<pattern>REMEMBER * IS *</pattern>
+		<template><learn scope="topic"><category><pattern>WHAT IS <eval><star/></eval></pattern><template><eval><star index="2"/></eval></template></category></learn>Noted.</template>
+	</category>
+	<category>
+		<pattern>GLOBALLY * IS *</pattern>
+		<template><learn><category><pattern>WHAT IS <eval><star/></eval></pattern><template><eval><star index="2"/></eval></template></category></learn>Noted everywhere.</template>
+	</category>
+	<category default="true">
+		<pattern>FALLBACK</pattern>
+		<template>I don't know.</template>
+	</category>
+</aiml>`
+
+	engines := map[string]func(g *Golem){
+		"tree":         func(g *Golem) {},
+		"consolidated": func(g *Golem) { g.SetTemplateProcessor(g.GetConsolidatedProcessor()) },
+	}
+	for name, configure := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			configure(g)
+			if err := g.LoadAIMLFromString(aiml); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("topic-scope")
+
+			steps := []struct {
+				input    string
+				expected string
+			}{
+				{"lets talk about chess", "OK, chess."},
+				{"remember a rook is a castle", "Noted."},
+				{"what is a rook", "a castle"},
+				{"globally a pawn is the smallest piece", "Noted everywhere."},
+				{"lets talk about cooking", "OK, cooking."},
+				{"what is a rook", "I don't know."},
+				{"what is a pawn", "the smallest piece"},
+				{"lets talk about CHESS", "OK, CHESS."},
+				{"what is a rook", "a castle"},
+			}
+			for _, step := range steps {
+				response, err := g.ProcessInput(step.input, session)
+				if err != nil {
+					t.Fatalf("ProcessInput(%q) failed: %v", step.input, err)
+				}
+				if response != step.expected {
+					t.Errorf("%s: expected '%s', got '%s'", step.input, step.expected, response)
+				}
+			}
+
+			learned := g.ListLearnedCategories()
+			topics := make(map[string]string)
+			for _, category := range learned {
+				topics[strings.ToUpper(category.Pattern)] = category.Topic
+			}
+			if topics["WHAT IS A ROOK"] != "chess" || topics["WHAT IS A PAWN"] != "" {
+				t.Errorf("Expected only the scoped category to carry the topic, got %v", topics)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package golem
 
 import (
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -600,3 +602,167 @@ func TestEnhancedLearnConcurrentSessions(t *testing.T) {
 		}
 	}
 }
+
+// TestListAndRemoveLearnedCategories tests inspecting and removing learned categories
+func TestListAndRemoveLearnedCategories(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>HELLO</pattern>
+		<template>Hi there</template>
+	</category>
+	<category>
+		<pattern>TEACH *</pattern>
+		<template><learn><category><pattern><eval><star/></eval></pattern><template>I was taught <eval><star/></eval></template></category></learn>Learned</template>
+	</category>
+	<category>
+		<pattern>GOODBYE</pattern>
+		<template>Bye</template>
+	</category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("learned_list_test")
+
+	if learned := g.ListLearnedCategories(); len(learned) != 0 {
+		t.Fatalf("Expected no learned categories before learning, got %d", len(learned))
+	}
+
+	for _, input := range []string{"teach apples", "teach pears"} {
+		if _, err := g.ProcessInput(input, session); err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", input, err)
+		}
+	}
+
+	learned := g.ListLearnedCategories()
+	if len(learned) != 2 {
+		t.Fatalf("Expected 2 learned categories, got %d: %v", len(learned), learned)
+	}
+	if NormalizePattern(learned[0].Pattern) != "APPLES" || NormalizePattern(learned[1].Pattern) != "PEARS" {
+		t.Errorf("Unexpected learned patterns: %q, %q", learned[0].Pattern, learned[1].Pattern)
+	}
+
+	// Loaded categories are never removed
+	if g.RemoveLearnedCategory("hello") {
+		t.Error("Expected RemoveLearnedCategory to leave loaded categories alone")
+	}
+	if g.RemoveLearnedCategory("bananas") {
+		t.Error("Expected RemoveLearnedCategory to report false for an unknown pattern")
+	}
+
+	if !g.RemoveLearnedCategory("apples") {
+		t.Fatal("Expected RemoveLearnedCategory to remove the learned category")
+	}
+	if learned := g.ListLearnedCategories(); len(learned) != 1 || NormalizePattern(learned[0].Pattern) != "PEARS" {
+		t.Errorf("Expected only PEARS to remain learned, got %v", learned)
+	}
+	if len(session.LearnedCategories) != 1 {
+		t.Errorf("Expected 1 session learned category after removal, got %d", len(session.LearnedCategories))
+	}
+
+	if _, err := g.ProcessInput("apples", session); err == nil {
+		t.Error("Expected removed category to no longer match")
+	}
+
+	// The remaining categories still match their own templates after the index is rebuilt
+	for input, expected := range map[string]string{"pears": "I was taught pears", "hello": "Hi there", "goodbye": "Bye"} {
+		response, err := g.ProcessInput(input, session)
+		if err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", input, err)
+		}
+		if !strings.EqualFold(response, expected) {
+			t.Errorf("ProcessInput(%q): expected %q, got %q", input, expected, response)
+		}
+	}
+}
+
+// TestRelearnedCategoryListedAndKeptOnRemoval tests that a category taught again is
+// listed with its new template, and keeps it when other learned categories are removed
+func TestRelearnedCategoryListedAndKeptOnRemoval(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>TEACH * AS *</pattern>
+		<template><learn><category><pattern><eval><star/></eval></pattern><template><eval><star index="2"/></eval></template></category></learn>Learned</template>
+	</category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("relearn_test")
+	// Enough categories after APPLES that appending moves the categories slice
+	inputs := []string{"teach apples as first", "teach pearc as pear"}
+	for _, fruit := range []string{"plums", "figs", "limes", "dates", "kiwis", "grapes"} {
+		inputs = append(inputs, "teach "+fruit+" as fruit")
+	}
+	inputs = append(inputs, "teach apples as second")
+	for _, input := range inputs {
+		if _, err := g.ProcessInput(input, session); err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", input, err)
+		}
+	}
+
+	assertApples := func(when string) {
+		t.Helper()
+		if response, err := g.ProcessInput("apples", session); err != nil || response != "second" {
+			t.Errorf("%s: expected apples to answer 'second', got %q (err: %v)", when, response, err)
+		}
+		for _, category := range g.ListLearnedCategories() {
+			if NormalizePattern(category.Pattern) == "APPLES" && category.Template != "second" {
+				t.Errorf("%s: expected APPLES to be listed with template 'second', got %q", when, category.Template)
+			}
+		}
+	}
+
+	assertApples("after relearning")
+	if !g.RemoveLearnedCategory("pearc") {
+		t.Fatal("Expected RemoveLearnedCategory to remove PEARC")
+	}
+	assertApples("after removing another category")
+}
+
+// TestReviewLearnedCategoriesWhileLearning tests that learned categories can be listed
+// and removed while another session learns. Run with -race.
+func TestReviewLearnedCategoriesWhileLearning(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>TEACH *</pattern>
+		<template><learn><category><pattern><eval><star/></eval></pattern><template>Known</template></category></learn>Learned</template>
+	</category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("review_learner")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 30; n++ {
+			if _, err := g.ProcessInput(fmt.Sprintf("teach word%d", n), session); err != nil {
+				t.Errorf("ProcessInput(teach) failed: %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 30; n++ {
+			g.ListLearnedCategories()
+			g.RemoveLearnedCategory(fmt.Sprintf("word%d", n))
+		}
+	}()
+	wg.Wait()
+
+	// Whatever the interleaving, the listing and the session agree
+	if listed, kept := len(g.ListLearnedCategories()), len(session.LearnedCategories); listed != kept {
+		t.Errorf("Expected the %d listed categories to match the session's %d", listed, kept)
+	}
+}
@@ -0,0 +1,122 @@
+package golem
+
+import "fmt"
+
+// makeRoomForLearned enforces MaxLearnedPerSession before session learns a new
+// category. At the cap it either rejects the category (RejectLearnedOverLimit) or
+// evicts the session's oldest learned categories until there is room.
+func (g *Golem) makeRoomForLearned(session *ChatSession) error {
+	if session == nil || g.MaxLearnedPerSession <= 0 {
+		return nil
+	}
+
+	if len(session.LearnedOrder) >= g.MaxLearnedPerSession && g.RejectLearnedOverLimit {
+		g.LogWarn("Session %s reached the limit of %d learned categories, rejecting new category",
+			session.ID, g.MaxLearnedPerSession)
+		return fmt.Errorf("learned category limit reached (%d)", g.MaxLearnedPerSession)
+	}
+
+	evicted := false
+	for len(session.LearnedOrder) >= g.MaxLearnedPerSession {
+		oldest := session.LearnedOrder[0]
+		session.LearnedOrder = session.LearnedOrder[1:]
+		if g.evictLearnedCategory(session, oldest) {
+			g.LogWarn("Session %s reached the limit of %d learned categories, evicted oldest: %s",
+				session.ID, g.MaxLearnedPerSession, oldest)
+			evicted = true
+		}
+	}
+	if evicted {
+		g.InvalidatePatternMatchingKnowledgeBase()
+	}
+	return nil
+}
+
+// evictLearnedCategory removes the learned category indexed under key from the
+// knowledge base and from session's learning records. Returns false if it's gone
+// already (e.g. unlearned). It runs while a template learns, so the caller holds
+// learnMutex for writing to keep other sessions from matching mid-removal.
+func (g *Golem) evictLearnedCategory(session *ChatSession, key string) bool {
+	removed := g.aimlKB.removeCategories(func(category *Category) bool {
+		return category.Learned && categoryPatternKey(category) == key
+	})
+	if len(removed) == 0 {
+		return false
+	}
+
+	for i := range session.LearnedCategories {
+		if categoryPatternKey(&session.LearnedCategories[i]) == key {
+			session.LearnedCategories = append(session.LearnedCategories[:i], session.LearnedCategories[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// updateCategory replaces the category indexed under key with category. The index
+// can point into a backing array that appending to Categories has since replaced, so
+// the category is replaced in Categories (the last one with the key, which the index
+// holds) and the index repointed at it. Callers hold learnMutex.
+func (kb *AIMLKnowledgeBase) updateCategory(key string, category Category) {
+	if category.PriorityBoost != 0 {
+		kb.markBoosted(key)
+	}
+	for i := len(kb.Categories) - 1; i >= 0; i-- {
+		if categoryPatternKey(&kb.Categories[i]) == key {
+			kb.Categories[i] = category
+			kb.Patterns[key] = &kb.Categories[i]
+			return
+		}
+	}
+	kb.Categories = append(kb.Categories, category)
+	kb.Patterns[key] = &kb.Categories[len(kb.Categories)-1]
+}
+
+// removeCategories removes the categories remove reports true for and rebuilds the
+// pattern index, returning the removed categories. Callers hold learnMutex.
+func (kb *AIMLKnowledgeBase) removeCategories(remove func(*Category) bool) []Category {
+	var removed []Category
+	kept := make([]Category, 0, len(kb.Categories))
+	for i := range kb.Categories {
+		if remove(&kb.Categories[i]) {
+			removed = append(removed, kb.Categories[i])
+			continue
+		}
+		kept = append(kept, kb.Categories[i])
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	kb.Categories = kept
+	kb.reindexPatterns()
+	return removed
+}
+
+// reindexPatterns rebuilds the pattern index after categories were removed, since
+// removing from the slice moves the categories the index points at
+func (kb *AIMLKnowledgeBase) reindexPatterns() {
+	kb.Patterns = make(map[string]*Category, len(kb.Categories))
+	kb.boostedKeys = nil
+	for i := range kb.Categories {
+		category := &kb.Categories[i]
+		key := categoryPatternKey(category)
+		kb.Patterns[key] = category
+		if category.PriorityBoost != 0 {
+			kb.markBoosted(key)
+		}
+		if category.Default {
+			kb.Patterns["DEFAULT"] = category
+		}
+	}
+}
+
+// removeLearnedKey returns order without key
+func removeLearnedKey(order []string, key string) []string {
+	for i, k := range order {
+		if k == key {
+			return append(order[:i], order[i+1:]...)
+		}
+	}
+	return order
+}
@@ -0,0 +1,182 @@
+package golem
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMaxLearnedPerSession(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>TEACH *</pattern>
+        <template><learn><category><pattern><eval><star/></eval></pattern><template>Learned <eval><star/></eval></template></category></learn>OK</template>
+    </category>
+    <category>
+        <pattern>*</pattern>
+        <template>Unknown</template>
+    </category>
+</aiml>`
+
+	tests := []struct {
+		name      string
+		reject    bool
+		known     []string
+		unknown   []string
+		logSubstr string
+	}{
+		{"evict oldest", false, []string{"beta", "gamma"}, []string{"alpha"}, "evicted oldest: ALPHA"},
+		{"reject new", true, []string{"alpha", "beta"}, []string{"gamma"}, "rejecting new category"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			var logs bytes.Buffer
+			g.logger = log.New(&logs, "", 0)
+			g.SetLogLevel(LogLevelWarn)
+			g.MaxLearnedPerSession = 2
+			g.RejectLearnedOverLimit = tt.reject
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+
+			session := g.CreateSession("learn_limit")
+			for _, word := range []string{"alpha", "beta", "gamma"} {
+				if _, err := g.ProcessInput("teach "+word, session); err != nil {
+					t.Fatalf("ProcessInput(teach %s) failed: %v", word, err)
+				}
+			}
+
+			for _, word := range tt.known {
+				response, _ := g.ProcessInput(word, session)
+				if response != "Learned "+word {
+					t.Errorf("Expected %s to be learned, got %q", word, response)
+				}
+			}
+			for _, word := range tt.unknown {
+				response, _ := g.ProcessInput(word, session)
+				if response != "Unknown" {
+					t.Errorf("Expected %s not to be learned, got %q", word, response)
+				}
+			}
+			if len(session.LearnedOrder) != 2 || len(g.ListLearnedCategories()) != 2 {
+				t.Errorf("Expected 2 learned categories, got order %v and %d in the knowledge base",
+					session.LearnedOrder, len(g.ListLearnedCategories()))
+			}
+			if !strings.Contains(logs.String(), tt.logSubstr) {
+				t.Errorf("Expected a log containing %q, got: %s", tt.logSubstr, logs.String())
+			}
+
+			// The limit is per session
+			other := g.CreateSession("learn_limit_other")
+			if _, err := g.ProcessInput("teach delta", other); err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response, _ := g.ProcessInput("delta", other); response != "Learned delta" {
+				t.Errorf("Expected another session to learn independently, got %q", response)
+			}
+		})
+	}
+}
+
+func TestEvictionKeepsRelearnedTemplate(t *testing.T) {
+	g := NewForTesting(t, false)
+	g.MaxLearnedPerSession = 8
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>TEACH * AS *</pattern>
+        <template><learn><category><pattern><eval><star/></eval></pattern><template><eval><star index="2"/></eval></template></category></learn>OK</template>
+    </category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("evict_relearned")
+	// Enough categories after APPLES that appending moves the categories slice, then
+	// APPLES is taught again and one more category evicts the oldest (OLDEST)
+	inputs := []string{"teach oldest as gone", "teach apples as first"}
+	for _, fruit := range []string{"plums", "figs", "limes", "dates", "kiwis", "grapes"} {
+		inputs = append(inputs, "teach "+fruit+" as fruit")
+	}
+	inputs = append(inputs, "teach apples as second", "teach pears as fruit")
+	for _, input := range inputs {
+		if _, err := g.ProcessInput(input, session); err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", input, err)
+		}
+	}
+
+	if _, err := g.ProcessInput("oldest", session); err == nil {
+		t.Error("Expected the oldest learned category to be evicted")
+	}
+	if response, err := g.ProcessInput("apples", session); err != nil || response != "second" {
+		t.Errorf("Expected apples to keep its relearned template after eviction, got %q (err: %v)", response, err)
+	}
+}
+
+// TestConcurrentLearningEviction tests that sessions learning past the limit, which
+// evicts categories, don't race with another session matching. Run with -race.
+func TestConcurrentLearningEviction(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>TEACH *</pattern>
+        <template><learn><category><pattern><eval><star/></eval></pattern><template>Learned <eval><star/></eval></template></category></learn>OK</template>
+    </category>
+    <category>
+        <pattern>HELLO</pattern>
+        <template>Hi</template>
+    </category>
+    <category>
+        <pattern>*</pattern>
+        <template>Unknown</template>
+    </category>
+</aiml>`
+
+	g := NewForTesting(t, false)
+	g.MaxLearnedPerSession = 3
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	learners := []*ChatSession{g.CreateSession("learner_a"), g.CreateSession("learner_b")}
+	matcher := g.CreateSession("matcher")
+
+	var wg sync.WaitGroup
+	for i, session := range learners {
+		wg.Add(1)
+		go func(i int, session *ChatSession) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				if _, err := g.ProcessInput(fmt.Sprintf("teach word%d%c", n, 'a'+i), session); err != nil {
+					t.Errorf("ProcessInput(teach) failed: %v", err)
+				}
+			}
+		}(i, session)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 40; n++ {
+			if response, err := g.ProcessInput("hello", matcher); err != nil || response != "Hi" {
+				t.Errorf("Expected Hi while others learn, got %q (%v)", response, err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	for _, session := range learners {
+		if len(session.LearnedOrder) != g.MaxLearnedPerSession {
+			t.Errorf("Expected session %s to keep %d learned categories, got %d",
+				session.ID, g.MaxLearnedPerSession, len(session.LearnedOrder))
+		}
+	}
+	if learned := len(g.ListLearnedCategories()); learned != 2*g.MaxLearnedPerSession {
+		t.Errorf("Expected %d learned categories in the knowledge base, got %d", 2*g.MaxLearnedPerSession, learned)
+	}
+}
@@ -3,6 +3,8 @@ package golem
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -262,3 +264,196 @@ func TestLoadAllRelatedFiles(t *testing.T) {
 	t.Logf("Substitutions: %d", len(g.aimlKB.Substitutions))
 	t.Logf("Properties: %d", len(g.aimlKB.Properties))
 }
+
+func TestReloadKnowledgeBase(t *testing.T) {
+	g := NewForTesting(t, false)
+	dir := t.TempDir()
+	aimlPath := filepath.Join(dir, "bot.aiml")
+
+	writeBot := func(categories string) {
+		content := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">` + categories + `
+</aiml>`
+		if err := os.WriteFile(aimlPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write AIML: %v", err)
+		}
+	}
+
+	writeBot(`
+<category><pattern>MY NAME IS *</pattern><template><think><set name="name"><star/></set></think>Hi <get name="name"/>.</template></category>
+<category><pattern>HELLO</pattern><template>Version one.</template></category>`)
+	if err := g.ReloadKnowledgeBase(dir); err != nil {
+		t.Fatalf("Initial load failed: %v", err)
+	}
+
+	session := g.CreateSession("reload-test")
+	if response, err := g.ProcessInput("my name is Ada", session); err != nil || response != "Hi Ada." {
+		t.Fatalf("Expected greeting, got %q (err: %v)", response, err)
+	}
+	if response, err := g.ProcessInput("hello", session); err != nil || response != "Version one." {
+		t.Fatalf("Expected version one, got %q (err: %v)", response, err)
+	}
+
+	// Reload with changed categories
+	writeBot(`
+<category><pattern>HELLO</pattern><template>Version two, <get name="name"/>.</template></category>
+<category><pattern>GOODBYE</pattern><template>Bye.</template></category>`)
+	if err := g.ReloadKnowledgeBase(dir); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if response, err := g.ProcessInput("hello", session); err != nil || response != "Version two, Ada." {
+		t.Errorf("Expected reloaded category with session variable intact, got %q (err: %v)", response, err)
+	}
+	if response, err := g.ProcessInput("goodbye", session); err != nil || response != "Bye." {
+		t.Errorf("Expected new category to match, got %q (err: %v)", response, err)
+	}
+	if _, err := g.ProcessInput("my name is Bob", session); err == nil {
+		t.Error("Expected removed category to no longer match")
+	}
+	if g.sessions["reload-test"] != session {
+		t.Error("Expected session to survive the reload")
+	}
+
+	// A failed reload keeps the current knowledge base
+	if err := g.ReloadKnowledgeBase(t.TempDir()); err == nil {
+		t.Fatal("Expected reloading an empty directory to fail")
+	}
+	if response, err := g.ProcessInput("goodbye", session); err != nil || response != "Bye." {
+		t.Errorf("Expected previous knowledge base after failed reload, got %q (err: %v)", response, err)
+	}
+}
+
+func TestReloadKnowledgeBaseKeepsOldOnBrokenFile(t *testing.T) {
+	g := NewForTesting(t, false)
+	dir := t.TempDir()
+
+	good := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category><pattern>HELLO</pattern><template>Version one.</template></category>
+</aiml>`
+	if err := os.WriteFile(filepath.Join(dir, "good.aiml"), []byte(good), 0644); err != nil {
+		t.Fatalf("Failed to write AIML: %v", err)
+	}
+	if err := g.ReloadKnowledgeBase(dir); err != nil {
+		t.Fatalf("Initial load failed: %v", err)
+	}
+
+	// The good file changes, but another file in the directory is malformed
+	good = strings.Replace(good, "Version one.", "Version two.", 1)
+	if err := os.WriteFile(filepath.Join(dir, "good.aiml"), []byte(good), 0644); err != nil {
+		t.Fatalf("Failed to write AIML: %v", err)
+	}
+	broken := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category><pattern>BROKEN</pattern><template>Unclosed
+</aiml>`
+	if err := os.WriteFile(filepath.Join(dir, "broken.aiml"), []byte(broken), 0644); err != nil {
+		t.Fatalf("Failed to write AIML: %v", err)
+	}
+
+	err := g.ReloadKnowledgeBase(dir)
+	if err == nil {
+		t.Fatal("Expected reloading a directory with a malformed file to fail")
+	}
+	if !strings.Contains(err.Error(), "broken.aiml") {
+		t.Errorf("Expected the error to name the malformed file, got %v", err)
+	}
+
+	session := g.CreateSession("reload-broken")
+	if response, err := g.ProcessInput("hello", session); err != nil || response != "Version one." {
+		t.Errorf("Expected the previous knowledge base after failed reload, got %q (err: %v)", response, err)
+	}
+
+	// Loading the directory directly still skips the malformed file
+	kb, err := g.LoadAIMLFromDirectory(dir)
+	if err != nil {
+		t.Fatalf("Expected LoadAIMLFromDirectory to skip the malformed file, got %v", err)
+	}
+	if len(kb.Categories) != 1 {
+		t.Errorf("Expected 1 category from the good file, got %d", len(kb.Categories))
+	}
+
+	// A reload running at the same time doesn't change how a direct load treats it
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			if g.ReloadKnowledgeBase(dir) == nil {
+				t.Error("Expected each concurrent reload to fail")
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			if _, err := g.LoadAIMLFromDirectory(dir); err != nil {
+				t.Errorf("Expected each concurrent load to skip the malformed file, got %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+// TestLoadAIMLWithBOMAndCRLF tests loading an AIML file authored on Windows
+func TestLoadAIMLWithBOMAndCRLF(t *testing.T) {
+	content := "\ufeff<?xml version=\"1.0\" encoding=\"UTF-8\"?>\r\n" +
+		"<aiml version=\"2.0\">\r\n" +
+		"<category>\r\n" +
+		"<pattern>HELLO\r\nTHERE</pattern>\r\n" +
+		"<template>Hi!</template>\r\n" +
+		"</category>\r\n" +
+		"<category>\r\n" +
+		"<pattern>GOODBYE</pattern>\r\n" +
+		"<template>Bye\r\nnow</template>\r\n" +
+		"</category>\r\n" +
+		"</aiml>\r\n"
+
+	filename := filepath.Join(t.TempDir(), "windows.aiml")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write AIML file: %v", err)
+	}
+
+	g := NewForTesting(t, false)
+	kb, err := g.LoadAIML(filename)
+	if err != nil {
+		t.Fatalf("Failed to load AIML file: %v", err)
+	}
+	if len(kb.Categories) != 2 {
+		t.Fatalf("Expected 2 categories, got %d", len(kb.Categories))
+	}
+	for _, category := range kb.Categories {
+		if strings.Contains(category.Pattern, "\r") || strings.Contains(category.Template, "\r") {
+			t.Errorf("Category still contains a carriage return: %q -> %q", category.Pattern, category.Template)
+		}
+	}
+
+	g.SetKnowledgeBase(kb)
+	session := g.CreateSession("windows")
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"hello there", "Hi!"},
+		{"goodbye", "Bye\nnow"},
+	}
+	for _, tt := range tests {
+		response, err := g.ProcessInput(tt.input, session)
+		if err != nil {
+			t.Errorf("ProcessInput(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if response != tt.expected {
+			t.Errorf("ProcessInput(%q) = %q, want %q", tt.input, response, tt.expected)
+		}
+	}
+
+	// Content loaded from a string gets the same treatment
+	if err := g.LoadAIMLFromString(content); err != nil {
+		t.Fatalf("Failed to load AIML from string: %v", err)
+	}
+	if response, err := g.ProcessInput("hello there", session); err != nil || response != "Hi!" {
+		t.Errorf("Expected 'Hi!' after loading from string, got %q (%v)", response, err)
+	}
+}
@@ -0,0 +1,69 @@
+package golem
+
+import (
+	"strings"
+)
+
+// LanguageVariable is the session variable that selects the language of bot
+// properties: with it set to "es", <bot name="name"/> reads the "name.es" property
+// when there is one
+const LanguageVariable = "language"
+
+// localizedPropertyKey returns the property key holding a key's value for a language:
+// "key.<language>" or, for a regional language such as "es-MX", "key.es". It
+// reports false when the knowledge base has neither.
+func (kb *AIMLKnowledgeBase) localizedPropertyKey(key, language string) (string, bool) {
+	language = strings.TrimSpace(language)
+	if language == "" {
+		return "", false
+	}
+	candidates := []string{key + "." + language}
+	if base, _, regional := strings.Cut(strings.ReplaceAll(language, "_", "-"), "-"); regional {
+		candidates = append(candidates, key+"."+base)
+	}
+	for _, candidate := range candidates {
+		if _, exists := kb.Properties[candidate]; exists {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// GetPropertyForLanguage returns a property in the given language, preferring
+// "key.<language>" (e.g. "name.es") and falling back to GetProperty(key)
+func (kb *AIMLKnowledgeBase) GetPropertyForLanguage(key, language string) string {
+	if localized, ok := kb.localizedPropertyKey(key, language); ok {
+		return kb.Properties[localized]
+	}
+	return kb.GetProperty(key)
+}
+
+// GetLanguage returns the session's property language (see LanguageVariable)
+func (session *ChatSession) GetLanguage() string {
+	return session.Variables[LanguageVariable]
+}
+
+// SetLanguage sets the session's property language (see LanguageVariable). An empty
+// language restores the base properties.
+func (session *ChatSession) SetLanguage(language string) {
+	if session.Variables == nil {
+		session.Variables = make(map[string]string)
+	}
+	if language == "" {
+		delete(session.Variables, LanguageVariable)
+		return
+	}
+	session.Variables[LanguageVariable] = language
+}
+
+// language returns the language bot properties are read in: the session's language
+// or, without a session, the global language variable
+func (ctx *VariableContext) language() string {
+	if ctx.Session != nil {
+		return ctx.Session.GetLanguage()
+	}
+	if ctx.KnowledgeBase != nil {
+		return ctx.KnowledgeBase.Variables[LanguageVariable]
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package golem
+
+import (
+	"testing"
+)
+
+func TestLocalizedProperties(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>WHO ARE YOU</pattern>
+		<template><bot name="greeting"/> I am <bot name="name"/>, version <bot name="version"/>.</template>
+	</category>
+	<category>
+		<pattern>SPEAK *</pattern>
+		<template><think><set name="language"><star/></set></think>OK.</template>
+	</category>
+</aiml>`
+
+	engines := map[string]bool{"tree": false, "legacy": true}
+	for name, legacy := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if legacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			kb := g.GetKnowledgeBase()
+			kb.SetProperty("name", "Golem")
+			kb.SetProperty("name.es", "Gólem")
+			kb.SetProperty("name.fr", "Golème")
+			kb.SetProperty("greeting", "Hello!")
+			kb.SetProperty("greeting.es", "¡Hola!")
+			kb.SetProperty("greeting.fr", "Bonjour !")
+			kb.SetProperty("version", "1.0")
+
+			session := g.CreateSession("localized")
+			ask := func() string {
+				response, err := g.ProcessInput("who are you", session)
+				if err != nil {
+					t.Fatalf("ProcessInput failed: %v", err)
+				}
+				return response
+			}
+
+			if response := ask(); response != "Hello! I am Golem, version 1.0." {
+				t.Errorf("Without a language: got '%s'", response)
+			}
+
+			// Properties without a translation fall back to the base key
+			session.SetLanguage("es")
+			if response := ask(); response != "¡Hola! I am Gólem, version 1.0." {
+				t.Errorf("In Spanish: got '%s'", response)
+			}
+
+			// The language can be switched from a template
+			if _, err := g.ProcessInput("speak fr", session); err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if session.GetLanguage() != "fr" {
+				t.Errorf("Expected the template to set the language, got '%s'", session.GetLanguage())
+			}
+			if response := ask(); response != "Bonjour ! I am Golème, version 1.0." {
+				t.Errorf("In French: got '%s'", response)
+			}
+
+			// Regional languages fall back to their base language
+			session.SetLanguage("es-MX")
+			if response := ask(); response != "¡Hola! I am Gólem, version 1.0." {
+				t.Errorf("In Mexican Spanish: got '%s'", response)
+			}
+
+			// Unknown languages and clearing the language use the base properties
+			session.SetLanguage("de")
+			if response := ask(); response != "Hello! I am Golem, version 1.0." {
+				t.Errorf("In German: got '%s'", response)
+			}
+			session.SetLanguage("")
+			if response := ask(); response != "Hello! I am Golem, version 1.0." {
+				t.Errorf("After clearing the language: got '%s'", response)
+			}
+		})
+	}
+
+	kb := NewAIMLKnowledgeBase()
+	kb.SetProperty("name", "Golem")
+	kb.SetProperty("name.es", "Gólem")
+	if value := kb.GetPropertyForLanguage("name", "es"); value != "Gólem" {
+		t.Errorf("GetPropertyForLanguage(name, es) = '%s'", value)
+	}
+	if value := kb.GetPropertyForLanguage("name", "it"); value != "Golem" {
+		t.Errorf("GetPropertyForLanguage(name, it) = '%s'", value)
+	}
+}
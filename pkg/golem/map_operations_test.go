@@ -1,6 +1,8 @@
 package golem
 
 import (
+	"bytes"
+	"log"
 	"strings"
 	"testing"
 )
@@ -379,3 +381,73 @@ func TestMapTagEdgeCases(t *testing.T) {
 		t.Errorf("Expected response to contain 'Removed', got '%s'", response)
 	}
 }
+
+func TestMapTagReverseLookup(t *testing.T) {
+	g := NewForTesting(t, false)
+	var logs bytes.Buffer
+	g.logger = log.New(&logs, "", 0)
+	g.SetLogLevel(LogLevelWarn)
+
+	aimlContent := `
+<aiml version="2.0">
+    <category>
+        <pattern>CAPITAL OF *</pattern>
+        <template><map name="country2capital"><star/></map></template>
+    </category>
+    <category>
+        <pattern>COUNTRY WITH CAPITAL *</pattern>
+        <template><map name="country2capital" mode="reverse"><star/></map></template>
+    </category>
+    <category>
+        <pattern>ADD CAPITAL * *</pattern>
+        <template><map name="country2capital" key="<star/>" operation="set"><star index="2"/></map>Added.</template>
+    </category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	g.aimlKB.Maps["country2capital"] = map[string]string{
+		"France":  "Paris",
+		"Japan":   "Tokyo",
+		"Ireland": "Dublin",
+		"Eire":    "Dublin",
+	}
+	session := g.CreateSession("reverse-map")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"CAPITAL OF France", "Paris"},
+		{"COUNTRY WITH CAPITAL Paris", "France"},
+		{"COUNTRY WITH CAPITAL Tokyo", "Japan"},
+		{"COUNTRY WITH CAPITAL Atlantis", "Atlantis"}, // Unknown values are returned unchanged
+		{"COUNTRY WITH CAPITAL Dublin", "Eire"},       // Non-unique values return the first key in sorted order
+	}
+	for _, tt := range tests {
+		response, err := g.ProcessInput(tt.input, session)
+		if err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+		}
+		if response != tt.expected {
+			t.Errorf("ProcessInput(%q) = '%s', expected '%s'", tt.input, response, tt.expected)
+		}
+	}
+
+	if !strings.Contains(logs.String(), "Map 'country2capital' has value 'Dublin' for keys 'Eire' and 'Ireland'") {
+		t.Errorf("Expected a duplicate value warning, got logs: %s", logs.String())
+	}
+
+	// Changing the map refreshes the cached inverse
+	if _, err := g.ProcessInput("ADD CAPITAL Italy Rome", session); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	response, err := g.ProcessInput("COUNTRY WITH CAPITAL Rome", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if response != "Italy" {
+		t.Errorf("Expected reverse lookup to see the new entry, got '%s'", response)
+	}
+}
@@ -0,0 +1,151 @@
+package golem
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Stages at which a match can be made, in the order they are tried
+const (
+	MatchStageDollar   = "dollar"   // $-prefixed exact pattern
+	MatchStageExact    = "exact"    // Exact pattern key lookup
+	MatchStageWildcard = "wildcard" // Highest priority candidate of the matching loop
+	MatchStageDefault  = "default"  // The DEFAULT category
+)
+
+// Reasons recorded for each candidate considered by the matching loop
+const (
+	MatchReasonMatched           = "matched"
+	MatchReasonTopicMismatch     = "topic mismatch"
+	MatchReasonThatMismatch      = "that mismatch"
+	MatchReasonThatIndexMismatch = "that index mismatch"
+	MatchReasonPatternMismatch   = "pattern mismatch"
+)
+
+// MatchCandidate records the decision made about one category during matching
+type MatchCandidate struct {
+	Key      string    `json:"key"` // Pattern index key (pattern, that, that index and topic)
+	Pattern  string    `json:"pattern"`
+	That     string    `json:"that,omitempty"`
+	Topic    string    `json:"topic,omitempty"`
+	Category *Category `json:"-"`
+	Included bool      `json:"included"`           // Passed every filter and is ranked by priority
+	Selected bool      `json:"selected"`           // The category the match returned
+	Reason   string    `json:"reason"`             // MatchReasonMatched or why it was excluded
+	Priority int       `json:"priority,omitempty"` // Ranking priority of included candidates
+}
+
+// MatchTrace explains a single matching decision: how the input was normalized, the
+// context it was matched in, and why each candidate was included or excluded. An
+// exact or $ match returns before the matching loop runs, so it has no candidates,
+// except that an exact match is ranked against any categories with a priority boost.
+type MatchTrace struct {
+	OriginalInput       string            `json:"original_input"`
+	NormalizedInput     string            `json:"normalized_input"`
+	CasePreservingInput string            `json:"case_preserving_input"`
+	Topic               string            `json:"topic,omitempty"`
+	That                string            `json:"that,omitempty"` // Normalized that context
+	Stage               string            `json:"stage,omitempty"`
+	Matched             *Category         `json:"-"`
+	MatchedPattern      string            `json:"matched_pattern,omitempty"`
+	Wildcards           map[string]string `json:"wildcards,omitempty"`
+	Candidates          []MatchCandidate  `json:"candidates"`
+}
+
+// MatchPatternDebug matches input like MatchInput and returns a trace of the decisions
+// made along the way, for debuggers and analysis tools. An input that matches nothing
+// still returns its trace alongside the error.
+func (g *Golem) MatchPatternDebug(input string, session *ChatSession) (*MatchTrace, error) {
+	if g.aimlKB == nil {
+		return nil, fmt.Errorf("no AIML knowledge base loaded")
+	}
+	if err := g.checkInputLength(input); err != nil {
+		return nil, err
+	}
+
+	trace := &MatchTrace{
+		OriginalInput:       input,
+		CasePreservingInput: g.CachedNormalizeForMatchingCasePreserving(input),
+	}
+
+	normalizedInput := g.CachedNormalizePattern(input)
+	normalizedThat := ""
+	if lastThat := session.GetLastThat(); lastThat != "" {
+		normalizedThat = g.CachedNormalizeThatPattern(lastThat)
+	}
+
+	_, wildcards, err := g.aimlKB.matchPatternTraced(g, normalizedInput, g.captureInput(input), session.GetSessionTopic(), normalizedThat, 0, trace)
+	trace.Wildcards = wildcards
+
+	// Ranked candidates first, best first, then the excluded ones by key
+	sort.SliceStable(trace.Candidates, func(i, j int) bool {
+		a, b := trace.Candidates[i], trace.Candidates[j]
+		if a.Included != b.Included {
+			return a.Included
+		}
+		if a.Included {
+			// The same order the matcher ranks them in
+			return preferPattern(
+				PatternPriority{Priority: a.Priority, LiteralWords: calculatePatternPriority(a.Pattern).LiteralWords, Key: a.Key},
+				PatternPriority{Priority: b.Priority, LiteralWords: calculatePatternPriority(b.Pattern).LiteralWords, Key: b.Key})
+		}
+		return a.Key < b.Key
+	})
+
+	return trace, err
+}
+
+// setContext records the normalized input and context the match is made in
+func (trace *MatchTrace) setContext(input, topic, that string) {
+	if trace == nil {
+		return
+	}
+	trace.NormalizedInput = input
+	trace.Topic = topic
+	trace.That = that
+}
+
+// exclude records a candidate rejected by the matching loop
+func (trace *MatchTrace) exclude(key string, category *Category, reason string) {
+	if trace == nil {
+		return
+	}
+	trace.Candidates = append(trace.Candidates, newMatchCandidate(key, category, reason))
+}
+
+// include records a candidate that passed every filter
+func (trace *MatchTrace) include(key string, category *Category, priority int) {
+	if trace == nil {
+		return
+	}
+	candidate := newMatchCandidate(key, category, MatchReasonMatched)
+	candidate.Included = true
+	candidate.Priority = priority
+	trace.Candidates = append(trace.Candidates, candidate)
+}
+
+// setMatch records the category the match returned and the stage that found it
+func (trace *MatchTrace) setMatch(stage string, category *Category) {
+	if trace == nil {
+		return
+	}
+	trace.Stage = stage
+	trace.Matched = category
+	trace.MatchedPattern = category.Pattern
+	for i := range trace.Candidates {
+		if trace.Candidates[i].Category == category {
+			trace.Candidates[i].Selected = true
+		}
+	}
+}
+
+func newMatchCandidate(key string, category *Category, reason string) MatchCandidate {
+	return MatchCandidate{
+		Key:      key,
+		Pattern:  category.Pattern,
+		That:     category.That,
+		Topic:    category.Topic,
+		Category: category,
+		Reason:   reason,
+	}
+}
@@ -0,0 +1,105 @@
+package golem
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMatchPatternDebugExplainsTopicExclusion(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>TELL ME ABOUT *</pattern>
+		<topic>SPORTS</topic>
+		<template>Sports talk about <star/>.</template>
+	</category>
+	<category>
+		<pattern>TELL ME *</pattern>
+		<template>Here is <star/>.</template>
+	</category>
+	<category>
+		<pattern>HELLO</pattern>
+		<template>Hi!</template>
+	</category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("match-trace-test")
+
+	// Exact matches return before the matching loop
+	trace, err := g.MatchPatternDebug("hello", session)
+	if err != nil {
+		t.Fatalf("MatchPatternDebug failed: %v", err)
+	}
+	if trace.Stage != MatchStageExact || trace.MatchedPattern != "HELLO" || len(trace.Candidates) != 0 {
+		t.Errorf("Expected an exact match with no candidates, got %+v", trace)
+	}
+
+	session.SetSessionTopic("music")
+
+	trace, err = g.MatchPatternDebug("Tell me about Jazz!", session)
+	if err != nil {
+		t.Fatalf("MatchPatternDebug failed: %v", err)
+	}
+
+	if trace.OriginalInput != "Tell me about Jazz!" || trace.NormalizedInput != "TELL ME ABOUT JAZZ" {
+		t.Errorf("Unexpected normalization: %q -> %q", trace.OriginalInput, trace.NormalizedInput)
+	}
+	if trace.CasePreservingInput != "Tell me about Jazz" {
+		t.Errorf("Expected case-preserving input %q, got %q", "Tell me about Jazz", trace.CasePreservingInput)
+	}
+	if trace.Topic != "music" {
+		t.Errorf("Expected topic 'music', got %q", trace.Topic)
+	}
+	if trace.Stage != MatchStageWildcard || trace.MatchedPattern != "TELL ME *" {
+		t.Errorf("Expected wildcard match of 'TELL ME *', got stage %q pattern %q", trace.Stage, trace.MatchedPattern)
+	}
+	if trace.Wildcards["star1"] != "about Jazz!" {
+		t.Errorf("Expected star1 'about Jazz!', got %q", trace.Wildcards["star1"])
+	}
+
+	reasons := make(map[string]MatchCandidate)
+	for _, candidate := range trace.Candidates {
+		reasons[candidate.Pattern] = candidate
+	}
+	if c := reasons["TELL ME ABOUT *"]; c.Included || c.Reason != MatchReasonTopicMismatch || c.Topic != "SPORTS" {
+		t.Errorf("Expected topic-filtered exclusion of 'TELL ME ABOUT *', got %+v", c)
+	}
+	if c := reasons["TELL ME *"]; !c.Included || !c.Selected || c.Reason != MatchReasonMatched {
+		t.Errorf("Expected 'TELL ME *' to be included and selected, got %+v", c)
+	}
+	if c := reasons["HELLO"]; c.Included || c.Reason != MatchReasonPatternMismatch {
+		t.Errorf("Expected 'HELLO' to be excluded as a pattern mismatch, got %+v", c)
+	}
+	if !trace.Candidates[0].Selected {
+		t.Errorf("Expected the selected candidate first, got %+v", trace.Candidates[0])
+	}
+
+	if _, err := json.Marshal(trace); err != nil {
+		t.Errorf("Trace should be JSON serializable: %v", err)
+	}
+
+	// In the matching topic the topic category wins
+	session.SetSessionTopic("sports")
+	trace, err = g.MatchPatternDebug("tell me about jazz", session)
+	if err != nil {
+		t.Fatalf("MatchPatternDebug failed: %v", err)
+	}
+	if trace.MatchedPattern != "TELL ME ABOUT *" {
+		t.Errorf("Expected 'TELL ME ABOUT *' in topic sports, got %q", trace.MatchedPattern)
+	}
+
+	// Unmatched inputs still return their trace
+	trace, err = g.MatchPatternDebug("goodbye", session)
+	if err == nil {
+		t.Error("Expected an error for unmatched input")
+	}
+	if trace == nil || trace.Stage != "" || len(trace.Candidates) != 3 {
+		t.Errorf("Expected a trace with three excluded candidates, got %+v", trace)
+	}
+}
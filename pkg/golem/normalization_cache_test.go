@@ -1,6 +1,8 @@
 package golem
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -294,3 +296,58 @@ func TestTextNormalizationCachePerformance(t *testing.T) {
 		t.Error("Expected cache hits to be recorded")
 	}
 }
+
+func TestTextNormalizationCacheConcurrent(t *testing.T) {
+	g := New(false)
+	g.textNormalizationCache = NewTextNormalizationCache(16, 300)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				input := fmt.Sprintf("input number %d", (worker+i)%32)
+				if got := g.CachedNormalizeForMatchingCasePreserving(input); got != NormalizeForMatchingCasePreserving(input) {
+					t.Errorf("Unexpected result for %q: %q", input, got)
+					return
+				}
+				g.GetTextNormalizationCacheStats()
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	if results := g.GetTextNormalizationCacheStats()["results"].(int); results > 16 {
+		t.Errorf("Cache grew past its max size: %d results", results)
+	}
+}
+
+// benchmarkInputs is a small set of inputs that recur, as they do with chatty clients
+var benchmarkInputs = []string{
+	"Hello there!", "What's your name?", "Tell me about the weather today.",
+	"I'm fine, thanks.", "What time is it?", "Goodbye!",
+}
+
+// BenchmarkNormalizeRepeatedInputs measures normalizing recurring inputs through the
+// text normalization cache
+func BenchmarkNormalizeRepeatedInputs(b *testing.B) {
+	g := New(false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		input := benchmarkInputs[i%len(benchmarkInputs)]
+		g.CachedNormalizePattern(input)
+		g.CachedNormalizeForMatchingCasePreserving(input)
+	}
+}
+
+// BenchmarkNormalizeRepeatedInputsUncached measures the same inputs without the cache
+func BenchmarkNormalizeRepeatedInputsUncached(b *testing.B) {
+	g := New(false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		input := benchmarkInputs[i%len(benchmarkInputs)]
+		g.normalizeInputForMatching(input)
+		NormalizeForMatchingCasePreserving(input)
+	}
+}
@@ -198,6 +198,63 @@ func TestNormalizeDenormalizeIntegration(t *testing.T) {
 	}
 }
 
+// TestNormalizeDenormalizeNestedFormatting tests nested normalize, denormalize and
+// formatting tags, which both engines resolve inner-first
+func TestNormalizeDenormalizeNestedFormatting(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "Formatting inside normalize",
+			template: "<normalize><lowercase>Hello, World!</lowercase></normalize>",
+			expected: "HELLO WORLD",
+		},
+		{
+			name:     "Normalize inside formatting",
+			template: "<lowercase><normalize>Hello, World!</normalize></lowercase>",
+			expected: "hello world",
+		},
+		{
+			name:     "Normalize inside normalize",
+			template: "<normalize>Hi, <normalize>there!</normalize> you</normalize>",
+			expected: "HI THERE YOU",
+		},
+		{
+			name:     "Denormalize inside denormalize",
+			template: "<denormalize>HELLO <denormalize>THERE</denormalize></denormalize>",
+			expected: "Hello there.",
+		},
+		{
+			name:     "Formatting around nested tags",
+			template: "<uppercase><denormalize><normalize><lowercase>Good Morning!</lowercase></normalize></denormalize></uppercase>",
+			expected: "GOOD MORNING.",
+		},
+		{
+			name:     "Sibling tags",
+			template: "<normalize>it's</normalize> and <denormalize><uppercase>ok</uppercase></denormalize>",
+			expected: "IT IS and Ok.",
+		},
+	}
+
+	engines := map[string]bool{"tree": false, "legacy": true}
+	for engine, legacy := range engines {
+		for _, tt := range tests {
+			t.Run(engine+"/"+tt.name, func(t *testing.T) {
+				g := NewForTesting(t, false)
+				if legacy {
+					g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+				}
+				result := g.ProcessTemplate(tt.template, make(map[string]string))
+				if result != tt.expected {
+					t.Errorf("ProcessTemplate() = %q, want %q", result, tt.expected)
+				}
+			})
+		}
+	}
+}
+
 // TestNormalizeDenormalizeWithWildcards tests normalize and denormalize with wildcards
 func TestNormalizeDenormalizeWithWildcards(t *testing.T) {
 	g := NewForTesting(t, false) // Disable verbose mode for cleaner test output
@@ -381,6 +438,86 @@ func TestNormalizeDenormalizeEdgeCases(t *testing.T) {
 	}
 }
 
+// TestCustomNormalizationRules tests <normalize> and input matching with a custom ruleset
+func TestCustomNormalizationRules(t *testing.T) {
+	g := NewForTesting(t, false)
+	g.SetNormalizationRules(&NormalizationRules{
+		CharsToSpace:  "/",
+		CharsToDelete: ",!?-",
+	})
+
+	t.Run("NormalizeTag", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			template string
+			expected string
+		}{
+			{
+				name:     "Chars to space",
+				template: "<normalize>red/green/blue</normalize>",
+				expected: "RED GREEN BLUE",
+			},
+			{
+				name:     "Chars to delete",
+				template: "<normalize>e-mail, please!</normalize>",
+				expected: "EMAIL PLEASE",
+			},
+			{
+				name:     "Chars outside the rules are kept",
+				template: "<normalize>pi is 3.14 @home</normalize>",
+				expected: "PI IS 3.14 @HOME",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result := g.ProcessTemplate(tt.template, make(map[string]string))
+				if result != tt.expected {
+					t.Errorf("ProcessTemplate() = %v, want %v", result, tt.expected)
+				}
+			})
+		}
+	})
+
+	t.Run("Matching", func(t *testing.T) {
+		err := g.LoadAIMLFromString(`<aiml version="2.0">
+			<category><pattern>SEND EMAIL</pattern><template>sending</template></category>
+			<category><pattern>RED OR BLUE</pattern><template>both</template></category>
+		</aiml>`)
+		if err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+
+		session := g.CreateSession("custom_rules")
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"send e-mail!", "sending"},
+			{"red/or/blue?", "both"},
+		}
+
+		for _, tt := range tests {
+			response, err := g.ProcessInput(tt.input, session)
+			if err != nil {
+				t.Errorf("ProcessInput(%q) failed: %v", tt.input, err)
+				continue
+			}
+			if response != tt.expected {
+				t.Errorf("ProcessInput(%q) = %q, want %q", tt.input, response, tt.expected)
+			}
+		}
+	})
+
+	t.Run("ResetToDefaults", func(t *testing.T) {
+		g.SetNormalizationRules(nil)
+		result := g.ProcessTemplate("<normalize>red/green, e-mail</normalize>", make(map[string]string))
+		if result != "RED/GREEN E MAIL" {
+			t.Errorf("ProcessTemplate() = %v, want %v", result, "RED/GREEN E MAIL")
+		}
+	})
+}
+
 // TestNormalizeDenormalizePerformance tests performance with multiple tags
 func TestNormalizeDenormalizePerformance(t *testing.T) {
 	g := NewForTesting(t, false) // Disable verbose mode for cleaner test output
@@ -0,0 +1,57 @@
+package golem
+
+import "testing"
+
+func TestNormalizeInputMatchesMatcher(t *testing.T) {
+	inputs := []string{
+		"Hello, world!",
+		"  what's   your NAME?  ",
+		"I'm fine; thanks.",
+		"Tell me about café society",
+		"e-mail me at user@example.com",
+		"don't stop",
+		"",
+	}
+
+	configs := map[string]func(g *Golem){
+		"default": func(g *Golem) {},
+		"substitutions": func(g *Golem) {
+			g.aimlKB.Substitutions["normal"] = map[string]string{"don't": "do not", "I'm": "I am"}
+		},
+		"custom rules": func(g *Golem) {
+			g.SetNormalizationRules(&NormalizationRules{CharsToSpace: "-@", CharsToDelete: "'"})
+		},
+		"fold diacritics": func(g *Golem) { g.SetFoldDiacritics(true) },
+	}
+
+	for name, configure := range configs {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if err := g.LoadAIMLFromString(`<aiml version="2.0"><category><pattern>*</pattern><template>ok</template></category></aiml>`); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			configure(g)
+			session := g.CreateSession("normalize")
+
+			for _, input := range inputs {
+				trace, err := g.MatchPatternDebug(input, session)
+				if err != nil {
+					t.Fatalf("MatchPatternDebug(%q) failed: %v", input, err)
+				}
+				if got := g.NormalizeInput(input); got != trace.NormalizedInput {
+					t.Errorf("NormalizeInput(%q) = %q, but the matcher used %q", input, got, trace.NormalizedInput)
+				}
+			}
+		})
+	}
+
+	// Loaded substitutions are part of the normalization
+	g := NewForTesting(t, false)
+	if err := g.LoadAIMLFromString(`<aiml version="2.0"><category><pattern>*</pattern><template>ok</template></category></aiml>`); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	g.aimlKB.Substitutions["normal"] = map[string]string{"don't": "do not"}
+	if got := g.NormalizeInput("Don't stop!"); got != "DO NOT STOP" {
+		t.Errorf("Expected substitutions to apply, got %q", got)
+	}
+}
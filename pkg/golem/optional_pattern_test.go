@@ -0,0 +1,119 @@
+package golem
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestOptionalPatternSegmentsRegex(t *testing.T) {
+	kb := NewAIMLKnowledgeBase()
+	tests := []struct {
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{
+			pattern: "HELLO [THERE] WORLD",
+			matches: []string{"HELLO WORLD", "HELLO THERE WORLD"},
+			misses:  []string{"HELLO  WORLD", "HELLO THEREWORLD", "HELLO FRIEND WORLD"},
+		},
+		{
+			pattern: "[PLEASE] HELP ME",
+			matches: []string{"HELP ME", "PLEASE HELP ME"},
+			misses:  []string{" HELP ME", "PLEASEHELP ME"},
+		},
+		{
+			pattern: "THANK YOU [VERY MUCH]",
+			matches: []string{"THANK YOU", "THANK YOU VERY MUCH"},
+			misses:  []string{"THANK YOU VERY", "THANK YOU MUCH"},
+		},
+		{
+			pattern: "I [REALLY] LIKE *",
+			matches: []string{"I LIKE CATS", "I REALLY LIKE CATS"},
+			misses:  []string{"I DO LIKE CATS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re, err := regexp.Compile(patternToRegexWithSets(tt.pattern, kb))
+			if err != nil {
+				t.Fatalf("Pattern %q produced an invalid regex: %v", tt.pattern, err)
+			}
+			for _, input := range tt.matches {
+				if !re.MatchString(input) {
+					t.Errorf("Expected %q to match %q (regex %s)", tt.pattern, input, re)
+				}
+			}
+			for _, input := range tt.misses {
+				if re.MatchString(input) {
+					t.Errorf("Expected %q not to match %q (regex %s)", tt.pattern, input, re)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionalPatternSegmentsMatching(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>HELLO [THERE] WORLD</pattern>
+        <template>Greetings.</template>
+    </category>
+    <category>
+        <pattern>[PLEASE] TELL ME ABOUT *</pattern>
+        <template>About <star/>.</template>
+    </category>
+    <category>
+        <pattern>*</pattern>
+        <template>Pardon?</template>
+    </category>
+</aiml>`
+
+	g := NewForTesting(t, false)
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	session := g.CreateSession("optional_segments")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"hello world", "Greetings."},
+		{"Hello there, world!", "Greetings."},
+		{"hello friend world", "Pardon?"},
+		{"tell me about robots", "About robots."},
+		{"please tell me about robots", "About robots."},
+	}
+
+	for _, tt := range tests {
+		response, err := g.ProcessInput(tt.input, session)
+		if err != nil {
+			t.Errorf("ProcessInput(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if response != tt.expected {
+			t.Errorf("ProcessInput(%q) = %q, want %q", tt.input, response, tt.expected)
+		}
+	}
+}
+
+func TestOptionalPatternSegmentsValidation(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	valid := []string{"HELLO [THERE] WORLD", "[PLEASE] HELP", "HI [THERE FRIEND]"}
+	for _, pattern := range valid {
+		if err := g.validatePattern(pattern); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", pattern, err)
+		}
+	}
+
+	invalid := []string{"HELLO [THERE WORLD", "HELLO THERE] WORLD", "HELLO [] WORLD", "HELLO [[THERE]] WORLD"}
+	for _, pattern := range invalid {
+		if err := g.validatePattern(pattern); err == nil {
+			t.Errorf("Expected %q to be rejected", pattern)
+		}
+	}
+}
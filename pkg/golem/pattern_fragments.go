@@ -0,0 +1,183 @@
+package golem
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// setSkeletonMarker stands in for a set reference while a skeleton regex is built.
+// It is a private-use rune, so it can't occur in a pattern and passes through
+// patternToRegexWithSetFragments unchanged.
+const setSkeletonMarker = "\uE000"
+
+// setFragment is the compiled membership test for one set
+type setFragment struct {
+	hash  string         // setContentHash of the members it was built from
+	regex *regexp.Regexp // nil when the set can't be matched through a skeleton
+}
+
+// setFragmentCache interns the compiled fragment of each set, so the categories that
+// use a set share one compiled copy of its members instead of each pattern regex
+// carrying its own alternation
+type setFragmentCache struct {
+	fragments map[string]*setFragment // By set name and case mode
+	mutex     sync.RWMutex
+}
+
+// newSetFragmentCache creates an empty set fragment cache
+func newSetFragmentCache() *setFragmentCache {
+	return &setFragmentCache{fragments: make(map[string]*setFragment)}
+}
+
+// get returns the fragment matching exactly one member of the set, or nil when the set
+// can't be matched through a skeleton: it has fewer than two members (the pattern
+// regex doesn't treat those as an alternation), a member that isn't a single plain
+// word, or a member that is a prefix of another (the alternation could then pick a
+// different member than the skeleton's one-word group)
+func (cache *setFragmentCache) get(setName string, members []string, ignoreCase bool) *regexp.Regexp {
+	key := setName
+	if ignoreCase {
+		key += "|i"
+	}
+	hash := setContentHash(members)
+
+	cache.mutex.RLock()
+	fragment, exists := cache.fragments[key]
+	cache.mutex.RUnlock()
+	if exists && fragment.hash == hash {
+		return fragment.regex
+	}
+
+	fragment = &setFragment{hash: hash}
+	if words, ok := plainSetWords(members); ok {
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = regexp.QuoteMeta(word)
+		}
+		source := "^(?:" + strings.Join(quoted, "|") + ")$"
+		if ignoreCase {
+			source = "(?i)" + source
+		}
+		fragment.regex = regexp.MustCompile(source)
+	}
+
+	cache.mutex.Lock()
+	cache.fragments[key] = fragment
+	cache.mutex.Unlock()
+	return fragment.regex
+}
+
+// size returns the number of interned fragments
+func (cache *setFragmentCache) size() int {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	return len(cache.fragments)
+}
+
+// clear drops every interned fragment
+func (cache *setFragmentCache) clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.fragments = make(map[string]*setFragment)
+}
+
+// plainSetWords returns the sorted, uppercased members of a set that can be matched
+// through a skeleton (see setFragmentCache.get)
+func plainSetWords(members []string) ([]string, bool) {
+	if len(members) < 2 {
+		return nil, false
+	}
+	words := make([]string, len(members))
+	for i, member := range members {
+		word := strings.ToUpper(member)
+		if word == "" {
+			return nil, false
+		}
+		for _, r := range word {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return nil, false
+			}
+		}
+		words[i] = word
+	}
+	sort.Strings(words)
+	for i := 1; i < len(words); i++ {
+		if words[i] != words[i-1] && strings.HasPrefix(words[i], words[i-1]) {
+			return nil, false
+		}
+	}
+	return words, true
+}
+
+// findPatternSubmatch matches input against the regex of an AIML pattern (as built by
+// patternToRegexWithSetsCached), returning its submatches, or nil when it doesn't match
+func findPatternSubmatch(g *Golem, pattern, input string, ignoreCase bool, kb *AIMLKnowledgeBase) ([]string, error) {
+	if g != nil && g.setFragments != nil && g.patternRegexCache != nil && kb != nil {
+		if matches, decided := g.matchPatternSkeleton(pattern, input, ignoreCase, kb); decided {
+			return matches, nil
+		}
+	}
+
+	regexPattern := patternToRegexWithSetsCached(g, pattern, kb)
+	if ignoreCase {
+		regexPattern = "(?i)" + regexPattern
+	}
+	re, err := regexp.Compile(regexPattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.FindStringSubmatch(input), nil
+}
+
+// matchPatternSkeleton matches a pattern that uses sets through its skeleton: the
+// pattern regex with each set replaced by a one-word group. Skeletons don't depend on
+// set names or sizes, so patterns that differ only in which sets they use share one
+// compiled skeleton in the pattern regex cache, and the sets' members are compiled
+// once, as interned fragments. Each word a set group captures is checked against its
+// fragment. The skeleton accepts everything the full regex does, so a skeleton miss
+// is decided; when a captured word isn't a member the full regex may still match
+// another way, and the match is left undecided for the caller to run it.
+func (g *Golem) matchPatternSkeleton(pattern, input string, ignoreCase bool, kb *AIMLKnowledgeBase) ([]string, bool) {
+	if !strings.Contains(strings.ToLower(pattern), "<set") {
+		return nil, false
+	}
+
+	var fragments []*regexp.Regexp
+	eligible := true
+	skeleton := patternToRegexWithSetFragments(pattern, func(setName string) string {
+		fragment := g.setFragments.get(setName, kb.Sets[setName], ignoreCase)
+		if fragment == nil {
+			eligible = false
+		}
+		fragments = append(fragments, fragment)
+		return setSkeletonMarker
+	})
+	if !eligible || len(fragments) == 0 {
+		return nil, false
+	}
+	for i := range fragments {
+		skeleton = strings.Replace(skeleton, setSkeletonMarker, fmt.Sprintf(`(?P<set%d>[^\s]+)`, i), 1)
+	}
+	if ignoreCase {
+		skeleton = "(?i)" + skeleton
+	}
+
+	re, err := g.patternRegexCache.GetCompiledRegex(skeleton)
+	if err != nil {
+		return nil, false
+	}
+	matches := re.FindStringSubmatch(input)
+	if matches == nil {
+		return nil, true
+	}
+	for i, fragment := range fragments {
+		if !fragment.MatchString(matches[re.SubexpIndex(fmt.Sprintf("set%d", i))]) {
+			return nil, false
+		}
+	}
+	return matches, true
+}
@@ -0,0 +1,177 @@
+package golem
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPatternSkeletonMatchesFullRegex(t *testing.T) {
+	kb := NewAIMLKnowledgeBase()
+	kb.Sets["COLORS"] = []string{"red", "green", "blue"}
+	kb.Sets["ANIMALS"] = []string{"cat", "dog"}
+	kb.Sets["SHADES"] = []string{"red", "reddish"}
+	kb.Sets["PLACES"] = []string{"new york", "paris"}
+
+	patterns := []string{
+		"I LIKE <set>colors</set>",
+		`I LIKE <set name="animals"/> *`,
+		"* <set>colors</set> *",
+		"<set>colors</set> <set>animals</set>",
+		"i like <set>colors</set>",
+		"* <set>shades</set> *",
+		"VISIT <set>places</set>",
+		"_ <set>colors</set>",
+	}
+	inputs := []string{
+		"I LIKE RED",
+		"I LIKE GREEN CATS",
+		"I LIKE DOG FOOD",
+		"I LIKE PURPLE",
+		"I SAW RED CARS",
+		"I SAW A BLUE DOG AND RED CAT",
+		"BLUE CAT",
+		"i like blue",
+		"THE REDDISH SKY",
+		"VISIT NEW YORK",
+		"VISIT LONDON",
+		"BRIGHT RED",
+	}
+
+	g := NewForTesting(t, false)
+	for _, pattern := range patterns {
+		for _, input := range inputs {
+			ignoreCase := pattern != strings.ToUpper(pattern)
+			expected, err := findPatternSubmatch(nil, pattern, input, ignoreCase, kb)
+			if err != nil {
+				t.Fatalf("Full regex for %q failed: %v", pattern, err)
+			}
+			got, err := findPatternSubmatch(g, pattern, input, ignoreCase, kb)
+			if err != nil {
+				t.Fatalf("Skeleton match for %q failed: %v", pattern, err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("Pattern %q, input %q: expected %q, got %q", pattern, input, expected, got)
+			}
+		}
+	}
+
+	// Sets that can't use a skeleton aren't interned as fragments
+	if g.setFragments.get("SHADES", kb.Sets["SHADES"], false) != nil {
+		t.Error("Expected no fragment for a set with a member that prefixes another")
+	}
+	if g.setFragments.get("PLACES", kb.Sets["PLACES"], false) != nil {
+		t.Error("Expected no fragment for a set with multi-word members")
+	}
+}
+
+func TestPatternSkeletonsShareCompiledRegexes(t *testing.T) {
+	g := NewForTesting(t, false)
+	kb := NewAIMLKnowledgeBase()
+	kb.Sets["COLORS"] = []string{"red", "green", "blue"}
+	kb.Sets["ANIMALS"] = []string{"cat", "dog"}
+	g.ClearRegexCaches()
+
+	for _, pattern := range []string{"I LIKE <set>colors</set>", "I LIKE <set>animals</set>", `I LIKE <set name="colors"/>`} {
+		if _, err := findPatternSubmatch(g, pattern, "I LIKE DOG", false, kb); err != nil {
+			t.Fatalf("Match for %q failed: %v", pattern, err)
+		}
+	}
+
+	stats := g.GetRegexCacheStats()["pattern_regex"].(map[string]interface{})
+	if stats["patterns"] != 1 {
+		t.Errorf("Expected the three patterns to share one skeleton, got %v", stats["patterns"])
+	}
+	if stats["set_fragments"] != 2 {
+		t.Errorf("Expected one fragment per set, got %v", stats["set_fragments"])
+	}
+
+	// A set that changes gets a new fragment
+	kb.Sets["COLORS"] = append(kb.Sets["COLORS"], "purple")
+	if matches, _ := findPatternSubmatch(g, "I LIKE <set>colors</set>", "I LIKE PURPLE", false, kb); matches == nil {
+		t.Error("Expected a member added to the set to match")
+	}
+
+	// Matching through the knowledge base gives the same wildcards
+	if err := g.LoadAIMLFromString(`<aiml version="2.0">
+    <category><pattern>* LIKES <set>colors</set> *</pattern><template><star/>|<star index="2"/>|<star index="3"/></template></category>
+</aiml>`); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	g.aimlKB.Sets["COLORS"] = kb.Sets["COLORS"]
+	response, err := g.ProcessInput("my sister likes green apples", g.CreateSession("skeleton_test"))
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if !strings.EqualFold(response, "my sister|green|apples") {
+		t.Errorf("Expected the set and wildcards to be captured, got '%s'", response)
+	}
+}
+
+// BenchmarkPatternRegexMemory measures the heap the pattern regex cache retains for
+// knowledge bases of many similar patterns sharing a large set, with each pattern
+// compiled in full versus as a skeleton plus interned set fragment
+func BenchmarkPatternRegexMemory(b *testing.B) {
+	kb := NewAIMLKnowledgeBase()
+	var members []string
+	for i := 0; i < 300; i++ {
+		members = append(members, fmt.Sprintf("ITEM%dX", i))
+	}
+	kb.Sets["ITEMS"] = members
+
+	for _, categories := range []int{100, 400} {
+		patterns := make([]string, categories)
+		for i := range patterns {
+			patterns[i] = fmt.Sprintf("WORD%d LIKES <set>items</set> *", i)
+		}
+
+		b.Run(fmt.Sprintf("full/%d", categories), func(b *testing.B) {
+			var retained uint64
+			for n := 0; n < b.N; n++ {
+				cache := NewRegexCache(500, 3600)
+				retained += retainedBytes(func() {
+					for _, pattern := range patterns {
+						if _, err := cache.GetCompiledRegex(patternToRegexWithSetsCached(nil, pattern, kb)); err != nil {
+							b.Fatalf("Failed to compile %q: %v", pattern, err)
+						}
+					}
+				}, cache)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N*categories), "retained-B/category")
+		})
+
+		b.Run(fmt.Sprintf("interned/%d", categories), func(b *testing.B) {
+			g := New(false)
+			var retained uint64
+			for n := 0; n < b.N; n++ {
+				g.ClearRegexCaches()
+				retained += retainedBytes(func() {
+					for i, pattern := range patterns {
+						if _, err := findPatternSubmatch(g, pattern, fmt.Sprintf("WORD%d LIKES ITEM7X NOW", i), false, kb); err != nil {
+							b.Fatalf("Failed to match %q: %v", pattern, err)
+						}
+					}
+				}, g.patternRegexCache, g.setFragments)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N*categories), "retained-B/category")
+		})
+	}
+}
+
+// retainedBytes returns the heap growth left after fill runs and a GC, keeping the
+// given values alive until it has been measured
+func retainedBytes(fill func(), keepAlive ...interface{}) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	fill()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(keepAlive)
+	if after.HeapAlloc < before.HeapAlloc {
+		return 0
+	}
+	return after.HeapAlloc - before.HeapAlloc
+}
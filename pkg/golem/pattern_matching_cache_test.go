@@ -302,6 +302,51 @@ func TestGolemPatternMatchingCacheIntegration(t *testing.T) {
 	// Should not cause any errors
 }
 
+func TestPatternMatchingCacheRuntimeSetAdd(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>I LIKE <set>colors</set></pattern>
+        <template>Nice color: <star/></template>
+    </category>
+    <category>
+        <pattern>LEARN COLOR *</pattern>
+        <template><think><set name="colors" operation="add"><star/></set></think>Learned <star/></template>
+    </category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	g.aimlKB.AddSetMembers("colors", []string{"red", "blue"})
+
+	session := g.CreateSession("runtime_set_add")
+
+	// Populate the caches with a failed match for the new member
+	if response, _ := g.ProcessInput("I like purple", session); strings.Contains(response, "Nice color") {
+		t.Fatalf("Expected 'purple' not to match before it is added, got: %s", response)
+	}
+	if response, _ := g.ProcessInput("I like red", session); !strings.Contains(response, "Nice color") {
+		t.Fatalf("Expected 'red' to match, got: %s", response)
+	}
+
+	if response, _ := g.ProcessInput("learn color purple", session); !strings.Contains(response, "Learned") {
+		t.Fatalf("Expected learn response, got: %s", response)
+	}
+	if !g.aimlKB.IsSetMember("colors", "purple") {
+		t.Fatalf("Expected 'purple' to be a member of colors, got %v", g.aimlKB.GetSetMembers("colors"))
+	}
+
+	response, err := g.ProcessInput("I like purple", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if !strings.Contains(strings.ToLower(response), "nice color: purple") {
+		t.Errorf("Expected 'purple' to match after runtime add, got: %s", response)
+	}
+}
+
 func TestPatternMatchingCachePerformance(t *testing.T) {
 	// Create a Golem instance
 	g := NewForTesting(t, false) // Disable verbose logging
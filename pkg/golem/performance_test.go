@@ -687,3 +687,156 @@ func BenchmarkConcurrentAccess(b *testing.B) {
 		}
 	})
 }
+
+// TestPathologicalPatternPerformance tests that wildcard-heavy patterns stay bounded on long input
+func TestPathologicalPatternPerformance(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	// Adjacent wildcards followed by a literal that never appears in the input
+	aiml := `<category>
+		<pattern>* * * * * * * * * * * * UNMATCHED</pattern>
+		<template>matched</template>
+	</category>
+	<category>
+		<pattern>HELLO</pattern>
+		<template>hello</template>
+	</category>`
+
+	err := g.LoadAIMLFromString(aiml)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	ctx := g.createSession("test_session")
+
+	// Just under the default limit, so the input is actually matched
+	input := strings.Repeat("A ", DefaultMaxInputLength/2-1)
+
+	start := time.Now()
+	response, _ := g.ProcessInput(input, ctx)
+	duration := time.Since(start)
+
+	if response == "matched" {
+		t.Errorf("Pattern should not match input without the trailing literal")
+	}
+	if duration > 2*time.Second {
+		t.Errorf("Pathological pattern took %v, expected under 2s", duration)
+	}
+}
+
+// TestMaxInputLength tests that over-long input is rejected before matching
+func TestMaxInputLength(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aiml := `<category>
+		<pattern>*</pattern>
+		<template>matched</template>
+	</category>`
+
+	err := g.LoadAIMLFromString(aiml)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	ctx := g.createSession("test_session")
+
+	t.Run("DefaultLimit", func(t *testing.T) {
+		response, err := g.ProcessInput(strings.Repeat("a", DefaultMaxInputLength+1), ctx)
+		if err != nil {
+			t.Fatalf("Over-long input should be answered, not fail: %v", err)
+		}
+		if response != defaultErrorResponse {
+			t.Errorf("Expected the default error response %q, got %q", defaultErrorResponse, response)
+		}
+
+		response, err = g.ProcessInput(strings.Repeat("a", DefaultMaxInputLength), ctx)
+		if err != nil {
+			t.Fatalf("Input at the limit should be accepted: %v", err)
+		}
+		if response != "matched" {
+			t.Errorf("Expected 'matched', got %q", response)
+		}
+	})
+
+	t.Run("CountsCharactersNotBytes", func(t *testing.T) {
+		g.SetMaxInputLength(5)
+		defer g.SetMaxInputLength(DefaultMaxInputLength)
+
+		if response, _ := g.ProcessInput("héllö", ctx); response != "matched" {
+			t.Errorf("Five-character input should be matched, got %q", response)
+		}
+		if response, _ := g.ProcessInput("héllö!", ctx); response != defaultErrorResponse {
+			t.Errorf("Six-character input should be rejected, got %q", response)
+		}
+	})
+
+	t.Run("CustomErrorResponse", func(t *testing.T) {
+		g.SetMaxInputLength(3)
+		defer g.SetMaxInputLength(DefaultMaxInputLength)
+		g.GetKnowledgeBase().Properties["error_response"] = "That is too long for me."
+		defer delete(g.GetKnowledgeBase().Properties, "error_response")
+
+		response, _, err := g.ProcessInputWithCategory("abcd", ctx)
+		if err != nil || response != "That is too long for me." {
+			t.Errorf("Expected the custom error_response, got %q (err %v)", response, err)
+		}
+		if response, _ := g.ProcessInputWithThatIndex("abcd", ctx, 1); response != "That is too long for me." {
+			t.Errorf("Expected the custom error_response with a that index, got %q", response)
+		}
+		if response, _ := g.ProcessInput("abc", ctx); response != "matched" {
+			t.Errorf("Input at the limit should be matched, got %q", response)
+		}
+		if _, _, err := g.MatchInput("abcd", ctx); err == nil || !strings.Contains(err.Error(), "input too long") {
+			t.Errorf("MatchInput should still report over-long input as an error, got %v", err)
+		}
+	})
+
+	t.Run("EmptyErrorResponse", func(t *testing.T) {
+		g.SetMaxInputLength(3)
+		defer g.SetMaxInputLength(DefaultMaxInputLength)
+		g.GetKnowledgeBase().Properties["error_response"] = ""
+		defer delete(g.GetKnowledgeBase().Properties, "error_response")
+
+		response, err := g.ProcessInput("abcd", ctx)
+		if err != nil || response != defaultErrorResponse {
+			t.Errorf("Expected the default error response for an empty property, got %q (err %v)", response, err)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		g.SetMaxInputLength(0)
+		defer g.SetMaxInputLength(DefaultMaxInputLength)
+
+		response, err := g.ProcessInput(strings.Repeat("a", DefaultMaxInputLength*2), ctx)
+		if err != nil {
+			t.Fatalf("Input should be accepted with the limit disabled: %v", err)
+		}
+		if response != "matched" {
+			t.Errorf("Expected 'matched', got %q", response)
+		}
+	})
+}
+
+// BenchmarkPathologicalPattern benchmarks a wildcard-heavy pattern against long unmatched input
+func BenchmarkPathologicalPattern(b *testing.B) {
+	g := New(false)
+	g.persistentLearning = NewPersistentLearningManager(b.TempDir())
+
+	aiml := `<category>
+		<pattern>* * * * * * * * * * * * UNMATCHED</pattern>
+		<template>matched</template>
+	</category>`
+
+	err := g.LoadAIMLFromString(aiml)
+	if err != nil {
+		b.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	ctx := g.createSession("test_session")
+	input := strings.Repeat("A ", DefaultMaxInputLength/2-1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.ProcessInput(input, ctx)
+	}
+}
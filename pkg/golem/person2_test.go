@@ -277,7 +277,7 @@ func TestPerson2EdgeCases(t *testing.T) {
 		{
 			name:     "Pronouns with punctuation",
 			template: "<person2>I, me, my, mine!</person2>",
-			expected: "I, me, my, mine!",
+			expected: "they, them, their, theirs!",
 		},
 		{
 			name:     "Pronouns with numbers",
@@ -297,7 +297,7 @@ func TestPerson2EdgeCases(t *testing.T) {
 		{
 			name:     "Pronouns in quotes",
 			template: "<person2>I said \"I am happy\" and we said \"we are sad\"</person2>",
-			expected: "they said \"I am happy\" and they said \"we are sad\"",
+			expected: "they said \"they are happy\" and they said \"they are sad\"",
 		},
 		{
 			name:     "Mixed pronouns and non-pronouns",
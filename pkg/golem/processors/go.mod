@@ -4,4 +4,6 @@ go 1.21
 
 require github.com/helix90/my-golem v0.0.0-00010101000000-000000000000
 
+require golang.org/x/text v0.14.0 // indirect
+
 replace github.com/helix90/my-golem => ../../../
@@ -422,3 +422,169 @@ func TestRandomTagPerformance(t *testing.T) {
 		t.Errorf("Expected some random results, got none")
 	}
 }
+
+func TestRandomTagStablePerInput(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>ASK *</pattern>
+        <template><random>
+            <li>alpha</li>
+            <li>beta</li>
+            <li>gamma</li>
+            <li>delta</li>
+            <li>epsilon</li>
+        </random></template>
+    </category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	config := g.GetTemplateProcessingConfig()
+	config.StableRandomPerInput = true
+	g.UpdateTemplateProcessingConfig(config)
+
+	session := g.CreateSession("stable-random")
+
+	// Identical input repeated within the session reproduces the selection
+	first, err := g.ProcessInput("ask about the weather", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		response, err := g.ProcessInput("ask about the weather", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != first {
+			t.Fatalf("Expected repeated input to reproduce '%s', got '%s'", first, response)
+		}
+	}
+
+	// Different inputs are free to select different items
+	seen := map[string]bool{first: true}
+	for i := 0; i < 20; i++ {
+		response, err := g.ProcessInput(fmt.Sprintf("ask question %d", i), session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		seen[response] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected different inputs to vary the selection, only saw %v", seen)
+	}
+
+	if len(session.RandomSelections) != 21 {
+		t.Errorf("Expected 21 stored selections on the session, got %d", len(session.RandomSelections))
+	}
+}
+
+func TestRandomTagExhaustOptions(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>TOUR</pattern>
+        <template><random>
+            <li>museum</li>
+            <li>harbour</li>
+            <li>castle</li>
+            <li>market</li>
+            <li>park</li>
+        </random></template>
+    </category>
+    <category>
+        <pattern>OTHER</pattern>
+        <template><random>
+            <li>one</li>
+            <li>two</li>
+        </random></template>
+    </category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	config := g.GetTemplateProcessingConfig()
+	config.ExhaustRandomOptions = true
+	g.UpdateTemplateProcessingConfig(config)
+	g.SetRandomSeed(7)
+
+	session := g.CreateSession("tour")
+	for round := 0; round < 3; round++ {
+		seen := make(map[string]bool)
+		for i := 0; i < 5; i++ {
+			response, err := g.ProcessInput("tour", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if seen[response] {
+				t.Fatalf("Round %d: '%s' repeated before all options were used (seen %v)", round, response, seen)
+			}
+			seen[response] = true
+
+			// Another block's deck does not disturb this one
+			if _, err := g.ProcessInput("other", session); err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+		}
+		if len(seen) != 5 {
+			t.Errorf("Round %d: expected all 5 options, got %v", round, seen)
+		}
+	}
+
+	if len(session.RandomDecks) != 2 {
+		t.Errorf("Expected one deck per block, got %d", len(session.RandomDecks))
+	}
+
+	// Decks are per session
+	fresh := g.CreateSession("tour2")
+	if _, err := g.ProcessInput("tour", fresh); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if len(fresh.RandomDecks) != 1 {
+		t.Errorf("Expected a new deck for the new session, got %d", len(fresh.RandomDecks))
+	}
+}
+
+func TestRandomTagAvoidRecentOptions(t *testing.T) {
+	g := NewForTesting(t, false)
+	template := `<random><li>alpha</li><li>beta</li><li>gamma</li></random>`
+
+	countPicks := func(avoidRecent bool) map[string]int {
+		config := g.GetTemplateProcessingConfig()
+		config.AvoidRecentRandomOptions = avoidRecent
+		g.UpdateTemplateProcessingConfig(config)
+		g.SetRandomSeed(42)
+
+		session := g.CreateSession("recent_random")
+		// alpha was produced in the last two responses, beta eight turns before
+		session.ResponseHistory = []string{"Beta it is."}
+		for i := 0; i < 6; i++ {
+			session.ResponseHistory = append(session.ResponseHistory, "Something else.")
+		}
+		session.ResponseHistory = append(session.ResponseHistory, "Alpha it is.", "Alpha again.")
+
+		counts := make(map[string]int)
+		for i := 0; i < 600; i++ {
+			counts[g.ProcessTemplateWithContext(template, map[string]string{}, session)]++
+		}
+		return counts
+	}
+
+	plain := countPicks(false)
+	weighted := countPicks(true)
+
+	if len(weighted) != 3 {
+		t.Fatalf("Expected every option to stay possible, got %v", weighted)
+	}
+	// Recently produced options are picked less often, the most recent least of all
+	if weighted["alpha"] >= plain["alpha"] || weighted["beta"] >= plain["beta"] {
+		t.Errorf("Expected recent options to be picked less often: plain %v, weighted %v", plain, weighted)
+	}
+	if !(weighted["alpha"] < weighted["beta"] && weighted["beta"] < weighted["gamma"]) {
+		t.Errorf("Expected picks to follow recency (alpha < beta < gamma), got %v", weighted)
+	}
+}
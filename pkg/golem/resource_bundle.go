@@ -0,0 +1,86 @@
+package golem
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// resourceBundle is the content of a combined JSON resource file, which holds the
+// collections otherwise spread over .map, .set, .substitution and .properties files
+type resourceBundle struct {
+	Maps          map[string]map[string]string `json:"maps"`
+	Sets          map[string][]string          `json:"sets"`
+	Substitutions map[string]map[string]string `json:"substitutions"`
+	Properties    map[string]string            `json:"properties"`
+}
+
+// LoadResourcesFromJSON loads maps, sets, substitutions and properties from a single
+// JSON file of the form
+//
+//	{"maps": {"name": {"key": "value"}}, "sets": {"name": ["member"]},
+//	 "substitutions": {"name": {"pattern": "replacement"}}, "properties": {"key": "value"}}
+//
+// and merges them into the knowledge base, creating it if none is loaded. Every section
+// is optional. Map, substitution and property entries replace existing entries with the
+// same key, and set members are added to any existing members without duplicates.
+// Unknown sections are an error, so a misspelled section isn't silently ignored.
+func (g *Golem) LoadResourcesFromJSON(path string) error {
+	g.LogInfo("Loading resource file: %s", path)
+
+	content, err := readResourceFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read resource file %s: %v", path, err)
+	}
+
+	var bundle resourceBundle
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&bundle); err != nil {
+		return fmt.Errorf("failed to parse JSON in resource file %s: %v", path, err)
+	}
+
+	g.kbMutex.Lock()
+	defer g.kbMutex.Unlock()
+
+	if g.aimlKB == nil {
+		g.aimlKB = NewAIMLKnowledgeBase()
+	}
+	kb := g.aimlKB
+
+	for mapName, entries := range bundle.Maps {
+		if kb.Maps[mapName] == nil {
+			kb.Maps[mapName] = make(map[string]string)
+		}
+		for key, value := range entries {
+			kb.Maps[mapName][key] = value
+		}
+		kb.invalidateReverseMap(mapName)
+	}
+
+	for setName, members := range bundle.Sets {
+		kb.AddSetMembers(setName, members)
+	}
+
+	for subName, entries := range bundle.Substitutions {
+		if kb.Substitutions[subName] == nil {
+			kb.Substitutions[subName] = make(map[string]string)
+		}
+		for pattern, replacement := range entries {
+			kb.Substitutions[subName][pattern] = replacement
+		}
+	}
+
+	for key, value := range bundle.Properties {
+		kb.Properties[key] = value
+	}
+
+	// Cached normalizations applied the previous substitutions, and cached templates
+	// the previous properties
+	g.ClearTextNormalizationCache()
+	g.ClearTemplateCache()
+
+	g.LogInfo("Loaded %d maps, %d sets, %d substitutions and %d properties from %s",
+		len(bundle.Maps), len(bundle.Sets), len(bundle.Substitutions), len(bundle.Properties), path)
+	return nil
+}
@@ -0,0 +1,80 @@
+package golem
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadResourcesFromJSON(t *testing.T) {
+	dir := t.TempDir()
+	resources := `{
+		"maps": {"capitals": {"France": "Paris", "Italy": "Rome"}},
+		"sets": {"colors": ["red", "green", "Red"]},
+		"substitutions": {"normal": {"wanna": "want to"}},
+		"properties": {"name": "Golem", "color": "blue"}
+	}`
+	path := filepath.Join(dir, "resources.json")
+	if err := os.WriteFile(path, []byte(resources), 0644); err != nil {
+		t.Fatalf("Failed to write resource file: %v", err)
+	}
+
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category><pattern>I LIKE <set>colors</set></pattern><template>I like <star/> too.</template></category>
+	<category><pattern>CAPITAL OF *</pattern><template><map name="capitals"><star/></map></template></category>
+	<category><pattern>I WANT TO DANCE</pattern><template>Let us dance.</template></category>
+	<category><pattern>WHO ARE YOU</pattern><template>I am <bot name="name"/>.</template></category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	if err := g.LoadResourcesFromJSON(path); err != nil {
+		t.Fatalf("LoadResourcesFromJSON failed: %v", err)
+	}
+
+	kb := g.GetKnowledgeBase()
+	if kb.Maps["capitals"]["Italy"] != "Rome" {
+		t.Errorf("Expected the capitals map, got %v", kb.Maps["capitals"])
+	}
+	if members := kb.GetSetMembers("colors"); !reflect.DeepEqual(members, []string{"RED", "GREEN"}) {
+		t.Errorf("Expected the colors set without duplicates, got %v", members)
+	}
+	if kb.Substitutions["normal"]["wanna"] != "want to" {
+		t.Errorf("Expected the normal substitutions, got %v", kb.Substitutions["normal"])
+	}
+	if kb.Properties["color"] != "blue" {
+		t.Errorf("Expected the color property, got %v", kb.Properties)
+	}
+
+	session := g.CreateSession("resources")
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"I like green", "I like green too."},
+		{"capital of France", "Paris"},
+		{"I wanna dance", "Let us dance."},
+		{"who are you", "I am Golem."},
+	}
+	for _, tc := range tests {
+		response, err := g.ProcessInput(tc.input, session)
+		if err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", tc.input, err)
+		}
+		if response != tc.expected {
+			t.Errorf("ProcessInput(%q) = %q, expected %q", tc.input, response, tc.expected)
+		}
+	}
+
+	// A misspelled section is reported rather than ignored
+	badPath := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(badPath, []byte(`{"map": {}}`), 0644); err != nil {
+		t.Fatalf("Failed to write resource file: %v", err)
+	}
+	if err := g.LoadResourcesFromJSON(badPath); err == nil {
+		t.Error("Expected an error for an unknown section")
+	}
+}
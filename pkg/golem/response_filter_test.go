@@ -0,0 +1,68 @@
+package golem
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddResponseFilterOrderAndComposition(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>HELLO</pattern>
+		<template>Hello darn world</template>
+	</category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	var calls []string
+
+	// First filter: profanity filter
+	g.AddResponseFilter(func(response string) string {
+		calls = append(calls, "profanity")
+		return strings.ReplaceAll(response, "darn", "****")
+	})
+	// Second filter: sees the output of the first one
+	g.AddResponseFilter(func(response string) string {
+		calls = append(calls, "markdown")
+		return "**" + response + "**"
+	})
+	// Nil filters are ignored
+	g.AddResponseFilter(nil)
+
+	session := g.CreateSession("filter-test")
+	response, err := g.ProcessInput("hello", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+
+	expected := "**Hello **** world**"
+	if response != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, response)
+	}
+
+	if len(calls) != 2 || calls[0] != "profanity" || calls[1] != "markdown" {
+		t.Errorf("Expected filters to run in registration order [profanity markdown], got %v", calls)
+	}
+
+	// The filtered response is what gets recorded in the response history
+	if last := session.GetResponseByIndex(1); last != expected {
+		t.Errorf("Expected response history to contain '%s', got '%s'", expected, last)
+	}
+}
+
+func TestResponseFiltersNotAppliedToTemplates(t *testing.T) {
+	g := NewForTesting(t, false)
+	g.AddResponseFilter(strings.ToUpper)
+
+	// Filters only run in the chat path, not in direct template processing
+	result := g.ProcessTemplate("hello", map[string]string{})
+	if result != "hello" {
+		t.Errorf("Expected 'hello', got '%s'", result)
+	}
+}
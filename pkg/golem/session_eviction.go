@@ -0,0 +1,115 @@
+package golem
+
+import (
+	"time"
+)
+
+// SetSessionIdleTimeout sets how long a session may go without a turn before
+// EvictIdleSessions (and the sweeper started by StartSessionSweeper) evicts it.
+// Zero or a negative duration disables eviction, which is the default.
+func (g *Golem) SetSessionIdleTimeout(timeout time.Duration) {
+	g.sessionMutex.Lock()
+	defer g.sessionMutex.Unlock()
+	g.sessionIdleTimeout = timeout
+}
+
+// SetSessionEvictionHandler registers a callback invoked with each idle session
+// before it is evicted, so apps can persist it elsewhere. Passing nil removes the
+// handler.
+func (g *Golem) SetSessionEvictionHandler(handler func(session *ChatSession)) {
+	g.sessionMutex.Lock()
+	defer g.sessionMutex.Unlock()
+	g.sessionEvictionHandler = handler
+}
+
+// touchSession records a turn on the session for idle eviction
+func (g *Golem) touchSession(session *ChatSession) {
+	g.sessionMutex.Lock()
+	session.LastAccessed = g.now()
+	g.sessionMutex.Unlock()
+}
+
+// EvictIdleSessions removes every active session whose LastAccessed is older than
+// the idle timeout and returns how many were evicted. The eviction handler runs
+// for each session before it is discarded. Evicted sessions are also dropped from
+// the default in-memory store; other stores keep them, so LoadSession can bring an
+// evicted session back.
+func (g *Golem) EvictIdleSessions() int {
+	g.sessionMutex.Lock()
+	timeout := g.sessionIdleTimeout
+	handler := g.sessionEvictionHandler
+	if timeout <= 0 {
+		g.sessionMutex.Unlock()
+		return 0
+	}
+
+	cutoff := g.now().Add(-timeout)
+	var evicted []*ChatSession
+	for id, session := range g.sessions {
+		if session.LastAccessed.Before(cutoff) {
+			evicted = append(evicted, session)
+			delete(g.sessions, id)
+			if g.currentID == id {
+				g.currentID = ""
+			}
+		}
+	}
+	g.sessionMutex.Unlock()
+
+	// The handler runs without the lock so it may call back into the Golem
+	_, inMemory := g.sessionStore.(*MemorySessionStore)
+	for _, session := range evicted {
+		if handler != nil {
+			handler(session)
+		}
+		if inMemory {
+			if err := g.sessionStore.Delete(session.ID); err != nil {
+				g.LogWarn("Failed to delete stored session %s: %v", session.ID, err)
+			}
+		}
+		g.LogInfo("Evicted idle session %s", session.ID)
+	}
+	return len(evicted)
+}
+
+// StartSessionSweeper starts a background goroutine that calls EvictIdleSessions
+// every interval, replacing any sweeper already running. Stop it with
+// StopSessionSweeper.
+func (g *Golem) StartSessionSweeper(interval time.Duration) {
+	g.StopSessionSweeper()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	g.sessionMutex.Lock()
+	g.sweeperStop = stop
+	g.sweeperDone = done
+	g.sessionMutex.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.EvictIdleSessions()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopSessionSweeper stops the sweeper started by StartSessionSweeper and waits
+// for it to exit. It does nothing if no sweeper is running.
+func (g *Golem) StopSessionSweeper() {
+	g.sessionMutex.Lock()
+	stop, done := g.sweeperStop, g.sweeperDone
+	g.sweeperStop, g.sweeperDone = nil, nil
+	g.sessionMutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
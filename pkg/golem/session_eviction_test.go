@@ -0,0 +1,117 @@
+package golem
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEvictIdleSessions(t *testing.T) {
+	g := NewForTesting(t, false)
+	if err := g.LoadAIMLFromString(`<aiml version="2.0"><category><pattern>HELLO</pattern><template>Hi</template></category></aiml>`); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	var clockMutex sync.Mutex
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g.timeNow = func() time.Time {
+		clockMutex.Lock()
+		defer clockMutex.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		clockMutex.Lock()
+		defer clockMutex.Unlock()
+		now = now.Add(d)
+	}
+
+	var evicted []string
+	g.SetSessionEvictionHandler(func(session *ChatSession) {
+		evicted = append(evicted, session.ID)
+	})
+
+	idle := g.CreateSession("idle")
+	active := g.CreateSession("active")
+	if !idle.LastAccessed.Equal(now) {
+		t.Errorf("Expected LastAccessed to be set on creation, got %v", idle.LastAccessed)
+	}
+
+	// Eviction is disabled until a timeout is set
+	advance(time.Hour)
+	if n := g.EvictIdleSessions(); n != 0 {
+		t.Fatalf("Expected no eviction without a timeout, got %d", n)
+	}
+
+	g.SetSessionIdleTimeout(30 * time.Minute)
+	advance(-time.Hour)
+
+	advance(20 * time.Minute)
+	if _, err := g.ProcessInput("hello", active); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if !active.LastAccessed.Equal(now) {
+		t.Errorf("Expected LastAccessed to be updated by the turn, got %v", active.LastAccessed)
+	}
+
+	advance(15 * time.Minute)
+	if n := g.EvictIdleSessions(); n != 1 {
+		t.Fatalf("Expected 1 eviction, got %d", n)
+	}
+	if len(evicted) != 1 || evicted[0] != "idle" {
+		t.Errorf("Expected handler to see the idle session, got %v", evicted)
+	}
+	if g.SessionCount() != 1 {
+		t.Errorf("Expected 1 remaining session, got %d", g.SessionCount())
+	}
+	if _, err := g.sessionStore.Load("idle"); err == nil {
+		t.Error("Expected the evicted session to be removed from the in-memory store")
+	}
+
+	advance(time.Hour)
+	g.EvictIdleSessions()
+	if g.SessionCount() != 0 {
+		t.Errorf("Expected all sessions evicted, got %d", g.SessionCount())
+	}
+	if len(evicted) != 2 || evicted[1] != "active" {
+		t.Errorf("Expected handler to see the active session last, got %v", evicted)
+	}
+}
+
+func TestSessionSweeper(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	var clockMutex sync.Mutex
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g.timeNow = func() time.Time {
+		clockMutex.Lock()
+		defer clockMutex.Unlock()
+		return now
+	}
+
+	evictedCh := make(chan string, 1)
+	g.SetSessionEvictionHandler(func(session *ChatSession) {
+		evictedCh <- session.ID
+	})
+	g.SetSessionIdleTimeout(10 * time.Minute)
+	g.CreateSession("sweep_me")
+
+	g.StartSessionSweeper(5 * time.Millisecond)
+	defer g.StopSessionSweeper()
+
+	clockMutex.Lock()
+	now = now.Add(11 * time.Minute)
+	clockMutex.Unlock()
+
+	select {
+	case id := <-evictedCh:
+		if id != "sweep_me" {
+			t.Errorf("Expected sweep_me to be evicted, got %s", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Sweeper did not evict the idle session")
+	}
+
+	g.StopSessionSweeper()
+	// Stopping twice is harmless
+	g.StopSessionSweeper()
+}
@@ -56,7 +56,7 @@ func (sm *SessionManagement) getDefaultContextConfig() *ContextConfig {
 
 // SetSessionTopic sets the topic for a session
 func (sm *SessionManagement) SetSessionTopic(session *ChatSession, topic string) {
-	session.Topic = topic
+	session.SetSessionTopic(topic)
 	session.LastActivity = time.Now().Format(time.RFC3339)
 }
 
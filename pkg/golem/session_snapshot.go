@@ -0,0 +1,109 @@
+package golem
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SessionSnapshot is the conversational state of a session: its variables, topic and
+// histories. Unlike a SessionStore, it leaves out learning and context analytics, so
+// a snapshot stays readable and can be restored into a fresh Golem.
+type SessionSnapshot struct {
+	ID              string            `json:"id"`
+	Variables       map[string]string `json:"variables"`
+	Topic           string            `json:"topic"`
+	History         []string          `json:"history"`
+	ThatHistory     []string          `json:"that_history"`
+	RequestHistory  []string          `json:"request_history"`
+	ResponseHistory []string          `json:"response_history"`
+}
+
+// Snapshot returns a copy of the session's conversational state
+func (session *ChatSession) Snapshot() *SessionSnapshot {
+	return &SessionSnapshot{
+		ID:              session.ID,
+		Variables:       session.SnapshotVariables(),
+		Topic:           session.Topic,
+		History:         append([]string{}, session.History...),
+		ThatHistory:     append([]string{}, session.ThatHistory...),
+		RequestHistory:  append([]string{}, session.RequestHistory...),
+		ResponseHistory: append([]string{}, session.ResponseHistory...),
+	}
+}
+
+// Restore replaces the session's conversational state with a copy of a snapshot.
+// The session keeps its own ID, and the topic is restored without notifying
+// OnTopicChange.
+func (session *ChatSession) Restore(snapshot *SessionSnapshot) {
+	session.RestoreVariables(snapshot.Variables)
+	session.Topic = snapshot.Topic
+	session.History = append([]string{}, snapshot.History...)
+	session.ThatHistory = append([]string{}, snapshot.ThatHistory...)
+	session.RequestHistory = append([]string{}, snapshot.RequestHistory...)
+	session.ResponseHistory = append([]string{}, snapshot.ResponseHistory...)
+}
+
+// SaveSessionSnapshot writes a snapshot of the session to a JSON file
+func (g *Golem) SaveSessionSnapshot(session *ChatSession, path string) error {
+	data, err := json.MarshalIndent(session.Snapshot(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session %s: %v", session.ID, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session snapshot %s: %v", path, err)
+	}
+	return nil
+}
+
+// LoadSessionSnapshot reads a snapshot written by SaveSessionSnapshot and restores it
+// into a new session with the snapshot's ID, replacing any active session with that
+// ID. The restored session becomes the current session.
+func (g *Golem) LoadSessionSnapshot(path string) (*ChatSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session snapshot %s: %v", path, err)
+	}
+	var snapshot SessionSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse session snapshot %s: %v", path, err)
+	}
+	if snapshot.ID == "" {
+		return nil, fmt.Errorf("session snapshot %s has no session ID", path)
+	}
+
+	session := g.createSession(snapshot.ID)
+	session.Restore(&snapshot)
+	g.saveSession(session)
+	return session, nil
+}
+
+// saveSessionCommand handles "save <file>", writing a snapshot of the current session
+func (g *Golem) saveSessionCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("save command requires a file")
+	}
+	session := g.getCurrentSession()
+	if session == nil {
+		return fmt.Errorf("no current session to save")
+	}
+	if err := g.SaveSessionSnapshot(session, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Saved session %s to %s\n", session.ID, args[0])
+	return nil
+}
+
+// loadSessionCommand handles "load-session <file>", restoring a saved session and
+// switching to it
+func (g *Golem) loadSessionCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("load-session command requires a file")
+	}
+	session, err := g.LoadSessionSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded session %s (%d messages)\n", session.ID, len(session.History))
+	return nil
+}
@@ -0,0 +1,81 @@
+package golem
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSessionSnapshotRoundTrip(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>MY NAME IS *</pattern>
+		<template><think><set name="name"><star/></set><set name="topic">TEA</set></think>Do you like tea?</template>
+	</category>
+	<category>
+		<pattern>YES</pattern>
+		<that>DO YOU LIKE TEA</that>
+		<template>Me too, <get name="name"/>.</template>
+	</category>
+</aiml>`
+
+	g := NewForTesting(t, false)
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	session := g.CreateSession("debugging")
+	if _, err := g.ProcessInput("my name is Ada", session); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+
+	snapshotFile := filepath.Join(t.TempDir(), "session.json")
+	if err := g.Execute("save", []string{snapshotFile}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	// A fresh instance resumes the conversation where it left off
+	resumed := NewForTesting(t, false)
+	if err := resumed.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	if err := resumed.Execute("load-session", []string{snapshotFile}); err != nil {
+		t.Fatalf("load-session failed: %v", err)
+	}
+	restored := resumed.getCurrentSession()
+	if restored == nil || restored.ID != "debugging" {
+		t.Fatalf("Expected the restored session to be current, got %+v", restored)
+	}
+	if !reflect.DeepEqual(restored.Snapshot(), session.Snapshot()) {
+		t.Errorf("Restored state differs:\nsaved:    %+v\nrestored: %+v", session.Snapshot(), restored.Snapshot())
+	}
+	if restored.GetSessionTopic() != "TEA" || restored.Variables["name"] != "Ada" {
+		t.Errorf("Expected topic and variables to be restored, got topic '%s', variables %v", restored.GetSessionTopic(), restored.Variables)
+	}
+
+	response, err := resumed.ProcessInput("yes", restored)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if response != "Me too, Ada." {
+		t.Errorf("Expected the restored that history to be matched, got '%s'", response)
+	}
+
+	// Snapshots are copies, not views of the session
+	snapshot := restored.Snapshot()
+	snapshot.Variables["name"] = "Grace"
+	snapshot.History[0] = "changed"
+	if restored.Variables["name"] != "Ada" || restored.History[0] == "changed" {
+		t.Error("Expected changes to a snapshot not to affect the session")
+	}
+
+	if err := resumed.Execute("save", []string{}); err == nil {
+		t.Error("Expected an error when no file is given")
+	}
+	if err := resumed.Execute("load-session", []string{filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("Expected an error for a missing snapshot file")
+	}
+	if err := NewForTesting(t, false).Execute("save", []string{snapshotFile}); err == nil {
+		t.Error("Expected an error when there is no current session")
+	}
+}
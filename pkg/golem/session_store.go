@@ -0,0 +1,153 @@
+package golem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrSessionNotFound is returned by SessionStore.Load for an unknown session ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore persists chat sessions between turns. The Golem saves every session it
+// creates and the session of every processed turn; ProcessInputForSession loads the
+// session through the store at the start of each turn, so instances sharing a store
+// share conversations. See SetSessionStore.
+type SessionStore interface {
+	Save(session *ChatSession) error
+	Load(id string) (*ChatSession, error)
+	Delete(id string) error
+}
+
+// MemorySessionStore keeps sessions in memory. It is the default store.
+type MemorySessionStore struct {
+	sessions map[string]*ChatSession
+	mutex    sync.RWMutex
+}
+
+// NewMemorySessionStore creates an empty in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*ChatSession)}
+}
+
+// Save stores the session
+func (s *MemorySessionStore) Save(session *ChatSession) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions[session.ID] = session
+	return nil
+}
+
+// Load returns the stored session, or ErrSessionNotFound
+func (s *MemorySessionStore) Load(id string) (*ChatSession, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	session, exists := s.sessions[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return session, nil
+}
+
+// Delete removes the session; deleting an unknown session is not an error
+func (s *MemorySessionStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// FileSessionStore keeps each session as a JSON file in a directory, so sessions
+// survive a restart
+type FileSessionStore struct {
+	Dir   string
+	mutex sync.Mutex
+}
+
+// NewFileSessionStore creates a file session store in dir, creating the directory
+// if needed
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %v", err)
+	}
+	return &FileSessionStore{Dir: dir}, nil
+}
+
+// path returns the file holding a session; IDs are escaped so they cannot leave Dir
+func (s *FileSessionStore) path(id string) string {
+	return filepath.Join(s.Dir, url.PathEscape(id)+".json")
+}
+
+// Save writes the session to its file, replacing it atomically
+func (s *FileSessionStore) Save(session *ChatSession) error {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session %s: %v", session.ID, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	filename := s.path(session.ID)
+	tempFile := filename + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session %s: %v", session.ID, err)
+	}
+	if err := os.Rename(tempFile, filename); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to write session %s: %v", session.ID, err)
+	}
+	return nil
+}
+
+// Load reads the session from its file, or returns ErrSessionNotFound
+func (s *FileSessionStore) Load(id string) (*ChatSession, error) {
+	s.mutex.Lock()
+	data, err := os.ReadFile(s.path(id))
+	s.mutex.Unlock()
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %v", id, err)
+	}
+
+	var session ChatSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %v", id, err)
+	}
+	restoreDecodedContextMetadata(session.ContextMetadata)
+	return &session, nil
+}
+
+// restoreDecodedContextMetadata converts the context analytics counters that JSON
+// decodes as float64 and map[string]interface{} back to the int types the session's
+// context management expects
+func restoreDecodedContextMetadata(metadata map[string]interface{}) {
+	if count, ok := metadata["pruning_count"].(float64); ok {
+		metadata["pruning_count"] = int(count)
+	}
+	if decoded, ok := metadata["tag_distribution"].(map[string]interface{}); ok {
+		distribution := make(map[string]int, len(decoded))
+		for tag, count := range decoded {
+			if n, ok := count.(float64); ok {
+				distribution[tag] = int(n)
+			}
+		}
+		metadata["tag_distribution"] = distribution
+	}
+}
+
+// Delete removes the session's file; deleting an unknown session is not an error
+func (s *FileSessionStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete session %s: %v", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package golem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const sessionStoreTestAIML = `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>MY NAME IS *</pattern>
+		<template><think><set name="name"><star/></set></think>Nice to meet you, <star/>. Do you like tea?</template>
+	</category>
+	<category>
+		<pattern>YES</pattern>
+		<that>NICE TO MEET YOU * DO YOU LIKE TEA</that>
+		<template>Tea it is, <get name="name"/>.</template>
+	</category>
+	<category>
+		<pattern>WHAT IS MY NAME</pattern>
+		<template>Your name is <get name="name"/>.</template>
+	</category>
+</aiml>`
+
+func TestMemorySessionStoreIsDefault(t *testing.T) {
+	g := NewForTesting(t, false)
+	if err := g.LoadAIMLFromString(sessionStoreTestAIML); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("memory")
+	loaded, err := g.LoadSession("memory")
+	if err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	if loaded != session {
+		t.Error("Expected the in-memory store to return the created session")
+	}
+
+	if response, err := g.ProcessInputForSession("memory", "my name is Ada"); err != nil || response != "Nice to meet you, Ada. Do you like tea?" {
+		t.Fatalf("Unexpected response %q (err %v)", response, err)
+	}
+	if session.Variables["name"] != "Ada" {
+		t.Errorf("Expected the turn to update the stored session, got %v", session.Variables)
+	}
+
+	if _, err := g.LoadSession("unknown"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestFileSessionStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	newGolem := func() *Golem {
+		g := NewForTesting(t, false)
+		if err := g.LoadAIMLFromString(sessionStoreTestAIML); err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		store, err := NewFileSessionStore(dir)
+		if err != nil {
+			t.Fatalf("NewFileSessionStore failed: %v", err)
+		}
+		g.SetSessionStore(store)
+		return g
+	}
+
+	first := newGolem()
+	if _, err := first.ProcessInputForSession("alice", "my name is Alice"); err != nil {
+		t.Fatalf("ProcessInputForSession failed: %v", err)
+	}
+	first.CreateSession("bob")
+
+	// A new instance over the same directory picks up the conversation, including the
+	// that context of the last response
+	second := newGolem()
+	response, err := second.ProcessInputForSession("alice", "yes")
+	if err != nil {
+		t.Fatalf("ProcessInputForSession after restart failed: %v", err)
+	}
+	if response != "Tea it is, Alice." {
+		t.Errorf("Expected the that context and predicates to survive, got %q", response)
+	}
+	session, err := second.LoadSession("alice")
+	if err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	if len(session.RequestHistory) != 2 {
+		t.Errorf("Expected 2 requests in the restored history, got %v", session.RequestHistory)
+	}
+	if response, _ := second.ProcessInput("what is my name", session); response != "Your name is Alice." {
+		t.Errorf("Expected the restored session to work with ProcessInput, got %q", response)
+	}
+
+	if _, err := second.LoadSession("bob"); err != nil {
+		t.Errorf("Expected a created session to be stored: %v", err)
+	}
+	if err := second.Execute("session", []string{"delete", "bob"}); err != nil {
+		t.Fatalf("session delete failed: %v", err)
+	}
+	if _, err := newGolem().LoadSession("bob"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected the deleted session to be gone from the store, got %v", err)
+	}
+}
+
+func TestFileSessionStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileSessionStore failed: %v", err)
+	}
+
+	// IDs cannot escape the store's directory
+	session := &ChatSession{ID: "../escape", Variables: map[string]string{"a": "b"}}
+	if err := store.Save(session); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.json")); !os.IsNotExist(err) {
+		t.Error("Session file was written outside the store directory")
+	}
+	loaded, err := store.Load("../escape")
+	if err != nil || loaded.Variables["a"] != "b" {
+		t.Errorf("Expected to load the session back, got %v (err %v)", loaded, err)
+	}
+
+	// Context analytics counters come back with the types the session code expects
+	session = &ChatSession{ID: "metadata", ContextMetadata: map[string]interface{}{
+		"pruning_count":    3,
+		"tag_distribution": map[string]int{"greeting": 2},
+	}}
+	if err := store.Save(session); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err = store.Load("metadata")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if count, ok := loaded.ContextMetadata["pruning_count"].(int); !ok || count != 3 {
+		t.Errorf("Expected pruning_count 3 as an int, got %#v", loaded.ContextMetadata["pruning_count"])
+	}
+	if tags, ok := loaded.ContextMetadata["tag_distribution"].(map[string]int); !ok || tags["greeting"] != 2 {
+		t.Errorf("Expected tag_distribution as map[string]int, got %#v", loaded.ContextMetadata["tag_distribution"])
+	}
+
+	if err := store.Delete("metadata"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete("metadata"); err != nil {
+		t.Errorf("Deleting an unknown session should not fail: %v", err)
+	}
+	if _, err := store.Load("metadata"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after delete, got %v", err)
+	}
+}
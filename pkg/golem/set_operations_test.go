@@ -361,3 +361,61 @@ func TestSetTagEdgeCases(t *testing.T) {
 		t.Errorf("Expected response to contain '3', got '%s'", response)
 	}
 }
+
+func TestSetTagComputedItems(t *testing.T) {
+	aimlContent := `<aiml version="2.0">
+    <category>
+        <pattern>VISIT *</pattern>
+        <template><think><set name="city"><star/></set><set name="visited" operation="add"><get name="city"/></set></think>Visited <get name="city"/>.</template>
+    </category>
+    <category>
+        <pattern>FORGET *</pattern>
+        <template><think><set name="city"><star/></set><set name="visited" operation="remove"><get name="city"/></set></think>Forgot <get name="city"/>.</template>
+    </category>
+    <category>
+        <pattern>BEEN TO *</pattern>
+        <template><think><set name="city"><star/></set></think><set name="visited" operation="contains"><get name="city"/></set></template>
+    </category>
+    <category>
+        <pattern>WHERE HAVE I BEEN</pattern>
+        <template><set name="visited" operation="get"></set></template>
+    </category>
+</aiml>`
+
+	engines := map[string]bool{"tree": false, "legacy": true}
+	for name, legacy := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if legacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("computed")
+
+			tests := []struct {
+				input    string
+				expected string
+			}{
+				{"visit Paris", "Visited Paris."},
+				{"visit Lima", "Visited Lima."},
+				// The stored items are the values, not the <get> tag re-read later
+				{"where have I been", "Paris Lima"},
+				{"been to Paris", "true"},
+				{"been to Oslo", "false"},
+				{"forget Paris", "Forgot Paris."},
+				{"where have I been", "Lima"},
+			}
+			for _, tt := range tests {
+				response, err := g.ProcessInput(tt.input, session)
+				if err != nil {
+					t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+				}
+				if response != tt.expected {
+					t.Errorf("%s: expected '%s', got '%s'", tt.input, tt.expected, response)
+				}
+			}
+		})
+	}
+}
@@ -522,6 +522,66 @@ func TestSetTagEnhancedGetOperation(t *testing.T) {
 	}
 }
 
+// TestSetTagEnhancedGetDefault tests the default attribute of the get form, returned
+// when the set is empty
+func TestSetTagEnhancedGetDefault(t *testing.T) {
+	aimlContent := `
+	<aiml version="2.0">
+		<category>
+			<pattern>SHOW QUEUE</pattern>
+			<template>Queue: <set name="queue" operation="get" default="nothing"/></template>
+		</category>
+		<category>
+			<pattern>SHOW QUEUE PAIRED</pattern>
+			<template>Queue: <set name="queue" operation="get" default="nothing"></set></template>
+		</category>
+		<category>
+			<pattern>QUEUE *</pattern>
+			<template><think><set name="queue" operation="add"><star/></set></think>Queued.</template>
+		</category>
+		<category>
+			<pattern>CLEAR QUEUE</pattern>
+			<template><think><set name="queue" operation="clear"></set></think>Cleared.</template>
+		</category>
+	</aiml>`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"SHOW QUEUE", "Queue: nothing"},
+		{"SHOW QUEUE PAIRED", "Queue: nothing"},
+		{"QUEUE apples", "Queued."},
+		{"SHOW QUEUE", "Queue: apples"},
+		{"SHOW QUEUE PAIRED", "Queue: apples"},
+		{"CLEAR QUEUE", "Cleared."},
+		{"SHOW QUEUE", "Queue: nothing"},
+	}
+
+	engines := map[string]func(g *Golem){
+		"tree":         func(g *Golem) {},
+		"consolidated": func(g *Golem) { g.SetTemplateProcessor(g.GetConsolidatedProcessor()) },
+	}
+
+	for name, configure := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			configure(g)
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("set-get-default-" + name)
+
+			for _, tt := range tests {
+				response, _ := g.ProcessInput(tt.input, session)
+				if response != tt.expected {
+					t.Errorf("ProcessInput(%q): expected %q, got %q", tt.input, tt.expected, response)
+				}
+			}
+		})
+	}
+}
+
 // TestSetTagEnhancedWithWildcards tests set operations with wildcards
 func TestSetTagEnhancedWithWildcards(t *testing.T) {
 	g := NewForTesting(t, false)
@@ -2,6 +2,7 @@ package golem
 
 import (
 	"testing"
+	"time"
 )
 
 func TestSizeTagProcessing(t *testing.T) {
@@ -791,3 +792,152 @@ func TestIdTagEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestUptimeTagWithFrozenClock(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(90 * time.Second)
+	g.startTime = start
+	g.timeNow = func() time.Time { return now }
+
+	if g.aimlKB == nil {
+		g.aimlKB = NewAIMLKnowledgeBase()
+	}
+
+	ctx := &VariableContext{
+		LocalVars:     make(map[string]string),
+		KnowledgeBase: g.aimlKB,
+	}
+
+	// Tree processor path
+	result := g.processTemplateWithContext("Up for <uptime/> seconds", map[string]string{}, ctx)
+	if result != "Up for 90 seconds" {
+		t.Errorf("Expected 'Up for 90 seconds', got '%s'", result)
+	}
+
+	// Consolidated pipeline path
+	result = g.processUptimeTagsWithContext("Up for <uptime/> seconds", ctx)
+	if result != "Up for 90 seconds" {
+		t.Errorf("Expected 'Up for 90 seconds', got '%s'", result)
+	}
+
+	// Advance the clock
+	now = now.Add(time.Hour)
+	result = g.processTemplateWithContext("<uptime/>", map[string]string{}, ctx)
+	if result != "3690" {
+		t.Errorf("Expected '3690', got '%s'", result)
+	}
+}
+
+func TestCategoryCountTagProcessing(t *testing.T) {
+	g := NewForTesting(t, false)
+	g.aimlKB = NewAIMLKnowledgeBase()
+	g.aimlKB.Categories = []Category{
+		{Pattern: "HELLO", Template: "Hi"},
+		{Pattern: "BYE", Template: "Bye"},
+		{Pattern: "THANKS", Template: "Welcome"},
+	}
+
+	ctx := &VariableContext{
+		LocalVars:     make(map[string]string),
+		KnowledgeBase: g.aimlKB,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"Self-closing categorycount", "I know <categorycount/> things", "I know 3 things"},
+		{"Categorycount matches size", "<categorycount/>=<size/>", "3=3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := g.processTemplateWithContext(tt.template, map[string]string{}, ctx)
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+			result = g.processSizeTagsWithContext(g.processCategoryCountTagsWithContext(tt.template, ctx), ctx)
+			if result != tt.expected {
+				t.Errorf("Consolidated: expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+
+	// No knowledge base
+	result := g.processCategoryCountTagsWithContext("<categorycount/>", &VariableContext{})
+	if result != "0" {
+		t.Errorf("Expected '0' with no knowledge base, got '%s'", result)
+	}
+}
+
+func TestSessionCountTagProcessing(t *testing.T) {
+	g := NewForTesting(t, false)
+	g.aimlKB = NewAIMLKnowledgeBase()
+
+	ctx := &VariableContext{
+		LocalVars:     make(map[string]string),
+		KnowledgeBase: g.aimlKB,
+	}
+
+	result := g.processTemplateWithContext("Sessions: <sessioncount/>", map[string]string{}, ctx)
+	if result != "Sessions: 0" {
+		t.Errorf("Expected 'Sessions: 0', got '%s'", result)
+	}
+
+	g.CreateSession("alice")
+	g.CreateSession("bob")
+
+	result = g.processTemplateWithContext("Sessions: <sessioncount/>", map[string]string{}, ctx)
+	if result != "Sessions: 2" {
+		t.Errorf("Expected 'Sessions: 2', got '%s'", result)
+	}
+
+	result = g.processSessionCountTagsWithContext("Sessions: <sessioncount/>", ctx)
+	if result != "Sessions: 2" {
+		t.Errorf("Consolidated: expected 'Sessions: 2', got '%s'", result)
+	}
+}
+
+func TestVersionMatchesLibraryVersion(t *testing.T) {
+	engines := map[string]func(g *Golem){
+		"tree":         func(g *Golem) {},
+		"consolidated": func(g *Golem) { g.SetTemplateProcessor(g.GetConsolidatedProcessor()) },
+	}
+
+	for name, configure := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			configure(g)
+			err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category><pattern>VERSION</pattern><template>Version <version/></template></category>
+</aiml>`)
+			if err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			if err := g.loadDefaultProperties(g.aimlKB); err != nil {
+				t.Fatalf("Failed to load default properties: %v", err)
+			}
+
+			if version := g.aimlKB.GetProperty("version"); version != GetVersion() {
+				t.Errorf("Expected the version property to be %q, got %q", GetVersion(), version)
+			}
+			response, err := g.ProcessInput("version", g.CreateSession("version"))
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != "Version "+GetVersion() {
+				t.Errorf("Expected 'Version %s', got '%s'", GetVersion(), response)
+			}
+
+			// Without the property, <version/> falls back to 2.0
+			delete(g.aimlKB.Properties, "version")
+			if response, _ := g.ProcessInput("version", g.CreateSession("no_version")); response != "Version 2.0" {
+				t.Errorf("Expected 'Version 2.0', got '%s'", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package golem
+
+import (
+	"strings"
+	"sync"
+)
+
+// sraiSideEffectTags are the tags that change session or knowledge base state, or
+// that recurse through the shared tree processor. A template using any of them is
+// never part of a concurrent SRAI batch.
+var sraiSideEffectTags = map[string]bool{
+	"set":      true,
+	"var":      true,
+	"learn":    true,
+	"learnf":   true,
+	"unlearn":  true,
+	"unlearnf": true,
+	"srai":     true,
+	"sr":       true,
+	"random":   true, // Stable selections are recorded in the session
+	"system":   true,
+	"eval":     true,
+}
+
+// collectionOperationTags change state only when given an operation attribute
+var collectionOperationTags = map[string]bool{
+	"list":  true,
+	"array": true,
+	"map":   true,
+}
+
+// hasSideEffects reports whether processing node or any of its descendants may change
+// state that another SRAI in the same template could observe
+func hasSideEffects(node *ASTNode) bool {
+	if node.Type == NodeTypeTag || node.Type == NodeTypeSelfClosingTag {
+		if sraiSideEffectTags[node.TagName] {
+			return true
+		}
+		if _, exists := node.Attributes["operation"]; exists && collectionOperationTags[node.TagName] {
+			return true
+		}
+		// <topic>NEW</topic> sets the topic; <topic/> only reads it
+		if node.TagName == "topic" && len(node.Children) > 0 {
+			return true
+		}
+	}
+	for _, child := range node.Children {
+		if hasSideEffects(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// batchedSRAI is one top-level <srai> of a concurrently resolved template
+type batchedSRAI struct {
+	content   string
+	category  *Category // nil when the content matches no category
+	wildcards map[string]string
+	ctx       *VariableContext
+	response  string
+}
+
+// processBatchedSRAI resolves the top-level <srai> tags of a template concurrently
+// when TemplateProcessingConfig.ConcurrentSRAI is set, reporting false when the
+// template must be processed sequentially instead. Batching only applies when the
+// template has two or more top-level SRAIs and neither the template nor any of the
+// SRAI targets has side effects, so the order of resolution can't be observed.
+//
+// The batch runs inside the caller's knowledge base read lock (ProcessInput holds it
+// for the whole turn), so the goroutines don't take it again: a recursive RLock can
+// deadlock behind a waiting writer.
+func (tp *TreeProcessor) processBatchedSRAI(root *ASTNode) (string, bool) {
+	config := tp.golem.templateConfig
+	if config == nil || !config.ConcurrentSRAI || tp.golem.templateEngine != nil || tp.golem.aimlKB == nil {
+		return "", false
+	}
+	if tp.ctx == nil || tp.ctx.RecursionDepth >= MaxSRAIRecursionDepth {
+		return "", false
+	}
+
+	sraiCount := 0
+	for _, child := range root.Children {
+		if child.Type == NodeTypeTag && child.TagName == "srai" {
+			for _, grandchild := range child.Children {
+				if hasSideEffects(grandchild) {
+					return "", false
+				}
+			}
+			sraiCount++
+		} else if hasSideEffects(child) {
+			return "", false
+		}
+	}
+	if sraiCount < 2 {
+		return "", false
+	}
+
+	// Process the template in order, matching every SRAI and checking its target
+	// before resolving any of them; everything else is already final. Falling back
+	// re-processes the template, so the star counter is rewound first.
+	starCounter := tp.starCounter
+	outputs := make([]string, len(root.Children))
+	batch := make(map[int]*batchedSRAI, sraiCount)
+	for i, child := range root.Children {
+		if child.Type != NodeTypeTag || child.TagName != "srai" {
+			outputs[i] = tp.processNode(child)
+			continue
+		}
+		var content strings.Builder
+		for _, grandchild := range child.Children {
+			content.WriteString(tp.processNode(grandchild))
+		}
+		job := &batchedSRAI{content: strings.TrimSpace(content.String())}
+		batch[i] = job
+
+		category, wildcards, err := tp.golem.aimlKB.MatchPatternWithTopic(job.content, tp.ctx.currentTopic())
+		if err != nil || category == nil {
+			continue
+		}
+		if tp.ctx.isActiveCategory(category, job.content) {
+			tp.starCounter = starCounter
+			return "", false
+		}
+		target, err := NewASTParser(category.Template).Parse()
+		if err != nil || hasSideEffects(target) {
+			tp.golem.LogInfo("SRAI target '%s' has side effects, resolving sequentially", category.Pattern)
+			tp.starCounter = starCounter
+			return "", false
+		}
+
+		// Each SRAI gets its own copies of the maps template processing writes to
+		ctx := tp.sraiContext(category, job.content)
+		ctx.LocalVars = copyVariables(tp.ctx.LocalVars)
+		if tp.ctx.Session != nil {
+			ctx.Session = copySessionState(tp.ctx.Session)
+		}
+		job.category = category
+		job.wildcards = wildcards
+		job.ctx = ctx
+	}
+
+	limit := config.MaxParallelOps
+	if limit < 1 {
+		limit = 1
+	}
+	tp.golem.LogInfo("Resolving %d SRAIs concurrently (at most %d at a time)", sraiCount, limit)
+
+	semaphore := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, job := range batch {
+		if job.category == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(job *batchedSRAI) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			response, err := tp.ProcessTemplate(job.category.Template, job.wildcards, job.ctx)
+			if err != nil {
+				tp.golem.LogError("Error in template processing: %v", err)
+				response = "[Error processing template]"
+			}
+			job.response = response
+		}(job)
+	}
+	wg.Wait()
+
+	// Reassemble the template in order
+	for i := range root.Children {
+		job, isSRAI := batch[i]
+		if !isSRAI {
+			continue
+		}
+		if job.category == nil {
+			tp.golem.LogInfo("SRAI no match for: '%s'", job.content)
+			outputs[i] = job.content
+			continue
+		}
+		tp.golem.notifySRAIMatched(job.category, job.content)
+		outputs[i] = tp.applyEmptySRAIFallback(job.response, job.content, job.category, tp.sraiContext(job.category, job.content))
+		tp.golem.LogInfo("SRAI result: '%s' -> '%s'", job.content, outputs[i])
+	}
+	return tp.joinChildOutputs(root.Children, outputs), true
+}
+
+// copyVariables returns a copy of a variable map
+func copyVariables(variables map[string]string) map[string]string {
+	copied := make(map[string]string, len(variables))
+	for name, value := range variables {
+		copied[name] = value
+	}
+	return copied
+}
+
+// copySessionState returns a copy of a session for one SRAI of a batch, with its own
+// copy of every map and slice template processing could write, so the SRAIs never
+// share mutable session state. Writes to the copy are discarded with it.
+func copySessionState(session *ChatSession) *ChatSession {
+	copied := *session
+	copied.Variables = copyVariables(session.Variables)
+	copied.History = append([]string(nil), session.History...)
+	copied.ThatHistory = append([]string(nil), session.ThatHistory...)
+	copied.RequestHistory = append([]string(nil), session.RequestHistory...)
+	copied.ResponseHistory = append([]string(nil), session.ResponseHistory...)
+	copied.LearnedCategories = append([]Category(nil), session.LearnedCategories...)
+	copied.LearnedOrder = append([]string(nil), session.LearnedOrder...)
+
+	if session.ContextWeights != nil {
+		copied.ContextWeights = make(map[string]float64, len(session.ContextWeights))
+		for key, weight := range session.ContextWeights {
+			copied.ContextWeights[key] = weight
+		}
+	}
+	if session.ContextUsage != nil {
+		copied.ContextUsage = make(map[string]int, len(session.ContextUsage))
+		for key, count := range session.ContextUsage {
+			copied.ContextUsage[key] = count
+		}
+	}
+	if session.ContextTags != nil {
+		copied.ContextTags = make(map[string][]string, len(session.ContextTags))
+		for key, tags := range session.ContextTags {
+			copied.ContextTags[key] = append([]string(nil), tags...)
+		}
+	}
+	if session.ContextMetadata != nil {
+		copied.ContextMetadata = make(map[string]interface{}, len(session.ContextMetadata))
+		for key, value := range session.ContextMetadata {
+			copied.ContextMetadata[key] = value
+		}
+	}
+	if session.LearningStats != nil {
+		stats := *session.LearningStats
+		stats.LearningSources = copyCounts(session.LearningStats.LearningSources)
+		stats.PatternTypes = copyCounts(session.LearningStats.PatternTypes)
+		stats.TemplateLengths = append([]int(nil), session.LearningStats.TemplateLengths...)
+		copied.LearningStats = &stats
+	}
+	if session.RandomSelections != nil {
+		copied.RandomSelections = copyCounts(session.RandomSelections)
+	}
+	if session.RandomDecks != nil {
+		copied.RandomDecks = make(map[string][]int, len(session.RandomDecks))
+		for key, deck := range session.RandomDecks {
+			copied.RandomDecks[key] = append([]int(nil), deck...)
+		}
+	}
+	return &copied
+}
+
+// copyCounts returns a copy of a map of counts or indices
+func copyCounts(counts map[string]int) map[string]int {
+	if counts == nil {
+		return nil
+	}
+	copied := make(map[string]int, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
@@ -0,0 +1,80 @@
+package golem
+
+import "strings"
+
+// collapseSRAIWhitespace reports whether spaces left around <srai> and <sr> substitution
+// points are collapsed (see TemplateProcessingConfig.PreserveSRAIWhitespace)
+func (g *Golem) collapseSRAIWhitespace() bool {
+	return g.templateConfig == nil || !g.templateConfig.PreserveSRAIWhitespace
+}
+
+// appendCollapsingSpaces appends piece to text, dropping the spaces and tabs that
+// piece starts with when text already ends with one. Newlines are kept.
+func appendCollapsingSpaces(text, piece string) string {
+	if strings.HasSuffix(text, " ") || strings.HasSuffix(text, "\t") {
+		piece = strings.TrimLeft(piece, " \t")
+	}
+	return text + piece
+}
+
+// replaceSRAITag replaces each occurrence of an <srai> tag in template with its
+// response. Unless PreserveSRAIWhitespace is set, runs of spaces the substitution
+// leaves are collapsed, and spaces it leaves at the start or end of the template are
+// dropped, as joinChildOutputs does for the tree processor.
+func (g *Golem) replaceSRAITag(template, tag, response string) string {
+	if !g.collapseSRAIWhitespace() {
+		return strings.ReplaceAll(template, tag, response)
+	}
+	parts := strings.Split(template, tag)
+	result := parts[0]
+	for _, part := range parts[1:] {
+		result = appendCollapsingSpaces(appendCollapsingSpaces(result, response), part)
+	}
+	if parts[0] == "" {
+		result = strings.TrimLeft(result, " \t")
+	}
+	if parts[len(parts)-1] == "" {
+		result = strings.TrimRight(result, " \t")
+	}
+	return result
+}
+
+// isSRAINode reports whether node is an <srai> or <sr> tag
+func isSRAINode(node *ASTNode) bool {
+	return (node.Type == NodeTypeTag || node.Type == NodeTypeSelfClosingTag) &&
+		(node.TagName == "srai" || node.TagName == "sr")
+}
+
+// joinChildOutputs joins the processed outputs of a node's children. Next to an <srai>
+// or <sr> child, runs of spaces are collapsed to one and spaces at the start or end of
+// the joined output are dropped, so an SRAI that resolves to nothing, or to text with
+// its own spaces, doesn't leave double spaces behind. With PreserveSRAIWhitespace the
+// outputs are joined unchanged.
+func (tp *TreeProcessor) joinChildOutputs(children []*ASTNode, outputs []string) string {
+	if !tp.golem.collapseSRAIWhitespace() {
+		return strings.Join(outputs, "")
+	}
+
+	result := ""
+	afterSRAI := false
+	for i, output := range outputs {
+		isSRAI := isSRAINode(children[i])
+		if isSRAI || afterSRAI {
+			if result == "" {
+				output = strings.TrimLeft(output, " \t")
+			}
+			result = appendCollapsingSpaces(result, output)
+		} else {
+			result += output
+		}
+		if isSRAI {
+			afterSRAI = true
+		} else if output != "" {
+			afterSRAI = false
+		}
+	}
+	if afterSRAI {
+		result = strings.TrimRight(result, " \t")
+	}
+	return result
+}
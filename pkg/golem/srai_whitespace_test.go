@@ -0,0 +1,68 @@
+package golem
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSRAIWhitespacePolicy(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category><pattern>NOTHING</pattern><template><think><set name="x">1</set></think></template></category>
+	<category><pattern>NAME</pattern><template> Alice </template></category>
+	<category><pattern>EMPTY MIDDLE</pattern><template>Hello <srai>NOTHING</srai> world</template></category>
+	<category><pattern>PADDED</pattern><template>Hi <srai>NAME</srai> there</template></category>
+	<category><pattern>EMPTY START</pattern><template><srai>NOTHING</srai> Hello</template></category>
+	<category><pattern>TWO EMPTY</pattern><template>A <srai>NOTHING</srai> <srai>NOTHING</srai> B</template></category>
+	<category><pattern>NESTED</pattern><template><uppercase>a <srai>NOTHING</srai></uppercase> b</template></category>
+	<category><pattern>NEWLINES</pattern><template>Line one
+<srai>NOTHING</srai>
+Line two</template></category>
+	<category><pattern>SR *</pattern><template>You said <sr/> ok</template></category>
+</aiml>`
+
+	tests := []struct {
+		input     string
+		collapsed string
+	}{
+		{"empty middle", "Hello world"},
+		{"padded", "Hi Alice there"},
+		{"empty start", "Hello"},
+		{"two empty", "A B"},
+		{"nested", "A b"},
+		{"newlines", "Line one\n\nLine two"},
+		{"sr nothing", "You said ok"},
+	}
+
+	engines := map[string]bool{"tree": false, "legacy": true}
+	for engine, legacy := range engines {
+		for _, preserve := range []bool{false, true} {
+			g := NewForTesting(t, false)
+			if legacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			g.templateConfig.PreserveSRAIWhitespace = preserve
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("whitespace")
+
+			for _, tt := range tests {
+				response, err := g.ProcessInput(tt.input, session)
+				if err != nil {
+					t.Fatalf("%s: ProcessInput(%q) failed: %v", engine, tt.input, err)
+				}
+				if !preserve {
+					if response != tt.collapsed {
+						t.Errorf("%s: ProcessInput(%q) = %q, expected %q", engine, tt.input, response, tt.collapsed)
+					}
+				} else if tt.input == "empty middle" && response != "Hello  world" {
+					t.Errorf("%s: expected spaces to be preserved, got %q", engine, response)
+				}
+				if !preserve && strings.Contains(response, "  ") {
+					t.Errorf("%s: ProcessInput(%q) left a double space: %q", engine, tt.input, response)
+				}
+			}
+		}
+	}
+}
@@ -1,13 +1,16 @@
 package golem
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -31,16 +34,37 @@ type SRAIXConfig struct {
 	Method string `json:"method"`
 	// Headers to include in requests
 	Headers map[string]string `json:"headers"`
+	// Body format for non-GET requests: "json" (default) or "form", which sends the input
+	// and request parameters URL-encoded as application/x-www-form-urlencoded
+	RequestFormat string `json:"request_format"`
 	// Request timeout in seconds
 	Timeout int `json:"timeout"`
 	// Response format (json, xml, text)
 	ResponseFormat string `json:"response_format"`
 	// JSON path to extract response (for JSON responses)
 	ResponsePath string `json:"response_path"`
+	// Optional JSON Schema the whole JSON response must match (for JSON responses). A
+	// response that doesn't match is treated as a failed request, so the fallback or
+	// <sraix default> is used instead. With Stream, each event's JSON data is checked.
+	// See validateJSONSchema for the supported keywords.
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
 	// Fallback response when service is unavailable
 	FallbackResponse string `json:"fallback_response"`
 	// Whether to include wildcards in the request
 	IncludeWildcards bool `json:"include_wildcards"`
+	// Format of each turn that <sraix includehistory="N"> sends, with {request} and
+	// {response} placeholders (default DefaultSRAIXHistoryFormat)
+	HistoryFormat string `json:"history_format"`
+	// Whether the service streams its response as Server-Sent Events (text/event-stream).
+	// The data chunks are concatenated into a single response, stopping at [DONE].
+	Stream bool `json:"stream"`
+	// Consecutive failures (request errors or 5xx responses) after which the circuit
+	// opens and calls are skipped, returning the fallback or <sraix default>. Zero disables it.
+	CircuitBreakerThreshold int `json:"circuit_breaker_threshold"`
+	// Seconds an open circuit skips calls before a trial request (default 30)
+	CircuitBreakerCooldown int `json:"circuit_breaker_cooldown"`
+
+	responseSchema interface{} // ResponseSchema, parsed when the service is added
 }
 
 // SRAIXManager manages external service configurations and HTTP client
@@ -49,6 +73,10 @@ type SRAIXManager struct {
 	client  *http.Client
 	logger  *log.Logger
 	verbose bool
+	replay  *sraixReplay // Record/replay fixture, nil for live requests only
+
+	circuits sraixCircuits    // Per-service circuit breakers
+	now      func() time.Time // Clock source for circuit cooldowns, overridable in tests
 }
 
 // NewSRAIXManager creates a new SRAIX manager
@@ -60,6 +88,7 @@ func NewSRAIXManager(logger *log.Logger, verbose bool) *SRAIXManager {
 		},
 		logger:  logger,
 		verbose: verbose,
+		now:     time.Now,
 	}
 }
 
@@ -83,6 +112,19 @@ func (sm *SRAIXManager) AddConfig(config *SRAIXConfig) error {
 	if config.Headers == nil {
 		config.Headers = make(map[string]string)
 	}
+	switch strings.ToLower(config.RequestFormat) {
+	case "", "json", "form":
+	default:
+		return fmt.Errorf("invalid request format for SRAIX service '%s': %s", config.Name, config.RequestFormat)
+	}
+	config.responseSchema = nil
+	if len(config.ResponseSchema) > 0 {
+		schema, err := parseJSONSchema(config.ResponseSchema)
+		if err != nil {
+			return fmt.Errorf("invalid response schema for SRAIX service '%s': %v", config.Name, err)
+		}
+		config.responseSchema = schema
+	}
 
 	sm.configs[config.Name] = config
 	if sm.verbose {
@@ -106,8 +148,32 @@ func (sm *SRAIXManager) ListConfigs() map[string]*SRAIXConfig {
 	return sm.configs
 }
 
-// ProcessSRAIX processes a SRAIX tag by making an external HTTP request
+// ProcessSRAIX processes a SRAIX tag by making an external HTTP request, or by
+// serving a recorded response when replay is enabled
 func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[string]string) (string, error) {
+	return sm.ProcessSRAIXWithVariables(serviceName, input, wildcards, nil)
+}
+
+// ProcessSRAIXWithVariables is ProcessSRAIX with variables for {name} placeholders in
+// header values that the wildcards leave unresolved, such as a per-user token held in
+// a session predicate. Variables only reach headers, and headers they fill in are
+// redacted from verbose logs.
+func (sm *SRAIXManager) ProcessSRAIXWithVariables(serviceName, input string, wildcards, variables map[string]string) (string, error) {
+	if sm.replay != nil && !sm.replay.recording {
+		return sm.replay.lookup(serviceName, input)
+	}
+
+	response, err := sm.processSRAIXRequest(serviceName, input, wildcards, variables)
+	if err == nil && sm.replay != nil {
+		if recordErr := sm.replay.record(serviceName, input, response); recordErr != nil && sm.verbose {
+			sm.logger.Printf("Failed to record SRAIX response: %v", recordErr)
+		}
+	}
+	return response, err
+}
+
+// processSRAIXRequest makes the external HTTP request for a SRAIX tag
+func (sm *SRAIXManager) processSRAIXRequest(serviceName, input string, wildcards, variables map[string]string) (string, error) {
 	config, exists := sm.GetConfig(serviceName)
 	if !exists {
 		return "", fmt.Errorf("SRAIX service '%s' not configured", serviceName)
@@ -191,8 +257,12 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 		// Check if Content-Type is already configured
 		configuredContentType := config.Headers["Content-Type"]
 
-		// For form-urlencoded requests, use input directly as body
-		if configuredContentType == "application/x-www-form-urlencoded" {
+		if strings.EqualFold(config.RequestFormat, "form") {
+			// Encode the input and request parameters as form fields
+			body = strings.NewReader(sraixFormBody(input, wildcards, config.IncludeWildcards).Encode())
+			contentType = "application/x-www-form-urlencoded"
+		} else if configuredContentType == "application/x-www-form-urlencoded" {
+			// For form-urlencoded requests, use input directly as body
 			// Input is already in form-urlencoded format (e.g., "username=X&password=Y")
 			body = bytes.NewBufferString(input)
 			contentType = "application/x-www-form-urlencoded"
@@ -288,21 +358,40 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 
 	// Set headers - configured headers take precedence
 	// Substitute placeholders in header values (e.g., {access_token}, {user_id})
+	redactedHeaders := make(map[string]bool)
 	for key, value := range config.Headers {
 		// Substitute placeholders in header value
 		substitutedValue := sm.substituteURLTemplate(value, input, wildcards, config.Headers)
+		if resolved := substituteHeaderVariables(substitutedValue, variables); resolved != substitutedValue {
+			substitutedValue = resolved
+			redactedHeaders[http.CanonicalHeaderKey(key)] = true
+		}
 		req.Header.Set(key, substitutedValue)
 	}
 	// Only set Content-Type if not already configured
 	if contentType != "" && req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", contentType)
 	}
+	if config.Stream && req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 
 	// Set timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Timeout)*time.Second)
 	defer cancel()
 	req = req.WithContext(ctx)
 
+	// Skip the call while the service's circuit is open
+	if !sm.allowRequest(config) {
+		if sm.verbose {
+			sm.logger.Printf("SRAIX circuit for '%s' is open, skipping request", serviceName)
+		}
+		if config.FallbackResponse != "" {
+			return config.FallbackResponse, nil
+		}
+		return "", fmt.Errorf("SRAIX service '%s' unavailable: circuit open", serviceName)
+	}
+
 	// Make the request
 	if sm.verbose {
 		sm.logger.Printf("=== SRAIX Request to %s ===", serviceName)
@@ -310,6 +399,10 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 		sm.logger.Printf("URL: %s", url)
 		sm.logger.Printf("Headers:")
 		for key, values := range req.Header {
+			if redactedHeaders[key] {
+				sm.logger.Printf("  %s: [redacted]", key)
+				continue
+			}
 			for _, value := range values {
 				sm.logger.Printf("  %s: %s", key, value)
 			}
@@ -327,6 +420,7 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 
 	resp, err := sm.client.Do(req)
 	if err != nil {
+		sm.recordResult(config, true)
 		if sm.verbose {
 			sm.logger.Printf("SRAIX request failed: %v", err)
 		}
@@ -338,6 +432,27 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 	}
 	defer resp.Body.Close()
 
+	// The call only succeeds once its response has been read and validated, so a trial
+	// request that times out while streaming or is rejected doesn't close the circuit
+	failed := true
+	defer func() { sm.recordResult(config, failed) }()
+
+	// Streamed responses are consumed event by event; errors are read in full below
+	if config.Stream && resp.StatusCode < 400 {
+		response, err := sm.readEventStream(resp.Body, config)
+		if errors.Is(err, errSRAIXSchemaMismatch) {
+			return sm.rejectResponse(serviceName, config, err)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read event stream: %v", err)
+		}
+		failed = false
+		if sm.verbose {
+			sm.logger.Printf("SRAIX streamed response from %s: %s", serviceName, response)
+		}
+		return strings.TrimSpace(response), nil
+	}
+
 	// Read response body
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -358,8 +473,9 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 		sm.logger.Printf("============================")
 	}
 
-	// Check for HTTP errors
+	// Check for HTTP errors. Client errors mean the service is up.
 	if resp.StatusCode >= 400 {
+		failed = resp.StatusCode >= 500
 		// Return fallback response if configured
 		if config.FallbackResponse != "" {
 			return config.FallbackResponse, nil
@@ -374,14 +490,19 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 		if sm.verbose {
 			sm.logger.Printf("ResponseFormat is 'json', ResponsePath is '%s'", config.ResponsePath)
 		}
-		if config.ResponsePath != "" {
-			// Extract specific field from JSON response
+		if config.ResponsePath != "" || len(config.ResponseSchema) > 0 {
 			var jsonData interface{}
 			if err := json.Unmarshal(responseBody, &jsonData); err != nil {
 				return "", fmt.Errorf("failed to parse JSON response: %v", err)
 			}
-			// JSON path extraction (supports dot notation and array indices like "0.lat")
-			response = sm.extractJSONPath(jsonData, config.ResponsePath)
+			// Guard against upstream changes before trusting the response
+			if err := sm.validateResponse(config, jsonData); err != nil {
+				return sm.rejectResponse(serviceName, config, err)
+			}
+			if config.ResponsePath != "" {
+				// JSON path extraction (supports dot notation and array indices like "0.lat")
+				response = sm.extractJSONPath(jsonData, config.ResponsePath)
+			}
 		} else {
 			// No path specified, return full JSON
 			if sm.verbose {
@@ -397,6 +518,7 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 		// Default to text
 	}
 
+	failed = false
 	if sm.verbose {
 		sm.logger.Printf("SRAIX response from %s: %s", serviceName, response)
 	}
@@ -404,6 +526,94 @@ func (sm *SRAIXManager) ProcessSRAIX(serviceName, input string, wildcards map[st
 	return strings.TrimSpace(response), nil
 }
 
+// errSRAIXSchemaMismatch marks a response that doesn't match the service's response schema
+var errSRAIXSchemaMismatch = errors.New("SRAIX response does not match its schema")
+
+// validateResponse checks a decoded JSON response against the service's response
+// schema, if it has one
+func (sm *SRAIXManager) validateResponse(config *SRAIXConfig, jsonData interface{}) error {
+	if config.responseSchema == nil {
+		return nil
+	}
+	if err := validateJSONSchema(config.responseSchema, jsonData, "response"); err != nil {
+		return fmt.Errorf("%w: %v", errSRAIXSchemaMismatch, err)
+	}
+	return nil
+}
+
+// rejectResponse handles a response that failed validation: the fallback response if
+// the service has one, otherwise an error
+func (sm *SRAIXManager) rejectResponse(serviceName string, config *SRAIXConfig, err error) (string, error) {
+	if sm.verbose {
+		sm.logger.Printf("Warning: SRAIX response from '%s' rejected: %v", serviceName, err)
+	}
+	if config.FallbackResponse != "" {
+		return config.FallbackResponse, nil
+	}
+	return "", err
+}
+
+// readEventStream accumulates a Server-Sent Events stream into a single response. The
+// data of each event is appended in order until the stream ends or sends [DONE]. For JSON
+// services each event's data is checked against the ResponseSchema, if any, and with a
+// ResponsePath the path is extracted from it.
+func (sm *SRAIXManager) readEventStream(body io.Reader, config *SRAIXConfig) (string, error) {
+	var response strings.Builder
+	var data []string
+
+	// flush appends the buffered event data and reports whether the stream is done
+	flush := func() (bool, error) {
+		if len(data) == 0 {
+			return false, nil
+		}
+		chunk := strings.Join(data, "\n")
+		data = data[:0]
+		if chunk == "[DONE]" {
+			return true, nil
+		}
+		if config.ResponseFormat == "json" && (config.ResponsePath != "" || config.responseSchema != nil) {
+			var jsonData interface{}
+			if err := json.Unmarshal([]byte(chunk), &jsonData); err != nil {
+				return false, fmt.Errorf("failed to parse JSON event: %v", err)
+			}
+			if err := sm.validateResponse(config, jsonData); err != nil {
+				return false, err
+			}
+			if config.ResponsePath != "" {
+				chunk = sm.extractJSONPath(jsonData, config.ResponsePath)
+			}
+		}
+		response.WriteString(chunk)
+		return false, nil
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			// A blank line ends the event
+			done, err := flush()
+			if err != nil || done {
+				return response.String(), err
+			}
+		case strings.HasPrefix(line, "data:"):
+			value := strings.TrimPrefix(line, "data:")
+			data = append(data, strings.TrimPrefix(value, " "))
+		default:
+			// Comments (":") and event, id and retry fields carry no response text
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return response.String(), err
+	}
+
+	// The stream may end without a trailing blank line
+	_, err := flush()
+	return response.String(), err
+}
+
 // extractPlaceholders finds all {placeholder} patterns in a string
 func extractPlaceholders(template string) []string {
 	var placeholders []string
@@ -436,6 +646,41 @@ func extractPlaceholders(template string) []string {
 	return placeholders
 }
 
+// substituteHeaderVariables replaces {name} placeholders in a header value with
+// the matching variables, leaving unknown placeholders untouched
+func substituteHeaderVariables(value string, variables map[string]string) string {
+	if len(variables) == 0 {
+		return value
+	}
+	for _, name := range extractPlaceholders(value) {
+		if resolved, exists := variables[name]; exists {
+			value = strings.ReplaceAll(value, "{"+name+"}", resolved)
+		}
+	}
+	return value
+}
+
+// sraixFormBody builds the form fields of a RequestFormat "form" request: the input,
+// the botid, host and hint parameters when given, and, if includeWildcards is set,
+// every other wildcard under its own name
+func sraixFormBody(input string, wildcards map[string]string, includeWildcards bool) url.Values {
+	form := url.Values{}
+	form.Set("input", input)
+	for _, param := range []string{"botid", "host", "hint"} {
+		if value := wildcards[param]; value != "" {
+			form.Set(param, value)
+		}
+	}
+	if includeWildcards {
+		for key, value := range wildcards {
+			if _, exists := form[key]; !exists {
+				form.Set(key, value)
+			}
+		}
+	}
+	return form
+}
+
 // substituteURLTemplate replaces placeholders in URL template with actual values
 // Supported placeholders:
 //   {input} - the SRAIX input text
@@ -718,10 +963,18 @@ func (sm *SRAIXManager) buildConfigFromProperties(serviceName string, props map[
 			} else {
 				config.Timeout = timeout
 			}
+		case key == "requestformat":
+			config.RequestFormat = value
 		case key == "responseformat":
 			config.ResponseFormat = value
 		case key == "responsepath":
 			config.ResponsePath = value
+		case key == "responseschema":
+			if _, err := parseJSONSchema(json.RawMessage(value)); err != nil {
+				sm.logger.Printf("Warning: Invalid responseschema value for service '%s': %v", serviceName, err)
+			} else {
+				config.ResponseSchema = json.RawMessage(value)
+			}
 		case key == "fallback":
 			config.FallbackResponse = value
 		case key == "includewildcards":
@@ -731,6 +984,29 @@ func (sm *SRAIXManager) buildConfigFromProperties(serviceName string, props map[
 			} else {
 				config.IncludeWildcards = include
 			}
+		case key == "historyformat":
+			config.HistoryFormat = value
+		case key == "stream":
+			stream, err := strconv.ParseBool(value)
+			if err != nil {
+				sm.logger.Printf("Warning: Invalid stream value for service '%s': %s", serviceName, value)
+			} else {
+				config.Stream = stream
+			}
+		case key == "circuitbreakerthreshold":
+			threshold, err := strconv.Atoi(value)
+			if err != nil {
+				sm.logger.Printf("Warning: Invalid circuitbreakerthreshold value for service '%s': %s", serviceName, value)
+			} else {
+				config.CircuitBreakerThreshold = threshold
+			}
+		case key == "circuitbreakercooldown":
+			cooldown, err := strconv.Atoi(value)
+			if err != nil {
+				sm.logger.Printf("Warning: Invalid circuitbreakercooldown value for service '%s': %s", serviceName, value)
+			} else {
+				config.CircuitBreakerCooldown = cooldown
+			}
 		case strings.HasPrefix(key, "header."):
 			// Extract header name
 			headerName := strings.TrimPrefix(key, "header.")
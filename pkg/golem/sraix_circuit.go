@@ -0,0 +1,119 @@
+package golem
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states reported by SRAIXManager.CircuitState
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// DefaultCircuitBreakerCooldown is the cooldown, in seconds, used when a service
+// enables the circuit breaker without setting CircuitBreakerCooldown
+const DefaultCircuitBreakerCooldown = 30
+
+// sraixCircuit tracks consecutive failures of one SRAIX service. After the configured
+// threshold the circuit opens and calls are skipped until the cooldown passes; then a
+// single trial call is let through (half-open), which closes the circuit on success or
+// reopens it on failure.
+type sraixCircuit struct {
+	failures  int
+	openUntil time.Time
+	trial     bool // A half-open trial call is in flight
+}
+
+// sraixCircuits holds the circuit of every service that has enabled a breaker
+type sraixCircuits struct {
+	circuits map[string]*sraixCircuit
+	mutex    sync.Mutex
+}
+
+// circuitCooldown returns the configured cooldown for a service
+func circuitCooldown(config *SRAIXConfig) time.Duration {
+	cooldown := config.CircuitBreakerCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultCircuitBreakerCooldown
+	}
+	return time.Duration(cooldown) * time.Second
+}
+
+// allowRequest reports whether a call to the service may be made now
+func (sm *SRAIXManager) allowRequest(config *SRAIXConfig) bool {
+	if config.CircuitBreakerThreshold <= 0 {
+		return true
+	}
+
+	sm.circuits.mutex.Lock()
+	defer sm.circuits.mutex.Unlock()
+
+	circuit, exists := sm.circuits.circuits[config.Name]
+	if !exists || circuit.openUntil.IsZero() {
+		return true
+	}
+	if sm.now().Before(circuit.openUntil) || circuit.trial {
+		return false
+	}
+
+	// Cooldown over: let one trial request through
+	circuit.trial = true
+	if sm.verbose {
+		sm.logger.Printf("SRAIX circuit for '%s' is half-open, trying a request", config.Name)
+	}
+	return true
+}
+
+// recordResult updates the service's circuit with the outcome of a call
+func (sm *SRAIXManager) recordResult(config *SRAIXConfig, failed bool) {
+	if config.CircuitBreakerThreshold <= 0 {
+		return
+	}
+
+	sm.circuits.mutex.Lock()
+	defer sm.circuits.mutex.Unlock()
+
+	if sm.circuits.circuits == nil {
+		sm.circuits.circuits = make(map[string]*sraixCircuit)
+	}
+	circuit, exists := sm.circuits.circuits[config.Name]
+	if !exists {
+		circuit = &sraixCircuit{}
+		sm.circuits.circuits[config.Name] = circuit
+	}
+
+	if !failed {
+		if !circuit.openUntil.IsZero() && sm.verbose {
+			sm.logger.Printf("SRAIX circuit for '%s' closed", config.Name)
+		}
+		*circuit = sraixCircuit{}
+		return
+	}
+
+	circuit.failures++
+	if circuit.trial || circuit.failures >= config.CircuitBreakerThreshold {
+		circuit.openUntil = sm.now().Add(circuitCooldown(config))
+		circuit.trial = false
+		if sm.verbose {
+			sm.logger.Printf("SRAIX circuit for '%s' opened after %d consecutive failures", config.Name, circuit.failures)
+		}
+	}
+}
+
+// CircuitState reports the circuit breaker state of a service: CircuitClosed,
+// CircuitOpen or CircuitHalfOpen. Services without a breaker are always closed.
+func (sm *SRAIXManager) CircuitState(serviceName string) string {
+	sm.circuits.mutex.Lock()
+	defer sm.circuits.mutex.Unlock()
+
+	circuit, exists := sm.circuits.circuits[serviceName]
+	if !exists || circuit.openUntil.IsZero() {
+		return CircuitClosed
+	}
+	if sm.now().Before(circuit.openUntil) {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
@@ -35,6 +35,9 @@ func TestSRAIXConfigureFromProperties(t *testing.T) {
 		"sraix.custom.header.X-API-Key":      "custom-key",
 		"sraix.custom.header.X-Client-ID":    "client-123",
 		"sraix.custom.includewildcards":      "true",
+		"sraix.custom.stream":                "true",
+		"sraix.custom.circuitbreakerthreshold": "3",
+		"sraix.custom.circuitbreakercooldown":  "45",
 
 		// Invalid properties (should be ignored/warned)
 		"sraix.invalid.":                     "no property name",
@@ -109,6 +112,12 @@ func TestSRAIXConfigureFromProperties(t *testing.T) {
 	if !config.IncludeWildcards {
 		t.Error("Expected custom IncludeWildcards to be true")
 	}
+	if !config.Stream {
+		t.Error("Expected custom Stream to be true")
+	}
+	if config.CircuitBreakerThreshold != 3 || config.CircuitBreakerCooldown != 45 {
+		t.Errorf("Expected custom circuit breaker 3 failures / 45s, got %d / %d", config.CircuitBreakerThreshold, config.CircuitBreakerCooldown)
+	}
 
 	// Verify invalid properties were not configured
 	_, exists = sm.GetConfig("invalid")
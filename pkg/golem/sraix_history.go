@@ -0,0 +1,78 @@
+package golem
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultSRAIXHistoryFormat is the per-turn format used when a service sets no
+// HistoryFormat
+const DefaultSRAIXHistoryFormat = "User: {request}\nBot: {response}"
+
+// sraixHistoryPlaceholder marks where <sraix includehistory> puts the history in
+// the request content; without it the history goes ahead of the content
+const sraixHistoryPlaceholder = "{{history}}"
+
+// sraixHistory formats the last n completed request/response pairs of a session with
+// the service's HistoryFormat, one turn per line, oldest first
+func sraixHistory(config *SRAIXConfig, session *ChatSession, n int) string {
+	if session == nil || n <= 0 {
+		return ""
+	}
+
+	requests := session.RequestHistory
+	responses := session.ResponseHistory
+	// Some paths record the current request before the template runs; it has no
+	// response yet, so it isn't part of the history
+	if len(requests) == len(responses)+1 {
+		requests = requests[:len(requests)-1]
+	}
+	if n > len(requests) {
+		n = len(requests)
+	}
+	if n > len(responses) {
+		n = len(responses)
+	}
+
+	format := DefaultSRAIXHistoryFormat
+	if config != nil && config.HistoryFormat != "" {
+		format = config.HistoryFormat
+	}
+
+	turns := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		turn := strings.ReplaceAll(format, "{request}", requests[len(requests)-n+i])
+		turn = strings.ReplaceAll(turn, "{response}", responses[len(responses)-n+i])
+		turns = append(turns, turn)
+	}
+	return strings.Join(turns, "\n")
+}
+
+// withSRAIXHistory adds the session history requested by an includehistory attribute
+// to the content of a <sraix> call to serviceName. History is only sent when the
+// attribute is present; an invalid count is logged and sends none.
+func (g *Golem) withSRAIXHistory(serviceName, content, includeHistory string, session *ChatSession) string {
+	includeHistory = strings.TrimSpace(includeHistory)
+	if includeHistory == "" {
+		return strings.ReplaceAll(content, sraixHistoryPlaceholder, "")
+	}
+	n, err := strconv.Atoi(includeHistory)
+	if err != nil || n < 0 {
+		g.LogWarn("Invalid includehistory value for SRAIX service '%s': %s", serviceName, includeHistory)
+		return strings.ReplaceAll(content, sraixHistoryPlaceholder, "")
+	}
+
+	var config *SRAIXConfig
+	if g.sraixMgr != nil {
+		config, _ = g.sraixMgr.GetConfig(serviceName)
+	}
+	history := sraixHistory(config, session, n)
+
+	if strings.Contains(content, sraixHistoryPlaceholder) {
+		return strings.ReplaceAll(content, sraixHistoryPlaceholder, history)
+	}
+	if history == "" {
+		return content
+	}
+	return history + "\n" + content
+}
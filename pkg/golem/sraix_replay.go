@@ -0,0 +1,108 @@
+package golem
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// SRAIXFixtureEntry is a recorded SRAIX request and its response
+type SRAIXFixtureEntry struct {
+	Service  string `json:"service"`
+	Content  string `json:"content"`
+	Response string `json:"response"`
+}
+
+// sraixReplay records SRAIX responses to, or serves them from, a JSON fixture file
+type sraixReplay struct {
+	fixtureFile string
+	recording   bool
+	entries     []SRAIXFixtureEntry
+	index       map[string]int // (service, content) key -> position in entries
+	mutex       sync.Mutex
+}
+
+// sraixFixtureKey builds the lookup key for a recorded request
+func sraixFixtureKey(service, content string) string {
+	return service + "\x00" + content
+}
+
+// lookup returns the recorded response for a request
+func (r *sraixReplay) lookup(service, content string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if i, exists := r.index[sraixFixtureKey(service, content)]; exists {
+		return r.entries[i].Response, nil
+	}
+	return "", fmt.Errorf("no recorded SRAIX response for service '%s' and content '%s' in %s", service, content, r.fixtureFile)
+}
+
+// record stores a response, replacing any earlier one for the same request, and
+// rewrites the fixture file so recordings survive without an explicit save
+func (r *sraixReplay) record(service, content, response string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry := SRAIXFixtureEntry{Service: service, Content: content, Response: response}
+	key := sraixFixtureKey(service, content)
+	if i, exists := r.index[key]; exists {
+		r.entries[i] = entry
+	} else {
+		r.index[key] = len(r.entries)
+		r.entries = append(r.entries, entry)
+	}
+
+	data, err := json.MarshalIndent(r.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SRAIX fixture: %v", err)
+	}
+	if err := os.WriteFile(r.fixtureFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write SRAIX fixture %s: %v", r.fixtureFile, err)
+	}
+	return nil
+}
+
+// EnableSRAIXRecording makes live SRAIX requests and saves each successful
+// request/response pair to fixtureFile, for later use with EnableSRAIXReplay
+func (g *Golem) EnableSRAIXRecording(fixtureFile string) {
+	g.sraixMgr.replay = &sraixReplay{
+		fixtureFile: fixtureFile,
+		recording:   true,
+		index:       make(map[string]int),
+	}
+	g.LogInfo("Recording SRAIX responses to %s", fixtureFile)
+}
+
+// EnableSRAIXReplay serves SRAIX responses from a fixture file written by
+// EnableSRAIXRecording instead of calling external services. Requests are matched on
+// (service, content); unrecorded requests fail as if the service were unavailable.
+func (g *Golem) EnableSRAIXReplay(fixtureFile string) error {
+	data, err := os.ReadFile(fixtureFile)
+	if err != nil {
+		return fmt.Errorf("failed to read SRAIX fixture %s: %v", fixtureFile, err)
+	}
+
+	var entries []SRAIXFixtureEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse SRAIX fixture %s: %v", fixtureFile, err)
+	}
+
+	replay := &sraixReplay{
+		fixtureFile: fixtureFile,
+		entries:     entries,
+		index:       make(map[string]int, len(entries)),
+	}
+	for i, entry := range entries {
+		replay.index[sraixFixtureKey(entry.Service, entry.Content)] = i
+	}
+	g.sraixMgr.replay = replay
+	g.LogInfo("Replaying %d SRAIX responses from %s", len(entries), fixtureFile)
+	return nil
+}
+
+// DisableSRAIXReplay stops recording or replaying and returns to live SRAIX requests
+func (g *Golem) DisableSRAIXReplay() {
+	g.sraixMgr.replay = nil
+}
@@ -1,11 +1,16 @@
 package golem
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -585,3 +590,634 @@ func TestSRAIXFormUrlencodedVsJSON(t *testing.T) {
 	sm.AddConfig(formConfig)
 	sm.ProcessSRAIX("form_explicit", "key=value", make(map[string]string))
 }
+
+// TestSRAIXRecordReplay tests recording SRAIX responses to a fixture and replaying them without the service
+func TestSRAIXRecordReplay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var requestData map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&requestData)
+
+		response := map[string]interface{}{
+			"message": fmt.Sprintf("Live answer %d to: %v", requests, requestData["input"]),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+
+	config := &SRAIXConfig{
+		Name:           "external_chat",
+		BaseURL:        server.URL,
+		Method:         "POST",
+		Timeout:        5,
+		ResponseFormat: "json",
+		ResponsePath:   "message",
+	}
+	template := `<sraix service="external_chat">ASK <star/></sraix>`
+	fixtureFile := filepath.Join(t.TempDir(), "sraix_fixture.json")
+
+	// Record against the live service
+	recorder := NewForTesting(t, false)
+	if err := recorder.AddSRAIXConfig(config); err != nil {
+		t.Fatalf("Failed to add SRAIX config: %v", err)
+	}
+	recorder.SetKnowledgeBase(NewAIMLKnowledgeBase())
+	recorder.EnableSRAIXRecording(fixtureFile)
+
+	recorded := map[string]string{}
+	for _, question := range []string{"weather", "time"} {
+		recorded[question] = recorder.ProcessTemplate(template, map[string]string{"star1": question})
+		if !strings.HasPrefix(recorded[question], "Live answer") {
+			t.Fatalf("Expected live response while recording, got: %s", recorded[question])
+		}
+	}
+	server.Close()
+
+	var fixture []SRAIXFixtureEntry
+	data, err := os.ReadFile(fixtureFile)
+	if err != nil {
+		t.Fatalf("Expected fixture file to be written: %v", err)
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("Fixture is not valid JSON: %v", err)
+	}
+	if len(fixture) != 2 || fixture[0].Service != "external_chat" || fixture[0].Content != "ASK weather" {
+		t.Fatalf("Unexpected fixture contents: %+v", fixture)
+	}
+
+	// Replay with the service gone and no configuration at all
+	replayer := NewForTesting(t, false)
+	replayer.SetKnowledgeBase(NewAIMLKnowledgeBase())
+	if err := replayer.EnableSRAIXReplay(fixtureFile); err != nil {
+		t.Fatalf("EnableSRAIXReplay failed: %v", err)
+	}
+	for question, expected := range recorded {
+		for i := 0; i < 2; i++ {
+			response := replayer.ProcessTemplate(template, map[string]string{"star1": question})
+			if response != expected {
+				t.Errorf("Expected replayed response '%s' for %s, got '%s'", expected, question, response)
+			}
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected only the 2 recorded live requests, got %d", requests)
+	}
+
+	// Unrecorded requests fail as if the service were unavailable
+	if _, err := replayer.sraixMgr.ProcessSRAIX("external_chat", "ASK something new", map[string]string{}); err == nil {
+		t.Error("Expected an error for an unrecorded request")
+	}
+
+	if err := replayer.EnableSRAIXReplay(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing fixture file")
+	}
+}
+
+// TestSRAIXStreaming tests accumulating a Server-Sent Events response
+func TestSRAIXStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != "text/event-stream" {
+			t.Errorf("Expected Accept text/event-stream, got '%s'", accept)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+
+		if r.URL.Path == "/json" {
+			for _, token := range []string{"Hello", ", ", "world"} {
+				fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", token)
+				flusher.Flush()
+			}
+		} else {
+			fmt.Fprint(w, ": keep-alive\n\n")
+			fmt.Fprint(w, "event: message\ndata: Streamed \n\n")
+			fmt.Fprint(w, "data: text\n\n")
+			flusher.Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		fmt.Fprint(w, "data: ignored after done\n\n")
+	}))
+	defer server.Close()
+
+	g := NewForTesting(t, false)
+	g.SetKnowledgeBase(NewAIMLKnowledgeBase())
+
+	configs := []*SRAIXConfig{
+		{
+			Name:           "stream_text",
+			BaseURL:        server.URL + "/text",
+			Method:         "POST",
+			Timeout:        5,
+			ResponseFormat: "text",
+			Stream:         true,
+		},
+		{
+			Name:           "stream_json",
+			BaseURL:        server.URL + "/json",
+			Method:         "POST",
+			Timeout:        5,
+			ResponseFormat: "json",
+			ResponsePath:   "choices.0.delta.content",
+			Stream:         true,
+		},
+	}
+	for _, config := range configs {
+		if err := g.AddSRAIXConfig(config); err != nil {
+			t.Fatalf("Failed to add SRAIX config: %v", err)
+		}
+	}
+
+	tests := []struct {
+		service  string
+		expected string
+	}{
+		{"stream_text", "Streamed text"},
+		{"stream_json", "Hello, world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			template := `<sraix service="` + tt.service + `">hi</sraix>`
+			response := g.ProcessTemplate(template, make(map[string]string))
+			if response != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+}
+
+// TestSRAIXHeaderVariables tests resolving {name} header placeholders from the variable context
+func TestSRAIXHeaderVariables(t *testing.T) {
+	var quietAuthorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/quiet" {
+			quietAuthorization = r.Header.Get("Authorization")
+			fmt.Fprint(w, "ok")
+			return
+		}
+		fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("Authorization"), r.Header.Get("X-Client"), r.Header.Get("X-Missing"))
+	}))
+	defer server.Close()
+
+	g := NewForTesting(t, false)
+	kb := NewAIMLKnowledgeBase()
+	kb.Properties["client_name"] = "golem-bot"
+	g.SetKnowledgeBase(kb)
+
+	config := &SRAIXConfig{
+		Name:    "per_user",
+		BaseURL: server.URL,
+		Method:  "POST",
+		Headers: map[string]string{
+			"Authorization": "Bearer {user_token}",
+			"X-Client":      "{client_name}",
+			"X-Missing":     "{not_set}",
+		},
+		Timeout:        5,
+		ResponseFormat: "text",
+	}
+	if err := g.AddSRAIXConfig(config); err != nil {
+		t.Fatalf("Failed to add SRAIX config: %v", err)
+	}
+
+	template := `<sraix service="per_user">hi</sraix>`
+	for _, processor := range []string{"tree", "consolidated"} {
+		t.Run(processor, func(t *testing.T) {
+			if processor == "consolidated" {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+				defer g.SetTemplateProcessor(nil)
+			}
+			session := g.CreateSession("header_vars_" + processor)
+			session.Variables["user_token"] = "token-for-" + processor
+
+			response := g.ProcessTemplateWithContext(template, make(map[string]string), session)
+			expected := "Bearer token-for-" + processor + "|golem-bot|{not_set}"
+			if response != expected {
+				t.Errorf("Expected '%s', got '%s'", expected, response)
+			}
+		})
+	}
+
+	// Resolved header values never appear in verbose logs
+	var logs bytes.Buffer
+	sm := NewSRAIXManager(log.New(&logs, "", 0), true)
+	if err := sm.AddConfig(&SRAIXConfig{
+		Name:           "logged",
+		BaseURL:        server.URL + "/quiet",
+		Method:         "POST",
+		Headers:        map[string]string{"Authorization": "Bearer {user_token}"},
+		Timeout:        5,
+		ResponseFormat: "text",
+	}); err != nil {
+		t.Fatalf("Failed to add SRAIX config: %v", err)
+	}
+	if _, err := sm.ProcessSRAIXWithVariables("logged", "hi", make(map[string]string), map[string]string{"user_token": "s3cret"}); err != nil {
+		t.Fatalf("ProcessSRAIXWithVariables failed: %v", err)
+	}
+	if quietAuthorization != "Bearer s3cret" {
+		t.Errorf("Expected the token in the request header, got '%s'", quietAuthorization)
+	}
+	if strings.Contains(logs.String(), "s3cret") {
+		t.Errorf("Resolved header value leaked into logs:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "Authorization: [redacted]") {
+		t.Errorf("Expected redacted Authorization header in logs:\n%s", logs.String())
+	}
+}
+
+// TestSRAIXCircuitBreaker tests opening, half-opening and closing a service's circuit
+func TestSRAIXCircuitBreaker(t *testing.T) {
+	status := http.StatusInternalServerError
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+		fmt.Fprint(w, "pong")
+	}))
+	defer server.Close()
+
+	g := NewForTesting(t, false)
+	g.SetKnowledgeBase(NewAIMLKnowledgeBase())
+	sm := g.sraixMgr
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sm.now = func() time.Time { return now }
+
+	if err := g.AddSRAIXConfig(&SRAIXConfig{
+		Name:                    "flaky",
+		BaseURL:                 server.URL,
+		Method:                  "POST",
+		Timeout:                 5,
+		ResponseFormat:          "text",
+		CircuitBreakerThreshold: 2,
+		CircuitBreakerCooldown:  10,
+	}); err != nil {
+		t.Fatalf("Failed to add SRAIX config: %v", err)
+	}
+
+	ask := func() string {
+		return g.ProcessTemplate(`<sraix service="flaky" default="busy">ping</sraix>`, make(map[string]string))
+	}
+	expect := func(step, response string, wantCalls int, wantState string) {
+		t.Helper()
+		if got := ask(); got != response {
+			t.Errorf("%s: expected response '%s', got '%s'", step, response, got)
+		}
+		if calls != wantCalls {
+			t.Errorf("%s: expected %d calls to the service, got %d", step, wantCalls, calls)
+		}
+		if state := sm.CircuitState("flaky"); state != wantState {
+			t.Errorf("%s: expected circuit %s, got %s", step, wantState, state)
+		}
+	}
+
+	expect("first failure", "busy", 1, CircuitClosed)
+	expect("second failure opens", "busy", 2, CircuitOpen)
+	expect("open circuit skips the call", "busy", 2, CircuitOpen)
+
+	now = now.Add(11 * time.Second)
+	if state := sm.CircuitState("flaky"); state != CircuitHalfOpen {
+		t.Errorf("Expected circuit half-open after the cooldown, got %s", state)
+	}
+	expect("failed trial reopens", "busy", 3, CircuitOpen)
+
+	now = now.Add(11 * time.Second)
+	status = http.StatusOK
+	expect("successful trial closes", "pong", 4, CircuitClosed)
+
+	// Client errors mean the service is up and do not count as failures
+	status = http.StatusNotFound
+	for i := 0; i < 3; i++ {
+		ask()
+	}
+	if calls != 7 || sm.CircuitState("flaky") != CircuitClosed {
+		t.Errorf("Expected 4xx responses to leave the circuit closed, got %d calls and %s", calls, sm.CircuitState("flaky"))
+	}
+
+	// Without a threshold the breaker is disabled
+	status = http.StatusInternalServerError
+	if err := g.AddSRAIXConfig(&SRAIXConfig{Name: "unguarded", BaseURL: server.URL, Timeout: 5}); err != nil {
+		t.Fatalf("Failed to add SRAIX config: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		g.ProcessTemplate(`<sraix service="unguarded">ping</sraix>`, make(map[string]string))
+	}
+	if calls != 12 || sm.CircuitState("unguarded") != CircuitClosed {
+		t.Errorf("Expected every call to reach a service without a breaker, got %d calls and %s", calls, sm.CircuitState("unguarded"))
+	}
+}
+
+func TestSRAIXCircuitBreakerReadFailures(t *testing.T) {
+	event := `{"delta": "Hello"}`
+	hang := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		if hang {
+			// Send the headers and part of an event, then stall until the client gives up
+			fmt.Fprint(w, "data: ")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", event)
+	}))
+	defer server.Close()
+
+	g := NewForTesting(t, false)
+	g.SetKnowledgeBase(NewAIMLKnowledgeBase())
+	sm := g.sraixMgr
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sm.now = func() time.Time { return now }
+
+	if err := g.AddSRAIXConfig(&SRAIXConfig{
+		Name:                    "stalling",
+		BaseURL:                 server.URL,
+		Timeout:                 1,
+		ResponseFormat:          "json",
+		ResponsePath:            "delta",
+		ResponseSchema:          json.RawMessage(`{"type": "object", "required": ["delta"]}`),
+		Stream:                  true,
+		CircuitBreakerThreshold: 1,
+		CircuitBreakerCooldown:  10,
+	}); err != nil {
+		t.Fatalf("Failed to add SRAIX config: %v", err)
+	}
+
+	expect := func(step, response, wantState string) {
+		t.Helper()
+		got := g.ProcessTemplate(`<sraix service="stalling" default="busy">hi</sraix>`, make(map[string]string))
+		if got != response {
+			t.Errorf("%s: expected response '%s', got '%s'", step, response, got)
+		}
+		if state := sm.CircuitState("stalling"); state != wantState {
+			t.Errorf("%s: expected circuit %s, got %s", step, wantState, state)
+		}
+	}
+
+	expect("timeout while reading opens", "busy", CircuitOpen)
+
+	now = now.Add(11 * time.Second)
+	expect("trial timing out while reading reopens", "busy", CircuitOpen)
+
+	now = now.Add(11 * time.Second)
+	hang = false
+	event = `{"text": "Hello"}`
+	expect("trial rejected by the schema reopens", "busy", CircuitOpen)
+
+	now = now.Add(11 * time.Second)
+	event = `{"delta": "Hello"}`
+	expect("successful trial closes", "Hello", CircuitClosed)
+}
+
+func TestSRAIXResponseSchema(t *testing.T) {
+	body := `{"current": {"temp": 21.5, "conditions": "sunny"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	g := NewForTesting(t, false)
+	g.SetKnowledgeBase(NewAIMLKnowledgeBase())
+	g.sraixMgr.logger = log.New(&logs, "", 0)
+	g.sraixMgr.verbose = true // Rejected responses are logged in verbose mode
+
+	schema := json.RawMessage(`{
+		"type": "object",
+		"required": ["current"],
+		"properties": {
+			"current": {
+				"type": "object",
+				"required": ["temp"],
+				"properties": {
+					"temp": {"type": "number"},
+					"conditions": {"type": "string", "enum": ["sunny", "cloudy", "rain"]}
+				}
+			}
+		}
+	}`)
+	for _, config := range []*SRAIXConfig{
+		{Name: "weather", BaseURL: server.URL, Method: "GET", ResponseFormat: "json", ResponsePath: "current.temp", ResponseSchema: schema},
+		{Name: "weather_fallback", BaseURL: server.URL, Method: "GET", ResponseFormat: "json", ResponsePath: "current.temp", ResponseSchema: schema, FallbackResponse: "no forecast"},
+	} {
+		if err := g.AddSRAIXConfig(config); err != nil {
+			t.Fatalf("Failed to add SRAIX config: %v", err)
+		}
+	}
+
+	ask := func(service string) string {
+		return g.ProcessTemplate(`<sraix service="`+service+`" default="unavailable">forecast</sraix>`, make(map[string]string))
+	}
+
+	if response := ask("weather"); response != "21.5" {
+		t.Errorf("Expected a matching response to be extracted, got '%s'", response)
+	}
+	if strings.Contains(logs.String(), "Warning") {
+		t.Errorf("Expected no warnings for a matching response, got:\n%s", logs.String())
+	}
+
+	// The upstream API changes the type of temp
+	body = `{"current": {"temp": "21.5 C", "conditions": "sunny"}}`
+	if response := ask("weather"); response != "unavailable" {
+		t.Errorf("Expected the default for a response violating the schema, got '%s'", response)
+	}
+	if !strings.Contains(logs.String(), "response.current.temp: expected type number, got string") {
+		t.Errorf("Expected a schema warning, got:\n%s", logs.String())
+	}
+	if response := ask("weather_fallback"); response != "no forecast" {
+		t.Errorf("Expected the configured fallback, got '%s'", response)
+	}
+
+	// Missing properties and values outside an enum also fail validation
+	for _, invalid := range []string{`{"forecast": []}`, `{"current": {"temp": 3, "conditions": "snow"}}`} {
+		body = invalid
+		if response := ask("weather"); response != "unavailable" {
+			t.Errorf("Expected the default for %s, got '%s'", invalid, response)
+		}
+	}
+
+	if err := g.AddSRAIXConfig(&SRAIXConfig{Name: "broken", BaseURL: server.URL, ResponseSchema: json.RawMessage(`["not", "a", "schema"]`)}); err == nil {
+		t.Error("Expected an error for an invalid response schema")
+	}
+}
+
+// TestSRAIXStreamingResponseSchema tests that the events of a streamed JSON response
+// are checked against the response schema too
+func TestSRAIXStreamingResponseSchema(t *testing.T) {
+	events := []string{`{"delta": "Hello"}`, `{"delta": ", world"}`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	g := NewForTesting(t, false)
+	g.SetKnowledgeBase(NewAIMLKnowledgeBase())
+
+	schema := json.RawMessage(`{"type": "object", "required": ["delta"], "properties": {"delta": {"type": "string"}}}`)
+	for _, config := range []*SRAIXConfig{
+		{Name: "stream", BaseURL: server.URL, ResponseFormat: "json", ResponsePath: "delta", ResponseSchema: schema, Stream: true},
+		{Name: "stream_fallback", BaseURL: server.URL, ResponseFormat: "json", ResponsePath: "delta", ResponseSchema: schema, Stream: true, FallbackResponse: "no stream"},
+	} {
+		if err := g.AddSRAIXConfig(config); err != nil {
+			t.Fatalf("Failed to add SRAIX config: %v", err)
+		}
+	}
+
+	ask := func(service string) string {
+		return g.ProcessTemplate(`<sraix service="`+service+`" default="unavailable">hi</sraix>`, make(map[string]string))
+	}
+
+	if response := ask("stream"); response != "Hello, world" {
+		t.Errorf("Expected a matching stream to be assembled, got '%s'", response)
+	}
+
+	// One event changes the type of delta
+	events = []string{`{"delta": "Hello"}`, `{"delta": 42}`}
+	if response := ask("stream"); response != "unavailable" {
+		t.Errorf("Expected the default for a stream violating the schema, got '%s'", response)
+	}
+	if response := ask("stream_fallback"); response != "no stream" {
+		t.Errorf("Expected the configured fallback, got '%s'", response)
+	}
+}
+
+func TestSRAIXRequestFormatForm(t *testing.T) {
+	var received url.Values
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form body: %v", err)
+		}
+		received = r.PostForm
+		fmt.Fprintf(w, "got %s", r.PostForm.Get("input"))
+	}))
+	defer server.Close()
+
+	sm := NewSRAIXManager(log.New(io.Discard, "", 0), false)
+	if err := sm.AddConfig(&SRAIXConfig{
+		Name:             "legacy_form",
+		BaseURL:          server.URL,
+		Method:           "POST",
+		RequestFormat:    "form",
+		IncludeWildcards: true,
+	}); err != nil {
+		t.Fatalf("Failed to add SRAIX config: %v", err)
+	}
+
+	response, err := sm.ProcessSRAIX("legacy_form", "fish & chips, 5% off", map[string]string{"hint": "menu", "city": "Leeds"})
+	if err != nil {
+		t.Fatalf("SRAIX processing failed: %v", err)
+	}
+	if response != "got fish & chips, 5% off" {
+		t.Errorf("Expected the input to round-trip through the form body, got '%s'", response)
+	}
+	if contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected a form Content-Type, got '%s'", contentType)
+	}
+	if received.Get("hint") != "menu" || received.Get("city") != "Leeds" {
+		t.Errorf("Expected request parameters as form fields, got %v", received)
+	}
+
+	// Without IncludeWildcards only the input and SRAIX parameters are sent
+	if err := sm.AddConfig(&SRAIXConfig{Name: "form_only", BaseURL: server.URL, RequestFormat: "form"}); err != nil {
+		t.Fatalf("Failed to add SRAIX config: %v", err)
+	}
+	if _, err := sm.ProcessSRAIX("form_only", "hello", map[string]string{"hint": "greeting", "city": "Leeds"}); err != nil {
+		t.Fatalf("SRAIX processing failed: %v", err)
+	}
+	if len(received) != 2 || received.Get("input") != "hello" || received.Get("hint") != "greeting" {
+		t.Errorf("Expected only input and hint, got %v", received)
+	}
+
+	// The format can be set from properties, and unknown formats are rejected
+	if err := sm.ConfigureFromProperties(map[string]string{
+		"sraix.props_form.baseurl":       server.URL,
+		"sraix.props_form.requestformat": "form",
+	}); err != nil {
+		t.Fatalf("ConfigureFromProperties failed: %v", err)
+	}
+	if config, _ := sm.GetConfig("props_form"); config == nil || config.RequestFormat != "form" {
+		t.Errorf("Expected requestformat to be read from properties, got %+v", config)
+	}
+	if err := sm.AddConfig(&SRAIXConfig{Name: "bad_format", BaseURL: server.URL, RequestFormat: "xml"}); err == nil {
+		t.Error("Expected an error for an unknown request format")
+	}
+}
+
+func TestSRAIXIncludeHistory(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		received, _ = request["input"].(string)
+		fmt.Fprint(w, "answer")
+	}))
+	defer server.Close()
+
+	aimlContent := `<aiml version="2.0">
+    <category><pattern>HELLO</pattern><template>Hi there</template></category>
+    <category><pattern>MY NAME IS *</pattern><template>Nice to meet you</template></category>
+    <category><pattern>ASK *</pattern><template><sraix service="llm" includehistory="2"><star/></sraix></template></category>
+    <category><pattern>PLACEHOLDER *</pattern><template><sraix service="chat" includehistory="1">Context: {{history}} Question: <star/></sraix></template></category>
+    <category><pattern>PRIVATE *</pattern><template><sraix service="llm"><star/></sraix></template></category>
+</aiml>`
+
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			if err := g.AddSRAIXConfig(&SRAIXConfig{Name: "llm", BaseURL: server.URL, Method: "POST"}); err != nil {
+				t.Fatalf("Failed to add SRAIX config: %v", err)
+			}
+			if err := g.AddSRAIXConfig(&SRAIXConfig{Name: "chat", BaseURL: server.URL, Method: "POST", HistoryFormat: "[{request} => {response}]"}); err != nil {
+				t.Fatalf("Failed to add SRAIX config: %v", err)
+			}
+
+			session := g.CreateSession("sraix_history_" + name)
+			for _, input := range []string{"hello", "my name is Ann"} {
+				if _, err := g.ProcessInput(input, session); err != nil {
+					t.Fatalf("ProcessInput(%q) failed: %v", input, err)
+				}
+			}
+
+			// The last two turns go ahead of the content, oldest first
+			if _, err := g.ProcessInput("ask what is my name", session); err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			expected := "User: hello\nBot: Hi there\nUser: my name is Ann\nBot: Nice to meet you\nwhat is my name"
+			if received != expected {
+				t.Errorf("Expected request content %q, got %q", expected, received)
+			}
+
+			// The {{history}} placeholder places the history, in the service's format
+			if _, err := g.ProcessInput("placeholder why", session); err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			expected = "Context: [ask what is my name => answer] Question: why"
+			if received != expected {
+				t.Errorf("Expected request content %q, got %q", expected, received)
+			}
+
+			// Without the attribute no history is sent
+			if _, err := g.ProcessInput("private secret", session); err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if received != "secret" {
+				t.Errorf("Expected only the content without includehistory, got %q", received)
+			}
+		})
+	}
+}
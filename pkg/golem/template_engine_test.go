@@ -0,0 +1,110 @@
+package golem
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// stubTemplateEngine records the templates it receives and returns a fixed response
+type stubTemplateEngine struct {
+	templates []string
+	wildcards []map[string]string
+	response  string
+	err       error
+}
+
+func (s *stubTemplateEngine) ProcessTemplate(template string, wildcards map[string]string, ctx *VariableContext) (string, error) {
+	s.templates = append(s.templates, template)
+	s.wildcards = append(s.wildcards, wildcards)
+	return s.response, s.err
+}
+
+func TestSetTemplateProcessorStub(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>HELLO *</pattern>
+        <template>Hi <star/>!</template>
+    </category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("template_engine_stub")
+
+	// Default engine is the tree processor
+	response, err := g.ProcessInput("hello world", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if !strings.Contains(response, "Hi world!") {
+		t.Errorf("Expected default processing, got: %s", response)
+	}
+
+	stub := &stubTemplateEngine{response: "stubbed"}
+	g.SetTemplateProcessor(stub)
+
+	response, err = g.ProcessInput("hello world", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if response != "stubbed" {
+		t.Errorf("Expected stub response, got: %s", response)
+	}
+	if len(stub.templates) != 1 || stub.templates[0] != "Hi <star/>!" {
+		t.Errorf("Expected stub to receive the raw template, got: %v", stub.templates)
+	}
+	if stub.wildcards[0]["star1"] != "world" {
+		t.Errorf("Expected star1 wildcard 'world', got: %v", stub.wildcards[0])
+	}
+
+	// Engine errors are reported like tree processor errors
+	stub.err = errors.New("boom")
+	response, _ = g.ProcessInput("hello world", session)
+	if response != "[Error processing template]" {
+		t.Errorf("Expected error placeholder, got: %s", response)
+	}
+
+	// nil restores the tree processor
+	g.SetTemplateProcessor(nil)
+	response, err = g.ProcessInput("hello world", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if !strings.Contains(response, "Hi world!") {
+		t.Errorf("Expected default processing after reset, got: %s", response)
+	}
+}
+
+func TestProcessTemplateE(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	response, err := g.ProcessTemplateE("Hello <star/>!", map[string]string{"star1": "world"})
+	if err != nil {
+		t.Fatalf("ProcessTemplateE failed: %v", err)
+	}
+	if response != "Hello world!" {
+		t.Errorf("Expected 'Hello world!', got '%s'", response)
+	}
+
+	// Tree processor failures surface as errors
+	g.GetTemplateProcessingConfig().StrictTagErrors = true
+	template := "Hi <think><set name=\"x\">y</set></think><uppercase>there"
+	if _, err := g.ProcessTemplateE(template, nil); err == nil {
+		t.Error("Expected an error from a failing tag in strict mode")
+	}
+	if response := g.ProcessTemplate(template, nil); response != "[Error processing template]" {
+		t.Errorf("Expected ProcessTemplate to keep the placeholder, got '%s'", response)
+	}
+
+	// Errors from an installed engine are returned unchanged
+	boom := errors.New("boom")
+	g.SetTemplateProcessor(&stubTemplateEngine{err: boom})
+	if _, err := g.ProcessTemplateE("anything", nil); !errors.Is(err, boom) {
+		t.Errorf("Expected the engine's error, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package golem
+
+// foldedTemplate is the precomputed output of a template without tags
+type foldedTemplate struct {
+	template string // The template the output was computed from
+	output   string
+}
+
+// foldTemplate returns the precomputed output of a template made only of text,
+// comments and CDATA, or nil if the template has tags (or doesn't parse) and so must
+// be processed each time. The output is what the tree processor would produce for it.
+func foldTemplate(template string) *foldedTemplate {
+	ast, err := NewASTParser(template).Parse()
+	if err != nil || !isStaticNode(ast) {
+		return nil
+	}
+	return &foldedTemplate{
+		template: template,
+		output:   trimTemplateOutput(staticNodeText(ast)),
+	}
+}
+
+// isStaticNode reports whether node and its descendants are free of tags
+func isStaticNode(node *ASTNode) bool {
+	switch node.Type {
+	case NodeTypeText, NodeTypeComment, NodeTypeCDATA:
+	default:
+		return false
+	}
+	for _, child := range node.Children {
+		if !isStaticNode(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// staticNodeText returns the output of a static node, as processNode renders it
+func staticNodeText(node *ASTNode) string {
+	switch node.Type {
+	case NodeTypeComment:
+		return ""
+	case NodeTypeText:
+		if len(node.Children) > 0 {
+			text := ""
+			for _, child := range node.Children {
+				text += staticNodeText(child)
+			}
+			return text
+		}
+	}
+	return node.Content
+}
+
+// foldCategoryTemplate precomputes the output of a category's template if it has no
+// tags. It is called when the category is indexed, so reads during matching don't race.
+func foldCategoryTemplate(category *Category) {
+	if category.folded != nil && category.folded.template == category.Template {
+		return
+	}
+	category.folded = foldTemplate(category.Template)
+}
+
+// processCategoryTemplate processes a matched category's template, returning the
+// folded output directly when the template has no tags and the tree processor is in
+// use. A template changed since it was folded is processed normally.
+func (g *Golem) processCategoryTemplate(category *Category, wildcards map[string]string, ctx *VariableContext) string {
+	if g.templateEngine == nil && g.useTreeProcessing && category.folded != nil && category.folded.template == category.Template {
+		return category.folded.output
+	}
+	return g.processTemplateWithContext(category.Template, wildcards, ctx)
+}
@@ -0,0 +1,150 @@
+package golem
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTemplateFolding(t *testing.T) {
+	templates := []struct {
+		name     string
+		template string
+		static   bool
+	}{
+		{name: "Plain text", template: "Hello there!", static: true},
+		{name: "Entities", template: "Fish &amp; chips &lt;3", static: true},
+		{name: "Formatting whitespace", template: "\n\t\tLine one.\n\t\tLine two.\n\t", static: true},
+		{name: "Leading spaces", template: "   indented", static: true},
+		{name: "Comment", template: "Before<!-- note -->after", static: true},
+		{name: "CDATA", template: "<![CDATA[<b>raw</b>]]> text", static: true},
+		{name: "Empty", template: "", static: true},
+		{name: "Wildcard", template: "You said <star/>", static: false},
+		{name: "Formatting tag", template: "<uppercase>loud</uppercase>", static: false},
+		{name: "Nested tag", template: "Say <random><li>hi</li></random>", static: false},
+	}
+
+	for _, tt := range templates {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			err := g.LoadAIMLFromString(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category><pattern>TEST *</pattern><template>%s</template></category>
+</aiml>`, tt.template))
+			if err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+
+			category := g.aimlKB.Patterns["TEST *"]
+			if (category.folded != nil) != tt.static {
+				t.Fatalf("Expected folded=%v for %q", tt.static, category.Template)
+			}
+
+			session := g.CreateSession("folding")
+			folded, err := g.ProcessInput("test input", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			ctx := &VariableContext{LocalVars: map[string]string{}, Session: session, KnowledgeBase: g.aimlKB}
+			processed := g.processTemplateWithContext(category.Template, map[string]string{"star1": "input"}, ctx)
+			if tt.static && folded != processed {
+				t.Errorf("Folded output %q differs from processed output %q", folded, processed)
+			}
+		})
+	}
+
+	t.Run("Changed template", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category><pattern>HELLO</pattern><template>Hi.</template></category>
+</aiml>`)
+		if err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+
+		// A template changed after folding is processed again
+		g.aimlKB.Patterns["HELLO"].Template = "<uppercase>hi</uppercase>"
+		response, err := g.ProcessInput("hello", g.CreateSession("changed"))
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != "HI" {
+			t.Errorf("Expected 'HI', got '%s'", response)
+		}
+	})
+
+	t.Run("Tree processing disabled", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category><pattern>HELLO</pattern><template>Hi.</template></category>
+</aiml>`)
+		if err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+
+		// Marks where the folded output is used instead of processing the template
+		g.aimlKB.Patterns["HELLO"].folded.output = "folded"
+		session := g.CreateSession("tree_disabled")
+		if response, _ := g.ProcessInput("hello", session); response != "folded" {
+			t.Errorf("Expected the folded output with the tree processor, got '%s'", response)
+		}
+
+		g.DisableTreeProcessing()
+		if response, _ := g.ProcessInput("hello", session); response != "Hi." {
+			t.Errorf("Expected the template to be processed with tree processing disabled, got '%s'", response)
+		}
+	})
+}
+
+// benchmarkStaticCategories loads a knowledge base of static templates and returns
+// its categories
+func benchmarkStaticCategories(b *testing.B) (*Golem, []*Category) {
+	g := New(false)
+	g.persistentLearning = NewPersistentLearningManager(b.TempDir())
+
+	aiml := ""
+	for i := 0; i < 100; i++ {
+		aiml += fmt.Sprintf(`<category>
+			<pattern>PATTERN %d</pattern>
+			<template>
+				This is static response number %d, with a few sentences of text.
+				It has no tags &amp; no wildcards, so it can be folded.
+			</template>
+		</category>`, i, i)
+	}
+	if err := g.LoadAIMLFromString(aiml); err != nil {
+		b.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	categories := make([]*Category, 0, len(g.aimlKB.Patterns))
+	for _, category := range g.aimlKB.Patterns {
+		categories = append(categories, category)
+	}
+	return g, categories
+}
+
+// BenchmarkStaticTemplatesFolded measures answering with folded static templates
+func BenchmarkStaticTemplatesFolded(b *testing.B) {
+	g, categories := benchmarkStaticCategories(b)
+	session := g.createSession("test_session")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.processCategoryWithContext(categories[i%len(categories)], "", nil, session)
+	}
+}
+
+// BenchmarkStaticTemplatesUnfolded measures the same templates with folding disabled
+func BenchmarkStaticTemplatesUnfolded(b *testing.B) {
+	g, categories := benchmarkStaticCategories(b)
+	for _, category := range categories {
+		category.folded = nil
+	}
+	session := g.createSession("test_session")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.processCategoryWithContext(categories[i%len(categories)], "", nil, session)
+	}
+}
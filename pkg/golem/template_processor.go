@@ -2,6 +2,7 @@ package golem
 
 import (
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -76,6 +77,13 @@ type ProcessorMetrics struct {
 	CacheMisses  int64         `json:"cache_misses"`
 }
 
+// TemplateEngine processes a whole matched template into a response.
+// The default engine is the TreeProcessor; alternates can be installed with
+// SetTemplateProcessor, e.g. to A/B test processing strategies.
+type TemplateEngine interface {
+	ProcessTemplate(template string, wildcards map[string]string, ctx *VariableContext) (string, error)
+}
+
 // TemplateProcessor defines the interface for template processors
 type TemplateProcessor interface {
 	// Name returns the processor name
@@ -108,6 +116,8 @@ type ProcessorRegistry struct {
 	processors map[string]TemplateProcessor
 	order      []string
 	metrics    map[string]*ProcessorMetrics
+	// metricsMutex guards metric updates, since templates are processed concurrently
+	metricsMutex sync.Mutex
 }
 
 // NewProcessorRegistry creates a new processor registry
@@ -179,14 +189,18 @@ func (r *ProcessorRegistry) ProcessTemplate(template string, wildcards map[strin
 		processingTime := time.Since(startTime)
 
 		// Update metrics
+		r.metricsMutex.Lock()
 		metrics := r.metrics[processor.Name()]
 		metrics.TotalCalls++
 		metrics.TotalTime += processingTime
 		metrics.AverageTime = time.Duration(int64(metrics.TotalTime) / metrics.TotalCalls)
 		metrics.LastCallTime = time.Now()
-
 		if err != nil {
 			metrics.ErrorCount++
+		}
+		r.metricsMutex.Unlock()
+
+		if err != nil {
 			return response, err
 		}
 
@@ -203,6 +217,8 @@ func (r *ProcessorRegistry) GetMetrics() map[string]*ProcessorMetrics {
 
 // ResetMetrics resets metrics for all processors
 func (r *ProcessorRegistry) ResetMetrics() {
+	r.metricsMutex.Lock()
+	defer r.metricsMutex.Unlock()
 	for _, metrics := range r.metrics {
 		*metrics = ProcessorMetrics{}
 	}
@@ -0,0 +1,54 @@
+package golem
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxThatDepth returns the that history depth of new sessions
+func (g *Golem) maxThatDepth() int {
+	if g.MaxThatDepth > 0 {
+		return g.MaxThatDepth
+	}
+	return DefaultMaxThatDepth
+}
+
+// initializeSessionContext initializes a session's context configuration, applying
+// MaxThatDepth to sessions that did not already have one
+func (g *Golem) initializeSessionContext(session *ChatSession) {
+	fresh := session.ContextConfig == nil
+	session.InitializeContextConfig()
+	if fresh {
+		session.ContextConfig.MaxThatDepth = g.maxThatDepth()
+	}
+}
+
+// thatIndexDepthWarnings lists the categories of a document whose <that index> is
+// beyond the number of responses sessions retain, so they can never match
+func thatIndexDepthWarnings(aiml *AIML, maxDepth int) []string {
+	var warnings []string
+	for i := range aiml.Categories {
+		category := &aiml.Categories[i]
+		if category.ThatIndex > maxDepth {
+			warnings = append(warnings, fmt.Sprintf("%s: pattern '%s': that index %d can never match, sessions retain only %d responses (MaxThatDepth)",
+				describeCategorySource(category), category.Pattern, category.ThatIndex, maxDepth))
+		}
+	}
+	return warnings
+}
+
+// checkThatIndexDepth reports unreachable <that index> values as load warnings, or as
+// an error when StrictParsing is enabled
+func (g *Golem) checkThatIndexDepth(aiml *AIML) error {
+	warnings := thatIndexDepthWarnings(aiml, g.maxThatDepth())
+	if len(warnings) == 0 {
+		return nil
+	}
+	if g.StrictParsing {
+		return fmt.Errorf("%s", strings.Join(warnings, "; "))
+	}
+	for _, warning := range warnings {
+		g.LogWarn("%s", warning)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package golem
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -658,3 +659,114 @@ func TestThatTagContractionNormalization(t *testing.T) {
 		}
 	}
 }
+
+func TestThatTagIndexSingleQuotes(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version='2.0'>
+<category priority='5'>
+<pattern>YES</pattern>
+<that index='2'>DO YOU LIKE BOOKS</that>
+<template>Books it is.</template>
+</category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	category := g.aimlKB.Categories[0]
+	if category.ThatIndex != 2 || category.PriorityBoost != 5 {
+		t.Errorf("Expected that index 2 and priority 5, got %d and %d", category.ThatIndex, category.PriorityBoost)
+	}
+
+	session := g.CreateSession("single-quotes")
+	session.AddToThatHistory("DO YOU LIKE BOOKS")
+	session.AddToThatHistory("DO YOU LIKE MOVIES")
+	response, err := g.ProcessInputWithThatIndex("yes", session, 2)
+	if err != nil {
+		t.Fatalf("Failed to process input: %v", err)
+	}
+	if response != "Books it is." {
+		t.Errorf("Expected 'Books it is.', got '%s'", response)
+	}
+
+	// Each value ends at its own kind of quote, so it may contain the other kind
+	result, found := g.extractTagContentWithAttributes(`<that index='1' note="it's" other='say "hi"'>HELLO</that>`, "that")
+	if !found || result.Content != "HELLO" {
+		t.Fatalf("Expected to extract 'HELLO', got %q (found %v)", result.Content, found)
+	}
+	expected := map[string]string{"index": "1", "note": "it's", "other": `say "hi"`}
+	for name, value := range expected {
+		if result.Attributes[name] != value {
+			t.Errorf("Expected attribute %s=%q, got %q", name, value, result.Attributes[name])
+		}
+	}
+}
+
+func TestThatMatchingIgnoresResponseMarkup(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category>
+<pattern>COFFEE</pattern>
+<template><think><set name="drink">coffee</set></think><b>Do you</b> <formal>like coffee</formal>?</template>
+</category>
+<category>
+<pattern>TEA</pattern>
+<template>Tea &amp;amp; biscuits?</template>
+</category>
+<category>
+<pattern>YES</pattern>
+<that>DO YOU LIKE COFFEE</that>
+<template>Coffee it is.</template>
+</category>
+<category>
+<pattern>YES</pattern>
+<that>TEA * BISCUITS</that>
+<template>Tea it is.</template>
+</category>
+<category>
+<pattern>YES</pattern>
+<template>Yes to what?</template>
+</category>
+</aiml>`
+
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("that_markup_" + name)
+
+			tests := []struct {
+				prompt   string
+				expected string
+			}{
+				{"coffee", "Coffee it is."},
+				{"tea", "Tea it is."},
+			}
+			for _, tt := range tests {
+				if _, err := g.ProcessInput(tt.prompt, session); err != nil {
+					t.Fatalf("ProcessInput(%q) failed: %v", tt.prompt, err)
+				}
+				// The history keeps the markup; it's only ignored when matching
+				last := session.GetLastThat()
+				if !strings.ContainsAny(last, "<&") {
+					t.Errorf("Expected the stored that context to keep its markup, got %q", last)
+				}
+				response, err := g.ProcessInput("yes", session)
+				if err != nil {
+					t.Fatalf("ProcessInput(yes) failed: %v", err)
+				}
+				if response != tt.expected {
+					t.Errorf("After %q (%q): expected %q, got %q", tt.prompt, last, tt.expected, response)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package golem
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -530,3 +531,78 @@ func TestThatPatternWildcardTypeDetection(t *testing.T) {
 		})
 	}
 }
+
+func TestThatPatternAlternation(t *testing.T) {
+	matchTests := []struct {
+		thatContext       string
+		thatPattern       string
+		shouldMatch       bool
+		expectedWildcards map[string]string
+	}{
+		{"YES", "(YES|NO|MAYBE)", true, map[string]string{}},
+		{"MAYBE", "(YES|NO|MAYBE)", true, map[string]string{}},
+		{"PERHAPS", "(YES|NO|MAYBE)", false, nil},
+		{"NOT SURE", "(YES|NOT SURE)", true, map[string]string{}},
+		{"NO WAY", "(YES|NO) *", true, map[string]string{"that_star1": "WAY"}},
+		{"I SAID NO", "I * (YES|NO)", true, map[string]string{"that_star1": "SAID"}},
+		{"YESTERDAY", "(YES|NO)", false, nil},
+	}
+
+	g := NewForTesting(t, false)
+	for _, tt := range matchTests {
+		for name, golem := range map[string]*Golem{"without knowledge base": nil, "with knowledge base": g} {
+			matched, wildcards := matchThatPatternWithWildcardsWithGolem(golem, tt.thatContext, tt.thatPattern)
+			if matched != tt.shouldMatch {
+				t.Errorf("%s: expected %q to match %q = %v", name, tt.thatContext, tt.thatPattern, tt.shouldMatch)
+				continue
+			}
+			if matched && !reflect.DeepEqual(wildcards, tt.expectedWildcards) {
+				t.Errorf("%s: expected wildcards %v for %q, got %v", name, tt.expectedWildcards, tt.thatPattern, wildcards)
+			}
+		}
+	}
+
+	if err := validateThatPattern("(YES||NO)"); err == nil {
+		t.Error("Expected an error for an empty alternation option")
+	}
+
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category><pattern>ONE</pattern><template>Yes.</template></category>
+	<category><pattern>TWO</pattern><template>No!</template></category>
+	<category><pattern>THREE</pattern><template>Maybe so.</template></category>
+	<category><pattern>FOUR</pattern><template>Perhaps.</template></category>
+	<category>
+		<pattern>WHY</pattern>
+		<that>(YES|NO|MAYBE SO)</that>
+		<template>Because you asked.</template>
+	</category>
+	<category><pattern>WHY</pattern><template>Why what?</template></category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"one", "Because you asked."},
+		{"two", "Because you asked."},
+		{"three", "Because you asked."},
+		{"four", "Why what?"},
+	}
+	for _, tc := range tests {
+		session := g.CreateSession("alternation")
+		if _, err := g.ProcessInput(tc.input, session); err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		response, err := g.ProcessInput("why", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != tc.expected {
+			t.Errorf("After %q expected %q, got %q", tc.input, tc.expected, response)
+		}
+	}
+}
@@ -71,7 +71,8 @@ func TestEnhancedThatHistoryManagement(t *testing.T) {
 	// Test that history validation
 	t.Run("ThatHistoryValidation", func(t *testing.T) {
 		// Add some problematic items
-		session.AddToThatHistory("")          // Empty item
+		// AddToThatHistory skips empty items, but a stored session may still have one
+		session.ThatHistory = append(session.ThatHistory, "")
 		session.AddToThatHistory("Duplicate") // Will add duplicate next
 		session.AddToThatHistory("Duplicate") // Duplicate consecutive
 
@@ -360,3 +361,53 @@ func containsSubstringHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestThatHistoryExclusions(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+<category><pattern>ASK</pattern><template>Do you like tea?</template></category>
+<category><pattern>NOTE *</pattern><template><think><set name="note"><star/></set></think></template></category>
+<category><pattern>PING</pattern><template>[ack]</template></category>
+<category><pattern>YES</pattern><that>DO YOU LIKE TEA</that><template>Great, tea it is.</template></category>
+<category><pattern>YES</pattern><template>Yes to what?</template></category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	if err := g.SetThatIgnorePattern(`^\[ack\]$`); err != nil {
+		t.Fatalf("SetThatIgnorePattern failed: %v", err)
+	}
+
+	for _, between := range []string{"note remember the milk", "ping"} {
+		t.Run(between, func(t *testing.T) {
+			session := g.CreateSession(between)
+			for _, input := range []string{"ask", between} {
+				if _, err := g.ProcessInput(input, session); err != nil {
+					t.Fatalf("Failed to process %q: %v", input, err)
+				}
+			}
+			if len(session.ThatHistory) != 1 {
+				t.Errorf("Expected only the question in that history, got %q", session.ThatHistory)
+			}
+			response, err := g.ProcessInput("yes", session)
+			if err != nil {
+				t.Fatalf("Failed to process input: %v", err)
+			}
+			if response != "Great, tea it is." {
+				t.Errorf("Expected the question to stay the <that> context, got '%s'", response)
+			}
+		})
+	}
+
+	session := g.CreateSession("direct")
+	session.AddToThatHistory("")
+	session.AddToThatHistory(" \n\t")
+	if len(session.ThatHistory) != 0 {
+		t.Errorf("Expected blank responses to be skipped, got %q", session.ThatHistory)
+	}
+
+	if err := g.SetThatIgnorePattern("[unclosed"); err == nil {
+		t.Error("Expected an error for an invalid ignore pattern")
+	}
+}
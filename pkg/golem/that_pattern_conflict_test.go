@@ -1,6 +1,11 @@
 package golem
 
 import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -663,3 +668,75 @@ func TestThatPatternConflictIntegration(t *testing.T) {
 		}
 	}
 }
+
+func TestCategoryKeyCollisionWarning(t *testing.T) {
+	collidingAIML := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>YES</pattern>
+        <that>DO YOU LIKE COFFEE</that>
+        <template>First answer</template>
+    </category>
+    <category>
+        <pattern>YES</pattern>
+        <that>DO YOU LIKE TEA</that>
+        <template>Different that, no collision</template>
+    </category>
+    <category>
+        <pattern>YES</pattern>
+        <that>DO YOU LIKE COFFEE</that>
+        <template>Second answer</template>
+    </category>
+</aiml>`
+
+	t.Run("warning", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		var logs bytes.Buffer
+		g.logger = log.New(&logs, "", 0)
+		g.SetLogLevel(LogLevelWarn)
+
+		aimlFile := filepath.Join(t.TempDir(), "coffee.aiml")
+		if err := os.WriteFile(aimlFile, []byte(collidingAIML), 0644); err != nil {
+			t.Fatalf("Failed to write AIML: %v", err)
+		}
+		kb, err := g.LoadAIML(aimlFile)
+		if err != nil {
+			t.Fatalf("LoadAIML failed: %v", err)
+		}
+
+		output := logs.String()
+		if strings.Count(output, "[WARN] ambiguous categories") != 1 {
+			t.Fatalf("Expected exactly one collision warning, got logs:\n%s", output)
+		}
+		for _, source := range []string{aimlFile + ", category 1", aimlFile + ", category 3"} {
+			if !strings.Contains(output, source) {
+				t.Errorf("Expected warning to name %q, got: %s", source, output)
+			}
+		}
+		if strings.Contains(output, "category 2") {
+			t.Errorf("Category with a different that must not be reported, got: %s", output)
+		}
+
+		// Last writer still wins, and the category with a different that is kept
+		key := categoryPatternKey(&Category{Pattern: "YES", That: "DO YOU LIKE COFFEE"})
+		if kb.Patterns[key] == nil || kb.Patterns[key].Template != "Second answer" {
+			t.Errorf("Expected the later category to win, got %v", kb.Patterns[key])
+		}
+		if len(kb.Patterns) != 2 {
+			t.Errorf("Expected 2 indexed patterns, got %d", len(kb.Patterns))
+		}
+	})
+
+	t.Run("strict parsing", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		g.StrictParsing = true
+
+		err := g.LoadAIMLFromString(collidingAIML)
+		if err == nil {
+			t.Fatal("Expected an error for colliding categories in strict mode")
+		}
+		if !strings.Contains(err.Error(), "category 1") || !strings.Contains(err.Error(), "category 3") {
+			t.Errorf("Expected error to name both categories, got: %v", err)
+		}
+	})
+}
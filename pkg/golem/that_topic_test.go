@@ -345,6 +345,75 @@ func TestTopicManagement(t *testing.T) {
 	}
 }
 
+func TestTopicTemplateTag(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>LET US TALK ABOUT *</pattern>
+		<template><topic><uppercase><star/></uppercase></topic>OK, <star/> it is.</template>
+	</category>
+	<category>
+		<pattern>WHAT ARE WE TALKING ABOUT</pattern>
+		<template>We are talking about <topic/>.</template>
+	</category>
+	<category>
+		<pattern>RECOMMEND SOMETHING</pattern>
+		<template>Try Casablanca.</template>
+		<topic>MOVIES</topic>
+	</category>
+	<category>
+		<pattern>RECOMMEND SOMETHING</pattern>
+		<template>Pick a topic first.</template>
+	</category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	// A <topic> tag inside a template does not make the category topic-specific
+	for _, category := range g.aimlKB.Categories {
+		if category.Pattern == "LET US TALK ABOUT *" && category.Topic != "" {
+			t.Errorf("Expected no category topic, got '%s'", category.Topic)
+		}
+	}
+
+	session := g.CreateSession("topic-tag-test")
+	session.SetSessionTopic("weather")
+
+	response, err := g.ProcessInput("what are we talking about", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if response != "We are talking about weather." {
+		t.Errorf("Expected topic to be read from the session, got '%s'", response)
+	}
+
+	response, err = g.ProcessInput("let us talk about movies", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if response != "OK, movies it is." {
+		t.Errorf("Expected setting the topic to produce no output, got '%s'", response)
+	}
+	if topic := session.GetSessionTopic(); topic != "MOVIES" {
+		t.Errorf("Expected session topic 'MOVIES', got '%s'", topic)
+	}
+	if value := session.Variables["topic"]; value != "MOVIES" {
+		t.Errorf("Expected topic predicate 'MOVIES', got '%s'", value)
+	}
+
+	response, err = g.ProcessInput("recommend something", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if response != "Try Casablanca." {
+		t.Errorf("Expected topic-specific category to match, got '%s'", response)
+	}
+}
+
 func TestThatTagWithWildcards(t *testing.T) {
 	g := NewForTesting(t, false)
 
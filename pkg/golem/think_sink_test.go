@@ -0,0 +1,174 @@
+package golem
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetThinkSink(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>I FEEL *</pattern>
+		<template><think><set name="mood"><star/></set></think>Noted.</template>
+	</category>
+	<category>
+		<pattern>QUIET</pattern>
+		<template><think></think>Shh.</template>
+	</category>
+</aiml>`
+
+	engines := map[string]func(g *Golem){
+		"tree":         func(g *Golem) {},
+		"consolidated": func(g *Golem) { g.SetTemplateProcessor(g.GetConsolidatedProcessor()) },
+	}
+
+	for name, configure := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			configure(g)
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("think-sink-" + name)
+
+			// No sink: think content is silently suppressed
+			if response, _ := g.ProcessInput("i feel calm", session); response != "Noted." {
+				t.Errorf("Expected 'Noted.', got %q", response)
+			}
+
+			var received []string
+			g.SetThinkSink(func(content string) {
+				received = append(received, content)
+			})
+
+			response, err := g.ProcessInput("i feel happy", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != "Noted." {
+				t.Errorf("Think content should stay out of the response, got %q", response)
+			}
+			if len(received) != 1 || !strings.Contains(received[0], "happy") {
+				t.Errorf("Expected the sink to receive the think content, got %q", received)
+			}
+			if name == "tree" && len(received) == 1 && received[0] != "mood = happy" {
+				t.Errorf("Expected the tree processor to report the assignment, got %q", received[0])
+			}
+			if mood := session.Variables["mood"]; mood != "happy" {
+				t.Errorf("Expected think side effects to still run, got mood %q", mood)
+			}
+
+			// Empty think blocks are not reported
+			if _, err := g.ProcessInput("quiet", session); err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if len(received) != 1 {
+				t.Errorf("Expected no report for an empty think block, got %q", received)
+			}
+
+			g.SetThinkSink(nil)
+			g.ProcessInput("i feel tired", session)
+			if len(received) != 1 {
+				t.Errorf("Expected no reports after removing the sink, got %q", received)
+			}
+		})
+	}
+}
+
+func TestThinkLocalScope(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>PRICE *</pattern>
+		<template><think scope="local"><set name="scratch"><star/></set></think><think><set name="item"><star/></set></think>Price of <get name="scratch"/> noted.</template>
+	</category>
+	<category>
+		<pattern>RECALL</pattern>
+		<template>[<get name="scratch"/>] [<get name="item"/>]</template>
+	</category>
+</aiml>`
+
+	engines := map[string]bool{"tree": false, "legacy": true}
+	for name, legacy := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if legacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("local_think")
+
+			// The local variable is readable within its own template
+			response, err := g.ProcessInput("price apples", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != "Price of apples noted." {
+				t.Errorf("Expected the local variable within the template, got '%s'", response)
+			}
+			if _, leaked := session.Variables["scratch"]; leaked {
+				t.Errorf("Expected the local variable not to reach the session, got %v", session.Variables)
+			}
+
+			// In a later turn only the session-scoped think variable remains
+			response, err = g.ProcessInput("recall", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if strings.HasPrefix(response, "[apples]") || !strings.HasSuffix(response, "[apples]") {
+				t.Errorf("Expected only the session variable in a later turn, got '%s'", response)
+			}
+		})
+	}
+}
+
+// TestThinkReachesSRAI tests that templates an SRAI inside <think> processes are part
+// of the think block: their assignments are reported and local scope applies
+func TestThinkReachesSRAI(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>CALM DOWN</pattern>
+		<template><set name="mood">calm</set></template>
+	</category>
+	<category>
+		<pattern>RELAX</pattern>
+		<template><think><srai>CALM DOWN</srai></think>Relaxed.</template>
+	</category>
+	<category>
+		<pattern>PRETEND</pattern>
+		<template><think scope="local"><srai>CALM DOWN</srai></think>Pretending.</template>
+	</category>
+</aiml>`
+
+	g := NewForTesting(t, false)
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	var received []string
+	g.SetThinkSink(func(content string) {
+		received = append(received, content)
+	})
+
+	session := g.CreateSession("think_srai")
+	if response, _ := g.ProcessInput("pretend", session); response != "Pretending." {
+		t.Errorf("Expected 'Pretending.', got '%s'", response)
+	}
+	if _, leaked := session.Variables["mood"]; leaked {
+		t.Errorf("Expected the SRAI's assignment to stay local, got %v", session.Variables)
+	}
+
+	received = nil
+	if response, _ := g.ProcessInput("relax", session); response != "Relaxed." {
+		t.Errorf("Expected 'Relaxed.', got '%s'", response)
+	}
+	if session.Variables["mood"] != "calm" {
+		t.Errorf("Expected the SRAI to set mood, got %v", session.Variables)
+	}
+	if len(received) != 1 || received[0] != "mood = calm" {
+		t.Errorf("Expected the SRAI's assignment to be reported, got %q", received)
+	}
+}
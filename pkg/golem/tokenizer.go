@@ -0,0 +1,28 @@
+package golem
+
+import "strings"
+
+// Tokenizer splits text into words. Input is matched against patterns, and wildcard
+// values are captured, word by word, so a tokenizer decides where the word boundaries
+// of user input are.
+type Tokenizer func(text string) []string
+
+// SetTokenizer sets the tokenizer applied to user input and that context before they
+// are normalized for matching and before wildcard values are captured from them, so
+// embedders can plug in, for example, a CJK segmenter for text written without spaces.
+// The words it returns are matched as if separated by spaces. Passing nil restores the
+// default, which splits on whitespace.
+func (g *Golem) SetTokenizer(tokenizer Tokenizer) {
+	g.tokenizer = tokenizer
+	g.ClearTextNormalizationCache()
+}
+
+// tokenizeText splits text with the configured tokenizer and rejoins the words with
+// single spaces. Without a tokenizer the text is returned unchanged, as normalization
+// already splits on whitespace.
+func (g *Golem) tokenizeText(text string) string {
+	if g.tokenizer == nil {
+		return text
+	}
+	return strings.Join(g.tokenizer(text), " ")
+}
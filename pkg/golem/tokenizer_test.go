@@ -0,0 +1,77 @@
+package golem
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestSetTokenizer(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category><pattern>我 爱 *</pattern><template>你爱<star/>。</template></category>
+	<category><pattern>TRAIN TO *</pattern><template>Booking a train to <star/>.</template></category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("tokenizer")
+	if _, err := g.ProcessInput("我爱猫", session); err == nil {
+		t.Fatal("Expected no match before setting a tokenizer")
+	}
+
+	// Each Han character is a word, and so is each run of other non-space characters
+	// between slashes
+	g.SetTokenizer(func(text string) []string {
+		var words []string
+		var word strings.Builder
+		flush := func() {
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		}
+		for _, r := range text {
+			switch {
+			case unicode.Is(unicode.Han, r):
+				flush()
+				words = append(words, string(r))
+			case unicode.IsSpace(r) || r == '/':
+				flush()
+			default:
+				word.WriteRune(r)
+			}
+		}
+		flush()
+		return words
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"我爱猫", "你爱猫。"},
+		{"train/to/Saint-Malo", "Booking a train to Saint-Malo."},
+	}
+	for _, tc := range tests {
+		response, err := g.ProcessInput(tc.input, session)
+		if err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", tc.input, err)
+		}
+		if response != tc.expected {
+			t.Errorf("ProcessInput(%q) = %q, expected %q", tc.input, response, tc.expected)
+		}
+	}
+
+	if normalized := g.NormalizeInput("我爱猫"); normalized != "我 爱 猫" {
+		t.Errorf("Expected NormalizeInput to use the tokenizer, got %q", normalized)
+	}
+
+	// Removing the tokenizer restores whitespace splitting
+	g.SetTokenizer(nil)
+	if _, err := g.ProcessInput("我爱猫", session); err == nil {
+		t.Error("Expected no match after removing the tokenizer")
+	}
+}
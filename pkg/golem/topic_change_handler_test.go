@@ -0,0 +1,74 @@
+package golem
+
+import (
+	"testing"
+)
+
+func TestOnTopicChange(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>LETS TALK ABOUT *</pattern>
+		<template><think><set name="topic"><star/></set></think>Sure, <star/>.</template>
+	</category>
+	<category>
+		<pattern>SWITCH TO MUSIC</pattern>
+		<template><topic>music</topic>Music it is.</template>
+	</category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("topic-change-test")
+
+	// No handler registered: topic changes are silent
+	session.SetSessionTopic("greetings")
+
+	type change struct{ old, new string }
+	var changes []change
+	g.OnTopicChange(func(s *ChatSession, old, new string) {
+		if s != session {
+			t.Errorf("Expected handler to receive the changed session")
+		}
+		changes = append(changes, change{old, new})
+	})
+
+	session.SetSessionTopic("weather")
+	session.SetSessionTopic("weather") // no-op
+	session.SetSessionTopic("WEATHER") // same topic for matching purposes
+
+	if _, err := g.ProcessInput("lets talk about sports", session); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if _, err := g.ProcessInput("lets talk about sports", session); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if _, err := g.ProcessInput("switch to music", session); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+
+	expected := []change{{"greetings", "weather"}, {"WEATHER", "sports"}, {"sports", "music"}}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d topic changes, got %d: %v", len(expected), len(changes), changes)
+	}
+	for i, c := range expected {
+		if changes[i] != c {
+			t.Errorf("Change %d: expected %v, got %v", i, c, changes[i])
+		}
+	}
+
+	// Removing the handler stops notifications
+	g.OnTopicChange(nil)
+	session.SetSessionTopic("cooking")
+	if len(changes) != len(expected) {
+		t.Errorf("Expected no notifications after removing handler, got %v", changes)
+	}
+
+	// Sessions built outside the Golem have no handler and must not panic
+	standalone := &ChatSession{ID: "standalone", Variables: map[string]string{}}
+	standalone.SetSessionTopic("anything")
+}
@@ -0,0 +1,131 @@
+package golem
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// TranscriptTurn is a single user turn in a JSON chat transcript
+type TranscriptTurn struct {
+	Input string `json:"input"`
+}
+
+// TranscriptResult records how a transcript turn was handled
+type TranscriptResult struct {
+	Input          string `json:"input"`
+	Response       string `json:"response"`
+	MatchedPattern string `json:"matched_pattern"`
+	Error          string `json:"error,omitempty"`
+}
+
+// LoadTranscript reads a JSON array of {"input": ...} turns from a file
+func LoadTranscript(filename string) ([]TranscriptTurn, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript %s: %v", filename, err)
+	}
+
+	var turns []TranscriptTurn
+	if err := json.Unmarshal(content, &turns); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript %s: %v", filename, err)
+	}
+	return turns, nil
+}
+
+// RunTranscript runs each turn through the loaded knowledge base in order, within a
+// single session, and records the response and the pattern that matched. Turns that
+// fail to match are recorded with an error rather than aborting the run, so the
+// results can be diffed against an expected transcript for regression testing.
+func (g *Golem) RunTranscript(turns []TranscriptTurn, session *ChatSession) ([]TranscriptResult, error) {
+	if g.aimlKB == nil {
+		return nil, fmt.Errorf("no AIML knowledge base loaded")
+	}
+	if session == nil {
+		return nil, fmt.Errorf("transcript requires a session")
+	}
+
+	results := make([]TranscriptResult, 0, len(turns))
+	for _, turn := range turns {
+		result := TranscriptResult{Input: turn.Input}
+
+		// The pattern comes from the same match that produced the response
+		response, category, err := g.ProcessInputWithCategory(turn.Input, session)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		if category != nil {
+			result.MatchedPattern = category.Pattern
+		}
+		result.Response = response
+		results = append(results, result)
+	}
+
+	g.LogInfo("Ran transcript with %d turns", len(turns))
+	return results, nil
+}
+
+// analyzeTranscriptCommand handles "analyze transcript <file> [--aiml <path>] [--output <file>]",
+// writing the transcript results as a JSON array to stdout or the output file
+func (g *Golem) analyzeTranscriptCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("analyze transcript requires a transcript file")
+	}
+
+	transcriptFile := args[0]
+	aimlPath := ""
+	outputFile := ""
+	for i := 1; i < len(args); i += 2 {
+		option := args[i]
+		if option != "--aiml" && option != "--output" {
+			return fmt.Errorf("unknown analyze transcript option: %s", option)
+		}
+		if i+1 >= len(args) {
+			return fmt.Errorf("analyze transcript option %s requires a value", option)
+		}
+		if option == "--aiml" {
+			aimlPath = args[i+1]
+		} else {
+			outputFile = args[i+1]
+		}
+	}
+
+	// Single-command mode starts with an empty knowledge base. When the results go to
+	// stdout, load messages go to stderr so the output stays valid JSON.
+	if aimlPath != "" {
+		var loadOutput io.Writer = os.Stdout
+		if outputFile == "" {
+			loadOutput = os.Stderr
+		}
+		if err := g.loadCommandTo(loadOutput, []string{aimlPath}); err != nil {
+			return err
+		}
+	}
+
+	turns, err := LoadTranscript(transcriptFile)
+	if err != nil {
+		return err
+	}
+
+	results, err := g.RunTranscript(turns, g.CreateSession(""))
+	if err != nil {
+		return err
+	}
+
+	output, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript results: %v", err)
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, append(output, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write transcript results to %s: %v", outputFile, err)
+		}
+		fmt.Printf("Wrote %d transcript results to %s\n", len(results), outputFile)
+		return nil
+	}
+
+	fmt.Println(string(output))
+	return nil
+}
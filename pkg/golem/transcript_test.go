@@ -0,0 +1,174 @@
+package golem
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTranscript(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>HELLO</pattern>
+        <template>Hi there! Do you like coffee?</template>
+    </category>
+    <category>
+        <pattern>YES</pattern>
+        <that>DO YOU LIKE COFFEE</that>
+        <template>Great, me too.</template>
+    </category>
+    <category>
+        <pattern>MY NAME IS *</pattern>
+        <template><think><set name="name"><star/></set></think>Nice to meet you, <get name="name"/>.</template>
+    </category>
+    <category>
+        <pattern>*</pattern>
+        <template>I do not understand.</template>
+    </category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	transcript := `[
+		{"input": "Hello"},
+		{"input": "yes"},
+		{"input": "My name is Alice"},
+		{"input": "something else"}
+	]`
+	transcriptFile := filepath.Join(t.TempDir(), "transcript.json")
+	if err := os.WriteFile(transcriptFile, []byte(transcript), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+
+	turns, err := LoadTranscript(transcriptFile)
+	if err != nil {
+		t.Fatalf("LoadTranscript failed: %v", err)
+	}
+	if len(turns) != 4 {
+		t.Fatalf("Expected 4 turns, got %d", len(turns))
+	}
+
+	results, err := g.RunTranscript(turns, g.CreateSession("transcript_test"))
+	if err != nil {
+		t.Fatalf("RunTranscript failed: %v", err)
+	}
+
+	expected := []TranscriptResult{
+		{Input: "Hello", Response: "Hi there! Do you like coffee?", MatchedPattern: "HELLO"},
+		{Input: "yes", Response: "Great, me too.", MatchedPattern: "YES"},
+		{Input: "My name is Alice", Response: "Nice to meet you, Alice.", MatchedPattern: "MY NAME IS *"},
+		{Input: "something else", Response: "I do not understand.", MatchedPattern: "*"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, want := range expected {
+		if results[i] != want {
+			t.Errorf("Turn %d: expected %+v, got %+v", i, want, results[i])
+		}
+	}
+
+	// Results serialize with the documented field names
+	encoded, err := json.Marshal(results[0])
+	if err != nil {
+		t.Fatalf("Failed to encode result: %v", err)
+	}
+	if string(encoded) != `{"input":"Hello","response":"Hi there! Do you like coffee?","matched_pattern":"HELLO"}` {
+		t.Errorf("Unexpected JSON encoding: %s", encoded)
+	}
+}
+
+func TestAnalyzeTranscriptCommand(t *testing.T) {
+	g := NewForTesting(t, false)
+	dir := t.TempDir()
+
+	aimlFile := filepath.Join(dir, "bot.aiml")
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>PING</pattern>
+        <template>PONG</template>
+    </category>
+</aiml>`
+	if err := os.WriteFile(aimlFile, []byte(aimlContent), 0644); err != nil {
+		t.Fatalf("Failed to write AIML: %v", err)
+	}
+	transcriptFile := filepath.Join(t.TempDir(), "turns.json")
+	if err := os.WriteFile(transcriptFile, []byte(`[{"input": "ping"}, {"input": "unmatched"}]`), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	outputFile := filepath.Join(t.TempDir(), "results.json")
+
+	if err := g.Execute("analyze", []string{"transcript", transcriptFile, "--aiml", aimlFile, "--output", outputFile}); err != nil {
+		t.Fatalf("analyze transcript failed: %v", err)
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read results: %v", err)
+	}
+	var results []TranscriptResult
+	if err := json.Unmarshal(content, &results); err != nil {
+		t.Fatalf("Results are not valid JSON: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Response != "PONG" || results[0].MatchedPattern != "PING" {
+		t.Errorf("Unexpected first result: %+v", results[0])
+	}
+	if results[1].Error == "" || results[1].MatchedPattern != "" {
+		t.Errorf("Expected unmatched turn to record an error, got %+v", results[1])
+	}
+
+	// Without --output only the results go to stdout, so it parses as JSON
+	stdout := captureStdout(t, func() {
+		if err := NewForTesting(t, false).Execute("analyze", []string{"transcript", transcriptFile, "--aiml", aimlFile}); err != nil {
+			t.Fatalf("analyze transcript failed: %v", err)
+		}
+	})
+	results = nil
+	if err := json.Unmarshal(stdout, &results); err != nil {
+		t.Fatalf("Expected stdout to hold only the JSON results, got %q: %v", stdout, err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results on stdout, got %d", len(results))
+	}
+
+	if err := g.Execute("analyze", []string{"transcript"}); err == nil {
+		t.Error("Expected an error when no transcript file is given")
+	}
+	if err := g.Execute("analyze", []string{"transcript", transcriptFile, "--output"}); err == nil || !strings.Contains(err.Error(), "requires a value") {
+		t.Errorf("Expected an error for an option without a value, got: %v", err)
+	}
+	if err := g.Execute("analyze", []string{"transcript", transcriptFile, "--verbose"}); err == nil || !strings.Contains(err.Error(), "unknown analyze transcript option") {
+		t.Errorf("Expected an error for an unknown option, got: %v", err)
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func()) []byte {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	original := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = original }()
+
+	captured := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		captured <- data
+	}()
+	fn()
+	writer.Close()
+	return <-captured
+}
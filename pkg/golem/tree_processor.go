@@ -3,6 +3,7 @@ package golem
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"html"
 	"regexp"
 	"sort"
@@ -11,12 +12,16 @@ import (
 	"time"
 )
 
-// TreeProcessor handles processing of AST nodes for AIML tag processing
+// TreeProcessor handles processing of AST nodes for AIML tag processing. One processor
+// is shared by every turn, so ProcessTemplate processes each template with a processor
+// of its own holding the per-template state below the metrics.
 type TreeProcessor struct {
 	golem       *Golem
-	ctx         *VariableContext
-	starCounter int // Tracks auto-incrementing star index for <star/> tags without explicit index
 	metrics     *ProcessorRegistry // Tracks metrics for different tag types/operations
+	ctx         *VariableContext
+	starCounter int   // Tracks auto-incrementing star index for <star/> tags without explicit index
+	tagErr      error // Error reported by the tag being processed (see failTag)
+	templateErr error // First tag error of the template, with StrictTagErrors
 }
 
 // NewTreeProcessor creates a new tree processor
@@ -137,6 +142,8 @@ func (p *TreeProcessorLogic) ResetMetrics() { p.metrics = &ProcessorMetrics{} }
 // trackMetric tracks metrics for a specific processor type
 func (tp *TreeProcessor) trackMetric(processorName string) {
 	if tp.metrics != nil {
+		tp.metrics.metricsMutex.Lock()
+		defer tp.metrics.metricsMutex.Unlock()
 		metrics := tp.metrics.metrics[processorName]
 		if metrics != nil {
 			metrics.TotalCalls++
@@ -147,8 +154,13 @@ func (tp *TreeProcessor) trackMetric(processorName string) {
 
 // ProcessTemplate processes a template using tree-based approach
 func (tp *TreeProcessor) ProcessTemplate(template string, wildcards map[string]string, ctx *VariableContext) (string, error) {
-	// Reset star counter for auto-incrementing <star/> tags
-	tp.starCounter = 0
+	run := &TreeProcessor{golem: tp.golem, metrics: tp.metrics, ctx: ctx}
+	return run.processTemplate(template, wildcards)
+}
+
+// processTemplate processes a template with this processor's per-template state
+func (tp *TreeProcessor) processTemplate(template string, wildcards map[string]string) (string, error) {
+	ctx := tp.ctx
 
 	// Track that wildcard processing might occur if wildcards are present
 	if len(wildcards) > 0 {
@@ -173,6 +185,9 @@ func (tp *TreeProcessor) ProcessTemplate(template string, wildcards map[string]s
 		}()
 	}
 
+	// Expose that-pattern wildcards as local variables for <that_starN/> resolution
+	populateThatWildcardLocalVars(wildcards, ctx)
+
 	// Store wildcards in session variables so they can be accessed by <star/> tags
 	if ctx != nil && ctx.Session != nil && len(wildcards) > 0 {
 		// Save current wildcards to restore them later
@@ -202,27 +217,33 @@ func (tp *TreeProcessor) ProcessTemplate(template string, wildcards map[string]s
 	}
 
 	// Process the AST
-	tp.ctx = ctx
-	result := tp.processNode(ast)
+	result, batched := tp.processBatchedSRAI(ast)
+	if !batched {
+		result = tp.processNode(ast)
+	}
+	if tp.templateErr != nil {
+		return "", tp.templateErr
+	}
+
+	// Return result as-is
+	// XML entities were already decoded during parsing, and we don't re-encode them
+	// to avoid double-escaping issues
+	return trimTemplateOutput(result), nil
+}
 
-	// Smart whitespace trimming:
-	// 1. Always trim trailing whitespace
-	// 2. Trim leading whitespace, but preserve leading spaces/tabs if there's no leading newline
-	//    (this allows indent tags to work while removing template formatting whitespace)
+// trimTemplateOutput applies smart whitespace trimming to a processed template. Trailing
+// whitespace is always trimmed; leading spaces and tabs are kept unless the output
+// starts with a newline, which allows indent tags to work while removing template
+// formatting whitespace.
+func trimTemplateOutput(result string) string {
 	result = strings.TrimRight(result, " \t\n\r")
 
 	// If result starts with newline, trim all leading whitespace (template formatting)
 	// Otherwise, just trim leading newlines but keep leading spaces/tabs (indent tag output)
 	if len(result) > 0 && (result[0] == '\n' || result[0] == '\r') {
-		result = strings.TrimLeft(result, " \t\n\r")
-	} else {
-		result = strings.TrimLeft(result, "\n\r")
+		return strings.TrimLeft(result, " \t\n\r")
 	}
-
-	// Return result as-is
-	// XML entities were already decoded during parsing, and we don't re-encode them
-	// to avoid double-escaping issues
-	return result, nil
+	return strings.TrimLeft(result, "\n\r")
 }
 
 // processNode processes a single AST node
@@ -231,11 +252,11 @@ func (tp *TreeProcessor) processNode(node *ASTNode) string {
 	case NodeTypeText:
 		// If this is a text node with children, process children
 		if len(node.Children) > 0 {
-			children := ""
-			for _, child := range node.Children {
-				children += tp.processNode(child)
+			outputs := make([]string, len(node.Children))
+			for i, child := range node.Children {
+				outputs[i] = tp.processNode(child)
 			}
-			return children
+			return tp.joinChildOutputs(node.Children, outputs)
 		}
 		// Return text content as-is; escaping happens at the end of ProcessTemplate
 		return node.Content
@@ -244,14 +265,61 @@ func (tp *TreeProcessor) processNode(node *ASTNode) string {
 	case NodeTypeCDATA:
 		return node.Content // CDATA is output as-is
 	case NodeTypeSelfClosingTag:
-		return tp.processSelfClosingTag(node)
+		return tp.processTagIsolated(node, tp.processSelfClosingTag)
 	case NodeTypeTag:
-		return tp.processTag(node)
+		return tp.processTagIsolated(node, tp.processTag)
 	default:
 		return ""
 	}
 }
 
+// processTagIsolated processes a tag so that a failure only affects that tag: a tag
+// that failed to parse, or whose processing reported an error through failTag, is
+// logged and resolves to its default attribute while the rest of the template still
+// renders. Without a default, a malformed tag resolves to its literal text and a tag
+// that failed processing to nothing. With StrictTagErrors the failure fails the whole
+// template instead.
+func (tp *TreeProcessor) processTagIsolated(node *ASTNode, process func(*ASTNode) string) string {
+	if node.ParseError != nil {
+		return tp.resolveFailedTag(node, node.ParseError, node.Content)
+	}
+
+	outerErr := tp.tagErr
+	tp.tagErr = nil
+	result := process(node)
+	err := tp.tagErr
+	tp.tagErr = outerErr
+	if err != nil {
+		return tp.resolveFailedTag(node, err, "")
+	}
+	return result
+}
+
+// failTag reports an error processing the current tag (see processTagIsolated)
+func (tp *TreeProcessor) failTag(err error) {
+	if tp.tagErr == nil {
+		tp.tagErr = err
+	}
+}
+
+// resolveFailedTag returns what a failed tag resolves to: its default attribute, or
+// fallback. With StrictTagErrors it records the error to fail the template instead.
+func (tp *TreeProcessor) resolveFailedTag(node *ASTNode, err error, fallback string) string {
+	if tp.golem.templateConfig != nil && tp.golem.templateConfig.StrictTagErrors {
+		if tp.templateErr == nil {
+			tp.templateErr = fmt.Errorf("error processing <%s> tag: %w", node.TagName, err)
+		}
+		return ""
+	}
+
+	result := fallback
+	if defaultValue, exists := node.Attributes["default"]; exists {
+		result = defaultValue
+	}
+	tp.golem.LogError("Error processing <%s> tag, resolving to '%s': %v", node.TagName, result, err)
+	return result
+}
+
 // processTag processes a tag node
 func (tp *TreeProcessor) processTag(node *ASTNode) string {
 	// Some tags need to process their children selectively (not all at once)
@@ -262,15 +330,38 @@ func (tp *TreeProcessor) processTag(node *ASTNode) string {
 		skipChildProcessing = true
 	}
 
+	// An empty paired form of a self-closing tag (<that></that>, <star> </star>) means
+	// the same as the self-closing form
+	if implicitlySelfClosingTags[node.TagName] && hasOnlyBlankChildren(node) {
+		return tp.processSelfClosingTag(node)
+	}
+
+	// Collect the assignments made inside a think block for the think sink
+	if node.TagName == "think" && tp.golem.thinkSink != nil && tp.ctx != nil {
+		ctx, outerLog := tp.ctx, tp.ctx.thinkLog
+		ctx.thinkLog = &[]string{}
+		defer func() { ctx.thinkLog = outerLog }()
+	}
+
+	// Assignments inside <think scope="local"> are scratch variables for this template
+	if node.TagName == "think" && strings.EqualFold(node.Attributes["scope"], "local") && tp.ctx != nil {
+		ctx, outerLocal := tp.ctx, tp.ctx.localThink
+		ctx.localThink = true
+		defer func() { ctx.localThink = outerLocal }()
+	}
+
 	// Process children first to handle nested tags (unless tag handles its own children)
 	var content string
 	if !skipChildProcessing {
 		processedChildren := make([]string, len(node.Children))
 		for i, child := range node.Children {
 			processedChildren[i] = tp.processNode(child)
+			if node.TagName == "eval" {
+				processedChildren[i] = tp.evalStoredMarkup(child, processedChildren[i])
+			}
 		}
 		// Join processed children
-		content = strings.Join(processedChildren, "")
+		content = tp.joinChildOutputs(node.Children, processedChildren)
 	}
 
 	// Process the tag based on its name
@@ -421,6 +512,12 @@ func (tp *TreeProcessor) processTag(node *ASTNode) string {
 		return tp.processVersionTag(node, content)
 	case "id":
 		return tp.processIdTag(node, content)
+	case "categorycount":
+		return tp.processSizeTag(node, content)
+	case "uptime":
+		return tp.processUptimeTag(node, content)
+	case "sessioncount":
+		return tp.processSessionCountTag(node, content)
 	case "request":
 		return tp.processRequestTag(node, content)
 	case "response":
@@ -451,6 +548,22 @@ func (tp *TreeProcessor) processTag(node *ASTNode) string {
 	}
 }
 
+// hasOnlyBlankChildren reports whether a tag has no children other than whitespace and comments
+func hasOnlyBlankChildren(node *ASTNode) bool {
+	for _, child := range node.Children {
+		switch child.Type {
+		case NodeTypeComment:
+		case NodeTypeText:
+			if strings.TrimSpace(child.Content) != "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // processSelfClosingTag processes self-closing tags
 func (tp *TreeProcessor) processSelfClosingTag(node *ASTNode) string {
 	// Check for that wildcard tags with embedded index
@@ -473,6 +586,11 @@ func (tp *TreeProcessor) processSelfClosingTag(node *ASTNode) string {
 		return tp.processThatWildcardWithEmbeddedIndex(node, "that_star")
 	}
 
+	// Of the set tag, only collection operations (e.g. operation="get") have a self-closing form
+	if _, hasOperation := node.Attributes["operation"]; hasOperation && node.TagName == "set" {
+		return tp.processSetTag(node, "")
+	}
+
 	switch node.TagName {
 	case "star":
 		return tp.processStarTag(node, "")
@@ -500,6 +618,12 @@ func (tp *TreeProcessor) processSelfClosingTag(node *ASTNode) string {
 		return tp.processVersionTag(node, "")
 	case "id":
 		return tp.processIdTag(node, "")
+	case "categorycount":
+		return tp.processSizeTag(node, "")
+	case "uptime":
+		return tp.processUptimeTag(node, "")
+	case "sessioncount":
+		return tp.processSessionCountTag(node, "")
 	case "request":
 		return tp.processRequestTag(node, "")
 	case "response":
@@ -561,24 +685,25 @@ func (tp *TreeProcessor) processSRAITag(node *ASTNode, content string) string {
 
 	// Try to match the SRAI content as a new AIML pattern
 	if tp.golem.aimlKB != nil {
-		category, wildcards, err := tp.golem.aimlKB.MatchPattern(sraiContent)
+		category, wildcards, err := tp.golem.aimlKB.MatchPatternWithTopic(sraiContent, tp.ctx.currentTopic())
 		tp.golem.LogInfo("SRAI pattern match: content='%s', err=%v, category=%v, wildcards=%v",
 			sraiContent, err, category != nil, wildcards)
 
-		if err == nil && category != nil {
-			// Create a new context with incremented recursion depth
-			// Preserve all context except increment recursion depth
-			newCtx := &VariableContext{
-				LocalVars:      tp.ctx.LocalVars,
-				Session:        tp.ctx.Session,
-				Topic:          tp.ctx.Topic,
-				KnowledgeBase:  tp.ctx.KnowledgeBase,
-				RecursionDepth: tp.ctx.RecursionDepth + 1,
-				Wildcards:      tp.ctx.Wildcards, // Preserve parent wildcards
-			}
+		if err == nil && category != nil && tp.ctx.isActiveCategory(category, sraiContent) {
+			// The SRAI leads straight back into a category being processed; recursing
+			// would only burn the depth budget, so stop here
+			tp.golem.LogWarn("SRAI self-reference detected: '%s' resolves to pattern '%s' already being processed, stopping recursion",
+				sraiContent, category.Pattern)
+			return ""
+		}
 
+		if err == nil && category != nil {
 			// Process the matched template with the new context
-			response := tp.golem.processTemplateWithContext(category.Template, wildcards, newCtx)
+			tp.golem.notifySRAIMatched(category, sraiContent)
+			newCtx := tp.sraiContext(category, sraiContent)
+			response := tp.golem.processCategoryTemplate(category, wildcards, newCtx)
+			response = tp.applyEmptySRAIFallback(response, sraiContent, category, newCtx)
+
 			tp.golem.LogInfo("SRAI result: '%s' -> '%s'", sraiContent, response)
 			return response
 		} else {
@@ -592,6 +717,40 @@ func (tp *TreeProcessor) processSRAITag(node *ASTNode, content string) string {
 	return sraiContent
 }
 
+// sraiContext returns the context an SRAI into category is processed with: the
+// caller's context one level deeper, with the category marked active for input
+func (tp *TreeProcessor) sraiContext(category *Category, input string) *VariableContext {
+	return &VariableContext{
+		LocalVars:        tp.ctx.LocalVars,
+		Session:          tp.ctx.Session,
+		Topic:            tp.ctx.Topic,
+		KnowledgeBase:    tp.ctx.KnowledgeBase,
+		RecursionDepth:   tp.ctx.RecursionDepth + 1,
+		Wildcards:        tp.ctx.Wildcards, // Preserve parent wildcards
+		ActiveCategories: tp.ctx.withActiveCategory(category, input),
+		thinkLog:         tp.ctx.thinkLog,
+		localThink:       tp.ctx.localThink,
+	}
+}
+
+// applyEmptySRAIFallback handles an SRAI that resolved to an empty response. An empty
+// resolution is sometimes intended (e.g. a pure <think> category) and sometimes hides
+// a bug, so it is always logged, and with FallbackOnEmptySRAI it falls through to the
+// default pattern.
+func (tp *TreeProcessor) applyEmptySRAIFallback(response, sraiContent string, category *Category, ctx *VariableContext) string {
+	if strings.TrimSpace(response) != "" {
+		return response
+	}
+	tp.golem.LogInfo("SRAI resolved to empty response: '%s' (matched pattern '%s')", sraiContent, category.Pattern)
+	if tp.golem.templateConfig != nil && tp.golem.templateConfig.FallbackOnEmptySRAI {
+		if defaultCategory := tp.golem.aimlKB.defaultCategory(); defaultCategory != nil && defaultCategory != category {
+			tp.golem.LogInfo("SRAI falling through to default pattern for: '%s'", sraiContent)
+			response = tp.golem.processTemplateWithContext(defaultCategory.Template, map[string]string{"star1": sraiContent}, ctx)
+		}
+	}
+	return response
+}
+
 func (tp *TreeProcessor) processSRAIXTag(node *ASTNode, content string) string {
 	// Process SRAIX tag - external service integration (SRAI eXtended)
 
@@ -626,6 +785,11 @@ func (tp *TreeProcessor) processSRAIXTag(node *ASTNode, content string) string {
 		hintText = strings.TrimSpace(tp.evaluateAttributeValue(val))
 	}
 
+	includeHistory := ""
+	if val, exists := node.Attributes["includehistory"]; exists {
+		includeHistory = strings.TrimSpace(tp.evaluateAttributeValue(val))
+	}
+
 	// The content is already processed by the AST
 	sraixContent := strings.TrimSpace(content)
 
@@ -705,8 +869,15 @@ func (tp *TreeProcessor) processSRAIXTag(node *ASTNode, content string) string {
 		}
 	}
 
+	// Add the conversation history when includehistory asks for it
+	var session *ChatSession
+	if tp.ctx != nil {
+		session = tp.ctx.Session
+	}
+	sraixContent = tp.golem.withSRAIXHistory(targetService, sraixContent, includeHistory, session)
+
 	// Make the external service request
-	response, err := tp.golem.sraixMgr.ProcessSRAIX(targetService, sraixContent, requestParams)
+	response, err := tp.golem.sraixMgr.ProcessSRAIXWithVariables(targetService, sraixContent, requestParams, tp.golem.sraixHeaderVariables(targetService, tp.ctx))
 	if err != nil {
 		tp.golem.LogInfo("SRAIX request failed: %v", err)
 		// Use default response if available
@@ -782,6 +953,15 @@ func (tp *TreeProcessor) processThinkTag(node *ASTNode, content string) string {
 
 	tp.golem.LogInfo("Think tag: processed '%s' (no output)", content)
 
+	// Set tags produce no output, so report their assignments alongside any text
+	if tp.ctx != nil && tp.ctx.thinkLog != nil {
+		report := *tp.ctx.thinkLog
+		if text := strings.TrimSpace(content); text != "" {
+			report = append(report, text)
+		}
+		tp.golem.notifyThink(strings.Join(report, "\n"))
+	}
+
 	// Think tags don't output anything
 	return ""
 }
@@ -870,12 +1050,16 @@ func (tp *TreeProcessor) processSetTag(node *ASTNode, content string) string {
 
 	// No operation and no existing Set collection - this is variable assignment (original behavior)
 	// Process the content to get the value
-	value := content // Content is already processed by processNode
+	value := tp.golem.canonicalizeBoolean(content) // Content is already processed by processNode
+
+	if tp.ctx != nil && tp.ctx.thinkLog != nil {
+		*tp.ctx.thinkLog = append(*tp.ctx.thinkLog, varKey+" = "+value)
+	}
 
 	// Set the variable in context
 	if tp.ctx != nil {
-		// Local variables are stored in LocalVars
-		if isLocalVar {
+		// Local variables, and any set inside <think scope="local">, are stored in LocalVars
+		if isLocalVar || tp.ctx.localThink {
 			if tp.ctx.LocalVars == nil {
 				tp.ctx.LocalVars = make(map[string]string)
 			}
@@ -920,6 +1104,9 @@ func (tp *TreeProcessor) processSetCollectionTag(node *ASTNode, name string, ope
 	// Check for required knowledge base
 	if tp.ctx == nil || tp.ctx.KnowledgeBase == nil || tp.ctx.KnowledgeBase.SetCollections == nil {
 		tp.golem.LogInfo("Set collection: no knowledge base available")
+		if operation == "get" || operation == "" {
+			return tp.setGetDefault(node)
+		}
 		return ""
 	}
 
@@ -943,6 +1130,11 @@ func (tp *TreeProcessor) processSetCollectionTag(node *ASTNode, name string, ope
 			setData.Index[item] = true
 			tp.golem.LogInfo("Added '%s' to set '%s'", item, name)
 		}
+		// Mirror into the pattern-matching sets so <set>name</set> patterns see the change
+		if item != "" && tp.ctx.KnowledgeBase.Sets != nil {
+			tp.ctx.KnowledgeBase.AddSetMember(name, item)
+			tp.golem.InvalidatePatternMatchingSet(strings.ToUpper(name))
+		}
 		return "" // Add operations don't return content
 
 	case "remove", "delete":
@@ -961,11 +1153,20 @@ func (tp *TreeProcessor) processSetCollectionTag(node *ASTNode, name string, ope
 		} else {
 			tp.golem.LogInfo("Item '%s' not found in set '%s'", item, name)
 		}
+		// Keep the pattern-matching sets in sync
+		if item != "" && tp.ctx.KnowledgeBase.Sets != nil {
+			tp.ctx.KnowledgeBase.RemoveSetMember(name, item)
+			tp.golem.InvalidatePatternMatchingSet(strings.ToUpper(name))
+		}
 		return "" // Remove operations don't return content
 
 	case "clear":
 		// Clear all items from set
 		tp.ctx.KnowledgeBase.SetCollections[name] = NewSetCollection()
+		if tp.ctx.KnowledgeBase.Sets != nil {
+			delete(tp.ctx.KnowledgeBase.Sets, strings.ToUpper(name))
+		}
+		tp.golem.InvalidatePatternMatchingSet(strings.ToUpper(name))
 		tp.golem.LogInfo("Cleared set '%s'", name)
 		return "" // Clear operations don't return content
 
@@ -994,6 +1195,9 @@ func (tp *TreeProcessor) processSetCollectionTag(node *ASTNode, name string, ope
 
 	case "get", "":
 		// Return all items in set (space-separated, in insertion order)
+		if len(setData.Items) == 0 {
+			return tp.setGetDefault(node)
+		}
 		result := strings.Join(setData.Items, " ")
 		tp.golem.LogInfo("Got all items from set '%s': '%s'", name, result)
 		return result
@@ -1005,6 +1209,15 @@ func (tp *TreeProcessor) processSetCollectionTag(node *ASTNode, name string, ope
 	}
 }
 
+// setGetDefault returns the evaluated default attribute of a set get, used when the
+// set is empty
+func (tp *TreeProcessor) setGetDefault(node *ASTNode) string {
+	if val, exists := node.Attributes["default"]; exists {
+		return tp.evaluateAttributeValue(val)
+	}
+	return ""
+}
+
 func (tp *TreeProcessor) processGetTag(node *ASTNode, content string) string {
 	// Process get tag - variable retrieval
 	// Check for both 'name' (session predicates) and 'var' (local variables)
@@ -1068,8 +1281,8 @@ func (tp *TreeProcessor) processGetTag(node *ASTNode, content string) string {
 				return value
 			}
 		}
-		// 5. Bot properties
-		if tp.ctx.KnowledgeBase != nil && tp.ctx.KnowledgeBase.Properties != nil {
+		// 5. Bot properties, unless DisablePropertyFallback is set
+		if tp.golem.propertyFallback() && tp.ctx.KnowledgeBase != nil && tp.ctx.KnowledgeBase.Properties != nil {
 			if value, exists := tp.ctx.KnowledgeBase.Properties[varKey]; exists {
 				return value
 			}
@@ -1089,6 +1302,10 @@ func (tp *TreeProcessor) processBotTag(node *ASTNode, content string) string {
 
 	// Get bot property from knowledge base
 	if tp.ctx != nil && tp.ctx.KnowledgeBase != nil {
+		// Prefer the property in the session's language, e.g. "name.es"
+		if localized, ok := tp.ctx.KnowledgeBase.localizedPropertyKey(name, tp.ctx.language()); ok {
+			return tp.ctx.KnowledgeBase.Properties[localized]
+		}
 		if value, exists := tp.ctx.KnowledgeBase.Properties[name]; exists {
 			return value
 		}
@@ -1145,6 +1362,7 @@ func (tp *TreeProcessor) processStarTag(node *ASTNode, content string) string {
 		}
 	}
 
+	tp.golem.LogDebug("Star tag: no wildcard captured for %s, resolving to empty", key)
 	return ""
 }
 
@@ -1186,7 +1404,7 @@ func (tp *TreeProcessor) processSRTag(node *ASTNode, content string) string {
 	}
 
 	// Try to match the star content as a pattern in the knowledge base
-	category, wildcards, err := tp.ctx.KnowledgeBase.MatchPattern(starContent)
+	category, wildcards, err := tp.ctx.KnowledgeBase.MatchPatternWithTopic(starContent, tp.ctx.currentTopic())
 	if err != nil || category == nil {
 		tp.golem.LogDebug("SR tag: no matching pattern for '%s'", starContent)
 		return ""
@@ -1251,7 +1469,8 @@ func (tp *TreeProcessor) processSRTag(node *ASTNode, content string) string {
 	}
 
 	// Process the matched template recursively
-	result := tp.golem.processTemplateWithContext(category.Template, wildcards, tp.ctx)
+	tp.golem.notifySRAIMatched(category, starContent)
+	result := tp.golem.processCategoryTemplate(category, wildcards, tp.ctx)
 
 	// Restore old wildcards
 	if tp.ctx.Session != nil && tp.ctx.Session.Variables != nil {
@@ -1362,6 +1581,11 @@ func (tp *TreeProcessor) processThatWildcardTag(node *ASTNode, wildcardType stri
 				return value
 			}
 		}
+		// Check local variables (populated whenever a that pattern with wildcards matched)
+		if value, exists := tp.ctx.LocalVars[key]; exists {
+			tp.golem.LogDebug("That wildcard tag: key=%s, value='%s' (from local vars)", key, value)
+			return value
+		}
 	}
 
 	tp.golem.LogDebug("That wildcard tag: key=%s not found", key)
@@ -1402,6 +1626,11 @@ func (tp *TreeProcessor) processThatWildcardWithEmbeddedIndex(node *ASTNode, wil
 				return value
 			}
 		}
+		// Check local variables (populated whenever a that pattern with wildcards matched)
+		if value, exists := tp.ctx.LocalVars[key]; exists {
+			tp.golem.LogDebug("That wildcard tag (embedded index): key=%s, value='%s' (from local vars)", key, value)
+			return value
+		}
 	}
 
 	tp.golem.LogDebug("That wildcard tag (embedded index): key=%s not found", key)
@@ -1409,7 +1638,24 @@ func (tp *TreeProcessor) processThatWildcardWithEmbeddedIndex(node *ASTNode, wil
 }
 
 func (tp *TreeProcessor) processTopicTag(node *ASTNode, content string) string {
-	// Process topic tag - topic reference
+	// Process topic tag - <topic/> returns the current topic, <topic>NEW</topic> sets it
+	if len(node.Children) > 0 {
+		topic := strings.TrimSpace(content)
+		if tp.ctx != nil {
+			if tp.ctx.Session != nil {
+				tp.ctx.Session.SetSessionTopic(topic)
+				// Keep <get name="topic"/> consistent with <set name="topic">
+				if tp.ctx.Session.Variables == nil {
+					tp.ctx.Session.Variables = make(map[string]string)
+				}
+				tp.ctx.Session.Variables["topic"] = topic
+			}
+			tp.ctx.Topic = topic
+		}
+		tp.golem.LogDebug("Topic tag: set topic to '%s'", topic)
+		return ""
+	}
+
 	index := 1
 	if idx, exists := node.Attributes["index"]; exists {
 		if parsed, err := strconv.Atoi(idx); err == nil {
@@ -1418,10 +1664,11 @@ func (tp *TreeProcessor) processTopicTag(node *ASTNode, content string) string {
 	}
 
 	// Get topic value
-	if tp.ctx != nil {
-		if index == 1 {
-			return tp.ctx.Topic
+	if tp.ctx != nil && index == 1 {
+		if tp.ctx.Session != nil {
+			return tp.ctx.Session.GetSessionTopic()
 		}
+		return tp.ctx.Topic
 	}
 
 	return ""
@@ -1445,11 +1692,145 @@ func (tp *TreeProcessor) processRandomTag(node *ASTNode, content string) string
 		return content
 	}
 
-	// Select random item
+	// Select random item, reusing the session's earlier choice for identical input if enabled
+	if index, ok := tp.stableRandomIndex(node, len(items)); ok {
+		return items[index]
+	}
+	if index, ok := tp.dealRandomIndex(node, len(items)); ok {
+		return items[index]
+	}
+	if index, ok := tp.recencyWeightedRandomIndex(items); ok {
+		return items[index]
+	}
 	index := tp.golem.randomIntTree(len(items))
 	return items[index]
 }
 
+// recencyWeightedRandomIndex picks an item at random with each item's weight lowered
+// by the session's recent responses that contain it. Every such response adds its
+// recency weight (see ChatSession.recencyWeight) to the item's penalty, and the item
+// is picked with weight 1/(1+penalty). It reports false when AvoidRecentRandomOptions
+// is disabled or there is no session.
+func (tp *TreeProcessor) recencyWeightedRandomIndex(items []string) (int, bool) {
+	if tp.golem.templateConfig == nil || !tp.golem.templateConfig.AvoidRecentRandomOptions {
+		return 0, false
+	}
+	if tp.ctx == nil || tp.ctx.Session == nil {
+		return 0, false
+	}
+	session := tp.ctx.Session
+	session.InitializeContextConfig()
+
+	weights := make([]float64, len(items))
+	total := 0.0
+	for i, item := range items {
+		penalty := 0.0
+		lowerItem := strings.ToLower(item)
+		for age := 0; age < len(session.ResponseHistory); age++ {
+			response := session.ResponseHistory[len(session.ResponseHistory)-1-age]
+			if strings.Contains(strings.ToLower(response), lowerItem) {
+				penalty += session.recencyWeight(age)
+			}
+		}
+		weights[i] = 1 / (1 + penalty)
+		total += weights[i]
+	}
+
+	const resolution = 1 << 30
+	target := float64(tp.golem.randomIntTree(resolution)) / resolution * total
+	for i, weight := range weights {
+		if target < weight {
+			tp.golem.LogDebug("Recency-weighted random selection: %d of %d (weights %v)", i, len(items), weights)
+			return i, true
+		}
+		target -= weight
+	}
+	return len(items) - 1, true
+}
+
+// dealRandomIndex deals the next selection for a <random> block from a shuffled deck
+// of its item indices kept on the session, reshuffling once every item has been
+// dealt. It reports false when ExhaustRandomOptions is disabled or there is no session.
+func (tp *TreeProcessor) dealRandomIndex(node *ASTNode, count int) (int, bool) {
+	if tp.golem.templateConfig == nil || !tp.golem.templateConfig.ExhaustRandomOptions {
+		return 0, false
+	}
+	if tp.ctx == nil || tp.ctx.Session == nil {
+		return 0, false
+	}
+	session := tp.ctx.Session
+
+	blockHash := fnv.New64a()
+	blockHash.Write([]byte(node.String()))
+	key := fmt.Sprintf("%x", blockHash.Sum64())
+
+	if session.RandomDecks == nil {
+		session.RandomDecks = make(map[string][]int)
+	}
+
+	// Drop indices that no longer exist, e.g. when an item produced no output
+	var deck []int
+	for _, index := range session.RandomDecks[key] {
+		if index < count {
+			deck = append(deck, index)
+		}
+	}
+	if len(deck) == 0 {
+		deck = make([]int, count)
+		for i := range deck {
+			deck[i] = i
+		}
+		for i := count - 1; i > 0; i-- {
+			j := tp.golem.randomIntTree(i + 1)
+			deck[i], deck[j] = deck[j], deck[i]
+		}
+	}
+
+	index := deck[0]
+	session.RandomDecks[key] = deck[1:]
+	tp.golem.LogDebug("Dealt random selection for block %s: %d of %d (%d left)", key, index, count, len(deck)-1)
+	return index, true
+}
+
+// stableRandomIndex returns a selection for a <random> block that is stable for the
+// (block, current input) pair within the session. It reports false when
+// StableRandomPerInput is disabled or there is no session input to key on.
+func (tp *TreeProcessor) stableRandomIndex(node *ASTNode, count int) (int, bool) {
+	if tp.golem.templateConfig == nil || !tp.golem.templateConfig.StableRandomPerInput {
+		return 0, false
+	}
+	if tp.ctx == nil || tp.ctx.Session == nil {
+		return 0, false
+	}
+	session := tp.ctx.Session
+	input := session.CurrentInput
+	if input == "" && len(session.RequestHistory) > 0 {
+		input = session.RequestHistory[len(session.RequestHistory)-1]
+	}
+	if input == "" {
+		return 0, false
+	}
+
+	blockHash := fnv.New64a()
+	blockHash.Write([]byte(node.String()))
+	key := fmt.Sprintf("%x|%s", blockHash.Sum64(), strings.ToUpper(strings.TrimSpace(input)))
+
+	if session.RandomSelections == nil {
+		session.RandomSelections = make(map[string]int)
+	}
+	if index, exists := session.RandomSelections[key]; exists && index < count {
+		return index, true
+	}
+
+	// Derive the selection from the session and key so different inputs vary
+	selection := fnv.New64a()
+	selection.Write([]byte(session.ID + "|" + key))
+	index := int(selection.Sum64() % uint64(count))
+	session.RandomSelections[key] = index
+	tp.golem.LogDebug("Stable random selection for '%s': %d of %d", key, index, count)
+	return index, true
+}
+
 func (tp *TreeProcessor) processListItemTag(node *ASTNode, content string) string {
 	// Process list item tag - process and return children
 	var result strings.Builder
@@ -1472,6 +1853,54 @@ func (tp *TreeProcessor) processConditionTag(node *ASTNode, content string) stri
 		actualValue = tp.golem.resolveVariable(varName, tp.ctx)
 	}
 
+	// Set iteration: foreach="menu" processes the condition once per member of the
+	// set, with the member bound to the condition's variable
+	if setName, hasForeach := node.Attributes["foreach"]; hasForeach && hasName {
+		return tp.processConditionForeach(node, varName, tp.evaluateAttributeValue(setName))
+	}
+
+	// Existence check: exists="true" matches any set variable, even one set to empty;
+	// exists="false" matches only unset variables
+	if existsAttr, hasExists := node.Attributes["exists"]; hasExists && hasName {
+		found := false
+		if tp.ctx != nil {
+			_, found = tp.golem.resolveVariableWithPresence(varName, tp.ctx)
+		}
+		if found == strings.EqualFold(strings.TrimSpace(existsAttr), "true") {
+			var result strings.Builder
+			for _, child := range node.Children {
+				result.WriteString(tp.processNode(child))
+			}
+			return result.String()
+		}
+		return "" // No match
+	}
+
+	// Map key check: mapkey="name" matches when the variable's value is a key in the map
+	if mapName, hasMapKey := node.Attributes["mapkey"]; hasMapKey && hasName {
+		mapName = tp.evaluateAttributeValue(mapName)
+		if tp.ctx != nil && tp.ctx.KnowledgeBase != nil && tp.ctx.KnowledgeBase.HasMapKey(mapName, actualValue) {
+			var result strings.Builder
+			for _, child := range node.Children {
+				result.WriteString(tp.processNode(child))
+			}
+			return result.String()
+		}
+		return "" // No match
+	}
+
+	// Regex check: matches="^\d+$" matches when the variable's value matches the regex
+	if pattern, hasMatches := node.Attributes["matches"]; hasMatches && hasName {
+		if tp.conditionValueMatches(pattern, actualValue) {
+			var result strings.Builder
+			for _, child := range node.Children {
+				result.WriteString(tp.processNode(child))
+			}
+			return result.String()
+		}
+		return "" // No match
+	}
+
 	// Type 1: Simple condition with value attribute
 	if hasExpectedValue {
 		if strings.EqualFold(actualValue, expectedValue) {
@@ -1491,6 +1920,18 @@ func (tp *TreeProcessor) processConditionTag(node *ASTNode, content string) stri
 		if child.Type == NodeTypeTag && child.TagName == "li" {
 			liValue, hasValue := child.Attributes["value"]
 
+			// An <li matches="..."> tests the variable's value against a regex
+			if pattern, hasMatches := child.Attributes["matches"]; hasMatches {
+				if tp.conditionValueMatches(pattern, actualValue) {
+					var result strings.Builder
+					for _, liChild := range child.Children {
+						result.WriteString(tp.processNode(liChild))
+					}
+					return strings.TrimSpace(result.String())
+				}
+				continue
+			}
+
 			// If no value, this is the default case - save it for later
 			if !hasValue || liValue == "" {
 				defaultLi = child
@@ -1530,6 +1971,65 @@ func (tp *TreeProcessor) processConditionTag(node *ASTNode, content string) stri
 	return "" // No match
 }
 
+// processConditionForeach processes a <condition foreach="set"> once per member of
+// the set, in set order, binding each member to varName as a local variable. Each
+// pass runs the condition as if foreach were absent, so a <li> without a value renders
+// every member and <li value="..."> can single some out. The non-empty results are
+// joined with spaces; the variable's previous local value is restored afterwards.
+func (tp *TreeProcessor) processConditionForeach(node *ASTNode, varName, setName string) string {
+	if tp.ctx == nil || tp.ctx.KnowledgeBase == nil {
+		return ""
+	}
+	members := tp.ctx.KnowledgeBase.GetSetMembers(setName)
+	tp.golem.LogDebug("Condition foreach: %d members of set '%s' bound to '%s'", len(members), setName, varName)
+
+	if tp.ctx.LocalVars == nil {
+		tp.ctx.LocalVars = make(map[string]string)
+	}
+	previous, hadPrevious := tp.ctx.LocalVars[varName]
+	defer func() {
+		if hadPrevious {
+			tp.ctx.LocalVars[varName] = previous
+		} else {
+			delete(tp.ctx.LocalVars, varName)
+		}
+	}()
+
+	pass := *node
+	pass.Attributes = make(map[string]string, len(node.Attributes))
+	for name, value := range node.Attributes {
+		if name != "foreach" {
+			pass.Attributes[name] = value
+		}
+	}
+
+	var results []string
+	for _, member := range members {
+		tp.ctx.LocalVars[varName] = member
+		if result := strings.TrimSpace(tp.processConditionTag(&pass, "")); result != "" {
+			results = append(results, result)
+		}
+	}
+	return strings.Join(results, " ")
+}
+
+// conditionValueMatches reports whether a variable's value matches the regex of a
+// matches attribute. Compiled regexes are cached; an invalid regex never matches.
+func (tp *TreeProcessor) conditionValueMatches(pattern, value string) bool {
+	var compiled *regexp.Regexp
+	var err error
+	if tp.golem.tagProcessingCache != nil {
+		compiled, err = tp.golem.tagProcessingCache.GetCompiledRegex(pattern)
+	} else {
+		compiled, err = regexp.Compile(pattern)
+	}
+	if err != nil {
+		tp.golem.LogWarn("Invalid regex in condition matches=\"%s\": %v", pattern, err)
+		return false
+	}
+	return compiled.MatchString(value)
+}
+
 func (tp *TreeProcessor) processMapTag(node *ASTNode, content string) string {
 	// Process map tag - mapping operations
 	// Check for required knowledge base
@@ -1589,6 +2089,7 @@ func (tp *TreeProcessor) processMapTag(node *ASTNode, content string) string {
 				value = content
 			}
 			tp.ctx.KnowledgeBase.Maps[name][key] = strings.TrimSpace(value)
+			tp.ctx.KnowledgeBase.invalidateReverseMap(name)
 			tp.golem.LogInfo("Set map '%s'['%s'] = '%s'", name, key, strings.TrimSpace(value))
 			tp.golem.LogInfo("After set: map '%s' = %v", name, tp.ctx.KnowledgeBase.Maps[name])
 			return "" // Set operations don't return content
@@ -1600,6 +2101,7 @@ func (tp *TreeProcessor) processMapTag(node *ASTNode, content string) string {
 		if key != "" {
 			if _, exists := tp.ctx.KnowledgeBase.Maps[name][key]; exists {
 				delete(tp.ctx.KnowledgeBase.Maps[name], key)
+				tp.ctx.KnowledgeBase.invalidateReverseMap(name)
 				tp.golem.LogInfo("Removed key '%s' from map '%s'", key, name)
 				tp.golem.LogInfo("After remove: map '%s' = %v", name, tp.ctx.KnowledgeBase.Maps[name])
 			} else {
@@ -1611,6 +2113,7 @@ func (tp *TreeProcessor) processMapTag(node *ASTNode, content string) string {
 	case "clear":
 		// Clear all entries
 		tp.ctx.KnowledgeBase.Maps[name] = make(map[string]string)
+		tp.ctx.KnowledgeBase.invalidateReverseMap(name)
 		tp.golem.LogInfo("Cleared map '%s'", name)
 		return "" // Clear operations don't return content
 
@@ -1667,6 +2170,19 @@ func (tp *TreeProcessor) processMapTag(node *ASTNode, content string) string {
 		return pairsString
 
 	case "get", "":
+		// Reverse mode looks up the key for a value
+		if mode, hasMode := node.Attributes["mode"]; hasMode && strings.EqualFold(mode, "reverse") {
+			if key == "" {
+				return ""
+			}
+			if original, exists := tp.ctx.KnowledgeBase.ReverseMap(tp.golem, name)[key]; exists {
+				tp.golem.LogInfo("Reverse mapped '%s' -> '%s'", key, original)
+				return original
+			}
+			tp.golem.LogInfo("Value '%s' not found in map '%s', returning value", key, name)
+			return key
+		}
+
 		// Get value by key (original functionality)
 		if key != "" {
 			if value, exists := tp.ctx.KnowledgeBase.Maps[name][key]; exists {
@@ -1956,10 +2472,17 @@ func (tp *TreeProcessor) processLearnTag(node *ASTNode, content string) string {
 		LocalVars:     tp.ctx.LocalVars,
 		KnowledgeBase: tp.ctx.KnowledgeBase,
 		Wildcards:     tp.ctx.Wildcards, // Pass actual wildcards for evaluation
+		Topic:         tp.ctx.Topic,
+	}
+
+	// scope="topic" confines the learned categories to the active topic
+	scopeAttr := ""
+	if scope, exists := node.Attributes["scope"]; exists {
+		scopeAttr = fmt.Sprintf(` scope="%s"`, tp.evaluateAttributeValue(scope))
 	}
 
 	// The underlying function processes both <learn> and <learnf> tags via regex
-	return tp.golem.processLearnTagsWithContext(fmt.Sprintf("<learn>%s</learn>", processedContent), learnCtx)
+	return tp.golem.processLearnTagsWithContext(fmt.Sprintf("<learn%s>%s</learn>", scopeAttr, processedContent), learnCtx)
 }
 
 func (tp *TreeProcessor) processLearnfTag(node *ASTNode, content string) string {
@@ -2169,10 +2692,39 @@ func (tp *TreeProcessor) processTagWithContent(node *ASTNode, content string) st
 
 // Text processing tags
 
+// urlOrEmailRegex matches URLs, bare domain names and email addresses
+var urlOrEmailRegex = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>]*[^\s<>.,!?;:)]|\b[\w.+-]+@[\w-]+(?:\.[\w-]+)+\b|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b(?:/[^\s<>]*[^\s<>.,!?;:)])?`)
+
+// preserveURLs reports whether case tags should leave URLs and email addresses unchanged
+func (tp *TreeProcessor) preserveURLs() bool {
+	return tp.golem.templateConfig != nil && tp.golem.templateConfig.PreserveURLs
+}
+
+// transformPreservingURLs applies transform to content, skipping entity references,
+// and URLs and email addresses when PreserveURLs is enabled
+func (tp *TreeProcessor) transformPreservingURLs(content string, caseTransform func(string) string) string {
+	transform := func(text string) string {
+		return transformPreservingEntities(text, caseTransform)
+	}
+	if !tp.preserveURLs() {
+		return transform(content)
+	}
+
+	var result strings.Builder
+	last := 0
+	for _, match := range urlOrEmailRegex.FindAllStringIndex(content, -1) {
+		result.WriteString(transform(content[last:match[0]]))
+		result.WriteString(content[match[0]:match[1]])
+		last = match[1]
+	}
+	result.WriteString(transform(content[last:]))
+	return result.String()
+}
+
 func (tp *TreeProcessor) processUppercaseTag(node *ASTNode, content string) string {
 	// Process content directly - convert to uppercase
 	tp.trackMetric("format") // Track format processor usage
-	processedContent := strings.ToUpper(content)
+	processedContent := tp.transformPreservingURLs(content, strings.ToUpper)
 
 	// Normalize whitespace like the original method
 	processedContent = strings.TrimSpace(processedContent)
@@ -2189,7 +2741,7 @@ func (tp *TreeProcessor) processUppercaseTag(node *ASTNode, content string) stri
 func (tp *TreeProcessor) processLowercaseTag(node *ASTNode, content string) string {
 	// Process content directly - convert to lowercase
 	tp.trackMetric("format") // Track format processor usage
-	processedContent := strings.ToLower(content)
+	processedContent := tp.transformPreservingURLs(content, strings.ToLower)
 
 	// Normalize whitespace like the original method
 	processedContent = strings.TrimSpace(processedContent)
@@ -2210,6 +2762,10 @@ func (tp *TreeProcessor) processFormalTag(node *ASTNode, content string) string
 	var result []string
 
 	for _, word := range words {
+		if tp.preserveURLs() && urlOrEmailRegex.MatchString(word) {
+			result = append(result, word)
+			continue
+		}
 		if len(word) > 0 {
 			// Capitalize first letter, lowercase the rest
 			capitalized := strings.ToUpper(string(word[0])) + strings.ToLower(word[1:])
@@ -2351,6 +2907,12 @@ func (tp *TreeProcessor) processReplaceTag(node *ASTNode, content string) string
 	search = tp.evaluateAttributeValue(search)
 	replace = tp.evaluateAttributeValue(replace)
 
+	// mode="regex" treats search as a regular expression; literal is the default
+	mode := ""
+	if val, exists := node.Attributes["mode"]; exists {
+		mode = tp.evaluateAttributeValue(val)
+	}
+
 	// Trim content
 	content = strings.TrimSpace(content)
 	if content == "" {
@@ -2359,16 +2921,16 @@ func (tp *TreeProcessor) processReplaceTag(node *ASTNode, content string) string
 
 	// Check cache first
 	var result string
-	cacheKey := fmt.Sprintf("%s|%s|%s", search, replace, content)
+	cacheKey := fmt.Sprintf("%s|%s|%s|%s", mode, search, replace, content)
 	if tp.golem.templateTagProcessingCache != nil {
 		if cached, found := tp.golem.templateTagProcessingCache.GetProcessedTag("replace", cacheKey, tp.ctx); found {
 			result = cached
 		} else {
-			result = strings.ReplaceAll(content, search, replace)
+			result = tp.golem.replaceText(content, search, replace, mode)
 			tp.golem.templateTagProcessingCache.SetProcessedTag("replace", cacheKey, result, tp.ctx)
 		}
 	} else {
-		result = strings.ReplaceAll(content, search, replace)
+		result = tp.golem.replaceText(content, search, replace, mode)
 	}
 
 	tp.golem.LogDebug("Replace tag: '%s' (search='%s', replace='%s') -> '%s'", content, search, replace, result)
@@ -2625,13 +3187,39 @@ func (tp *TreeProcessor) processEvalTag(node *ASTNode, content string) string {
 
 	tp.golem.LogDebug("Eval tag: evaluated content='%s'", content)
 
-	// Return the evaluated content
-	// Note: Unlike the regex processor which re-processes the content through
-	// the full template pipeline, the AST naturally handles nested evaluation
-	// through its tree traversal, so we simply return the already-evaluated content
+	// Markup read from variables and properties was already re-evaluated as the
+	// children were processed (see evalStoredMarkup); anything else is returned as-is
 	return content
 }
 
+// evalStoredMarkup runs the output of a <get> or <bot> child of <eval> through the
+// template pipeline once more, so a template stored in a variable or property is
+// executed. Output of any other child, such as <star/> or <input/>, stays literal so
+// user input can't inject template code. Each re-evaluation goes one level deeper,
+// so a variable holding <eval> of itself can't loop forever.
+func (tp *TreeProcessor) evalStoredMarkup(child *ASTNode, output string) string {
+	if child.Type != NodeTypeTag && child.Type != NodeTypeSelfClosingTag {
+		return output
+	}
+	if child.TagName != "get" && child.TagName != "bot" {
+		return output
+	}
+	if !strings.Contains(output, "<") || tp.ctx == nil {
+		return output
+	}
+	if tp.ctx.RecursionDepth >= MaxSRAIRecursionDepth {
+		tp.golem.LogWarn("Eval recursion depth limit reached (%d), dropping '%s'", MaxSRAIRecursionDepth, output)
+		return ""
+	}
+
+	evalCtx := *tp.ctx
+	evalCtx.RecursionDepth++
+	result := tp.golem.processTemplateWithContext(output, tp.ctx.Wildcards, &evalCtx)
+
+	tp.golem.LogDebug("Eval tag: re-evaluated '%s' -> '%s'", output, result)
+	return result
+}
+
 func (tp *TreeProcessor) processPersonTag(node *ASTNode, content string) string {
 	// Process person tag - pronoun substitution (1st/2nd person swap)
 	// Normalize whitespace
@@ -3471,7 +4059,7 @@ func (tp *TreeProcessor) processDateTag(node *ASTNode, content string) string {
 	}
 	// Convert C-style or alternative formats to Go time format
 	goFormat := tp.golem.convertToGoTimeFormat(format)
-	return time.Now().Format(goFormat)
+	return tp.clockWithOffset(node).Format(goFormat)
 }
 
 func (tp *TreeProcessor) processTimeTag(node *ASTNode, content string) string {
@@ -3491,7 +4079,17 @@ func (tp *TreeProcessor) processTimeTag(node *ASTNode, content string) string {
 		goFormat = defaultFormat
 	}
 
-	return time.Now().Format(goFormat)
+	return tp.clockWithOffset(node).Format(goFormat)
+}
+
+// clockWithOffset returns the clock for a <date> or <time> tag, moved by its offset
+// attribute (e.g. offset="+1d" or offset="-30m")
+func (tp *TreeProcessor) clockWithOffset(node *ASTNode) time.Time {
+	offset := ""
+	if val, exists := node.Attributes["offset"]; exists {
+		offset = tp.evaluateAttributeValue(val)
+	}
+	return tp.golem.clockWithOffset(offset)
 }
 
 // System tags
@@ -3499,7 +4097,7 @@ func (tp *TreeProcessor) processTimeTag(node *ASTNode, content string) string {
 func (tp *TreeProcessor) processSizeTag(node *ASTNode, content string) string {
 	// Size tag - knowledge base size
 	if tp.ctx != nil && tp.ctx.KnowledgeBase != nil {
-		return strconv.Itoa(len(tp.ctx.KnowledgeBase.Categories))
+		return strconv.Itoa(tp.ctx.KnowledgeBase.categoryCount())
 	}
 	return "0"
 }
@@ -3511,7 +4109,7 @@ func (tp *TreeProcessor) processVersionTag(node *ASTNode, content string) string
 			return version
 		}
 	}
-	return "1.0"
+	return "2.0"
 }
 
 func (tp *TreeProcessor) processIdTag(node *ASTNode, content string) string {
@@ -3524,6 +4122,16 @@ func (tp *TreeProcessor) processIdTag(node *ASTNode, content string) string {
 	return "golem"
 }
 
+func (tp *TreeProcessor) processUptimeTag(node *ASTNode, content string) string {
+	// Uptime tag - whole seconds since the Golem instance was created
+	return strconv.FormatInt(int64(tp.golem.Uptime()/time.Second), 10)
+}
+
+func (tp *TreeProcessor) processSessionCountTag(node *ASTNode, content string) string {
+	// Session count tag - number of active chat sessions
+	return strconv.Itoa(tp.golem.SessionCount())
+}
+
 func (tp *TreeProcessor) processRequestTag(node *ASTNode, content string) string {
 	// Request tag - previous request
 	// Index 1 = most recent, index 2 = 2nd most recent, etc.
@@ -3592,7 +4200,7 @@ func (tp *TreeProcessor) processUnlearnfTag(node *ASTNode, content string) strin
 	// Parse the AIML content within the unlearnf tag
 	categories, err := tp.golem.parseLearnContent(content)
 	if err != nil {
-		tp.golem.LogError("Failed to parse unlearnf content: %v", err)
+		tp.failTag(fmt.Errorf("failed to parse unlearnf content: %w", err))
 		return ""
 	}
 
@@ -3707,7 +4315,9 @@ func (tp *TreeProcessor) escapeXMLChars(s string) string {
 
 // Helper method for random number generation
 func (g *Golem) randomIntTree(max int) int {
-	// This would use the existing random number generation from the Golem instance
-	// For now, return a simple implementation
+	// Use the deterministic generator once a seed has been set
+	if g.seededRandom {
+		return g.randomInt(max)
+	}
 	return int(time.Now().UnixNano() % int64(max))
 }
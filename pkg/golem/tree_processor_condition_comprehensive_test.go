@@ -788,3 +788,291 @@ func TestConditionTagAIML2Examples(t *testing.T) {
 		})
 	}
 }
+
+// TestConditionTagWithRandomListItem verifies a <condition> branch containing a <random>
+// is selected once and randomizes exactly once, on both the tree and legacy paths
+func TestConditionTagWithRandomListItem(t *testing.T) {
+	const seed = 42
+	items := []string{"Alpha", "Beta", "Gamma"}
+	// One step of the deterministic generator used by randomInt
+	nextSeed := int64((seed*1103515245 + 12345) & 0x7fffffff)
+	expected := items[int(nextSeed)%len(items)]
+
+	template := `<condition name="mood"><li value="happy"><random><li>Alpha</li><li>Beta</li><li>Gamma</li></random></li><li>Neutral</li></condition>`
+
+	t.Run("tree processor", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>HOW ARE YOU</pattern>
+        <template>` + template + `</template>
+    </category>
+</aiml>`)
+		if err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		session := g.CreateSession("condition-random")
+		session.Variables["mood"] = "happy"
+		g.SetRandomSeed(seed)
+
+		response, err := g.ProcessInput("how are you", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, response)
+		}
+		if g.randomSeed != nextSeed {
+			t.Errorf("Expected a single random draw (seed %d), got seed %d", nextSeed, g.randomSeed)
+		}
+	})
+
+	t.Run("legacy condition processing", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		if err := g.LoadAIMLFromString(`<aiml version="2.0"><category><pattern>X</pattern><template>x</template></category></aiml>`); err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		session := g.CreateSession("condition-random-legacy")
+		session.Variables["mood"] = "happy"
+		g.SetRandomSeed(seed)
+
+		ctx := &VariableContext{
+			LocalVars:     make(map[string]string),
+			Session:       session,
+			KnowledgeBase: g.aimlKB,
+		}
+		response := g.processConditionTagsWithContext(template, ctx)
+		if response != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, response)
+		}
+		if g.randomSeed != nextSeed {
+			t.Errorf("Expected a single random draw (seed %d), got seed %d", nextSeed, g.randomSeed)
+		}
+
+		// The default branch is still found after a branch with nested <li> elements
+		session.Variables["mood"] = "tired"
+		if response := g.processConditionTagsWithContext(template, ctx); response != "Neutral" {
+			t.Errorf("Expected default branch 'Neutral', got '%s'", response)
+		}
+	})
+}
+
+// TestConditionTagExists tests that exists="true|false" distinguishes unset variables
+// from variables explicitly set to an empty string
+func TestConditionTagExists(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>CHECK NICKNAME</pattern>
+        <template><condition name="nickname" exists="true">set</condition><condition name="nickname" exists="false">unset</condition></template>
+    </category>
+    <category>
+        <pattern>CLEAR NICKNAME</pattern>
+        <template><think><set name="nickname"></set></think>cleared</template>
+    </category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		setupVars map[string]string
+		expected  string
+	}{
+		{name: "Unset variable", setupVars: nil, expected: "unset"},
+		{name: "Variable set to empty", setupVars: map[string]string{"nickname": ""}, expected: "set"},
+		{name: "Variable set to non-empty", setupVars: map[string]string{"nickname": "Ace"}, expected: "set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := g.CreateSession("condition-exists-" + tt.name)
+			for k, v := range tt.setupVars {
+				session.Variables[k] = v
+			}
+
+			response, err := g.ProcessInput("check nickname", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+
+	t.Run("Variable set to empty by a template", func(t *testing.T) {
+		session := g.CreateSession("condition-exists-template")
+		if _, err := g.ProcessInput("clear nickname", session); err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		response, err := g.ProcessInput("check nickname", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != "set" {
+			t.Errorf("Expected 'set', got '%s'", response)
+		}
+	})
+}
+
+func TestConditionTagMapKey(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>IS * A COUNTRY</pattern>
+        <template><think><set name="word"><star/></set></think><condition name="word" mapkey="country2capital">yes</condition></template>
+    </category>
+    <category>
+        <pattern>CHECK UNKNOWN MAP</pattern>
+        <template>[<condition name="word" mapkey="nosuchmap">yes</condition>]</template>
+    </category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	g.aimlKB.Maps["country2capital"] = map[string]string{
+		"France":        "Paris",
+		"UNITED STATES": "Washington",
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Present key", input: "is France a country", expected: "yes"},
+		{name: "Present key, different case", input: "is france a country", expected: "yes"},
+		{name: "Present multi-word key", input: "is united states a country", expected: "yes"},
+		{name: "Absent key", input: "is Paris a country", expected: ""},
+		{name: "Unknown map", input: "check unknown map", expected: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := g.CreateSession("condition-mapkey-" + tt.name)
+			session.Variables["word"] = "France"
+
+			response, err := g.ProcessInput(tt.input, session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+}
+
+// TestConditionTagMatches tests the matches attribute, which tests a variable's value
+// against a regex on the condition or on an <li>
+func TestConditionTagMatches(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>MY AGE IS *</pattern>
+        <template><think><set name="age"><star/></set></think><condition name="age" matches="^\d+$">Got it.</condition></template>
+    </category>
+    <category>
+        <pattern>CLASSIFY *</pattern>
+        <template><think><set name="value"><star/></set></think><condition name="value">
+            <li matches="^\d+$">number</li>
+            <li matches="^[a-z]+$">word</li>
+            <li>other</li>
+        </condition></template>
+    </category>
+    <category>
+        <pattern>BROKEN</pattern>
+        <template>[<condition name="age" matches="[0-9">yes</condition>]<condition name="age"><li matches="(">bad</li><li>fallback</li></condition></template>
+    </category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Numeric value matches", input: "my age is 42", expected: "Got it."},
+		{name: "Non-numeric value does not match", input: "my age is forty", expected: ""},
+		{name: "Numeric li", input: "classify 123", expected: "number"},
+		{name: "Word li", input: "classify hello", expected: "word"},
+		{name: "No li matches", input: "classify abc123", expected: "other"},
+		{name: "Invalid regex is no match", input: "broken", expected: "[]fallback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := g.CreateSession("condition-matches-" + tt.name)
+			session.Variables["age"] = "42"
+
+			response, err := g.ProcessInput(tt.input, session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+}
+
+// TestConditionTagForeach tests the foreach attribute, which renders the condition once
+// per member of a set with the member bound to the condition's variable
+func TestConditionTagForeach(t *testing.T) {
+	g := NewForTesting(t, false)
+	err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>MENU</pattern>
+        <template>Menu: <condition name="item" foreach="menuitems"><li>[<get name="item"/>]</li></condition> (<get name="item"/>)</template>
+    </category>
+    <category>
+        <pattern>SPECIALS</pattern>
+        <template><condition name="item" foreach="menuitems">
+            <li value="tea">tea (special)</li>
+            <li><lowercase><get name="item"/></lowercase></li>
+        </condition></template>
+    </category>
+    <category>
+        <pattern>EMPTY MENU</pattern>
+        <template>[<condition name="item" foreach="nosuchset"><li><get name="item"/></li></condition>]</template>
+    </category>
+</aiml>`)
+	if err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	g.aimlKB.AddSetMembers("menuitems", []string{"coffee", "tea", "juice"})
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "One li per member", input: "menu", expected: "Menu: [COFFEE] [TEA] [JUICE] (outside)"},
+		{name: "Valued li per member", input: "specials", expected: "coffee tea (special) juice"},
+		{name: "Unknown set", input: "empty menu", expected: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := g.CreateSession("condition-foreach-" + tt.name)
+			session.Variables["item"] = "outside"
+
+			response, err := g.ProcessInput(tt.input, session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+}
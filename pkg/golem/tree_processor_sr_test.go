@@ -142,7 +142,7 @@ func TestTreeProcessorSRTagNoMatch(t *testing.T) {
 	}
 
 	template := "You said: <sr/>"
-	expected := "You said: "
+	expected := "You said:" // The space left by the empty <sr/> is dropped
 
 	// Create tree processor
 	tp := NewTreeProcessor(g)
@@ -191,7 +191,7 @@ func TestTreeProcessorSRTagNoWildcard(t *testing.T) {
 	}
 
 	template := "You said: <sr/>"
-	expected := "You said: "
+	expected := "You said:" // The space left by the empty <sr/> is dropped
 
 	// Create tree processor
 	tp := NewTreeProcessor(g)
@@ -236,7 +236,7 @@ func TestTreeProcessorSRTagNoKnowledgeBase(t *testing.T) {
 	}
 
 	template := "You said: <sr/>"
-	expected := "You said: "
+	expected := "You said:" // The space left by the empty <sr/> is dropped
 
 	// Create tree processor
 	tp := NewTreeProcessor(g)
@@ -275,7 +275,7 @@ func TestTreeProcessorSRTagNoSession(t *testing.T) {
 	}
 
 	template := "You said: <sr/>"
-	expected := "You said: "
+	expected := "You said:" // The space left by the empty <sr/> is dropped
 
 	// Create tree processor
 	tp := NewTreeProcessor(g)
@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // TestTreeProcessorSRAITag tests the native AST implementation of the <srai> tag
@@ -199,6 +201,97 @@ func TestTreeProcessorSRAIRecursion(t *testing.T) {
 	}
 }
 
+// TestSRAISelfReference tests that an SRAI resolving back to a category already being
+// processed stops immediately instead of recursing down to the depth limit
+func TestSRAISelfReference(t *testing.T) {
+	aimlContent := `
+<aiml version="2.0">
+    <category>
+        <pattern>LOOP</pattern>
+        <template>x<srai>LOOP</srai></template>
+    </category>
+    <category>
+        <pattern>PING</pattern>
+        <template>ping <srai>PONG</srai></template>
+    </category>
+    <category>
+        <pattern>PONG</pattern>
+        <template>pong<srai>PING</srai></template>
+    </category>
+    <category>
+        <pattern>HELLO</pattern>
+        <template>Hello</template>
+    </category>
+    <category>
+        <pattern>TWICE</pattern>
+        <template><srai>HELLO</srai> <srai>HELLO</srai></template>
+    </category>
+    <category>
+        <pattern>HELP</pattern>
+        <template>helping</template>
+    </category>
+    <category>
+        <pattern>PLEASE *</pattern>
+        <template><srai><star/></srai></template>
+    </category>
+    <category>
+        <pattern>* AND *</pattern>
+        <template><srai><star/></srai> + <srai><star index="2"/></srai></template>
+    </category>
+    <category>
+        <pattern>A</pattern>
+        <template>a</template>
+    </category>
+    <category>
+        <pattern>B</pattern>
+        <template>b</template>
+    </category>
+    <category>
+        <pattern>C</pattern>
+        <template>c</template>
+    </category>
+</aiml>`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"LOOP", "x"},
+		{"PING", "ping pong"},
+		// Reusing a category in sibling SRAIs is not a self-reference
+		{"TWICE", "Hello Hello"},
+		// Recursive reductions reach the same category again with shorter input
+		{"please please help", "helping"},
+		{"a and b and c", "a + b + c"},
+	}
+
+	engines := map[string]func(g *Golem){
+		"tree":         func(g *Golem) {},
+		"consolidated": func(g *Golem) { g.SetTemplateProcessor(g.GetConsolidatedProcessor()) },
+	}
+
+	for name, configure := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			configure(g)
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("srai-self-reference-" + name)
+
+			for _, tt := range tests {
+				response, err := g.ProcessInput(tt.input, session)
+				if err != nil {
+					t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+				}
+				if response != tt.expected {
+					t.Errorf("ProcessInput(%q): expected %q, got %q", tt.input, tt.expected, response)
+				}
+			}
+		})
+	}
+}
+
 // TestTreeProcessorSRAIWithWildcards tests SRAI with complex wildcard patterns
 func TestTreeProcessorSRAIWithWildcards(t *testing.T) {
 	g := NewForTesting(t, false)
@@ -303,6 +396,67 @@ func TestTreeProcessorSRAIIntegration(t *testing.T) {
 	}
 }
 
+// TestTreeProcessorSRAIEmptyResolution tests SRAIs whose matched template produces no output
+func TestTreeProcessorSRAIEmptyResolution(t *testing.T) {
+	aimlContent := `
+<aiml version="2.0">
+    <category>
+        <pattern>REMEMBER BLUE</pattern>
+        <template><think><set name="color">blue</set></think></template>
+    </category>
+    <category>
+        <pattern>FAVORITE</pattern>
+        <template>[<srai>REMEMBER BLUE</srai>]</template>
+    </category>
+    <category>
+        <pattern>*</pattern>
+        <template>I have no answer for <star/></template>
+    </category>
+</aiml>`
+
+	t.Run("Empty by default", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		if err := g.LoadAIMLFromString(aimlContent); err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		session := g.CreateSession("srai-empty-default")
+
+		response, err := g.ProcessInput("FAVORITE", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != "[]" {
+			t.Errorf("Expected '[]', got '%s'", response)
+		}
+		if session.Variables["color"] != "blue" {
+			t.Errorf("Expected side effect color=blue, got '%s'", session.Variables["color"])
+		}
+	})
+
+	t.Run("Fallback to default pattern", func(t *testing.T) {
+		g := NewForTesting(t, false)
+		if err := g.LoadAIMLFromString(aimlContent); err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		config := g.GetTemplateProcessingConfig()
+		config.FallbackOnEmptySRAI = true
+		g.UpdateTemplateProcessingConfig(config)
+		session := g.CreateSession("srai-empty-fallback")
+
+		response, err := g.ProcessInput("FAVORITE", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if response != "[I have no answer for REMEMBER BLUE]" {
+			t.Errorf("Expected '[I have no answer for REMEMBER BLUE]', got '%s'", response)
+		}
+		// The empty category was still evaluated, so its side effects remain
+		if session.Variables["color"] != "blue" {
+			t.Errorf("Expected side effect color=blue, got '%s'", session.Variables["color"])
+		}
+	})
+}
+
 // TestTreeProcessorSRAIXTag tests the native AST implementation of the <sraix> tag
 func TestTreeProcessorSRAIXTag(t *testing.T) {
 	// Create a mock HTTP server
@@ -525,8 +679,8 @@ func TestTreeProcessorSRAIEdgeCases(t *testing.T) {
 		input    string
 		expected string
 	}{
-		{"TEST EMPTY", "Result:  end"},
-		{"TEST SPACE", "Before  after"},
+		{"TEST EMPTY", "Result: end"}, // Spaces around an empty SRAI are collapsed
+		{"TEST SPACE", "Before after"},
 		{"TEST NO MATCH", "Result: NO SUCH PATTERN"},
 	}
 
@@ -539,3 +693,215 @@ func TestTreeProcessorSRAIEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestConcurrentSRAI tests resolving independent top-level SRAIs in parallel
+func TestConcurrentSRAI(t *testing.T) {
+	// Each request is held briefly so overlapping requests can be observed
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		var requestData map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+		input, _ := requestData["input"].(string)
+		message := "Headlines from " + input
+		if strings.HasSuffix(r.URL.Path, "/weather") {
+			message = "Sunny in " + input
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"message": message},
+		})
+	}))
+	defer server.Close()
+
+	g := NewForTesting(t, false)
+	g.GetTemplateProcessingConfig().ConcurrentSRAI = true
+	for _, service := range []string{"weather", "news"} {
+		if err := g.AddSRAIXConfig(&SRAIXConfig{
+			Name:           service,
+			BaseURL:        server.URL + "/" + service,
+			Method:         "POST",
+			Timeout:        10,
+			ResponseFormat: "json",
+			ResponsePath:   "data.message",
+		}); err != nil {
+			t.Fatalf("Failed to add SRAIX config: %v", err)
+		}
+	}
+
+	aiml := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>WEATHER IN *</pattern>
+		<template><sraix service="weather"><star/></sraix></template>
+	</category>
+	<category>
+		<pattern>NEWS ABOUT *</pattern>
+		<template><sraix service="news"><star/></sraix></template>
+	</category>
+	<category>
+		<pattern>LOGGED WEATHER IN *</pattern>
+		<template><think><set name="last">weather</set></think><sraix service="weather"><star/></sraix></template>
+	</category>
+	<category>
+		<pattern>BRIEFING FOR *</pattern>
+		<template>Weather: <srai>WEATHER IN <star/></srai>. News: <srai>NEWS ABOUT <star/></srai>.</template>
+	</category>
+	<category>
+		<pattern>LOGGED BRIEFING FOR *</pattern>
+		<template><srai>LOGGED WEATHER IN <star/></srai> / <srai>NEWS ABOUT <star/></srai></template>
+	</category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aiml); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	t.Run("IndependentSRAIsRunInParallel", func(t *testing.T) {
+		atomic.StoreInt32(&maxInFlight, 0)
+		session := g.CreateSession("concurrent")
+		response, err := g.ProcessInput("briefing for Paris", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if expected := "Weather: Sunny in Paris. News: Headlines from Paris."; response != expected {
+			t.Errorf("Expected %q, got %q", expected, response)
+		}
+		if got := atomic.LoadInt32(&maxInFlight); got != 2 {
+			t.Errorf("Expected both SRAIX requests in flight together, saw at most %d", got)
+		}
+		if _, exists := session.Variables["star1"]; exists {
+			t.Errorf("Expected no wildcards left in the session, got %v", session.Variables)
+		}
+	})
+
+	t.Run("SideEffectsFallBackToSequential", func(t *testing.T) {
+		atomic.StoreInt32(&maxInFlight, 0)
+		session := g.CreateSession("sequential")
+		response, err := g.ProcessInput("logged briefing for Oslo", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		if expected := "Sunny in Oslo / Headlines from Oslo"; response != expected {
+			t.Errorf("Expected %q, got %q", expected, response)
+		}
+		if got := atomic.LoadInt32(&maxInFlight); got != 1 {
+			t.Errorf("Expected the SRAIs to be resolved one at a time, saw %d in flight", got)
+		}
+		if session.Variables["last"] != "weather" {
+			t.Errorf("Expected the <set> in the SRAI target to apply, got %v", session.Variables)
+		}
+	})
+
+	t.Run("SessionCopiesShareNoState", func(t *testing.T) {
+		session := g.CreateSession("copied")
+		session.Variables["name"] = "Ada"
+		session.RequestHistory = append(session.RequestHistory, "hello")
+		session.RandomDecks = map[string][]int{"block": {2, 0, 1}}
+		session.RandomSelections = map[string]int{"block|HELLO": 1}
+		session.ContextTags["hello"] = []string{"greeting"}
+
+		copied := copySessionState(session)
+		copied.Variables["name"] = "Grace"
+		copied.RequestHistory[0] = "bye"
+		copied.RandomDecks["block"][0] = 9
+		copied.RandomSelections["block|HELLO"] = 0
+		copied.ContextTags["hello"][0] = "farewell"
+		copied.LearningStats.PatternTypes["wildcard"]++
+
+		if session.Variables["name"] != "Ada" || session.RequestHistory[0] != "hello" ||
+			session.RandomDecks["block"][0] != 2 || session.RandomSelections["block|HELLO"] != 1 ||
+			session.ContextTags["hello"][0] != "greeting" || session.LearningStats.PatternTypes["wildcard"] != 0 {
+			t.Errorf("Expected writes to the copy to leave the session unchanged, got %+v", session)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		g.GetTemplateProcessingConfig().ConcurrentSRAI = false
+		defer func() { g.GetTemplateProcessingConfig().ConcurrentSRAI = true }()
+
+		atomic.StoreInt32(&maxInFlight, 0)
+		response, _ := g.ProcessInput("briefing for Rome", g.CreateSession("default"))
+		if expected := "Weather: Sunny in Rome. News: Headlines from Rome."; response != expected {
+			t.Errorf("Expected %q, got %q", expected, response)
+		}
+		if got := atomic.LoadInt32(&maxInFlight); got != 1 {
+			t.Errorf("Expected sequential resolution, saw %d in flight", got)
+		}
+	})
+}
+
+func TestSRAITopicAwareResolution(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>FAVOURITE</pattern>
+		<topic>COOKING</topic>
+		<template>Pasta, obviously.</template>
+	</category>
+	<category>
+		<pattern>FAVOURITE</pattern>
+		<template>I have no favourite.</template>
+	</category>
+	<category>
+		<pattern>WHAT DO YOU LIKE</pattern>
+		<template><srai>FAVOURITE</srai></template>
+	</category>
+	<category>
+		<pattern>SWITCH AND ASK</pattern>
+		<template><think><set name="topic">COOKING</set></think><srai>FAVOURITE</srai></template>
+	</category>
+</aiml>`
+
+	engines := map[string]bool{"tree": false, "legacy": true}
+	for name, legacy := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if legacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("srai_topic")
+
+			response, err := g.ProcessInput("what do you like", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != "I have no favourite." {
+				t.Errorf("Without a topic: expected the global category, got '%s'", response)
+			}
+
+			session.SetSessionTopic("cooking")
+			response, err = g.ProcessInput("what do you like", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != "Pasta, obviously." {
+				t.Errorf("Under the topic: expected the topic category, got '%s'", response)
+			}
+
+			// A topic set earlier in the same template applies to the SRAI
+			session.SetSessionTopic("")
+			response, err = g.ProcessInput("switch and ask", session)
+			if err != nil {
+				t.Fatalf("ProcessInput failed: %v", err)
+			}
+			if response != "Pasta, obviously." {
+				t.Errorf("After setting the topic: expected the topic category, got '%s'", response)
+			}
+		})
+	}
+}
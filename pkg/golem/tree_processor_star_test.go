@@ -0,0 +1,71 @@
+package golem
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestStarTagWithoutWildcards tests that star tags with no corresponding capture
+// resolve to empty in both template engines instead of leaking into the output
+func TestStarTagWithoutWildcards(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>HELLO</pattern>
+		<template>Hello <star/>friend</template>
+	</category>
+	<category>
+		<pattern>GOOD MORNING</pattern>
+		<template>Morning[<star index="2"/>][<star></star>][<uppercase><star/></uppercase>]</template>
+	</category>
+	<category>
+		<pattern>CALL ME *</pattern>
+		<template>OK <star/>[<star index="2"/>][<star index="3"></star>]</template>
+	</category>
+	<category>
+		<pattern>TEACH GREETING</pattern>
+		<template><learn><category><pattern>GREET *</pattern><template>Greetings, <star/></template></category></learn>Learned</template>
+	</category>
+</aiml>`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"hello", "Hello friend"},
+		{"good morning", "Morning[][][]"},
+		{"call me Ishmael", "OK Ishmael[][]"},
+		{"teach greeting", "Learned"},
+		// The learned template keeps its <star/> for when it matches
+		{"greet Ada", "Greetings, Ada"},
+	}
+
+	engines := map[string]func(g *Golem){
+		"tree":         func(g *Golem) {},
+		"consolidated": func(g *Golem) { g.SetTemplateProcessor(g.GetConsolidatedProcessor()) },
+	}
+
+	for name, configure := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			configure(g)
+			if err := g.LoadAIMLFromString(aimlContent); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("star-without-wildcards-" + name)
+
+			for _, tt := range tests {
+				response, err := g.ProcessInput(tt.input, session)
+				if err != nil {
+					t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+				}
+				if strings.Contains(response, "<star") {
+					t.Errorf("ProcessInput(%q) leaked a star tag: %q", tt.input, response)
+				}
+				if response != tt.expected {
+					t.Errorf("ProcessInput(%q): expected %q, got %q", tt.input, tt.expected, response)
+				}
+			}
+		})
+	}
+}
@@ -84,6 +84,54 @@ func TestTreeProcessorSubstringTag(t *testing.T) {
 			input:    "UNICODE TEST",
 			expected: "Hel",
 		},
+		{
+			name: "Substring negative start",
+			aiml: `
+<aiml version="2.0">
+    <category>
+        <pattern>LAST FIVE</pattern>
+        <template><substring start="-5" end="100">Hello World</substring></template>
+    </category>
+</aiml>`,
+			input:    "LAST FIVE",
+			expected: "World",
+		},
+		{
+			name: "Substring negative end",
+			aiml: `
+<aiml version="2.0">
+    <category>
+        <pattern>DROP LAST</pattern>
+        <template><substring start="0" end="-6">Hello World</substring></template>
+    </category>
+</aiml>`,
+			input:    "DROP LAST",
+			expected: "Hello",
+		},
+		{
+			name: "Substring negative indices with multibyte characters",
+			aiml: `
+<aiml version="2.0">
+    <category>
+        <pattern>MULTIBYTE NEGATIVE</pattern>
+        <template><substring start="-4" end="-1">café 世界!</substring></template>
+    </category>
+</aiml>`,
+			input:    "MULTIBYTE NEGATIVE",
+			expected: " 世界",
+		},
+		{
+			name: "Substring negative start beyond length clamps",
+			aiml: `
+<aiml version="2.0">
+    <category>
+        <pattern>CLAMP NEGATIVE</pattern>
+        <template><substring start="-50" end="2">日本語</substring></template>
+    </category>
+</aiml>`,
+			input:    "CLAMP NEGATIVE",
+			expected: "日本",
+		},
 	}
 
 	for _, tt := range tests {
@@ -198,6 +246,67 @@ func TestTreeProcessorReplaceTag(t *testing.T) {
 	}
 }
 
+// TestReplaceTagRegexMode tests <replace mode="regex"> on both template engines
+func TestReplaceTagRegexMode(t *testing.T) {
+	aiml := `<aiml version="2.0">
+    <category>
+        <pattern>SWAP DATE</pattern>
+        <template><replace mode="regex" search="(\d+)-(\d+)-(\d+)" replace="$3/$2/$1">Due 2024-05-17</replace></template>
+    </category>
+    <category>
+        <pattern>REVERSE NAME</pattern>
+        <template><replace mode="regex" search="(\w+) (\w+)" replace="${2}, ${1}">Ada Lovelace</replace></template>
+    </category>
+    <category>
+        <pattern>SQUEEZE *</pattern>
+        <template><replace mode="regex" search="\s+" replace=" "><star/></replace></template>
+    </category>
+    <category>
+        <pattern>LITERAL DOTS</pattern>
+        <template><replace search="." replace="!">a.b</replace></template>
+    </category>
+    <category>
+        <pattern>BAD REGEX</pattern>
+        <template><replace mode="regex" search="(unclosed" replace="x">left (unclosed alone</replace></template>
+    </category>
+</aiml>`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"SWAP DATE", "Due 17/05/2024"},
+		{"REVERSE NAME", "Lovelace, Ada"},
+		{"SQUEEZE a  b   c", "a b c"},
+		{"LITERAL DOTS", "a!b"},
+		{"BAD REGEX", "left (unclosed alone"},
+	}
+
+	engines := map[string]func(g *Golem){
+		"tree":         func(g *Golem) {},
+		"consolidated": func(g *Golem) { g.SetTemplateProcessor(g.GetConsolidatedProcessor()) },
+	}
+	for name, configure := range engines {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			configure(g)
+			if err := g.LoadAIMLFromString(aiml); err != nil {
+				t.Fatalf("Failed to load AIML: %v", err)
+			}
+			session := g.CreateSession("regex-replace")
+			for _, tt := range tests {
+				response, err := g.ProcessInput(tt.input, session)
+				if err != nil {
+					t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+				}
+				if response != tt.expected {
+					t.Errorf("%s: expected '%s', got '%s'", tt.input, tt.expected, response)
+				}
+			}
+		})
+	}
+}
+
 // TestTreeProcessorLengthTag tests the native AST implementation of the <length> tag
 func TestTreeProcessorLengthTag(t *testing.T) {
 	tests := []struct {
@@ -290,6 +399,30 @@ func TestTreeProcessorLengthTag(t *testing.T) {
 			input:    "COUNT SENTENCES",
 			expected: "3",
 		},
+		{
+			name: "Length sentences with abbreviations",
+			aiml: `
+<aiml version="2.0">
+    <category>
+        <pattern>COUNT ABBREVIATED SENTENCES</pattern>
+        <template><length type="sentences">Dr. Smith arrived. He sat.</length></template>
+    </category>
+</aiml>`,
+			input:    "COUNT ABBREVIATED SENTENCES",
+			expected: "2",
+		},
+		{
+			name: "Length sentences with titles and companies",
+			aiml: `
+<aiml version="2.0">
+    <category>
+        <pattern>COUNT TITLED SENTENCES</pattern>
+        <template><length type="sentences">Mr. Jones met Mrs. Lee at 5 p.m. sharp. Then they left! Was it late? Prof. Brown thought so.</length></template>
+    </category>
+</aiml>`,
+			input:    "COUNT TITLED SENTENCES",
+			expected: "4",
+		},
 	}
 
 	for _, tt := range tests {
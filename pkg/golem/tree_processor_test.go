@@ -373,3 +373,70 @@ func TestASTNodeHelpers(t *testing.T) {
 		t.Errorf("Expected text content 'option1option2', got %q", textContent)
 	}
 }
+
+func TestTreeProcessorEmptyPairedTags(t *testing.T) {
+	forms := []struct {
+		name        string
+		selfClosing string
+		paired      []string
+	}{
+		{name: "that", selfClosing: "<that/>", paired: []string{"<that></that>", "<that> </that>", "<that>\n</that>"}},
+		{name: "star", selfClosing: "<star/>", paired: []string{"<star></star>", "<star> </star>", "<star><!-- x --></star>"}},
+		{name: "star with index", selfClosing: `<star index="2"/>`, paired: []string{`<star index="2"></star>`}},
+		{name: "sr", selfClosing: "<sr/>", paired: []string{"<sr></sr>", "<sr> </sr>"}},
+	}
+
+	respond := func(t *testing.T, tag string) string {
+		g := NewForTesting(t, false)
+		err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category><pattern>HI</pattern><template>Hello there</template></category>
+	<category><pattern>ASK</pattern><template>Do you like tea</template></category>
+	<category><pattern>* AND *</pattern><template>[` + tag + `]</template></category>
+</aiml>`)
+		if err != nil {
+			t.Fatalf("Failed to load AIML: %v", err)
+		}
+		session := g.CreateSession("empty-paired-tags")
+		if _, err := g.ProcessInput("ask", session); err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		response, err := g.ProcessInput("hi and bye", session)
+		if err != nil {
+			t.Fatalf("ProcessInput failed: %v", err)
+		}
+		return response
+	}
+
+	for _, form := range forms {
+		t.Run(form.name, func(t *testing.T) {
+			expected := respond(t, form.selfClosing)
+			if expected == "[]" {
+				t.Fatalf("Self-closing %s produced no output", form.selfClosing)
+			}
+			for _, paired := range form.paired {
+				if got := respond(t, paired); got != expected {
+					t.Errorf("%q: expected %q (as %s), got %q", paired, expected, form.selfClosing, got)
+				}
+			}
+		})
+	}
+
+	// In a category, an empty <that></that> means no that constraint, like <that/>
+	t.Run("category that", func(t *testing.T) {
+		for _, that := range []string{"<that/>", "<that></that>", "<that> </that>"} {
+			g := NewForTesting(t, false)
+			err := g.LoadAIMLFromString(`<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category><pattern>YES</pattern>` + that + `<template>Agreed</template></category>
+</aiml>`)
+			if err != nil {
+				t.Fatalf("%s: failed to load AIML: %v", that, err)
+			}
+			response, err := g.ProcessInput("yes", g.CreateSession("empty-paired-category-that"))
+			if err != nil || response != "Agreed" {
+				t.Errorf("%s: expected %q, got %q (err: %v)", that, "Agreed", response, err)
+			}
+		}
+	})
+}
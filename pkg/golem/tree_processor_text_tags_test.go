@@ -45,6 +45,16 @@ func TestTreeProcessorPersonTag(t *testing.T) {
 			template: "<person>I think you should take my advice</person>",
 			expected: "you think I should take your advice",
 		},
+		{
+			name:     "Trailing punctuation",
+			template: "<person>I'm here! Give it to me.</person>",
+			expected: "you're here! Give it to you.",
+		},
+		{
+			name:     "Embedded punctuation",
+			template: "<person>(my) book, your pen; mine?</person>",
+			expected: "(your) book, my pen; yours?",
+		},
 	}
 
 	for _, tt := range tests {
@@ -99,6 +109,16 @@ func TestTreeProcessorPerson2Tag(t *testing.T) {
 			template: "<person2>I think my idea is better</person2>",
 			expected: "they think their idea is better",
 		},
+		{
+			name:     "Trailing punctuation",
+			template: "<person2>I'm here! Give it to me.</person2>",
+			expected: "they're here! Give it to them.",
+		},
+		{
+			name:     "Embedded punctuation",
+			template: "<person2>(my) book, our pen; mine?</person2>",
+			expected: "(their) book, their pen; theirs?",
+		},
 	}
 
 	for _, tt := range tests {
@@ -158,6 +178,21 @@ func TestTreeProcessorGenderTag(t *testing.T) {
 			template: "<gender>he said his friend told him she would help</gender>",
 			expected: "she said her friend told her he would help",
 		},
+		{
+			name:     "Trailing punctuation",
+			template: "<gender>He's here! Ask him.</gender>",
+			expected: "She's here! Ask her.",
+		},
+		{
+			name:     "Trailing punctuation lowercase",
+			template: "<gender>he's here!</gender>",
+			expected: "she's here!",
+		},
+		{
+			name:     "Embedded punctuation",
+			template: "<gender>(he) said \"his\" name, her; him?</gender>",
+			expected: "(she) said \"her\" name, his; her?",
+		},
 	}
 
 	for _, tt := range tests {
@@ -434,3 +434,71 @@ func TestTreeProcessorThatTagEmptyHistory(t *testing.T) {
 		})
 	}
 }
+
+// TestTreeProcessorThatWildcardLocalVars tests that that-pattern wildcards are exposed to templates
+func TestTreeProcessorThatWildcardLocalVars(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>PICK A COLOR</pattern>
+		<template>I LIKE RED CARS</template>
+	</category>
+
+	<category>
+		<pattern>WHY</pattern>
+		<that>I LIKE * CARS</that>
+		<template>Because <that_star1/> cars are fast</template>
+	</category>
+
+	<category>
+		<pattern>REALLY</pattern>
+		<that>I LIKE * CARS</that>
+		<template><think><set var="color"><that_star index="1"/></set></think>Yes, <get var="color"/> ones</template>
+	</category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	tests := []struct {
+		followUp string
+		expected string
+	}{
+		{followUp: "why", expected: "Because RED cars are fast"},
+		{followUp: "really", expected: "Yes, RED ones"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.followUp, func(t *testing.T) {
+			session := g.CreateSession("that-wildcards-" + tt.followUp)
+
+			if _, err := g.ProcessInput("pick a color", session); err != nil {
+				t.Fatalf("Failed to process first input: %v", err)
+			}
+
+			response, err := g.ProcessInput(tt.followUp, session)
+			if err != nil {
+				t.Fatalf("Failed to process '%s': %v", tt.followUp, err)
+			}
+			if !strings.EqualFold(response, tt.expected) {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, response)
+			}
+		})
+	}
+
+	// Without a session the value must still be reachable through local variables
+	ctx := &VariableContext{KnowledgeBase: g.aimlKB}
+	result := g.processTemplateWithContext("Color: <that_star1/>", map[string]string{"that_star1": "BLUE"}, ctx)
+	if result != "Color: BLUE" {
+		t.Errorf("Expected 'Color: BLUE', got '%s'", result)
+	}
+	if ctx.LocalVars["that_star1"] != "BLUE" {
+		t.Errorf("Expected that_star1 local var to be 'BLUE', got '%s'", ctx.LocalVars["that_star1"])
+	}
+	if result := g.processThatWildcardTagsWithContext("Color: <that_star1/>", ctx); result != "Color: BLUE" {
+		t.Errorf("Expected 'Color: BLUE' from regex path, got '%s'", result)
+	}
+}
@@ -1,6 +1,7 @@
 package golem
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -337,6 +338,61 @@ func TestThinkTagWithCollections(t *testing.T) {
 	}
 }
 
+// TestThinkTagClearsCollections tests clearing sets, lists and arrays inside <think>
+func TestThinkTagClearsCollections(t *testing.T) {
+	collections := []struct {
+		name  string
+		add   string
+		clear string
+		size  string
+	}{
+		{
+			name:  "set",
+			add:   `<set name="fruits" operation="add">apple</set><set name="fruits" operation="add">pear</set>`,
+			clear: `<think><set name="fruits" operation="clear"></set></think>`,
+			size:  `<set name="fruits" operation="size"></set>`,
+		},
+		{
+			name:  "list",
+			add:   `<list name="todo" operation="add">shop</list><list name="todo" operation="add">cook</list>`,
+			clear: `<think><list name="todo" operation="clear"></list></think>`,
+			size:  `<list name="todo" operation="size"></list>`,
+		},
+		{
+			name:  "array",
+			add:   `<array name="scores" index="0" operation="set">1</array><array name="scores" index="1" operation="set">2</array>`,
+			clear: `<think><array name="scores" operation="clear"></array></think>`,
+			size:  `<array name="scores" operation="size"></array>`,
+		},
+	}
+
+	// Both the default tree processor and the consolidated pipeline must handle them
+	for _, consolidated := range []bool{false, true} {
+		for _, tt := range collections {
+			t.Run(fmt.Sprintf("%s/consolidated=%v", tt.name, consolidated), func(t *testing.T) {
+				g := NewForTesting(t, false)
+				if consolidated {
+					g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+				}
+				g.SetKnowledgeBase(NewAIMLKnowledgeBase())
+				session := g.CreateSession("test_think_clear_" + tt.name)
+
+				g.ProcessTemplateWithContext(tt.add, nil, session)
+				if size := g.ProcessTemplateWithContext(tt.size, nil, session); size != "2" {
+					t.Fatalf("Expected 2 items before clearing, got '%s'", size)
+				}
+
+				if result := g.ProcessTemplateWithContext("Done."+tt.clear, nil, session); result != "Done." {
+					t.Errorf("Expected think to produce no output, got '%s'", result)
+				}
+				if size := g.ProcessTemplateWithContext(tt.size, nil, session); size != "0" {
+					t.Errorf("Expected %s to be empty after clearing in think, got size '%s'", tt.name, size)
+				}
+			})
+		}
+	}
+}
+
 // TestThinkTagWithDateTime tests <think> with date and time tags
 func TestThinkTagWithDateTime(t *testing.T) {
 	g := NewForTesting(t, false)
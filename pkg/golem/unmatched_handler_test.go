@@ -0,0 +1,62 @@
+package golem
+
+import (
+	"testing"
+)
+
+func TestSetUnmatchedHandler(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+	<category>
+		<pattern>HELLO</pattern>
+		<template>Hi there</template>
+	</category>
+</aiml>`
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("unmatched-test")
+
+	// No handler registered: unmatched input just returns an error
+	if _, err := g.ProcessInput("what is this", session); err == nil {
+		t.Fatal("Expected an error for unmatched input")
+	}
+
+	var unmatched []string
+	g.SetUnmatchedHandler(func(input string, s *ChatSession) {
+		if s != session {
+			t.Errorf("Expected handler to receive the processing session")
+		}
+		unmatched = append(unmatched, input)
+	})
+
+	if response, err := g.ProcessInput("hello", session); err != nil || response != "Hi there" {
+		t.Fatalf("Expected matched response, got %q (err: %v)", response, err)
+	}
+	if len(unmatched) != 0 {
+		t.Errorf("Handler should not fire for matched input, got %v", unmatched)
+	}
+
+	if _, err := g.ProcessInput("tell me a joke", session); err == nil {
+		t.Error("Expected an error for unmatched input")
+	}
+	if _, err := g.ProcessInputWithThatIndex("sing a song", session, 1); err == nil {
+		t.Error("Expected an error for unmatched input")
+	}
+	if len(unmatched) != 2 || unmatched[0] != "tell me a joke" || unmatched[1] != "sing a song" {
+		t.Errorf("Expected handler to record both unmatched inputs, got %v", unmatched)
+	}
+
+	// Removing the handler stops notifications
+	g.SetUnmatchedHandler(nil)
+	if _, err := g.ProcessInput("still unknown", session); err == nil {
+		t.Error("Expected an error for unmatched input")
+	}
+	if len(unmatched) != 2 {
+		t.Errorf("Expected no notifications after removing handler, got %v", unmatched)
+	}
+}
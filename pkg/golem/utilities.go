@@ -3,8 +3,6 @@ package golem
 import (
 	"fmt"
 	"math/rand"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,54 +10,14 @@ import (
 	"unicode"
 )
 
-// GetVersion reads the version string from the VERSION file
-// Returns "1.0.0" as default if the file cannot be read
-func GetVersion() string {
-	// Try to find the VERSION file starting from the current directory and walking up
-	versionFile := findVersionFile()
-	if versionFile == "" {
-		return "1.0.0" // Default fallback
-	}
-
-	content, err := os.ReadFile(versionFile)
-	if err != nil {
-		return "1.0.0" // Default fallback if read fails
-	}
-
-	version := strings.TrimSpace(string(content))
-	if version == "" {
-		return "1.0.0" // Default if file is empty
-	}
-
-	return version
-}
+// Version is the library version. Builds set it from the VERSION file with
+// -ldflags "-X github.com/helix90/my-golem/pkg/golem.Version=..." (see the Makefile);
+// other builds report "dev".
+var Version = "dev"
 
-// findVersionFile searches for the VERSION file starting from the current directory
-// and walking up to the project root
-func findVersionFile() string {
-	// Start from current directory and walk up
-	currentDir, err := os.Getwd()
-	if err != nil {
-		return ""
-	}
-
-	// Try up to 5 levels up from current directory
-	for i := 0; i < 5; i++ {
-		versionPath := filepath.Join(currentDir, "VERSION")
-		if _, err := os.Stat(versionPath); err == nil {
-			return versionPath
-		}
-
-		// Go up one directory
-		parentDir := filepath.Dir(currentDir)
-		if parentDir == currentDir {
-			// Reached root, stop
-			break
-		}
-		currentDir = parentDir
-	}
-
-	return ""
+// GetVersion returns the library version
+func GetVersion() string {
+	return Version
 }
 
 // Utilities provides general utility functions
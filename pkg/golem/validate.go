@@ -0,0 +1,151 @@
+package golem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileValidationResult lists the problems found in a single AIML file
+type FileValidationResult struct {
+	File     string
+	Errors   []string
+	Warnings []string
+}
+
+// ValidateAIMLDirectory parses and validates every AIML file under dirPath without
+// loading it into the knowledge base. Each file is checked with validateAIML and every
+// template with validateTemplateBalance. Categories that collide with an earlier
+// category are reported as warnings, or as errors when StrictParsing is enabled, and
+// so are AIML 2.0 features in AIML 1.x documents, with StrictVersion, and <that index>
+// values beyond the retained that history (see MaxThatDepth).
+func (g *Golem) ValidateAIMLDirectory(dirPath string) ([]FileValidationResult, error) {
+	var aimlFiles []string
+	err := filepath.WalkDir(dirPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && hasResourceExtension(path, ".aiml") {
+			aimlFiles = append(aimlFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %v", dirPath, err)
+	}
+	if len(aimlFiles) == 0 {
+		return nil, fmt.Errorf("no AIML files found in directory: %s", dirPath)
+	}
+
+	// Category keys seen so far, mapped to where they were defined
+	seen := make(map[string]string)
+
+	results := make([]FileValidationResult, 0, len(aimlFiles))
+	for _, aimlFile := range aimlFiles {
+		result := FileValidationResult{File: aimlFile}
+
+		content, err := readResourceFile(aimlFile)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to read file: %v", err))
+			results = append(results, result)
+			continue
+		}
+
+		aiml, err := g.parseAIML(string(content))
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			results = append(results, result)
+			continue
+		}
+
+		if err := g.validateAIML(aiml); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		// With StrictVersion and StrictParsing these already failed parsing
+		result.Warnings = append(result.Warnings, versionFeatureWarnings(aiml)...)
+		result.Warnings = append(result.Warnings, thatIndexDepthWarnings(aiml, g.maxThatDepth())...)
+
+		for i := range aiml.Categories {
+			category := &aiml.Categories[i]
+			location := fmt.Sprintf("%s, %s", aimlFile, category.Source)
+
+			if err := g.validateTemplateBalance(category.Template); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: pattern '%s': %v", category.Source, category.Pattern, err))
+			}
+
+			key := categoryPatternKey(category)
+			if previous, exists := seen[key]; exists {
+				message := fmt.Sprintf("ambiguous categories for pattern '%s' (that='%s', topic='%s'): %s is overridden by %s",
+					category.Pattern, category.That, category.Topic, previous, location)
+				if g.StrictParsing {
+					result.Errors = append(result.Errors, message)
+				} else {
+					result.Warnings = append(result.Warnings, message)
+				}
+			}
+			seen[key] = location
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// validateCommand handles "validate <dir> [--strict] [--strict-version]", printing the errors and
+// warnings for each AIML file and failing if any errors were found
+func (g *Golem) validateCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("validate command requires a directory")
+	}
+
+	// The options only apply to this validation run
+	defer func(strictParsing, strictVersion bool) {
+		g.StrictParsing, g.StrictVersion = strictParsing, strictVersion
+	}(g.StrictParsing, g.StrictVersion)
+
+	dirPath := args[0]
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--strict":
+			g.StrictParsing = true
+		case "--strict-version":
+			g.StrictVersion = true
+		default:
+			return fmt.Errorf("unknown validate option: %s", arg)
+		}
+	}
+
+	results, err := g.ValidateAIMLDirectory(dirPath)
+	if err != nil {
+		return err
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, result := range results {
+		errorCount += len(result.Errors)
+		warningCount += len(result.Warnings)
+
+		if len(result.Errors) == 0 && len(result.Warnings) == 0 {
+			fmt.Printf("OK      %s\n", result.File)
+			continue
+		}
+		status := "WARN"
+		if len(result.Errors) > 0 {
+			status = "FAIL"
+		}
+		fmt.Printf("%-7s %s (%d errors, %d warnings)\n", status, result.File, len(result.Errors), len(result.Warnings))
+		for _, message := range result.Errors {
+			fmt.Printf("  error: %s\n", message)
+		}
+		for _, message := range result.Warnings {
+			fmt.Printf("  warning: %s\n", message)
+		}
+	}
+
+	fmt.Printf("Validated %d files: %d errors, %d warnings\n", len(results), errorCount, warningCount)
+	if errorCount > 0 {
+		return fmt.Errorf("validation failed with %d errors", errorCount)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package golem
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateTemplateBalance(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"Hello <star/>!", ""},
+		{"<think><set name=\"x\"><star/></set></think>Done", ""},
+		{"<condition name=\"x\"><li value=\"a\">A</li><li>B</li></condition>", ""},
+		{"<uppercase>hello", "unclosed tag <uppercase>"},
+		{"hello</uppercase>", "unexpected closing tag </uppercase>"},
+		{"<think><set name=\"x\">y</think></set>", "mismatched closing tag </think>, expected </set>"},
+	}
+
+	for _, test := range tests {
+		err := g.validateTemplateBalance(test.template)
+		if test.expected == "" {
+			if err != nil {
+				t.Errorf("validateTemplateBalance(%q) returned unexpected error: %v", test.template, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("validateTemplateBalance(%q) = %v, expected %q", test.template, err, test.expected)
+		}
+	}
+}
+
+func TestValidateAIMLDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"good.aiml": `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>HELLO</pattern>
+        <template>Hi <get name="name"/>!</template>
+    </category>
+</aiml>`,
+		"unbalanced.aiml": `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>SHOUT *</pattern>
+        <template><uppercase><star/></template>
+    </category>
+</aiml>`,
+		"badpattern.aiml": `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern></pattern>
+        <template>Empty</template>
+    </category>
+</aiml>`,
+		"notes.txt": "not AIML",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	g := NewForTesting(t, false)
+	results, err := g.ValidateAIMLDirectory(dir)
+	if err != nil {
+		t.Fatalf("ValidateAIMLDirectory failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 AIML files to be validated, got %d", len(results))
+	}
+
+	byFile := make(map[string]FileValidationResult)
+	for _, result := range results {
+		byFile[filepath.Base(result.File)] = result
+	}
+
+	if good := byFile["good.aiml"]; len(good.Errors) != 0 || len(good.Warnings) != 0 {
+		t.Errorf("Expected good.aiml to be clean, got %+v", good)
+	}
+	if unbalanced := byFile["unbalanced.aiml"]; len(unbalanced.Errors) != 1 || !strings.Contains(unbalanced.Errors[0], "unclosed tag <uppercase>") {
+		t.Errorf("Expected an unclosed tag error for unbalanced.aiml, got %+v", unbalanced)
+	}
+	if badPattern := byFile["badpattern.aiml"]; len(badPattern.Errors) == 0 || !strings.Contains(badPattern.Errors[0], "pattern cannot be empty") {
+		t.Errorf("Expected an empty pattern error for badpattern.aiml, got %+v", badPattern)
+	}
+
+	// The command fails when any file has errors
+	if err := g.Execute("validate", []string{dir}); err == nil || !strings.Contains(err.Error(), "validation failed") {
+		t.Errorf("Expected validate command to fail, got: %v", err)
+	}
+	if err := g.Execute("validate", []string{}); err == nil {
+		t.Error("Expected an error when no directory is given")
+	}
+}
+
+func TestValidateCommandStrict(t *testing.T) {
+	dir := t.TempDir()
+	category := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>HELLO</pattern>
+        <template>Hi.</template>
+    </category>
+</aiml>`
+	for _, name := range []string{"a.aiml", "b.aiml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(category), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	g := NewForTesting(t, false)
+	results, err := g.ValidateAIMLDirectory(dir)
+	if err != nil {
+		t.Fatalf("ValidateAIMLDirectory failed: %v", err)
+	}
+	if len(results[1].Warnings) != 1 || !strings.Contains(results[1].Warnings[0], "ambiguous categories for pattern 'HELLO'") {
+		t.Errorf("Expected a collision warning for b.aiml, got %+v", results[1])
+	}
+
+	// Warnings alone do not fail validation
+	if err := g.Execute("validate", []string{dir}); err != nil {
+		t.Errorf("Expected validation with only warnings to pass, got: %v", err)
+	}
+
+	// With --strict, collisions are errors
+	strict := NewForTesting(t, false)
+	if err := strict.Execute("validate", []string{dir, "--strict"}); err == nil {
+		t.Error("Expected strict validation to fail on colliding categories")
+	}
+
+	// --strict only applies to that run
+	if strict.StrictParsing {
+		t.Error("Expected --strict not to outlive the validate command")
+	}
+	if err := strict.Execute("validate", []string{dir}); err != nil {
+		t.Errorf("Expected a later validation without --strict to pass, got: %v", err)
+	}
+}
+
+func TestThatIndexDepthWarning(t *testing.T) {
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>YES</pattern>
+        <that index="2">DO YOU LIKE TEA</that>
+        <template>Reachable.</template>
+    </category>
+    <category>
+        <pattern>NO</pattern>
+        <that index="5">DO YOU LIKE TEA</that>
+        <template>Unreachable.</template>
+    </category>
+</aiml>`
+
+	var logs bytes.Buffer
+	g := NewForTesting(t, false)
+	g.logger = log.New(&logs, "", 0)
+	g.SetLogLevel(LogLevelWarn)
+	g.MaxThatDepth = 3
+
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	if strings.Count(logs.String(), "can never match") != 1 || !strings.Contains(logs.String(), "pattern 'NO': that index 5 can never match, sessions retain only 3 responses") {
+		t.Errorf("Expected one warning for the unreachable that index, got logs: %s", logs.String())
+	}
+	if depth := g.CreateSession("depth").ContextConfig.MaxThatDepth; depth != 3 {
+		t.Errorf("Expected new sessions to retain 3 responses, got %d", depth)
+	}
+
+	// The default depth retains every index the parser accepts
+	logs.Reset()
+	unlimited := NewForTesting(t, false)
+	unlimited.logger = log.New(&logs, "", 0)
+	unlimited.SetLogLevel(LogLevelWarn)
+	if err := unlimited.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+	if strings.Contains(logs.String(), "can never match") {
+		t.Errorf("Expected no warning with the default depth, got logs: %s", logs.String())
+	}
+
+	// The validate command reports it too
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "that.aiml"), []byte(aimlContent), 0644); err != nil {
+		t.Fatalf("Failed to write AIML: %v", err)
+	}
+	results, err := g.ValidateAIMLDirectory(dir)
+	if err != nil {
+		t.Fatalf("ValidateAIMLDirectory failed: %v", err)
+	}
+	if len(results[0].Warnings) != 1 || !strings.Contains(results[0].Warnings[0], "that index 5 can never match") {
+		t.Errorf("Expected an unreachable that index warning, got %+v", results[0])
+	}
+
+	// With StrictParsing it is a load error
+	strict := NewForTesting(t, false)
+	strict.MaxThatDepth = 3
+	strict.StrictParsing = true
+	if err := strict.LoadAIMLFromString(aimlContent); err == nil || !strings.Contains(err.Error(), "that index 5") {
+		t.Errorf("Expected a strict load error, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package golem
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -430,3 +431,159 @@ func TestVariableContextInComplexTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestVariableNamespaces(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>I AM * FROM *</pattern>
+        <template><think><set name="user.name"><star/></set><set name="user.city"><star index="2"/></set><set name="username">unrelated</set></think>Hello <get name="user.name"/> from <get name="user.city"/></template>
+    </category>
+    <category>
+        <pattern>WHO AM I</pattern>
+        <template>You are <get name="user.name"/></template>
+    </category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("namespace_test")
+	response, err := g.ProcessInput("I am Alice from Paris", session)
+	if err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if response != "Hello Alice from Paris" {
+		t.Errorf("Expected dotted variables to round-trip, got '%s'", response)
+	}
+	if response, _ := g.ProcessInput("who am i", session); response != "You are Alice" {
+		t.Errorf("Expected dotted variable in later turn, got '%s'", response)
+	}
+
+	user := session.GetNamespace("user")
+	if len(user) != 2 || user["name"] != "Alice" || user["city"] != "Paris" {
+		t.Errorf("Expected user namespace {name: Alice, city: Paris}, got %v", user)
+	}
+	if withDot := session.GetNamespace("user."); len(withDot) != 2 {
+		t.Errorf("Expected trailing dot prefix to behave the same, got %v", withDot)
+	}
+	if empty := session.GetNamespace("account"); len(empty) != 0 {
+		t.Errorf("Expected empty namespace, got %v", empty)
+	}
+
+	// Global variables support the same lookup
+	g.aimlKB.Variables["bot.mood"] = "cheerful"
+	g.aimlKB.Variables["bot.age"] = "3"
+	if bot := g.aimlKB.GetNamespace("bot"); len(bot) != 2 || bot["mood"] != "cheerful" {
+		t.Errorf("Expected bot namespace from global variables, got %v", bot)
+	}
+}
+
+func TestSessionVariableSnapshot(t *testing.T) {
+	g := NewForTesting(t, false)
+
+	aimlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+    <category>
+        <pattern>MY NAME IS *</pattern>
+        <template><think><set name="name"><star/></set></think>Hello <get name="name"/></template>
+    </category>
+    <category>
+        <pattern>WHO AM I</pattern>
+        <template>You are <get name="name"/></template>
+    </category>
+</aiml>`
+	if err := g.LoadAIMLFromString(aimlContent); err != nil {
+		t.Fatalf("Failed to load AIML: %v", err)
+	}
+
+	session := g.CreateSession("snapshot")
+	if _, err := g.ProcessInput("my name is Alice", session); err != nil {
+		t.Fatalf("Error processing input: %v", err)
+	}
+
+	snapshot := session.SnapshotVariables()
+	if snapshot["name"] != "Alice" {
+		t.Fatalf("Expected the snapshot to hold name=Alice, got %v", snapshot)
+	}
+
+	// The snapshot is a copy in both directions
+	snapshot["name"] = "Mallory"
+	if _, err := g.ProcessInput("my name is Bob", session); err != nil {
+		t.Fatalf("Error processing input: %v", err)
+	}
+	if snapshot["name"] != "Mallory" {
+		t.Errorf("Expected later turns not to change the snapshot, got %v", snapshot)
+	}
+
+	// Restoring round-trips into a fresh session
+	snapshot["name"] = "Alice"
+	restored := g.CreateSession("restored")
+	restored.RestoreVariables(snapshot)
+	snapshot["name"] = "Mallory"
+	response, err := g.ProcessInput("who am i", restored)
+	if err != nil {
+		t.Fatalf("Error processing input: %v", err)
+	}
+	if response != "You are Alice" {
+		t.Errorf("Expected 'You are Alice', got '%s'", response)
+	}
+
+	restored.RestoreVariables(nil)
+	if len(restored.SnapshotVariables()) != 0 {
+		t.Errorf("Expected restoring nil to clear the variables, got %v", restored.Variables)
+	}
+	if _, err := g.ProcessInput("my name is Carol", restored); err != nil {
+		t.Fatalf("Error processing input after clearing: %v", err)
+	}
+}
+
+// TestGetPropertyFallback tests that <get> falls back to bot properties unless
+// DisablePropertyFallback is set, while <bot> always reads them
+func TestGetPropertyFallback(t *testing.T) {
+	for name, useLegacy := range map[string]bool{"tree": false, "legacy": true} {
+		t.Run(name, func(t *testing.T) {
+			g := NewForTesting(t, false)
+			if useLegacy {
+				g.SetTemplateProcessor(g.GetConsolidatedProcessor())
+			}
+			kb := NewAIMLKnowledgeBase()
+			kb.Properties["name"] = "Golem"
+			g.SetKnowledgeBase(kb)
+			session := g.CreateSession("property_fallback_" + name)
+			template := `[<get name="name"/>] [<bot name="name"/>]`
+
+			// By default an unset variable falls back to the property
+			if g.GetTemplateProcessingConfig().DisablePropertyFallback {
+				t.Fatal("Expected the property fallback to be enabled by default")
+			}
+			if response := g.ProcessTemplateWithContext(template, map[string]string{}, session); response != "[Golem] [Golem]" {
+				t.Errorf("Expected <get> to fall back to the property, got '%s'", response)
+			}
+
+			// A config built from scratch keeps the fallback
+			g.UpdateTemplateProcessingConfig(&TemplateProcessingConfig{EnableCaching: false, CacheSize: 1000, CacheTTL: 3600})
+			if response := g.ProcessTemplateWithContext(template, map[string]string{}, session); response != "[Golem] [Golem]" {
+				t.Errorf("Expected <get> to fall back to the property with a new config, got '%s'", response)
+			}
+
+			config := g.GetTemplateProcessingConfig()
+			config.DisablePropertyFallback = true
+			g.UpdateTemplateProcessingConfig(config)
+
+			// Without the fallback <get> doesn't see the property, only <bot> does
+			response := g.ProcessTemplateWithContext(template, map[string]string{}, session)
+			if strings.Contains(response, "[Golem] [") || !strings.HasSuffix(response, "[Golem]") {
+				t.Errorf("Expected only <bot> to read the property, got '%s'", response)
+			}
+
+			// A variable that is set is read either way
+			session.Variables["name"] = "Ann"
+			if response := g.ProcessTemplateWithContext(template, map[string]string{}, session); response != "[Ann] [Golem]" {
+				t.Errorf("Expected the user variable and the bot property, got '%s'", response)
+			}
+		})
+	}
+}